@@ -165,14 +165,27 @@ var (
 		Destination: &options.ExtIP,
 		EnvVars:     []string{"WAKUNODE2_EXT_IP"},
 	})
+	ENRFallbackIP = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:        "enr-fallback-ip",
+		Usage:       "IP address written in the ENR when the external address cannot be determined. By default no IP is written",
+		Value:       "",
+		Destination: &options.ENRFallbackIP,
+		EnvVars:     []string{"WAKUNODE2_ENR_FALLBACK_IP"},
+	})
 	ExtMultiaddresses = cliutils.NewGenericFlagMultiValue(&cli.GenericFlag{
 		Name:  "ext-multiaddr",
-		Usage: "External address to advertise to other nodes. Overrides --address and --ws-address flags. Option may be repeated",
+		Usage: "External address to advertise to other nodes. Overrides --address and --ws-address flags. Option may be repeated, in order of priority",
 		Value: &cliutils.MultiaddrSlice{
 			Values: &options.AdvertiseAddresses,
 		},
 		EnvVars: []string{"WAKUNODE2_EXT_MULTIADDR"},
 	})
+	ExtMultiaddrAllowPrivate = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:        "ext-multiaddr-allow-private",
+		Usage:       "Accept loopback, link-local and private IP addresses in --ext-multiaddr. Meant for test setups and private networks",
+		Destination: &options.AdvertisePrivateIPs,
+		EnvVars:     []string{"WAKUNODE2_EXT_MULTIADDR_ALLOW_PRIVATE"},
+	})
 	ShowAddresses = altsrc.NewBoolFlag(&cli.BoolFlag{
 		Name:        "show-addresses",
 		Usage:       "Display listening addresses according to current configuration",
@@ -329,6 +342,33 @@ var (
 		Destination: &options.Store.RetentionMaxMessages,
 		EnvVars:     []string{"WAKUNODE2_STORE_MESSAGE_RETENTION_CAPACITY"},
 	})
+	StoreMaxMessageAge = altsrc.NewDurationFlag(&cli.DurationFlag{
+		Name:        "store-max-message-age",
+		Value:       0,
+		Usage:       "maximum age of a message timestamp for it to be stored. Set to 0 to disable it",
+		Destination: &options.Store.MaxMessageAge,
+		EnvVars:     []string{"WAKUNODE2_STORE_MAX_MESSAGE_AGE"},
+	})
+	StoreMaxFutureDrift = altsrc.NewDurationFlag(&cli.DurationFlag{
+		Name:        "store-max-future-drift",
+		Value:       0,
+		Usage:       "maximum duration a message timestamp can be ahead of the current time for it to be stored. Set to 0 to disable it",
+		Destination: &options.Store.MaxFutureDrift,
+		EnvVars:     []string{"WAKUNODE2_STORE_MAX_FUTURE_DRIFT"},
+	})
+	StoreRejectRelay = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:        "store-reject-relay",
+		Usage:       "Do not relay messages whose timestamp is outside of the window accepted by the store",
+		Destination: &options.Store.RejectRelay,
+		EnvVars:     []string{"WAKUNODE2_STORE_REJECT_RELAY"},
+	})
+	StoreMaxQueryTimeRange = altsrc.NewDurationFlag(&cli.DurationFlag{
+		Name:        "store-max-query-time-range",
+		Value:       0,
+		Usage:       "maximum time range of a store query. Wider queries are rejected. Set to 0 to disable it",
+		Destination: &options.Store.MaxQueryTimeRange,
+		EnvVars:     []string{"WAKUNODE2_STORE_MAX_QUERY_TIME_RANGE"},
+	})
 	StoreMessageDBURL = altsrc.NewStringFlag(&cli.StringFlag{
 		Name:        "store-message-db-url",
 		Usage:       "The database connection URL for persistent storage.",
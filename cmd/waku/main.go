@@ -42,7 +42,9 @@ func main() {
 		PersistPeers,
 		NAT,
 		IPAddress,
+		ENRFallbackIP,
 		ExtMultiaddresses,
+		ExtMultiaddrAllowPrivate,
 		ShowAddresses,
 		CircuitRelay,
 		ForceReachability,
@@ -66,6 +68,10 @@ func main() {
 		StoreMessageDBURL,
 		StoreMessageRetentionTime,
 		StoreMessageRetentionCapacity,
+		StoreMaxMessageAge,
+		StoreMaxFutureDrift,
+		StoreRejectRelay,
+		StoreMaxQueryTimeRange,
 		StoreMessageDBMigration,
 		FilterFlag,
 		FilterNode,
@@ -142,6 +142,9 @@ func Execute(options NodeOptions) error {
 	}
 	if len(options.AdvertiseAddresses) != 0 {
 		nodeOpts = append(nodeOpts, node.WithAdvertiseAddresses(options.AdvertiseAddresses...))
+		if options.AdvertisePrivateIPs {
+			nodeOpts = append(nodeOpts, node.WithNonRoutableAdvertiseAddresses())
+		}
 	}
 
 	if options.ExtIP != "" {
@@ -153,6 +156,15 @@ func Execute(options NodeOptions) error {
 		nodeOpts = append(nodeOpts, node.WithExternalIP(ip))
 	}
 
+	if options.ENRFallbackIP != "" {
+		ip := net.ParseIP(options.ENRFallbackIP)
+		if ip == nil {
+			return nonRecoverErrorMsg("could not set enr fallback IP address: invalid IP")
+		}
+
+		nodeOpts = append(nodeOpts, node.WithENRFallbackIP(ip))
+	}
+
 	if options.DNS4DomainName != "" {
 		nodeOpts = append(nodeOpts, node.WithDNS4Domain(options.DNS4DomainName))
 	}
@@ -256,6 +268,7 @@ func Execute(options NodeOptions) error {
 		dbOptions := []persistence.DBOption{
 			persistence.WithDB(db),
 			persistence.WithRetentionPolicy(options.Store.RetentionMaxMessages, options.Store.RetentionTime),
+			persistence.WithMessageAgeBounds(options.Store.MaxMessageAge, options.Store.MaxFutureDrift),
 		}
 
 		if options.Store.Migration {
@@ -271,7 +284,7 @@ func Execute(options NodeOptions) error {
 	}
 
 	if options.Store.Enable {
-		nodeOpts = append(nodeOpts, node.WithWakuStore())
+		nodeOpts = append(nodeOpts, node.WithWakuStore(legacy_store.WithMaxQueryTimeRange(options.Store.MaxQueryTimeRange)))
 		nodeOpts = append(nodeOpts, node.WithMessageProvider(dbStore))
 	}
 
@@ -340,6 +353,10 @@ func Execute(options NodeOptions) error {
 		return nonRecoverError(err)
 	}
 
+	if options.Relay.Enable && options.Store.RejectRelay && dbStore != nil {
+		wakuNode.Relay().RegisterDefaultValidator(dbStore.RelayValidator())
+	}
+
 	for _, d := range discoveredNodes {
 		wakuNode.AddDiscoveredPeer(d.PeerID, d.PeerInfo.Addrs, wakupeerstore.DNSDiscovery, nil, d.ENR, true)
 	}
@@ -77,6 +77,10 @@ type StoreOptions struct {
 	DatabaseURL          string
 	RetentionTime        time.Duration
 	RetentionMaxMessages int
+	MaxMessageAge        time.Duration
+	MaxFutureDrift       time.Duration
+	RejectRelay          bool
+	MaxQueryTimeRange    time.Duration
 	//ResumeNodes          []multiaddr.Multiaddr
 	Nodes     []multiaddr.Multiaddr
 	Migration bool
@@ -146,6 +150,7 @@ type NodeOptions struct {
 	StaticNodes                  []multiaddr.Multiaddr
 	KeepAlive                    time.Duration
 	AdvertiseAddresses           []multiaddr.Multiaddr
+	AdvertisePrivateIPs          bool
 	ShowAddresses                bool
 	CircuitRelay                 bool
 	ForceReachability            string
@@ -156,6 +161,7 @@ type NodeOptions struct {
 	LogOutput                    string
 	NAT                          string
 	ExtIP                        string
+	ENRFallbackIP                string
 	PersistPeers                 bool
 	UserAgent                    string
 	PProf                        bool
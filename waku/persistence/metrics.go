@@ -33,9 +33,25 @@ var archiveQueryDurationSeconds = prometheus.NewHistogram(
 		Help: "History query duration",
 	})
 
+var archiveRejectedMessages = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "waku_archive_rejected_messages",
+		Help: "The number of messages not stored because their timestamp is outside of the accepted window",
+	},
+	[]string{"reason"},
+)
+
+var archiveDuplicateMessages = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "waku_archive_duplicate_messages",
+		Help: "The number of messages not stored because they were already in the archive",
+	})
+
 var collectors = []prometheus.Collector{
 	archiveMessages,
+	archiveDuplicateMessages,
 	archiveErrors,
+	archiveRejectedMessages,
 	archiveInsertDurationSeconds,
 	archiveQueryDurationSeconds,
 }
@@ -44,6 +60,8 @@ var collectors = []prometheus.Collector{
 type Metrics interface {
 	RecordMessage(num int)
 	RecordError(err metricsErrCategory)
+	RecordRejectedMessage(reason rejectionReason)
+	RecordDuplicateMessage()
 	RecordInsertDuration(duration time.Duration)
 	RecordQueryDuration(duration time.Duration)
 }
@@ -76,6 +94,18 @@ func (m *metricsImpl) RecordError(err metricsErrCategory) {
 	archiveErrors.WithLabelValues(string(err)).Inc()
 }
 
+type rejectionReason string
+
+var (
+	futureMessage rejectionReason = "future_message"
+	messageTooOld rejectionReason = "message_too_old"
+)
+
+// RecordRejectedMessage increases the counter of messages rejected due to their timestamp
+func (m *metricsImpl) RecordRejectedMessage(reason rejectionReason) {
+	archiveRejectedMessages.WithLabelValues(string(reason)).Inc()
+}
+
 // RecordInsertDuration tracks the duration for inserting a record in the archive database
 func (m *metricsImpl) RecordInsertDuration(duration time.Duration) {
 	archiveInsertDurationSeconds.Observe(duration.Seconds())
@@ -85,3 +115,8 @@ func (m *metricsImpl) RecordInsertDuration(duration time.Duration) {
 func (m *metricsImpl) RecordQueryDuration(duration time.Duration) {
 	archiveQueryDurationSeconds.Observe(duration.Seconds())
 }
+
+// RecordDuplicateMessage increases the counter for the number of duplicate messages not stored in the archive
+func (m *metricsImpl) RecordDuplicateMessage() {
+	archiveDuplicateMessages.Inc()
+}
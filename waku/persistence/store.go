@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -34,10 +35,13 @@ type MessageProvider interface {
 var ErrInvalidCursor = errors.New("invalid cursor")
 
 // ErrFutureMessage indicates that a message with timestamp in future was requested to be stored
-var ErrFutureMessage = errors.New("message timestamp in the future")
+var ErrFutureMessage = protocol.ErrFutureMessage
 
 // ErrMessageTooOld indicates that a message that was too old was requested to be stored.
-var ErrMessageTooOld = errors.New("message too old")
+var ErrMessageTooOld = protocol.ErrMessageTooOld
+
+// ErrDuplicateMessage indicates that a message was not stored because it was already in the DB
+var ErrDuplicateMessage = errors.New("duplicate message")
 
 // WALMode for sqlite.
 const WALMode = "wal"
@@ -59,6 +63,12 @@ type DBStore struct {
 	maxMessages int
 	maxDuration time.Duration
 
+	maxMessageAge  time.Duration
+	maxFutureDrift time.Duration
+
+	deduplicate       bool
+	duplicateMessages atomic.Int64
+
 	enableMigrations bool
 
 	wg     sync.WaitGroup
@@ -122,6 +132,29 @@ func WithRetentionPolicy(maxMessages int, maxDuration time.Duration) DBOption {
 	}
 }
 
+// WithMessageAgeBounds is a DBOption that specifies the window of timestamps accepted
+// when storing a message. Messages older than maxMessageAge, or with a timestamp more
+// than maxFutureDrift ahead of the current time are rejected. A zero value disables the
+// corresponding bound
+func WithMessageAgeBounds(maxMessageAge time.Duration, maxFutureDrift time.Duration) DBOption {
+	return func(d *DBStore) error {
+		d.maxMessageAge = maxMessageAge
+		d.maxFutureDrift = maxFutureDrift
+		return nil
+	}
+}
+
+// WithDeduplication is a DBOption that indicates whether a message that is already in
+// the DB (i.e. received more than once via relay) must not be stored again. Put returns
+// ErrDuplicateMessage for such a message. Duplicates are detected using the message hash.
+// Enabled by default
+func WithDeduplication(enabled bool) DBOption {
+	return func(d *DBStore) error {
+		d.deduplicate = enabled
+		return nil
+	}
+}
+
 type MigrationFn func(db *sql.DB, logger *zap.Logger) error
 
 // WithMigrations is a DBOption used to determine if migrations should
@@ -136,7 +169,9 @@ func WithMigrations(migrationFn MigrationFn) DBOption {
 
 // DefaultOptions returns the default DBoptions to be used.
 func DefaultOptions() []DBOption {
-	return []DBOption{}
+	return []DBOption{
+		WithDeduplication(true),
+	}
 }
 
 // Creates a new DB store using the db specified via options.
@@ -285,31 +320,57 @@ func (d *DBStore) Stop() {
 
 // Validate validates the message to be stored against possible fradulent conditions.
 func (d *DBStore) Validate(env *protocol.Envelope) error {
-	timestamp := env.Message().GetTimestamp()
+	return d.ValidateMessage(env.Message())
+}
+
+// ValidateMessage verifies that the timestamp of a message is within the window
+// of time accepted by the store
+func (d *DBStore) ValidateMessage(msg *wpb.WakuMessage) error {
+	timestamp := msg.GetTimestamp()
 	if timestamp == 0 {
 		return nil
 	}
 
-	n := time.Unix(0, timestamp)
-	upperBound := n.Add(MaxTimeVariance)
-	lowerBound := n.Add(-MaxTimeVariance)
-
-	// Ensure that messages don't "jump" to the front of the queue with future timestamps
-	if env.Message().GetTimestamp() > upperBound.UnixNano() {
-		return ErrFutureMessage
+	now := time.Now()
+	if d.timesource != nil {
+		now = d.timesource.Now()
 	}
 
-	if env.Message().GetTimestamp() < lowerBound.UnixNano() {
-		return ErrMessageTooOld
+	err := protocol.CheckTimestampBounds(timestamp, now, d.maxMessageAge, d.maxFutureDrift)
+	switch {
+	case errors.Is(err, ErrFutureMessage):
+		d.metrics.RecordRejectedMessage(futureMessage)
+	case errors.Is(err, ErrMessageTooOld):
+		d.metrics.RecordRejectedMessage(messageTooOld)
 	}
 
-	return nil
+	return err
+}
+
+// DuplicateMessages returns the number of messages that were not stored
+// because they were already present in the DB
+func (d *DBStore) DuplicateMessages() int {
+	return int(d.duplicateMessages.Load())
+}
+
+// RelayValidator returns a function that can be registered as a relay validator
+// so messages that would not be accepted by the store are not relayed either
+func (d *DBStore) RelayValidator() func(ctx context.Context, msg *wpb.WakuMessage, topic string) bool {
+	return func(ctx context.Context, msg *wpb.WakuMessage, topic string) bool {
+		return d.ValidateMessage(msg) == nil
+	}
 }
 
 // Put inserts a WakuMessage into the DB
 func (d *DBStore) Put(env *protocol.Envelope) error {
 
-	stmt, err := d.db.Prepare("INSERT INTO message (id, messageHash, storedAt, timestamp, contentTopic, pubsubTopic, payload, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)")
+	sqlStmt := "INSERT INTO message (id, messageHash, storedAt, timestamp, contentTopic, pubsubTopic, payload, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+	if d.deduplicate {
+		// messageHash is the primary key of the table, so a message that is already stored is skipped
+		sqlStmt += " ON CONFLICT DO NOTHING"
+	}
+
+	stmt, err := d.db.Prepare(sqlStmt)
 	if err != nil {
 		d.metrics.RecordError(insertFailure)
 		return err
@@ -323,11 +384,24 @@ func (d *DBStore) Put(env *protocol.Envelope) error {
 	hash := env.Hash()
 
 	start := time.Now()
-	_, err = stmt.Exec(env.Index().Digest, hash[:], storedAt, env.Message().GetTimestamp(), env.Message().ContentTopic, env.PubsubTopic(), env.Message().Payload, env.Message().GetVersion())
+	res, err := stmt.Exec(env.Index().Digest, hash[:], storedAt, env.Message().GetTimestamp(), env.Message().ContentTopic, env.PubsubTopic(), env.Message().Payload, env.Message().GetVersion())
 	if err != nil {
 		return err
 	}
 
+	duplicate := false
+	if d.deduplicate {
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			duplicate = true
+			d.duplicateMessages.Add(1)
+			d.metrics.RecordDuplicateMessage()
+		}
+	}
+
 	d.metrics.RecordInsertDuration(time.Since(start))
 
 	err = stmt.Close()
@@ -335,6 +409,10 @@ func (d *DBStore) Put(env *protocol.Envelope) error {
 		return err
 	}
 
+	if duplicate {
+		return ErrDuplicateMessage
+	}
+
 	return nil
 }
 
@@ -416,6 +494,12 @@ func (d *DBStore) prepareQuerySQL(query *pb.HistoryQuery) (string, []interface{}
 		conditions = append(conditions, "contentTopic IN ("+strings.Join(ctPlaceHolder, ", ")+")")
 	}
 
+	if query.MinVersion != nil {
+		paramCnt++
+		conditions = append(conditions, fmt.Sprintf("version >= $%d", paramCnt))
+		parameters = append(parameters, *query.MinVersion)
+	}
+
 	conditions, parameters, err := d.handleQueryCursor(query, &paramCnt, conditions, parameters)
 	if err != nil {
 		return "", nil, err
@@ -32,6 +32,8 @@ func TestStore(t *testing.T) {
 		{"testDbStore", testDbStore},
 		{"testStoreRetention", testStoreRetention},
 		{"testQuery", testQuery},
+		{"testMessageAgeBounds", testMessageAgeBounds},
+		{"testDeduplication", testDeduplication},
 	}
 	for _, driverName := range []string{"postgres", "sqlite"} {
 		// all tests are run for each db
@@ -195,3 +197,62 @@ func testQuery(t *testing.T, db *sql.DB, migrationFn func(*sql.DB, *zap.Logger)
 	require.NoError(t, err)
 	require.Equal(t, timestamp, insertTime.UnixNano())
 }
+
+func testMessageAgeBounds(t *testing.T, db *sql.DB, migrationFn func(*sql.DB, *zap.Logger) error) {
+	store, err := persistence.NewDBStore(prometheus.DefaultRegisterer, utils.Logger(), persistence.WithDB(db), persistence.WithMigrations(migrationFn), persistence.WithMessageAgeBounds(time.Hour, time.Minute))
+	require.NoError(t, err)
+
+	err = store.Start(context.Background(), timesource.NewDefaultClock())
+	require.NoError(t, err)
+	defer store.Stop()
+
+	now := time.Now()
+
+	// Message too old
+	msg := tests.CreateWakuMessage("test", proto.Int64(now.Add(-2*time.Hour).UnixNano()))
+	err = store.Validate(protocol.NewEnvelope(msg, now.UnixNano(), "test"))
+	require.ErrorIs(t, err, persistence.ErrMessageTooOld)
+
+	// Message too far in the future
+	msg = tests.CreateWakuMessage("test", proto.Int64(now.Add(2*time.Minute).UnixNano()))
+	err = store.Validate(protocol.NewEnvelope(msg, now.UnixNano(), "test"))
+	require.ErrorIs(t, err, persistence.ErrFutureMessage)
+	require.False(t, store.RelayValidator()(context.Background(), msg, "test"))
+
+	// Messages within the window are accepted
+	for _, ts := range []time.Time{now.Add(-59 * time.Minute), now, now.Add(30 * time.Second)} {
+		msg = tests.CreateWakuMessage("test", proto.Int64(ts.UnixNano()))
+		err = store.Validate(protocol.NewEnvelope(msg, now.UnixNano(), "test"))
+		require.NoError(t, err)
+		require.True(t, store.RelayValidator()(context.Background(), msg, "test"))
+	}
+}
+
+func testDeduplication(t *testing.T, db *sql.DB, migrationFn func(*sql.DB, *zap.Logger) error) {
+	store, err := persistence.NewDBStore(prometheus.DefaultRegisterer, utils.Logger(), persistence.WithDB(db), persistence.WithMigrations(migrationFn))
+	require.NoError(t, err)
+
+	err = store.Start(context.Background(), timesource.NewDefaultClock())
+	require.NoError(t, err)
+	defer store.Stop()
+
+	msg := tests.CreateWakuMessage("test", utils.GetUnixEpoch())
+
+	initialCount, err := store.Count()
+	require.NoError(t, err)
+
+	// The same message received twice via relay is stored once
+	require.NoError(t, store.Put(protocol.NewEnvelope(msg, *utils.GetUnixEpoch(), "test")))
+	require.ErrorIs(t, store.Put(protocol.NewEnvelope(msg, *utils.GetUnixEpoch(), "test")), persistence.ErrDuplicateMessage)
+
+	count, err := store.Count()
+	require.NoError(t, err)
+	require.Equal(t, initialCount+1, count)
+	require.Equal(t, 1, store.DuplicateMessages())
+
+	// Without deduplication, storing a duplicate fails due to the unique message hash
+	store2, err := persistence.NewDBStore(prometheus.DefaultRegisterer, utils.Logger(), persistence.WithDB(db), persistence.WithDeduplication(false))
+	require.NoError(t, err)
+	require.Error(t, store2.Put(protocol.NewEnvelope(msg, *utils.GetUnixEpoch(), "test")))
+	require.Equal(t, 0, store2.DuplicateMessages())
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/waku-org/go-waku/waku/v2/onlinechecker"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
 	"github.com/waku-org/go-waku/waku/v2/protocol/filter"
@@ -18,10 +22,16 @@ import (
 )
 
 type FilterConfig struct {
-	MaxPeers int       `json:"maxPeers"`
+	MaxPeers int `json:"maxPeers"`
+	// MinPeers is the number of peers that must accept the subscription for Subscribe to succeed.
+	// Failing to subscribe with the other peers does not fail it, and they are retried later
+	MinPeers int       `json:"minPeers"`
 	Peers    []peer.ID `json:"peers"`
 }
 
+// ErrNotEnoughPeers is returned by Subscribe when less than FilterConfig.MinPeers peers accepted the subscription
+var ErrNotEnoughPeers = errors.New("not enough peers accepted the filter subscription")
+
 func (fc FilterConfig) String() string {
 	jsonStr, err := json.Marshal(fc)
 	if err != nil {
@@ -33,6 +43,10 @@ func (fc FilterConfig) String() string {
 const filterSubLoopInterval = 5 * time.Second
 const filterSubMaxErrCnt = 3
 
+// DefaultDeduplicationCacheSize is the number of message hashes remembered by default to
+// deduplicate the messages pushed by the different peers of a subscription
+const DefaultDeduplicationCacheSize = 1000
+
 type Sub struct {
 	ContentFilter         protocol.ContentFilter
 	DataCh                chan *protocol.Envelope
@@ -47,11 +61,31 @@ type Sub struct {
 	resubscribeInProgress bool
 	id                    string
 	errcnt                int
+	droppedPeers          []peer.ID
+	reconnections         atomic.Int64
+	reconnectCh           chan<- ReconnectEvent
+	metrics               Metrics
+	seenMessages          *lru.Cache
+	maxBackoff            time.Duration
+	failedResubscribes    int
+}
+
+// ReconnectEvent is emitted when a subscription to a peer that dropped
+// is replaced by a subscription to a different peer
+type ReconnectEvent struct {
+	SubscriptionID string
+	OldPeer        peer.ID
+	NewPeer        peer.ID
 }
 
 type subscribeParameters struct {
 	batchInterval          time.Duration
 	multiplexChannelBuffer int
+	reconnectCh            chan<- ReconnectEvent
+	reg                    prometheus.Registerer
+	reconnectJitter        float64
+	maxResubscribeBackoff  time.Duration
+	dedupCacheSize         int
 }
 
 type SubscribeOptions func(*subscribeParameters)
@@ -68,14 +102,61 @@ func WithMultiplexChannelBuffer(value int) SubscribeOptions {
 	}
 }
 
+// WithReconnectChannel sets a channel on which a ReconnectEvent is sent each time the subscription
+// replaces a dropped peer with a new one. Events are discarded if the channel is not ready to receive them
+func WithReconnectChannel(ch chan<- ReconnectEvent) SubscribeOptions {
+	return func(params *subscribeParameters) {
+		params.reconnectCh = ch
+	}
+}
+
+// WithPrometheusRegisterer sets the registerer used for the subscription metrics
+func WithPrometheusRegisterer(reg prometheus.Registerer) SubscribeOptions {
+	return func(params *subscribeParameters) {
+		params.reg = reg
+	}
+}
+
+// WithReconnectJitter randomizes the interval between the checks for missing subscriptions
+// by the given fraction, so clients that lost their peers at the same time do not
+// resubscribe all at once
+func WithReconnectJitter(fraction float64) SubscribeOptions {
+	return func(params *subscribeParameters) {
+		params.reconnectJitter = fraction
+	}
+}
+
+// WithResubscribeBackoff backs off exponentially, up to maxBackoff, while resubscribing keeps
+// failing. Failed resubscriptions are then only retried on the next check for missing
+// subscriptions, instead of immediately. Disabled by default
+func WithResubscribeBackoff(maxBackoff time.Duration) SubscribeOptions {
+	return func(params *subscribeParameters) {
+		params.maxResubscribeBackoff = maxBackoff
+	}
+}
+
+// WithDeduplicationCacheSize sets how many message hashes are remembered to deliver only once the
+// messages pushed by the different peers of a subscription. A value of 0 disables it, i.e. when
+// the light node already deduplicates the messages with filter.WithMessageDeduplication
+func WithDeduplicationCacheSize(size int) SubscribeOptions {
+	return func(params *subscribeParameters) {
+		params.dedupCacheSize = size
+	}
+}
+
 func defaultOptions() []SubscribeOptions {
 	return []SubscribeOptions{
 		WithBatchInterval(5 * time.Second),
 		WithMultiplexChannelBuffer(100),
+		WithPrometheusRegisterer(prometheus.DefaultRegisterer),
+		WithReconnectJitter(utils.DefaultJitterFraction),
+		WithDeduplicationCacheSize(DefaultDeduplicationCacheSize),
 	}
 }
 
-// Subscribe
+// Subscribe subscribes to the content filter with up to config.MaxPeers peers, and keeps replacing the
+// peers that drop. Messages pushed by more than one peer are delivered once in DataCh. If the node is
+// online and less than config.MinPeers peers accept the subscription, ErrNotEnoughPeers is returned
 func Subscribe(ctx context.Context, wf *filter.WakuFilterLightNode, contentFilter protocol.ContentFilter, config FilterConfig, log *zap.Logger, params *subscribeParameters) (*Sub, error) {
 	sub := new(Sub)
 	sub.id = uuid.NewString()
@@ -88,17 +169,43 @@ func Subscribe(ctx context.Context, wf *filter.WakuFilterLightNode, contentFilte
 	sub.log = log.Named("filter-api").With(zap.String("apisub-id", sub.id), zap.Stringer("content-filter", sub.ContentFilter))
 	sub.log.Debug("filter subscribe params", zap.Int("max-peers", config.MaxPeers))
 	sub.closing = make(chan string, config.MaxPeers)
+	sub.reconnectCh = params.reconnectCh
+	sub.maxBackoff = params.maxResubscribeBackoff
+	reg := params.reg
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	sub.metrics = newMetrics(reg)
+	if params.dedupCacheSize > 0 {
+		seenMessages, err := lru.New(params.dedupCacheSize)
+		if err != nil {
+			return nil, err
+		}
+		sub.seenMessages = seenMessages
+	}
 
 	sub.onlineChecker = wf.OnlineChecker()
 	if wf.OnlineChecker().IsOnline() {
 		subs, err := sub.subscribe(contentFilter, sub.Config.MaxPeers)
+		if len(subs) < config.MinPeers {
+			for _, s := range subs {
+				if _, err := wf.UnsubscribeWithSubscription(sub.ctx, s); err != nil {
+					sub.log.Debug("failed to unsubscribe filter", zap.Error(err))
+				}
+			}
+			sub.cancel()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %d of %d: %v", ErrNotEnoughPeers, len(subs), config.MinPeers, err)
+			}
+			return nil, fmt.Errorf("%w: %d of %d", ErrNotEnoughPeers, len(subs), config.MinPeers)
+		}
 		if err == nil {
 			sub.multiplex(subs)
 		}
 	}
 	// filter subscription loop is to check if target subscriptions for a filter are active and if not
 	// trigger resubscribe.
-	go sub.subscriptionLoop(filterSubLoopInterval)
+	go sub.subscriptionLoop(filterSubLoopInterval, params.reconnectJitter)
 	return sub, nil
 }
 
@@ -111,13 +218,14 @@ func (apiSub *Sub) Unsubscribe(contentFilter protocol.ContentFilter) {
 	}
 }
 
-func (apiSub *Sub) subscriptionLoop(loopInterval time.Duration) {
+func (apiSub *Sub) subscriptionLoop(loopInterval time.Duration, jitter float64) {
 	defer utils.LogOnPanic()
-	ticker := time.NewTicker(loopInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(utils.Jitter(loopInterval, jitter))
+	defer timer.Stop()
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
+			timer.Reset(utils.Jitter(apiSub.nextInterval(loopInterval), jitter))
 			apiSub.errcnt = 0 //reset errorCount
 			if apiSub.onlineChecker.IsOnline() && len(apiSub.subs) < apiSub.Config.MaxPeers &&
 				!apiSub.resubscribeInProgress && len(apiSub.closing) < apiSub.Config.MaxPeers {
@@ -146,6 +254,7 @@ func (apiSub *Sub) checkAndResubscribe(subId string) {
 		apiSub.subs[subId].Close()
 		failedPeer = apiSub.subs[subId].PeerID
 		delete(apiSub.subs, subId)
+		apiSub.droppedPeers = append(apiSub.droppedPeers, failedPeer)
 	}
 	apiSub.log.Debug("subscription status", zap.Int("sub-count", len(apiSub.subs)), zap.Stringer("content-filter", apiSub.ContentFilter))
 	if apiSub.onlineChecker.IsOnline() && len(apiSub.subs) < apiSub.Config.MaxPeers {
@@ -167,6 +276,19 @@ func (apiSub *Sub) cleanup() {
 	close(apiSub.DataCh)
 }
 
+// nextInterval returns the time until the next check for missing subscriptions, which
+// doubles after each failed resubscription if the backoff is enabled
+func (apiSub *Sub) nextInterval(loopInterval time.Duration) time.Duration {
+	interval := loopInterval
+	for i := 0; i < apiSub.failedResubscribes && interval < apiSub.maxBackoff; i++ {
+		interval *= 2
+	}
+	if apiSub.maxBackoff > loopInterval && interval > apiSub.maxBackoff {
+		interval = apiSub.maxBackoff
+	}
+	return interval
+}
+
 // Attempts to resubscribe on topics that lack subscriptions
 func (apiSub *Sub) resubscribe(failedPeer peer.ID) {
 	// Re-subscribe asynchronously
@@ -181,15 +303,49 @@ func (apiSub *Sub) resubscribe(failedPeer peer.ID) {
 	}
 	subs, err := apiSub.subscribe(apiSub.ContentFilter, apiSub.Config.MaxPeers-existingSubCount, peersToExclude...)
 	if err != nil {
-		apiSub.log.Debug("failed to resubscribe for filter", zap.Error(err))
+		apiSub.failedResubscribes++
+		apiSub.log.Debug("failed to resubscribe for filter", zap.Error(err), zap.Int("failures", apiSub.failedResubscribes))
 		return
 	} //Not handling scenario where all requested subs are not received as that should get handled from user of the API.
 
+	apiSub.failedResubscribes = 0
 	apiSub.multiplex(subs)
+	apiSub.recordReconnections(subs)
+}
+
+// recordReconnections reports each new subscription as replacing one of the peers that dropped
+func (apiSub *Sub) recordReconnections(subs []*subscription.SubscriptionDetails) {
+	for _, s := range subs {
+		if len(apiSub.droppedPeers) == 0 {
+			return
+		}
+
+		oldPeer := apiSub.droppedPeers[0]
+		apiSub.droppedPeers = apiSub.droppedPeers[1:]
+
+		apiSub.reconnections.Add(1)
+		apiSub.metrics.RecordReconnection()
+		apiSub.log.Info("filter subscription reconnected", zap.Stringer("old-peer", oldPeer), zap.Stringer("new-peer", s.PeerID))
+
+		if apiSub.reconnectCh == nil {
+			continue
+		}
+
+		select {
+		case apiSub.reconnectCh <- ReconnectEvent{SubscriptionID: apiSub.id, OldPeer: oldPeer, NewPeer: s.PeerID}:
+		default:
+			apiSub.log.Debug("reconnect event discarded, channel is not ready")
+		}
+	}
+}
+
+// Reconnections returns the number of times a dropped peer of this subscription has been replaced
+func (apiSub *Sub) Reconnections() int {
+	return int(apiSub.reconnections.Load())
 }
 
 func possibleRecursiveError(err error) bool {
-	return errors.Is(err, utils.ErrNoPeersAvailable) || errors.Is(err, swarm.ErrDialBackoff)
+	return errors.Is(err, utils.ErrNoPeersAvailable) || errors.Is(err, filter.ErrNoPeersAvailable) || errors.Is(err, swarm.ErrDialBackoff)
 }
 
 func (apiSub *Sub) subscribe(contentFilter protocol.ContentFilter, peerCount int, peersToExclude ...peer.ID) ([]*subscription.SubscriptionDetails, error) {
@@ -209,8 +365,9 @@ func (apiSub *Sub) subscribe(contentFilter protocol.ContentFilter, peerCount int
 		if possibleRecursiveError(err) {
 			apiSub.errcnt++
 		}
-		//Inform of error, so that resubscribe can be triggered if required
-		if len(apiSub.closing) < apiSub.Config.MaxPeers {
+		//Inform of error, so that resubscribe can be triggered if required.
+		//With backoff enabled, it is retried on the next check instead
+		if apiSub.maxBackoff == 0 && len(apiSub.closing) < apiSub.Config.MaxPeers {
 			apiSub.closing <- ""
 		}
 		if len(subs) > 0 {
@@ -233,6 +390,12 @@ func (apiSub *Sub) multiplex(subs []*subscription.SubscriptionDetails) {
 			defer utils.LogOnPanic()
 			apiSub.log.Debug("new multiplex", zap.String("sub-id", subDetails.ID))
 			for env := range subDetails.C {
+				// The same message is pushed by each of the peers of the subscription
+				if apiSub.seenMessages != nil {
+					if seen, _ := apiSub.seenMessages.ContainsOrAdd(env.Hash(), struct{}{}); seen {
+						continue
+					}
+				}
 				apiSub.DataCh <- env
 			}
 		}(subDetails)
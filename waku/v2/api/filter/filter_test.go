@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
 	"github.com/waku-org/go-waku/waku/v2/protocol/filter"
@@ -54,7 +55,7 @@ func (s *FilterApiTestSuite) TestSubscribe() {
 	s.Require().Equal(contentFilter.PubsubTopic, s.TestTopic)
 	ctx, cancel := context.WithCancel(context.Background())
 	s.Log.Info("About to perform API Subscribe()")
-	params := subscribeParameters{300 * time.Second, 1024}
+	params := subscribeParameters{batchInterval: 300 * time.Second, multiplexChannelBuffer: 1024, dedupCacheSize: DefaultDeduplicationCacheSize}
 	apiSub, err := Subscribe(ctx, s.LightNode, contentFilter, apiConfig, s.Log, &params)
 	s.Require().NoError(err)
 	s.Require().Equal(apiSub.ContentFilter, contentFilter)
@@ -66,7 +67,7 @@ func (s *FilterApiTestSuite) TestSubscribe() {
 	}
 	subsArray := maps.Keys(apiSub.subs)
 	s.Require().True(subsArray[0] != subsArray[1])
-	// Publish msg and confirm it's received twice because of multiplexing
+	// Publish msg and confirm it's received once even though both peers push it
 	s.PublishMsg(&filter.WakuMsg{PubSubTopic: s.TestTopic, ContentTopic: s.TestContentTopic, Payload: "Test msg"})
 	cnt := 0
 	for msg := range apiSub.DataCh {
@@ -75,6 +76,11 @@ func (s *FilterApiTestSuite) TestSubscribe() {
 		break
 	}
 	s.Require().Equal(cnt, 1)
+	select {
+	case <-apiSub.DataCh:
+		s.Require().Fail("duplicate message delivered")
+	case <-time.After(500 * time.Millisecond):
+	}
 
 	//Verify HealthCheck
 	subs := s.LightNode.Subscriptions()
@@ -104,6 +110,66 @@ func (s *FilterApiTestSuite) TestSubscribe() {
 
 }
 
+func (s *FilterApiTestSuite) TestSubscribeMinPeers() {
+	contentFilter := protocol.ContentFilter{PubsubTopic: s.TestTopic, ContentTopics: protocol.NewContentTopicSet(s.TestContentTopic)}
+	params := subscribeParameters{batchInterval: 300 * time.Second, multiplexChannelBuffer: 1024}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Only one full node is available
+	_, err := Subscribe(ctx, s.LightNode, contentFilter, FilterConfig{MaxPeers: 2, MinPeers: 2}, s.Log, &params)
+	s.Require().ErrorIs(err, ErrNotEnoughPeers)
+	s.Require().Len(s.LightNode.Subscriptions(), 0)
+
+	// Missing the second peer does not fail the subscription as long as MinPeers is reached
+	apiSub, err := Subscribe(ctx, s.LightNode, contentFilter, FilterConfig{MaxPeers: 2, MinPeers: 1}, s.Log, &params)
+	s.Require().NoError(err)
+	s.Require().Len(apiSub.subs, 1)
+
+	cancel()
+	for range apiSub.DataCh {
+	}
+}
+
+func (s *FilterApiTestSuite) TestReconnectEvent() {
+	contentFilter := protocol.ContentFilter{PubsubTopic: s.TestTopic, ContentTopics: protocol.NewContentTopicSet(s.TestContentTopic)}
+
+	apiConfig := FilterConfig{MaxPeers: 1}
+	reconnectCh := make(chan ReconnectEvent, 1)
+	params := subscribeParameters{batchInterval: 300 * time.Second, multiplexChannelBuffer: 1024, reconnectCh: reconnectCh}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apiSub, err := Subscribe(ctx, s.LightNode, contentFilter, apiConfig, s.Log, &params)
+	s.Require().NoError(err)
+	s.Require().Len(apiSub.subs, 1)
+	s.Require().Equal(0, apiSub.Reconnections())
+
+	// Peer used once the original one drops
+	fullNodeData2 := s.GetWakuFilterFullNode(s.TestTopic, true)
+	s.ConnectToFullNode(s.LightNode, fullNodeData2.FullNode)
+
+	// Simulate the peer dropping and the failed ping being detected
+	s.FullNode.Stop()
+	s.FullNodeHost.Close()
+	s.LightNode.PingPeer(s.FullNodeHost.ID())
+
+	select {
+	case event := <-reconnectCh:
+		s.Require().Equal(apiSub.id, event.SubscriptionID)
+		s.Require().Equal(s.FullNodeHost.ID(), event.OldPeer)
+		s.Require().Equal(fullNodeData2.FullNodeHost.ID(), event.NewPeer)
+	case <-time.After(10 * time.Second):
+		s.Require().Fail("reconnect event was not emitted")
+	}
+	s.Require().Equal(1, apiSub.Reconnections())
+
+	cancel()
+	for range apiSub.DataCh {
+	}
+}
+
 func (s *FilterApiTestSuite) OnNewEnvelope(env *protocol.Envelope) error {
 	if env.Message().ContentTopic == s.ContentFilter.ContentTopicsList()[0] {
 		s.Log.Info("received message via filter")
@@ -192,3 +258,18 @@ func (s *FilterApiTestSuite) TestFilterManager() {
 	fm.UnsubscribeFilter(fID)
 	cancel()
 }
+
+func TestResubscribeBackoff(t *testing.T) {
+	sub := &Sub{}
+	sub.failedResubscribes = 3
+	// Disabled by default
+	require.Equal(t, 5*time.Second, sub.nextInterval(5*time.Second))
+
+	sub.maxBackoff = time.Minute
+	sub.failedResubscribes = 0
+	require.Equal(t, 5*time.Second, sub.nextInterval(5*time.Second))
+	sub.failedResubscribes = 2
+	require.Equal(t, 20*time.Second, sub.nextInterval(5*time.Second))
+	sub.failedResubscribes = 10
+	require.Equal(t, time.Minute, sub.nextInterval(5*time.Second))
+}
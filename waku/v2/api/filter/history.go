@@ -0,0 +1,196 @@
+package filter
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/filter"
+	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
+	"github.com/waku-org/go-waku/waku/v2/protocol/store"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// HistoryFetcher retrieves the messages matching a content filter that were stored since a point in time
+type HistoryFetcher func(ctx context.Context, contentFilter protocol.ContentFilter, since time.Time) ([]*protocol.Envelope, error)
+
+// StoreHistoryFetcher returns a HistoryFetcher that retrieves all the pages of messages from a storenode
+func StoreHistoryFetcher(s *store.WakuStore, opts ...store.RequestOption) HistoryFetcher {
+	return func(ctx context.Context, contentFilter protocol.ContentFilter, since time.Time) ([]*protocol.Envelope, error) {
+		criteria := store.FilterCriteria{
+			ContentFilter: contentFilter,
+			TimeStart:     proto.Int64(since.UnixNano()),
+		}
+
+		result, err := s.Query(ctx, criteria, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		var envelopes []*protocol.Envelope
+		for !result.IsComplete() {
+			for _, m := range result.Messages() {
+				envelopes = append(envelopes, protocol.NewEnvelope(m.Message, m.Message.GetTimestamp(), m.GetPubsubTopic()))
+			}
+
+			err = result.Next(ctx, opts...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return envelopes, nil
+	}
+}
+
+// HistorySub is a filter subscription that delivers the messages stored since
+// a point in time before delivering the messages received live
+type HistorySub struct {
+	*Sub
+
+	// C receives the stored messages ordered by timestamp, followed by the live messages
+	C <-chan *protocol.Envelope
+}
+
+// SubscribeWithHistory creates a filter subscription and retrieves the messages stored since a point in time.
+// Stored messages are delivered first, followed by the messages pushed by filter. Live messages received while
+// the history is being retrieved are held until the stored messages are delivered, and messages present in
+// both are delivered only once
+func SubscribeWithHistory(ctx context.Context, wf *filter.WakuFilterLightNode, fetchHistory HistoryFetcher, contentFilter protocol.ContentFilter, since time.Time, config FilterConfig, log *zap.Logger, opts ...SubscribeOptions) (*HistorySub, error) {
+	params := new(subscribeParameters)
+	optList := defaultOptions()
+	optList = append(optList, opts...)
+	for _, opt := range optList {
+		opt(params)
+	}
+
+	// The live subscription is created before retrieving the history, so there is no gap between both
+	sub, err := Subscribe(ctx, wf, contentFilter, config, log, params)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *protocol.Envelope, params.multiplexChannelBuffer)
+	go mergeHistory(sub.ctx, sub.DataCh, func(ctx context.Context) ([]*protocol.Envelope, error) {
+		return fetchHistory(ctx, contentFilter, since)
+	}, out, sub.log)
+
+	return &HistorySub{
+		Sub: sub,
+		C:   out,
+	}, nil
+}
+
+type historyResult struct {
+	envelopes []*protocol.Envelope
+	err       error
+}
+
+// mergeHistory sends to out the envelopes returned by fetchHistory ordered by timestamp,
+// followed by the envelopes received in live, skipping those already delivered from history
+func mergeHistory(ctx context.Context, live <-chan *protocol.Envelope, fetchHistory func(ctx context.Context) ([]*protocol.Envelope, error), out chan<- *protocol.Envelope, log *zap.Logger) {
+	defer utils.LogOnPanic()
+	defer close(out)
+
+	resultCh := make(chan historyResult, 1)
+	go func() {
+		defer utils.LogOnPanic()
+		envelopes, err := fetchHistory(ctx)
+		resultCh <- historyResult{envelopes, err}
+	}()
+
+	// Hold the live messages until the history is retrieved
+	var pending []*protocol.Envelope
+	var result historyResult
+	waiting := true
+	for waiting {
+		select {
+		case <-ctx.Done():
+			return
+		case env, ok := <-live:
+			if !ok {
+				live = nil
+				continue
+			}
+			pending = append(pending, env)
+		case result = <-resultCh:
+			waiting = false
+		}
+	}
+
+	if result.err != nil {
+		log.Error("retrieving message history", zap.Error(result.err))
+	}
+
+	sort.SliceStable(result.envelopes, func(i, j int) bool {
+		return result.envelopes[i].Message().GetTimestamp() < result.envelopes[j].Message().GetTimestamp()
+	})
+
+	// Only the hashes of the stored messages are kept, to skip the live messages that were also
+	// retrieved from history. Once a live message newer than all the stored messages is received,
+	// the following live messages can not be in the history anymore and the hashes are released
+	historyHashes := make(map[pb.MessageHash]struct{}, len(result.envelopes))
+	var newestHistoryTimestamp int64
+	send := func(env *protocol.Envelope) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case out <- env:
+			return true
+		}
+	}
+
+	for _, env := range result.envelopes {
+		if _, ok := historyHashes[env.Hash()]; ok {
+			continue
+		}
+		historyHashes[env.Hash()] = struct{}{}
+		newestHistoryTimestamp = max(newestHistoryTimestamp, env.Message().GetTimestamp())
+
+		if !send(env) {
+			return
+		}
+	}
+
+	sendLive := func(env *protocol.Envelope) bool {
+		if historyHashes != nil {
+			if _, ok := historyHashes[env.Hash()]; ok {
+				delete(historyHashes, env.Hash())
+				return true
+			}
+
+			if env.Message().GetTimestamp() > newestHistoryTimestamp {
+				historyHashes = nil
+			}
+		}
+
+		return send(env)
+	}
+
+	for _, env := range pending {
+		if !sendLive(env) {
+			return
+		}
+	}
+
+	if live == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case env, ok := <-live:
+			if !ok {
+				return
+			}
+			if !sendLive(env) {
+				return
+			}
+		}
+	}
+}
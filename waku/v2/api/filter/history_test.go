@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/tests"
+	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMergeHistory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pubsubTopic := "/waku/2/rs/1/0"
+	var envelopes []*protocol.Envelope
+	for i := 1; i <= 8; i++ {
+		msg := tests.CreateWakuMessage("/test/1/history/proto", proto.Int64(int64(i)), "payload")
+		envelopes = append(envelopes, protocol.NewEnvelope(msg, int64(i), pubsubTopic))
+	}
+
+	live := make(chan *protocol.Envelope, 10)
+	out := make(chan *protocol.Envelope, 10)
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+
+	fetchHistory := func(ctx context.Context) ([]*protocol.Envelope, error) {
+		close(fetching)
+		<-release
+		// Stored messages are not necessarily sorted
+		return []*protocol.Envelope{envelopes[2], envelopes[0], envelopes[4], envelopes[1], envelopes[3]}, nil
+	}
+
+	go mergeHistory(ctx, live, fetchHistory, out, utils.Logger())
+
+	// Live messages arriving during the backfill, some of which are also in the history
+	<-fetching
+	live <- envelopes[3]
+	live <- envelopes[4]
+	live <- envelopes[5]
+	live <- envelopes[6]
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	// Live message received after the backfill
+	live <- envelopes[7]
+
+	for i := range envelopes {
+		select {
+		case env := <-out:
+			require.Equal(t, envelopes[i].Hash(), env.Hash())
+		case <-ctx.Done():
+			require.Fail(t, "message not delivered")
+		}
+	}
+
+	// Once a live message newer than the history is received, the hashes of the stored messages are
+	// released, so live messages are no longer checked against them
+	live <- envelopes[2]
+	select {
+	case env := <-out:
+		require.Equal(t, envelopes[2].Hash(), env.Hash())
+	case <-ctx.Done():
+		require.Fail(t, "message not delivered")
+	}
+
+	// No duplicates are delivered
+	close(live)
+	_, ok := <-out
+	require.False(t, ok)
+}
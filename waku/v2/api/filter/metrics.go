@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"github.com/libp2p/go-libp2p/p2p/metricshelper"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var filterReconnections = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "waku_filter_subscription_reconnections",
+		Help: "The number of times a filter subscription replaced a dropped peer",
+	})
+
+var collectors = []prometheus.Collector{
+	filterReconnections,
+}
+
+// Metrics exposes the functions required to update prometheus metrics for filter subscriptions
+type Metrics interface {
+	RecordReconnection()
+}
+
+type metricsImpl struct {
+	reg prometheus.Registerer
+}
+
+func newMetrics(reg prometheus.Registerer) Metrics {
+	metricshelper.RegisterCollectors(reg, collectors...)
+	return &metricsImpl{
+		reg: reg,
+	}
+}
+
+// RecordReconnection increases the counter for the number of filter subscription reconnections
+func (m *metricsImpl) RecordReconnection() {
+	filterReconnections.Inc()
+}
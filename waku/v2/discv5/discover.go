@@ -55,6 +55,7 @@ type discV5Parameters struct {
 	udpPort       uint
 	advertiseAddr []multiaddr.Multiaddr
 	loopPredicate func(*enode.Node) bool
+	jitter        float64
 }
 
 type DiscoveryV5Option func(*discV5Parameters)
@@ -64,6 +65,7 @@ var protocolID = [6]byte{'d', '5', 'w', 'a', 'k', 'u'}
 const peerDelay = 100 * time.Millisecond
 const bucketSize = 16
 const delayBetweenDiscoveredPeerCnt = 5 * time.Second
+const lookupRestartInterval = 5 * time.Second
 
 func WithAutoUpdate(autoUpdate bool) DiscoveryV5Option {
 	return func(params *discV5Parameters) {
@@ -105,11 +107,20 @@ func WithAutoFindPeers(find bool) DiscoveryV5Option {
 	}
 }
 
+// WithJitter randomizes the interval between discovery lookups by the given fraction,
+// so nodes do not look up peers at the same time
+func WithJitter(fraction float64) DiscoveryV5Option {
+	return func(params *discV5Parameters) {
+		params.jitter = fraction
+	}
+}
+
 // DefaultOptions contains the default list of options used when setting up DiscoveryV5
 func DefaultOptions() []DiscoveryV5Option {
 	return []DiscoveryV5Option{
 		WithUDPPort(9000),
 		WithAutoFindPeers(true),
+		WithJitter(utils.DefaultJitterFraction),
 	}
 }
 
@@ -253,6 +264,61 @@ func (d *DiscoveryV5) Stop() {
 	})
 }
 
+// number of buckets and log distance of the closest bucket of the discv5 routing table
+const tableBuckets = 256 / 15
+const tableBucketMinDistance = 256 - tableBuckets
+
+// RoutingTableSize returns the number of nodes currently stored in the discv5 routing table
+func (d *DiscoveryV5) RoutingTableSize() int {
+	if d.listener == nil {
+		return 0
+	}
+
+	return len(d.listener.AllNodes())
+}
+
+// BucketSizes returns the number of nodes stored in each bucket of the discv5 routing table.
+// Buckets are ordered from the closest to the furthest from the local node
+func (d *DiscoveryV5) BucketSizes() []int {
+	result := make([]int, tableBuckets)
+	if d.listener == nil {
+		return result
+	}
+
+	localID := d.localnode.ID()
+	for _, n := range d.listener.AllNodes() {
+		dist := enode.LogDist(localID, n.ID())
+		if dist <= tableBucketMinDistance {
+			result[0]++
+		} else {
+			result[dist-tableBucketMinDistance-1]++
+		}
+	}
+
+	return result
+}
+
+// NodesWithCapabilities returns the number of nodes in the discv5 routing table whose ENR
+// advertises support for all the protocols specified in flags
+func (d *DiscoveryV5) NodesWithCapabilities(flags wenr.WakuEnrBitfield) int {
+	if d.listener == nil {
+		return 0
+	}
+
+	cnt := 0
+	for _, n := range d.listener.AllNodes() {
+		enrField, err := wenr.GetWakuEnrBitField(n)
+		if err != nil {
+			continue
+		}
+		if enrField&flags == flags {
+			cnt++
+		}
+	}
+
+	return cnt
+}
+
 func isWakuNode(node *enode.Node) bool {
 	enrField, err := wenr.GetWakuEnrBitField(node)
 	if err != nil {
@@ -471,7 +537,7 @@ restartLoop:
 			d.log.Debug("iterating discv5", zap.Error(err))
 		}
 
-		t := time.NewTimer(5 * time.Second)
+		t := time.NewTimer(utils.Jitter(lookupRestartInterval, d.params.jitter))
 		select {
 		case <-t.C:
 			t.Stop()
@@ -5,10 +5,96 @@ import (
 	"net"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
 	"github.com/stretchr/testify/require"
+	wenr "github.com/waku-org/go-waku/waku/v2/protocol/enr"
+	"github.com/waku-org/go-waku/waku/v2/utils"
 )
 
+func TestExternalIPv6AddressSelection(t *testing.T) {
+	a1, _ := ma.NewMultiaddr("/ip6/::1/tcp/60000/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+	a2, _ := ma.NewMultiaddr("/ip6/2001:db8::1/tcp/60001/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+
+	extAddr, err := selectMostExternalAddress(context.Background(), madns.DefaultResolver, []ma.Multiaddr{a1, a2})
+	require.NoError(t, err)
+	require.True(t, extAddr.IP.Equal(net.ParseIP("2001:db8::1")))
+	require.Equal(t, 60001, extAddr.Port)
+	require.Equal(t, "external", addressKind(extAddr))
+
+	extAddr, err = selectMostExternalAddress(context.Background(), madns.DefaultResolver, []ma.Multiaddr{a1})
+	require.NoError(t, err)
+	require.Equal(t, "loopback", addressKind(extAddr))
+}
+
+func TestDNSAddressSelection(t *testing.T) {
+	resolver, err := madns.NewResolver(madns.WithDefaultResolver(&madns.MockResolver{
+		IP: map[string][]net.IPAddr{
+			"node.example.com": {{IP: net.ParseIP("192.168.0.10")}, {IP: net.ParseIP("2001:db8::10")}},
+		},
+		TXT: map[string][]string{
+			"_dnsaddr.example.com": {
+				"dnsaddr=/ip4/192.168.0.20/tcp/30303",
+				"dnsaddr=/ip6/2001:db8::20/tcp/30304",
+				"dnsaddr=/ip4/203.0.113.20/tcp/443/wss",
+			},
+		},
+	}))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	dns6, _ := ma.NewMultiaddr("/dns6/node.example.com/tcp/60000")
+	addrs, err := extractIPAddressesForENR(ctx, resolver, dns6)
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	require.True(t, addrs[0].IP.Equal(net.ParseIP("2001:db8::10")))
+	require.Equal(t, 60000, addrs[0].Port)
+
+	dns, _ := ma.NewMultiaddr("/dns/node.example.com/tcp/60000")
+	addrs, err = extractIPAddressesForENR(ctx, resolver, dns)
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	// Both the v4 and v6 records are candidates, but not the wss one
+	dnsaddr, _ := ma.NewMultiaddr("/dnsaddr/example.com")
+	addrs, err = extractIPAddressesForENR(ctx, resolver, dnsaddr)
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+
+	extAddr, err := selectMostExternalAddress(ctx, resolver, []ma.Multiaddr{dnsaddr})
+	require.NoError(t, err)
+	require.True(t, extAddr.IP.Equal(net.ParseIP("2001:db8::20")))
+	require.Equal(t, 30304, extAddr.Port)
+}
+
+func TestMultipleExternalAddressSelection(t *testing.T) {
+	a1, _ := ma.NewMultiaddr("/ip4/192.168.0.106/tcp/60000")
+	a2, _ := ma.NewMultiaddr("/ip4/203.0.113.20/tcp/60001")
+	a3, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/60000")
+	a4, _ := ma.NewMultiaddr("/ip4/198.51.100.7/tcp/60002")
+	a5, _ := ma.NewMultiaddr("/ip6/2001:db8::1/tcp/60003")
+
+	extAddrs, err := selectExternalAddresses(context.Background(), madns.DefaultResolver, []ma.Multiaddr{a1, a2, a3, a4, a5, a2})
+	require.NoError(t, err)
+	require.Len(t, extAddrs, 5)
+	require.Equal(t, "198.51.100.7:60002", extAddrs[0].String())
+	require.Equal(t, "203.0.113.20:60001", extAddrs[1].String())
+	require.Equal(t, "[2001:db8::1]:60003", extAddrs[2].String())
+	require.Equal(t, "192.168.0.106:60000", extAddrs[3].String())
+	require.Equal(t, "127.0.0.1:60000", extAddrs[4].String())
+
+	// The other external addresses are added to the multiaddr key
+	w := &WakuNode{opts: &WakuNodeParameters{}}
+	extAddr, multiaddr, err := w.getENRAddresses(context.Background(), []ma.Multiaddr{a5, a3, a2, a1, a4})
+	require.NoError(t, err)
+	require.Equal(t, "198.51.100.7:60002", extAddr.String())
+	require.Len(t, multiaddr, 2)
+	require.Equal(t, "/ip4/203.0.113.20/tcp/60001", multiaddr[0].String())
+	require.Equal(t, "/ip6/2001:db8::1/tcp/60003", multiaddr[1].String())
+}
+
 func TestExternalAddressSelection(t *testing.T) {
 	a1, _ := ma.NewMultiaddr("/ip4/192.168.0.106/tcp/60000/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")                                                                                                            // Valid
 	a2, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/60000/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")                                                                                                                // Valid but should not be prefered
@@ -25,14 +111,17 @@ func TestExternalAddressSelection(t *testing.T) {
 
 	addrs := []ma.Multiaddr{a1, a2, a3, a4, a5, a6, a7}
 
-	w := &WakuNode{}
+	w := &WakuNode{opts: &WakuNodeParameters{}}
 	extAddr, multiaddr, err := w.getENRAddresses(context.Background(), []ma.Multiaddr{a1, a2, a3, a4, a5, a6, a7})
 	a4NoP2P, _ := decapsulateP2P(a4)
+	a5NoP2P, _ := decapsulateP2P(a5)
 	require.NoError(t, err)
 	require.Equal(t, extAddr.IP, net.IPv4(192, 168, 0, 106))
 	require.Equal(t, extAddr.Port, 60000)
-	require.Equal(t, multiaddr[0].String(), a4NoP2P.String())
-	require.Len(t, multiaddr, 4)
+	require.Equal(t, multiaddr[0].String(), a5NoP2P.String()) // wss with a domain name is preferred
+	require.Equal(t, multiaddr[1].String(), a4NoP2P.String())
+	require.Len(t, multiaddr, 5)
+	require.Equal(t, "/ip4/192.168.1.20/tcp/19710", multiaddr[4].String()) // Remaining private address
 
 	addrs = append(addrs, a8, a9, a10, a11, a12)
 	extAddr, _, err = w.getENRAddresses(context.Background(), addrs)
@@ -46,3 +135,238 @@ func TestExternalAddressSelection(t *testing.T) {
 	require.Len(t, multiaddr, 1)
 	require.Equal(t, multiaddr[0].String(), a8RelayNode.String()) // Should have included circuit-relay addr
 }
+
+func TestUpdateLocalNodeSeq(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	localnode, err := wenr.NewLocalnode(key)
+	require.NoError(t, err)
+
+	w := &WakuNode{opts: &WakuNodeParameters{}, log: utils.Logger()}
+	wakuFlag := wenr.NewWakuEnrBitfield(true, true, true, true)
+	ipAddr := &net.TCPAddr{IP: net.IPv4(192, 168, 1, 241), Port: 60000}
+	wsAddr, _ := ma.NewMultiaddr("/dns4/www.status.im/tcp/443/wss")
+
+	updated, err := w.updateLocalNode(context.Background(), localnode, []ma.Multiaddr{wsAddr}, ipAddr, 50000, wakuFlag, nil, false)
+	require.NoError(t, err)
+	require.True(t, updated)
+	seq := localnode.Node().Seq()
+
+	// Same values, the record should not change
+	updated, err = w.updateLocalNode(context.Background(), localnode, []ma.Multiaddr{wsAddr}, ipAddr, 50000, wakuFlag, nil, false)
+	require.NoError(t, err)
+	require.False(t, updated)
+	require.Equal(t, seq, localnode.Node().Seq())
+
+	ipAddr = &net.TCPAddr{IP: net.IPv4(192, 168, 1, 242), Port: 60000}
+	updated, err = w.updateLocalNode(context.Background(), localnode, []ma.Multiaddr{wsAddr}, ipAddr, 50000, wakuFlag, nil, false)
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.Greater(t, localnode.Node().Seq(), seq)
+
+	// Same values with discv5 updates allowed
+	localnode, err = wenr.NewLocalnode(key)
+	require.NoError(t, err)
+	updated, err = w.updateLocalNode(context.Background(), localnode, []ma.Multiaddr{wsAddr}, ipAddr, 50000, wakuFlag, nil, true)
+	require.NoError(t, err)
+	require.True(t, updated)
+	seq = localnode.Node().Seq()
+
+	updated, err = w.updateLocalNode(context.Background(), localnode, []ma.Multiaddr{wsAddr}, ipAddr, 50000, wakuFlag, nil, true)
+	require.NoError(t, err)
+	require.False(t, updated)
+	require.Equal(t, seq, localnode.Node().Seq())
+
+	// Fields that are no longer written are removed
+	updated, err = w.updateLocalNode(context.Background(), localnode, nil, ipAddr, 50000, wakuFlag, nil, true)
+	require.NoError(t, err)
+	require.True(t, updated)
+	_, ok := localnode.Entries()[wenr.MultiaddrENRField]
+	require.False(t, ok)
+}
+
+func TestENRFallbackIP(t *testing.T) {
+	params := &WakuNodeParameters{}
+	require.Error(t, WithENRFallbackIP(nil)(params))
+	require.Error(t, WithENRFallbackIP(net.IPv4zero)(params))
+	require.Error(t, WithENRFallbackIP(net.IP{10, 0, 0})(params))
+	require.NoError(t, WithENRFallbackIP(net.ParseIP("10.0.0.5"))(params))
+
+	ipAddr := &net.TCPAddr{IP: net.IPv4zero, Port: 60000}
+
+	// Without a fallback, the loopback address must not be advertised
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	localnode, err := wenr.NewLocalnode(key)
+	require.NoError(t, err)
+	w := &WakuNode{opts: &WakuNodeParameters{}, log: utils.Logger()}
+	_, err = w.updateLocalNode(context.Background(), localnode, nil, ipAddr, 50000, 0, nil, true)
+	require.NoError(t, err)
+	require.False(t, localnode.Node().IP().IsLoopback())
+
+	key, err = crypto.GenerateKey()
+	require.NoError(t, err)
+	localnode, err = wenr.NewLocalnode(key)
+	require.NoError(t, err)
+	w = &WakuNode{opts: params, log: utils.Logger()}
+	_, err = w.updateLocalNode(context.Background(), localnode, nil, ipAddr, 50000, 0, nil, true)
+	require.NoError(t, err)
+	require.True(t, localnode.Node().IP().Equal(net.ParseIP("10.0.0.5")))
+}
+
+func TestValidateAdvertiseAddrs(t *testing.T) {
+	parse := func(s string) ma.Multiaddr {
+		addr, err := ma.NewMultiaddr(s)
+		require.NoError(t, err)
+		return addr
+	}
+
+	require.NoError(t, validateAdvertiseAddrs(nil, false))
+	require.NoError(t, validateAdvertiseAddrs([]ma.Multiaddr{parse("/ip4/203.0.113.1/tcp/60000")}, false))
+	require.NoError(t, validateAdvertiseAddrs([]ma.Multiaddr{parse("/dns4/www.status.im/tcp/443/wss")}, false))
+
+	for _, s := range []string{"/ip4/127.0.0.1/tcp/60000", "/ip4/192.168.1.1/tcp/60000", "/ip6/fe80::1/tcp/60000"} {
+		addrs := []ma.Multiaddr{parse("/ip4/203.0.113.1/tcp/60000"), parse(s)}
+		require.Error(t, validateAdvertiseAddrs(addrs, false), s)
+		require.NoError(t, validateAdvertiseAddrs(addrs, true), s)
+	}
+
+	for _, s := range []string{"/ip4/0.0.0.0/tcp/60000", "/ip6/::/tcp/60000", "/ip4/224.0.0.1/tcp/60000"} {
+		require.Error(t, validateAdvertiseAddrs([]ma.Multiaddr{parse(s)}, true), s)
+	}
+
+	// The node is not created with an address that other peers cannot reach
+	_, err := New(WithAdvertiseAddresses(parse("/ip4/127.0.0.1/tcp/60000")))
+	require.Error(t, err)
+}
+
+func TestPrioritizedAdvertiseAddresses(t *testing.T) {
+	parse := func(s string) ma.Multiaddr {
+		addr, err := ma.NewMultiaddr(s)
+		require.NoError(t, err)
+		return addr
+	}
+
+	tests := []struct {
+		name         string
+		advertise    []ma.Multiaddr
+		primary      *net.TCPAddr
+		multiaddrENR []ma.Multiaddr
+	}{
+		{
+			name:         "ipv4 and ipv6",
+			advertise:    []ma.Multiaddr{parse("/ip6/2001:db8::1/tcp/60001"), parse("/ip4/203.0.113.1/tcp/60000")},
+			primary:      &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 60001},
+			multiaddrENR: []ma.Multiaddr{parse("/ip4/203.0.113.1/tcp/60000")},
+		},
+		{
+			name:         "ipv4 only",
+			advertise:    []ma.Multiaddr{parse("/ip4/203.0.113.2/tcp/60000"), parse("/ip4/203.0.113.1/tcp/60000")},
+			primary:      &net.TCPAddr{IP: net.ParseIP("203.0.113.2"), Port: 60000},
+			multiaddrENR: []ma.Multiaddr{parse("/ip4/203.0.113.1/tcp/60000")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := crypto.GenerateKey()
+			require.NoError(t, err)
+			localnode, err := wenr.NewLocalnode(key)
+			require.NoError(t, err)
+
+			params := &WakuNodeParameters{}
+			require.NoError(t, WithAdvertiseAddresses(tc.advertise...)(params))
+			w := &WakuNode{opts: params, log: utils.Logger()}
+
+			// The order of the advertised addresses is kept, instead of sorting them by IP
+			extAddr, multiaddrs, err := w.getENRAddresses(context.Background(), tc.advertise)
+			require.NoError(t, err)
+			require.Equal(t, tc.primary.String(), extAddr.String())
+			require.Equal(t, tc.multiaddrENR, multiaddrs)
+
+			// Even with discv5 auto update enabled, the advertised address is static
+			_, err = w.updateLocalNode(context.Background(), localnode, multiaddrs, extAddr, 50000, 0, params.advertiseAddrs, true)
+			require.NoError(t, err)
+			require.True(t, localnode.Node().IP().Equal(tc.primary.IP))
+			require.Equal(t, tc.primary.IP.To4() == nil, localnode.Node().Load(&enr.IPv4{}) != nil)
+		})
+	}
+}
+
+func TestCustomENRFields(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	localnode, err := wenr.NewLocalnode(key)
+	require.NoError(t, err)
+
+	params := &WakuNodeParameters{}
+	require.NoError(t, WithENRField("app", []byte{0x01})(params))
+	require.ErrorIs(t, WithENRField(wenr.MultiaddrENRField, []byte{0x01})(params), wenr.ErrReservedENRField)
+	require.ErrorIs(t, WithENRField("big", make([]byte, 400))(params), wenr.ErrENRTooLarge)
+
+	w := &WakuNode{opts: params, log: utils.Logger(), localNode: localnode}
+	ipAddr := &net.TCPAddr{IP: net.IPv4(192, 168, 1, 241), Port: 60000}
+	_, err = w.updateLocalNode(context.Background(), localnode, nil, ipAddr, 50000, 0, nil, false)
+	require.NoError(t, err)
+
+	value, err := w.GetENRField("app")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01}, value)
+
+	require.NoError(t, w.SetENRField("app", []byte{0x02}))
+	require.ErrorIs(t, w.SetENRField(wenr.WakuENRField, []byte{0x02}), wenr.ErrReservedENRField)
+
+	// A field that does not fit is refused instead of making the signature of the ENR panic
+	require.ErrorIs(t, w.SetENRField("big", make([]byte, 400)), wenr.ErrENRTooLarge)
+	require.NotNil(t, w.localNode.Node())
+
+	// The field is kept when the ENR is rebuilt
+	_, err = w.updateLocalNode(context.Background(), localnode, nil, &net.TCPAddr{IP: net.IPv4(192, 168, 1, 242), Port: 60000}, 50000, 0, nil, false)
+	require.NoError(t, err)
+	value, err = w.GetENRField("app")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x02}, value)
+}
+
+func TestPrioritizeENRMultiaddrs(t *testing.T) {
+	relay1WS, _ := ma.NewMultiaddr("/ip4/203.0.113.1/tcp/8000/ws/p2p/16Uiu2HAmDQugwDHM3YeUp86iGjrUvbdw3JPRgikC7YoGBsT2ymMg")
+	relay1WSS, _ := ma.NewMultiaddr("/dns4/relay-01.status.im/tcp/443/wss/p2p/16Uiu2HAmDQugwDHM3YeUp86iGjrUvbdw3JPRgikC7YoGBsT2ymMg")
+	relay2WSS, _ := ma.NewMultiaddr("/ip4/203.0.113.2/tcp/443/wss/p2p/16Uiu2HAmDCp8XJ9z1ev18zuv8NHekAsjNyezAvmMfFEJkiharitG")
+	relay1TCP, _ := ma.NewMultiaddr("/ip4/203.0.113.1/tcp/30303/p2p/16Uiu2HAmDQugwDHM3YeUp86iGjrUvbdw3JPRgikC7YoGBsT2ymMg")
+	relay3TCP, _ := ma.NewMultiaddr("/ip4/203.0.113.3/tcp/30303/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+
+	result := prioritizeENRMultiaddrs([]ma.Multiaddr{relay1TCP, relay3TCP, relay1WS, relay2WSS, relay1WSS, relay1WSS, relay3TCP})
+	require.Equal(t, []ma.Multiaddr{relay1WSS, relay2WSS, relay3TCP, relay1WS, relay1TCP}, result)
+
+	w := &WakuNode{opts: &WakuNodeParameters{maxENRMultiaddrs: 2}, log: utils.Logger()}
+	require.Equal(t, []ma.Multiaddr{relay1WSS, relay2WSS}, w.boundENRMultiaddrs(result))
+
+	w.opts.maxENRMultiaddrs = 0
+	require.Len(t, w.boundENRMultiaddrs(result), 5)
+}
+
+func TestQUICAddressSelection(t *testing.T) {
+	tcp, _ := ma.NewMultiaddr("/ip4/203.0.113.1/tcp/60000/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+	quic, _ := ma.NewMultiaddr("/ip4/203.0.113.1/udp/60000/quic-v1/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+	quic6, _ := ma.NewMultiaddr("/ip6/2001:db8::1/udp/60001/quic-v1/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+	quicNoPort, _ := ma.NewMultiaddr("/ip4/203.0.113.1/udp/0/quic-v1/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+	circuit, _ := ma.NewMultiaddr("/ip4/203.0.113.2/udp/30303/quic-v1/p2p/16Uiu2HAmDQugwDHM3YeUp86iGjrUvbdw3JPRgikC7YoGBsT2ymMg/p2p-circuit/p2p/16Uiu2HAmUVVrJo1KMw4QwUANYF7Ws4mfcRqf9xHaaGP87GbMuY2f")
+
+	quicAddrs, err := selectQUICListenAddresses([]ma.Multiaddr{tcp, quic, quic6, circuit})
+	require.NoError(t, err)
+	require.Len(t, quicAddrs, 2)
+	require.Equal(t, "/ip4/203.0.113.1/udp/60000/quic-v1", quicAddrs[0].String())
+	require.Equal(t, "/ip6/2001:db8::1/udp/60001/quic-v1", quicAddrs[1].String())
+
+	// QUIC addresses are not used for the ENR default keys
+	_, err = extractIPAddressesForENR(context.Background(), madns.DefaultResolver, quic)
+	require.Error(t, err)
+
+	w := &WakuNode{opts: &WakuNodeParameters{}}
+	extAddr, multiaddr, err := w.getENRAddresses(context.Background(), []ma.Multiaddr{quic, tcp, quic6, quicNoPort})
+	require.NoError(t, err)
+	require.Equal(t, "203.0.113.1:60000", extAddr.String())
+	require.Len(t, multiaddr, 2)
+	require.Equal(t, "/ip4/203.0.113.1/udp/60000/quic-v1", multiaddr[0].String())
+	require.Equal(t, "/ip6/2001:db8::1/udp/60001/quic-v1", multiaddr[1].String())
+}
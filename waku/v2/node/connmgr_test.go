@@ -0,0 +1,116 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/v2/peerstore"
+	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/filter"
+	"github.com/waku-org/go-waku/waku/v2/protocol/legacy_store"
+	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+)
+
+func TestConnectionLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	lowWatermark := 2
+	highWatermark := 4
+
+	hostAddr, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
+	wakuNode, err := New(
+		WithHostAddress(hostAddr),
+		WithConnectionLimits(lowWatermark, highWatermark, 0),
+	)
+	require.NoError(t, err)
+	require.NoError(t, wakuNode.Start(ctx))
+	defer wakuNode.Stop()
+
+	// A storenode is a service peer, so its connection must survive trimming
+	storeNode, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer storeNode.Close()
+
+	_, err = wakuNode.AddPeer(utils.EncapsulatePeerID(storeNode.ID(), storeNode.Addrs()[0])[0], peerstore.Static, nil, legacy_store.StoreID_v20beta4)
+	require.NoError(t, err)
+	require.NoError(t, wakuNode.DialPeerWithInfo(ctx, peer.AddrInfo{ID: storeNode.ID(), Addrs: storeNode.Addrs()}))
+
+	for i := 0; i < 2*highWatermark; i++ {
+		h, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+		require.NoError(t, err)
+		defer h.Close()
+		require.NoError(t, h.Connect(ctx, peer.AddrInfo{ID: wakuNode.Host().ID(), Addrs: wakuNode.Host().Addrs()}))
+	}
+
+	require.Greater(t, len(wakuNode.Host().Network().Peers()), highWatermark)
+
+	require.Eventually(t, func() bool {
+		wakuNode.Host().ConnManager().TrimOpenConns(ctx)
+		return len(wakuNode.Host().Network().Peers()) <= highWatermark
+	}, 10*time.Second, 100*time.Millisecond)
+
+	require.Equal(t, network.Connected, wakuNode.Host().Network().Connectedness(storeNode.ID()))
+}
+
+func TestConnectionLimitsFilterPeer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	highWatermark := 4
+
+	hostAddr1, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
+	fullNode, err := New(
+		WithHostAddress(hostAddr1),
+		WithWakuRelay(),
+		WithWakuFilterFullNode(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, fullNode.Start(ctx))
+	defer fullNode.Stop()
+
+	hostAddr2, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
+	lightNode, err := New(
+		WithHostAddress(hostAddr2),
+		WithWakuFilterLightNode(),
+		WithConnectionLimits(2, highWatermark, 0),
+	)
+	require.NoError(t, err)
+	require.NoError(t, lightNode.Start(ctx))
+	defer lightNode.Stop()
+
+	// The full node is not added as a service peer, so only the filter subscription protects it
+	require.NoError(t, lightNode.DialPeerWithInfo(ctx, peer.AddrInfo{ID: fullNode.Host().ID(), Addrs: fullNode.Host().Addrs()}))
+
+	_, err = lightNode.FilterLightnode().Subscribe(ctx, protocol.ContentFilter{
+		PubsubTopic:   relay.DefaultWakuTopic,
+		ContentTopics: protocol.NewContentTopicSet("abc"),
+	}, filter.WithPeer(fullNode.Host().ID()))
+	require.NoError(t, err)
+
+	for i := 0; i < 2*highWatermark; i++ {
+		h, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+		require.NoError(t, err)
+		defer h.Close()
+		require.NoError(t, h.Connect(ctx, peer.AddrInfo{ID: lightNode.Host().ID(), Addrs: lightNode.Host().Addrs()}))
+	}
+
+	require.Eventually(t, func() bool {
+		lightNode.Host().ConnManager().TrimOpenConns(ctx)
+		return len(lightNode.Host().Network().Peers()) <= highWatermark
+	}, 10*time.Second, 100*time.Millisecond)
+
+	require.Equal(t, network.Connected, lightNode.Host().Network().Connectedness(fullNode.Host().ID()))
+
+	// Once unsubscribed, the full node is no longer protected
+	_, err = lightNode.FilterLightnode().UnsubscribeAll(ctx)
+	require.NoError(t, err)
+	require.False(t, lightNode.Host().ConnManager().IsProtected(fullNode.Host().ID(), ""))
+}
@@ -0,0 +1,95 @@
+package node
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+// DefaultDNSCacheTTL is the time the DNS resolution results used for the ENR are cached
+const DefaultDNSCacheTTL = 5 * time.Minute
+
+// DefaultDNSCacheGracePeriod is the time after the expiration of a cached DNS resolution result
+// during which it is still used if the resolution fails
+const DefaultDNSCacheGracePeriod = time.Hour
+
+type dnsCacheEntry[T any] struct {
+	value     T
+	createdAt time.Time
+}
+
+// dnsCache is a madns.BasicResolver that caches the results of another resolver, keyed by hostname
+type dnsCache struct {
+	sync.Mutex
+
+	resolver    madns.BasicResolver
+	ttl         time.Duration
+	gracePeriod time.Duration
+	now         func() time.Time
+
+	ipAddrs map[string]dnsCacheEntry[[]net.IPAddr]
+	txts    map[string]dnsCacheEntry[[]string]
+}
+
+var _ madns.BasicResolver = (*dnsCache)(nil)
+
+func newDNSCache(resolver madns.BasicResolver, ttl time.Duration, gracePeriod time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver:    resolver,
+		ttl:         ttl,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+		ipAddrs:     make(map[string]dnsCacheEntry[[]net.IPAddr]),
+		txts:        make(map[string]dnsCacheEntry[[]string]),
+	}
+}
+
+func (c *dnsCache) LookupIPAddr(ctx context.Context, name string) ([]net.IPAddr, error) {
+	return lookup(c, c.ipAddrs, name, func() ([]net.IPAddr, error) {
+		return c.resolver.LookupIPAddr(ctx, name)
+	})
+}
+
+func (c *dnsCache) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return lookup(c, c.txts, name, func() ([]string, error) {
+		return c.resolver.LookupTXT(ctx, name)
+	})
+}
+
+// Refresh drops all the cached results, so the next lookups are resolved again
+func (c *dnsCache) Refresh() {
+	c.Lock()
+	defer c.Unlock()
+
+	clear(c.ipAddrs)
+	clear(c.txts)
+}
+
+func lookup[T any](c *dnsCache, entries map[string]dnsCacheEntry[T], name string, resolve func() (T, error)) (T, error) {
+	c.Lock()
+	entry, ok := entries[name]
+	c.Unlock()
+
+	now := c.now()
+	if ok && now.Sub(entry.createdAt) < c.ttl {
+		return entry.value, nil
+	}
+
+	value, err := resolve()
+	if err != nil {
+		// Serving the last good result to tolerate flaky DNS servers
+		if ok && now.Sub(entry.createdAt) < c.ttl+c.gracePeriod {
+			return entry.value, nil
+		}
+		return value, err
+	}
+
+	c.Lock()
+	entries[name] = dnsCacheEntry[T]{value: value, createdAt: now}
+	c.Unlock()
+
+	return value, nil
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingResolver struct {
+	lookups int
+	err     error
+	ip      net.IP
+}
+
+func (r *countingResolver) LookupIPAddr(ctx context.Context, name string) ([]net.IPAddr, error) {
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []net.IPAddr{{IP: r.ip}}, nil
+}
+
+func (r *countingResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	r.lookups++
+	return nil, r.err
+}
+
+func TestDNSCache(t *testing.T) {
+	ctx := context.Background()
+	resolver := &countingResolver{ip: net.ParseIP("203.0.113.1")}
+	cache := newDNSCache(resolver, time.Minute, time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	addrs, err := cache.LookupIPAddr(ctx, "node.example.com")
+	require.NoError(t, err)
+	require.True(t, addrs[0].IP.Equal(resolver.ip))
+
+	// Cached
+	_, err = cache.LookupIPAddr(ctx, "node.example.com")
+	require.NoError(t, err)
+	require.Equal(t, 1, resolver.lookups)
+
+	// Expired
+	now = now.Add(2 * time.Minute)
+	resolver.ip = net.ParseIP("203.0.113.2")
+	addrs, err = cache.LookupIPAddr(ctx, "node.example.com")
+	require.NoError(t, err)
+	require.Equal(t, 2, resolver.lookups)
+	require.True(t, addrs[0].IP.Equal(resolver.ip))
+
+	// Failed resolution within the grace period returns the last good result
+	now = now.Add(30 * time.Minute)
+	resolver.err = errors.New("dns failure")
+	addrs, err = cache.LookupIPAddr(ctx, "node.example.com")
+	require.NoError(t, err)
+	require.True(t, addrs[0].IP.Equal(net.ParseIP("203.0.113.2")))
+
+	// Failed resolution after the grace period
+	now = now.Add(2 * time.Hour)
+	_, err = cache.LookupIPAddr(ctx, "node.example.com")
+	require.Error(t, err)
+
+	// Refreshing drops the cached results
+	resolver.err = nil
+	_, err = cache.LookupIPAddr(ctx, "node.example.com")
+	require.NoError(t, err)
+	lookups := resolver.lookups
+	cache.Refresh()
+	_, err = cache.LookupIPAddr(ctx, "node.example.com")
+	require.NoError(t, err)
+	require.Equal(t, lookups+1, resolver.lookups)
+}
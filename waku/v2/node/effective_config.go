@@ -0,0 +1,179 @@
+package node
+
+import (
+	"time"
+)
+
+// redacted replaces the value of secrets in the effective configuration
+const redacted = "[redacted]"
+
+// EffectiveConfig describes the configuration a WakuNode is running with, once the
+// defaults have been applied. Secrets such as the private key, the keystore password
+// or the ethereum client address (which often embeds an API key) are redacted
+type EffectiveConfig struct {
+	Protocols ProtocolsConfig `json:"protocols"`
+	Limits    LimitsConfig    `json:"limits"`
+	Discovery DiscoveryConfig `json:"discovery"`
+	RLN       RLNConfig       `json:"rln"`
+	ENR       ENRConfig       `json:"enr"`
+}
+
+// ProtocolsConfig indicates which protocols and transports are enabled
+type ProtocolsConfig struct {
+	Relay            bool     `json:"relay"`
+	FilterFullNode   bool     `json:"filterFullNode"`
+	FilterLightNode  bool     `json:"filterLightNode"`
+	Store            bool     `json:"store"`
+	LightPush        bool     `json:"lightPush"`
+	WebSockets       bool     `json:"websockets"`
+	SecureWebSockets bool     `json:"secureWebsockets"`
+	NTP              bool     `json:"ntp"`
+	NTPURLs          []string `json:"ntpURLs,omitempty"`
+}
+
+// LimitsConfig contains the limits enforced by the node
+type LimitsConfig struct {
+	MaxPeerConnections           int            `json:"maxPeerConnections"`
+	MaxConnectionsPerIP          int            `json:"maxConnectionsPerIP"`
+	PeerStoreCapacity            int            `json:"peerStoreCapacity"`
+	MaxMsgSizeBytes              int            `json:"maxMsgSizeBytes"`
+	MinRelayPeersToPublish       int            `json:"minRelayPeersToPublish"`
+	StoreRateLimit               float64        `json:"storeRateLimit"`
+	MaxStreams                   map[string]int `json:"maxStreams,omitempty"`
+	KeepAliveRandomPeersInterval time.Duration  `json:"keepAliveRandomPeersInterval"`
+	KeepAliveAllPeersInterval    time.Duration  `json:"keepAliveAllPeersInterval"`
+	ConnectionKeepAliveInterval  time.Duration  `json:"connectionKeepAliveInterval"`
+	IdleConnectionTimeout        time.Duration  `json:"idleConnectionTimeout"`
+}
+
+// DiscoveryConfig describes the sources used to discover peers
+type DiscoveryConfig struct {
+	DiscV5           bool     `json:"discv5"`
+	DiscV5UDPPort    uint     `json:"discv5UDPPort,omitempty"`
+	DiscV5Bootnodes  []string `json:"discv5Bootnodes,omitempty"`
+	DiscV5AutoUpdate bool     `json:"discv5AutoUpdate"`
+	PeerExchange     bool     `json:"peerExchange"`
+	RendezvousPoint  bool     `json:"rendezvousPoint"`
+}
+
+// RLNConfig describes the RLN relay settings
+type RLNConfig struct {
+	Enabled                   bool   `json:"enabled"`
+	Mode                      string `json:"mode,omitempty"`
+	MembershipIndex           *uint  `json:"membershipIndex,omitempty"`
+	TreePath                  string `json:"treePath,omitempty"`
+	KeystorePath              string `json:"keystorePath,omitempty"`
+	KeystorePassword          string `json:"keystorePassword,omitempty"`
+	ETHClientAddress          string `json:"ethClientAddress,omitempty"`
+	MembershipContractAddress string `json:"membershipContractAddress,omitempty"`
+	HasIdentityCredential     bool   `json:"hasIdentityCredential"`
+}
+
+// ENRConfig contains the settings used to build the node record
+type ENRConfig struct {
+	ClusterID          uint16   `json:"clusterID"`
+	Shards             []uint16 `json:"shards,omitempty"`
+	HostAddress        string   `json:"hostAddress,omitempty"`
+	ListenAddresses    []string `json:"listenAddresses,omitempty"`
+	AdvertiseAddresses []string `json:"advertiseAddresses,omitempty"`
+	DNS4DomainName     string   `json:"dns4DomainName,omitempty"`
+}
+
+// EffectiveConfig returns the configuration the node is running with, after the defaults
+// have been applied to the options passed when creating it. Secrets are redacted
+func (w *WakuNode) EffectiveConfig() EffectiveConfig {
+	opts := w.opts
+
+	cfg := EffectiveConfig{
+		Protocols: ProtocolsConfig{
+			Relay:            opts.enableRelay,
+			FilterFullNode:   opts.enableFilterFullNode,
+			FilterLightNode:  opts.enableFilterLightNode,
+			Store:            opts.enableStore,
+			LightPush:        opts.enableLightPush,
+			WebSockets:       opts.enableWS,
+			SecureWebSockets: opts.enableWSS,
+			NTP:              opts.enableNTP,
+			NTPURLs:          opts.ntpURLs,
+		},
+		Limits: LimitsConfig{
+			MaxPeerConnections:           opts.maxPeerConnections,
+			MaxConnectionsPerIP:          opts.maxConnectionsPerIP,
+			PeerStoreCapacity:            opts.peerStoreCapacity,
+			MaxMsgSizeBytes:              opts.maxMsgSizeBytes,
+			MinRelayPeersToPublish:       opts.minRelayPeersToPublish,
+			StoreRateLimit:               float64(opts.storeRateLimit),
+			KeepAliveRandomPeersInterval: opts.keepAliveRandomPeersInterval,
+			KeepAliveAllPeersInterval:    opts.keepAliveAllPeersInterval,
+			ConnectionKeepAliveInterval:  opts.connKeepAliveInterval,
+			IdleConnectionTimeout:        opts.idleConnTimeout,
+		},
+		Discovery: DiscoveryConfig{
+			DiscV5:           opts.enableDiscV5,
+			DiscV5AutoUpdate: opts.discV5autoUpdate,
+			PeerExchange:     opts.enablePeerExchange,
+			RendezvousPoint:  opts.enableRendezvousPoint,
+		},
+		RLN: RLNConfig{
+			Enabled:               opts.enableRLN,
+			HasIdentityCredential: opts.rlnIdentityCredential != nil,
+		},
+		ENR: ENRConfig{
+			ClusterID:      opts.clusterID,
+			DNS4DomainName: opts.dns4Domain,
+		},
+	}
+
+	if len(opts.maxStreams) != 0 {
+		cfg.Limits.MaxStreams = make(map[string]int)
+		for p, n := range opts.maxStreams {
+			cfg.Limits.MaxStreams[string(p)] = n
+		}
+	}
+
+	if opts.enableDiscV5 {
+		cfg.Discovery.DiscV5UDPPort = opts.udpPort
+		for _, n := range opts.discV5bootnodes {
+			cfg.Discovery.DiscV5Bootnodes = append(cfg.Discovery.DiscV5Bootnodes, n.String())
+		}
+	}
+
+	if opts.enableRLN {
+		cfg.RLN.Mode = "static"
+		if opts.rlnRelayDynamic {
+			cfg.RLN.Mode = "dynamic"
+		} else if opts.rlnRelayValidationOnly {
+			cfg.RLN.Mode = "validation"
+		}
+		cfg.RLN.MembershipIndex = opts.rlnRelayMemIndex
+		cfg.RLN.TreePath = opts.rlnTreePath
+		cfg.RLN.KeystorePath = opts.keystorePath
+		if opts.keystorePassword != "" {
+			cfg.RLN.KeystorePassword = redacted
+		}
+		if opts.rlnETHClientAddress != "" {
+			cfg.RLN.ETHClientAddress = redacted
+		}
+		if opts.rlnRelayDynamic {
+			cfg.RLN.MembershipContractAddress = opts.rlnMembershipContractAddress.Hex()
+		}
+	}
+
+	if opts.shards != nil {
+		cfg.ENR.Shards = opts.shards.ShardIDs
+	}
+
+	if opts.hostAddr != nil {
+		cfg.ENR.HostAddress = opts.hostAddr.String()
+	}
+
+	for _, addr := range opts.multiAddr {
+		cfg.ENR.ListenAddresses = append(cfg.ENR.ListenAddresses, addr.String())
+	}
+
+	for _, addr := range opts.advertiseAddrs {
+		cfg.ENR.AdvertiseAddresses = append(cfg.ENR.AdvertiseAddresses, addr.String())
+	}
+
+	return cfg
+}
@@ -0,0 +1,36 @@
+//go:build !gowaku_no_rln
+// +build !gowaku_no_rln
+
+package node
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveConfigRLN(t *testing.T) {
+	membershipIndex := uint(5)
+	options := []WakuNodeOption{
+		WithDynamicRLNRelay("./keystore.json", "secret-password", "./rln_tree.db", common.HexToAddress("0x1234"), &membershipIndex, nil, "https://sepolia.infura.io/v3/secret-key"),
+	}
+
+	params := new(WakuNodeParameters)
+	for _, opt := range options {
+		require.NoError(t, opt(params))
+	}
+
+	wakuNode := &WakuNode{opts: params}
+	cfg := wakuNode.EffectiveConfig()
+
+	require.True(t, cfg.RLN.Enabled)
+	require.Equal(t, "dynamic", cfg.RLN.Mode)
+	require.Equal(t, &membershipIndex, cfg.RLN.MembershipIndex)
+	require.Equal(t, "./keystore.json", cfg.RLN.KeystorePath)
+	require.Equal(t, common.HexToAddress("0x1234").Hex(), cfg.RLN.MembershipContractAddress)
+
+	// Secrets are not exposed
+	require.Equal(t, redacted, cfg.RLN.KeystorePassword)
+	require.Equal(t, redacted, cfg.RLN.ETHClientAddress)
+}
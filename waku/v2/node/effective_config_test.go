@@ -0,0 +1,64 @@
+package node
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/v2/protocol/legacy_store"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	hostAddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	wakuNode, err := New(
+		WithHostAddress(hostAddr),
+		WithClusterID(16),
+		WithShards([]uint16{1, 2}),
+		WithDNS4Domain("example.org"),
+		WithWakuRelayAndMinPeers(2),
+		WithWakuFilterFullNode(),
+		WithLightPush(),
+		WithDiscoveryV5(9123, nil, true),
+		WithMaxPeerConnections(50),
+		WithMaxStreams(legacy_store.StoreID_v20beta4, 10),
+		WithKeepAlive(time.Minute, time.Hour),
+		WithIdleConnectionTimeout(5*time.Minute),
+	)
+	require.NoError(t, err)
+
+	cfg := wakuNode.EffectiveConfig()
+
+	require.True(t, cfg.Protocols.Relay)
+	require.True(t, cfg.Protocols.FilterFullNode)
+	require.True(t, cfg.Protocols.LightPush)
+	require.False(t, cfg.Protocols.Store)
+	require.False(t, cfg.Protocols.FilterLightNode)
+
+	require.Equal(t, 50, cfg.Limits.MaxPeerConnections)
+	require.Equal(t, 2, cfg.Limits.MinRelayPeersToPublish)
+	require.Equal(t, map[string]int{string(legacy_store.StoreID_v20beta4): 10}, cfg.Limits.MaxStreams)
+	require.Equal(t, time.Minute, cfg.Limits.KeepAliveRandomPeersInterval)
+	require.Equal(t, time.Hour, cfg.Limits.KeepAliveAllPeersInterval)
+	require.Equal(t, 5*time.Minute, cfg.Limits.IdleConnectionTimeout)
+
+	// Defaults are applied to the options that were not set
+	require.Equal(t, DefaultMaxPeerStoreCapacity, cfg.Limits.PeerStoreCapacity)
+	require.Equal(t, DefaultMaxConnectionsPerIP, cfg.Limits.MaxConnectionsPerIP)
+	require.Equal(t, float64(8), cfg.Limits.StoreRateLimit)
+
+	require.True(t, cfg.Discovery.DiscV5)
+	require.True(t, cfg.Discovery.DiscV5AutoUpdate)
+	require.Equal(t, uint(9123), cfg.Discovery.DiscV5UDPPort)
+	require.False(t, cfg.Discovery.PeerExchange)
+
+	require.False(t, cfg.RLN.Enabled)
+
+	require.Equal(t, uint16(16), cfg.ENR.ClusterID)
+	require.Equal(t, []uint16{1, 2}, cfg.ENR.Shards)
+	require.Equal(t, "example.org", cfg.ENR.DNS4DomainName)
+	require.Equal(t, hostAddr.String(), cfg.ENR.HostAddress)
+	require.NotEmpty(t, cfg.ENR.ListenAddresses)
+}
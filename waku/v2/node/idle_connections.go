@@ -0,0 +1,83 @@
+package node
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/waku-org/go-waku/logging"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+	"go.uber.org/zap"
+)
+
+// idleCheckFactor determines how often the connections are checked for inactivity,
+// as a fraction of the idle timeout
+const idleCheckFactor = 4
+
+// closeIdleConnections periodically closes the connections that had no open streams
+// for longer than idleTimeout. Connections to protected peers or to peers with active
+// filter subscriptions are kept open
+func (w *WakuNode) closeIdleConnections(ctx context.Context, idleTimeout time.Duration) {
+	defer utils.LogOnPanic()
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(idleTimeout / idleCheckFactor)
+	defer ticker.Stop()
+
+	idleSince := make(map[network.Conn]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			conns := make(map[network.Conn]struct{})
+			for _, conn := range w.host.Network().Conns() {
+				conns[conn] = struct{}{}
+
+				if len(conn.GetStreams()) != 0 || w.hasActiveSubscription(conn.RemotePeer()) {
+					idleSince[conn] = now
+					continue
+				}
+
+				since, ok := idleSince[conn]
+				if !ok {
+					since = conn.Stat().Opened
+					idleSince[conn] = since
+				}
+
+				if now.Sub(since) < idleTimeout {
+					continue
+				}
+
+				w.log.Debug("closing idle connection", logging.HostID("peer", conn.RemotePeer()), zap.Duration("idle", now.Sub(since)))
+				if err := conn.Close(); err != nil {
+					w.log.Debug("closing idle connection", zap.Error(err))
+				}
+				delete(idleSince, conn)
+			}
+
+			// Forget about the connections that were already closed
+			for conn := range idleSince {
+				if _, ok := conns[conn]; !ok {
+					delete(idleSince, conn)
+				}
+			}
+		}
+	}
+}
+
+// hasActiveSubscription determines whether a peer is protected from being disconnected
+// or has active filter subscriptions with this node
+func (w *WakuNode) hasActiveSubscription(peerID peer.ID) bool {
+	if w.host.ConnManager().IsProtected(peerID, "") {
+		return true
+	}
+
+	if fullNode := w.FilterFullNode(); w.opts.enableFilterFullNode && fullNode != nil && fullNode.HasSubscriber(peerID) {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package node
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleConnectionTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	idleTimeout := 2 * time.Second
+
+	hostAddr, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
+	wakuNode, err := New(
+		WithHostAddress(hostAddr),
+		WithConnectionKeepAlive(time.Second),
+		WithIdleConnectionTimeout(idleTimeout),
+	)
+	require.NoError(t, err)
+	require.NoError(t, wakuNode.Start(ctx))
+	defer wakuNode.Stop()
+
+	// Streams are kept open until the test finishes
+	const testProtocol = libp2pProtocol.ID("/test/idle/1.0.0")
+	wakuNode.Host().SetStreamHandler(testProtocol, func(s network.Stream) {
+		_, _ = io.Copy(io.Discard, s)
+		s.Close()
+	})
+
+	idleHost, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer idleHost.Close()
+
+	activeHost, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer activeHost.Close()
+
+	nodeInfo := peer.AddrInfo{ID: wakuNode.Host().ID(), Addrs: wakuNode.Host().Addrs()}
+	require.NoError(t, idleHost.Connect(ctx, nodeInfo))
+	require.NoError(t, activeHost.Connect(ctx, nodeInfo))
+
+	stream, err := activeHost.NewStream(ctx, wakuNode.Host().ID(), testProtocol)
+	require.NoError(t, err)
+	defer stream.Close()
+	_, err = stream.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return wakuNode.Host().Network().Connectedness(idleHost.ID()) != network.Connected
+	}, 3*idleTimeout, 100*time.Millisecond)
+
+	require.Equal(t, network.Connected, wakuNode.Host().Network().Connectedness(activeHost.ID()))
+}
@@ -1,17 +1,23 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"sort"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/libp2p/go-libp2p/core/event"
 	"github.com/multiformats/go-multiaddr"
 	ma "github.com/multiformats/go-multiaddr"
 	madns "github.com/multiformats/go-multiaddr-dns"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/waku-org/go-waku/logging"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
 	wenr "github.com/waku-org/go-waku/waku/v2/protocol/enr"
 	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
@@ -19,63 +25,161 @@ import (
 	"go.uber.org/zap"
 )
 
-func (w *WakuNode) updateLocalNode(localnode *enode.LocalNode, multiaddrs []ma.Multiaddr, ipAddr *net.TCPAddr, udpPort uint, wakuFlags wenr.WakuEnrBitfield, advertiseAddr []ma.Multiaddr, shouldAutoUpdate bool) error {
-	var options []wenr.ENROption
-	options = append(options, wenr.WithUDPPort(udpPort))
-	options = append(options, wenr.WithWakuBitfield(wakuFlags))
+// updateLocalNode writes the addresses and capabilities of the node in the ENR. Since the localnode
+// increases its sequence number on every modification, entries are only written when their value
+// changes, and the fields that are no longer needed are removed afterwards instead of being reset
+// beforehand. It returns whether the ENR was updated
+func (w *WakuNode) updateLocalNode(ctx context.Context, localnode *enode.LocalNode, multiaddrs []ma.Multiaddr, ipAddr *net.TCPAddr, udpPort uint, wakuFlags wenr.WakuEnrBitfield, advertiseAddr []ma.Multiaddr, shouldAutoUpdate bool) (bool, error) {
+	var advertisedIP *net.TCPAddr
+	if advertiseAddr != nil {
+		// Advertised addresses disable libp2p address updates and discv5
+		// predictions. The one with the highest priority is written in the
+		// default keys, and the rest are part of the multiaddr key
+		ipAddrs, err := selectAdvertisedAddresses(ctx, w.resolver(), advertiseAddr)
+		if err != nil {
+			return false, err
+		}
+		advertisedIP = ipAddrs[0]
+	}
 
-	// Reset ENR fields
-	wenr.DeleteField(localnode, wenr.MultiaddrENRField)
-	wenr.DeleteField(localnode, enr.TCP(0).ENRKey())
-	wenr.DeleteField(localnode, enr.IPv4{}.ENRKey())
-	wenr.DeleteField(localnode, enr.IPv6{}.ENRKey())
+	before, err := encodeEntries(localnode)
+	if err != nil {
+		return false, err
+	}
 
-	if advertiseAddr != nil {
-		// An advertised address disables libp2p address updates
-		// and discv5 predictions
-		ipAddr, err := selectMostExternalAddress(advertiseAddr)
+	err = w.applyLocalNodeUpdate(localnode, multiaddrs, ipAddr, udpPort, wakuFlags, advertisedIP, shouldAutoUpdate)
+	if err != nil {
+		return false, err
+	}
+
+	after, err := encodeEntries(localnode)
+	if err != nil {
+		return false, err
+	}
+
+	return !entriesEqual(before, after), nil
+}
+
+func entriesEqual(a map[string][]byte, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for key, value := range a {
+		if other, ok := b[key]; !ok || !bytes.Equal(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encodeEntries(localnode *enode.LocalNode) (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for _, entry := range localnode.Entries() {
+		value, err := rlp.EncodeToBytes(entry)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		result[entry.ENRKey()] = value
+	}
+	return result, nil
+}
+
+func (w *WakuNode) applyLocalNodeUpdate(localnode *enode.LocalNode, multiaddrs []ma.Multiaddr, ipAddr *net.TCPAddr, udpPort uint, wakuFlags wenr.WakuEnrBitfield, advertisedIP *net.TCPAddr, shouldAutoUpdate bool) error {
+	var options []wenr.ENROption
+	options = append(options, wenr.WithUDPPort(udpPort))
+	options = append(options, wenr.WithWakuBitfield(wakuFlags))
+	options = append(options, w.customENRFields()...)
+
+	// IP fields that are not written by this update are removed once the update is applied
+	staleFields := map[string]struct{}{
+		enr.TCP(0).ENRKey():  {},
+		enr.TCP6(0).ENRKey(): {},
+		enr.IPv4{}.ENRKey():  {},
+		enr.IPv6{}.ENRKey():  {},
+	}
+	keep := func(entries ...enr.Entry) {
+		for _, e := range entries {
+			delete(staleFields, e.ENRKey())
 		}
+	}
 
-		options = append(options, wenr.WithIP(ipAddr))
-	} else if !shouldAutoUpdate {
+	if udpPort != 0 {
+		// Setting the udp port writes the ip fields from the endpoints of the localnode
+		keep(enr.IPv4{}, enr.IPv6{})
+	}
+
+	staticIP := advertisedIP
+	if staticIP == nil && !shouldAutoUpdate {
 		// We received a libp2p address update. Autoupdate is disabled
 		// Using a static ip will disable endpoint prediction.
-		options = append(options, wenr.WithIP(ipAddr))
-	} else {
-		if ipAddr.Port != 0 {
-			// We received a libp2p address update, but we should still
-			// allow discv5 to update the enr record. We set the localnode
-			// keys manually. It's possible that the ENR record might get
-			// updated automatically
-			ip4 := ipAddr.IP.To4()
-			ip6 := ipAddr.IP.To16()
-			if ip4 != nil && !ip4.IsUnspecified() {
-				localnode.SetFallbackIP(ip4)
-				localnode.Set(enr.IPv4(ip4))
-				localnode.Set(enr.TCP(uint16(ipAddr.Port)))
-			} else {
-				localnode.Delete(enr.IPv4{})
-				localnode.Delete(enr.TCP(0))
-				localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
-			}
+		staticIP = ipAddr
+	}
 
-			if ip4 == nil && ip6 != nil && !ip6.IsUnspecified() {
-				localnode.Set(enr.IPv6(ip6))
-				localnode.Set(enr.TCP6(ipAddr.Port))
+	if staticIP != nil {
+		options = append(options, wenr.WithIP(staticIP))
+		if staticIP.Port != 0 {
+			keep(enr.IPv4{}, enr.IPv6{})
+			if staticIP.IP.To4() != nil {
+				keep(enr.TCP(0))
 			} else {
-				localnode.Delete(enr.IPv6{})
-				localnode.Delete(enr.TCP6(0))
+				keep(enr.TCP6(0))
 			}
 		}
+	} else if ipAddr.Port != 0 {
+		// We received a libp2p address update, but we should still
+		// allow discv5 to update the enr record. We set the localnode
+		// keys manually. It's possible that the ENR record might get
+		// updated automatically
+		keep(enr.IPv4{}, enr.IPv6{}, enr.TCP(0), enr.TCP6(0))
+
+		ip4 := ipAddr.IP.To4()
+		ip6 := ipAddr.IP.To16()
+		if ip4 != nil && !ip4.IsUnspecified() {
+			localnode.SetFallbackIP(ip4)
+			localnode.Set(enr.IPv4(ip4))
+			localnode.Set(enr.TCP(uint16(ipAddr.Port)))
+		} else {
+			localnode.Delete(enr.TCP(0))
+			// Advertising a loopback address would be useless to the other peers, so
+			// no IP is written unless a fallback was configured
+			fallbackIP := w.opts.enrFallbackIP
+			if fallbackIP == nil || fallbackIP.To4() == nil {
+				localnode.SetFallbackIP(net.IPv4zero)
+			}
+			if fallbackIP != nil {
+				localnode.SetFallbackIP(fallbackIP)
+			}
+		}
+
+		if ip4 == nil && ip6 != nil && !ip6.IsUnspecified() {
+			localnode.Set(enr.IPv6(ip6))
+			localnode.Set(enr.TCP6(ipAddr.Port))
+		} else {
+			localnode.Delete(enr.IPv6{})
+			localnode.Delete(enr.TCP6(0))
+		}
 	}
 
 	// Writing the IP + Port has priority over writting the multiaddress which might fail or not
 	// depending on the enr having space
-	options = append(options, wenr.WithMultiaddress(multiaddrs...))
+	if w.opts.strictENRSize {
+		options = append(options, wenr.WithAllMultiaddresses(multiaddrs...))
+	} else {
+		options = append(options, wenr.WithMultiaddress(multiaddrs...))
+	}
+
+	err := wenr.Update(w.log, localnode, options...)
+	if err != nil {
+		return err
+	}
+
+	for field := range staleFields {
+		wenr.DeleteField(localnode, field)
+	}
 
-	return wenr.Update(w.log, localnode, options...)
+	return nil
 }
 
 func isPrivate(addr *net.TCPAddr) bool {
@@ -90,6 +194,20 @@ func isLoopback(addr *net.TCPAddr) bool {
 	return addr.IP.IsLoopback()
 }
 
+// addressKind describes the kind of address selected for the ENR
+func addressKind(addr *net.TCPAddr) string {
+	switch {
+	case isExternal(addr):
+		return "external"
+	case isPrivate(addr):
+		return "private"
+	case isLoopback(addr):
+		return "loopback"
+	default:
+		return "unspecified"
+	}
+}
+
 func filterIP(ss []*net.TCPAddr, fn func(*net.TCPAddr) bool) (ret []*net.TCPAddr) {
 	for _, s := range ss {
 		if fn(s) {
@@ -99,38 +217,110 @@ func filterIP(ss []*net.TCPAddr, fn func(*net.TCPAddr) bool) (ret []*net.TCPAddr
 	return
 }
 
-func extractIPAddressForENR(addr ma.Multiaddr) (*net.TCPAddr, error) {
+// checkENRAddress determines whether the IP address of a multiaddress can be used
+// for the ENR record default keys
+func checkENRAddress(addr ma.Multiaddr) error {
 	// It's a p2p-circuit address. We shouldnt use these
 	// for building the ENR record default keys
 	_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
 	if err == nil {
-		return nil, errors.New("can't use IP address from a p2p-circuit address")
+		return errors.New("can't use IP address from a p2p-circuit address")
+	}
+
+	// quic addresses are handled by the multiaddr key, and the
+	// default keys only describe tcp ports
+	if isQUICMultiaddr(addr) {
+		return errors.New("can't use IP address from a quic address")
 	}
 
 	// ws and wss addresses are handled by the multiaddr key
 	// they shouldnt be used for building the ENR record default keys
 	_, err = addr.ValueForProtocol(ma.P_WS)
 	if err == nil {
-		return nil, errors.New("can't use IP address from a ws address")
+		return errors.New("can't use IP address from a ws address")
 	}
 	_, err = addr.ValueForProtocol(ma.P_WSS)
 	if err == nil {
-		return nil, errors.New("can't use IP address from a wss address")
+		return errors.New("can't use IP address from a wss address")
 	}
 
-	var ipStr string
-	dns4, err := addr.ValueForProtocol(ma.P_DNS4)
-	if err != nil {
-		ipStr, err = addr.ValueForProtocol(ma.P_IP4)
+	return nil
+}
+
+// validateAdvertiseAddrs verifies that the IP addresses set as advertised addresses can be reached by
+// other peers, since they are published as is in the ENR. Unspecified and multicast addresses are always
+// refused. Loopback, link-local and private addresses are only accepted when allowNonRoutable is set.
+// dns multiaddresses are not resolved here
+func validateAdvertiseAddrs(addrs []ma.Multiaddr, allowNonRoutable bool) error {
+	for _, addr := range addrs {
+		ip, err := manet.ToIP(addr)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case ip.IsUnspecified():
+			return fmt.Errorf("invalid advertise address %s: unspecified addresses cannot be advertised", addr)
+		case ip.IsMulticast():
+			return fmt.Errorf("invalid advertise address %s: multicast addresses cannot be advertised", addr)
+		case allowNonRoutable:
+			continue
+		case ip.IsLoopback():
+			return fmt.Errorf("invalid advertise address %s: loopback addresses are not reachable by other peers", addr)
+		case ip.IsLinkLocalUnicast():
+			return fmt.Errorf("invalid advertise address %s: link-local addresses are not reachable by other peers", addr)
+		case ip.IsPrivate():
+			return fmt.Errorf("invalid advertise address %s: private addresses are not reachable by other peers", addr)
+		}
+	}
+	return nil
+}
+
+// extractIPAddressesForENR returns the IP addresses of a multiaddress that can be used for the
+// ENR record default keys. dns, dns4, dns6 and dnsaddr multiaddresses are resolved, and all the
+// IPv4 and IPv6 addresses they resolve to are returned
+func extractIPAddressesForENR(ctx context.Context, resolver *madns.Resolver, addr ma.Multiaddr) ([]*net.TCPAddr, error) {
+	if err := checkENRAddress(addr); err != nil {
+		return nil, err
+	}
+
+	addrs := []ma.Multiaddr{addr}
+	if madns.Matches(addr) {
+		resolved, err := resolver.Resolve(ctx, addr)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		netIP, err := net.ResolveIPAddr("ip4", dns4)
+		addrs = resolved
+	}
+
+	var result []*net.TCPAddr
+	for _, addr := range addrs {
+		// dnsaddr records might resolve to addresses that cannot be used
+		if err := checkENRAddress(addr); err != nil {
+			continue
+		}
+
+		ipAddr, err := toTCPAddr(addr)
+		if err != nil {
+			continue
+		}
+		result = append(result, ipAddr)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("could not obtain ip address")
+	}
+
+	return result, nil
+}
+
+func toTCPAddr(addr ma.Multiaddr) (*net.TCPAddr, error) {
+	ipStr, err := addr.ValueForProtocol(ma.P_IP4)
+	if err != nil {
+		ipStr, err = addr.ValueForProtocol(ma.P_IP6)
 		if err != nil {
 			return nil, err
 		}
-		ipStr = netIP.String()
 	}
 
 	portStr, err := addr.ValueForProtocol(ma.P_TCP)
@@ -147,32 +337,94 @@ func extractIPAddressForENR(addr ma.Multiaddr) (*net.TCPAddr, error) {
 	}, nil
 }
 
-func selectMostExternalAddress(addresses []ma.Multiaddr) (*net.TCPAddr, error) {
-	var ipAddrs []*net.TCPAddr
-	for _, addr := range addresses {
-		ipAddr, err := extractIPAddressForENR(addr)
-		if err != nil {
-			continue
+// addressPriority ranks the kind of an address for the ENR. Lower values are preferred
+func addressPriority(addr *net.TCPAddr) int {
+	switch {
+	case isExternal(addr):
+		return 0
+	case isPrivate(addr):
+		return 1
+	case isLoopback(addr):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// selectExternalAddresses returns the IP addresses that can be used for the ENR, sorted
+// by priority: external addresses first, then private and loopback addresses. Addresses
+// of the same kind are sorted by IP and port so the result is deterministic
+func selectExternalAddresses(ctx context.Context, resolver *madns.Resolver, addresses []ma.Multiaddr) ([]*net.TCPAddr, error) {
+	ipAddrs, err := collectENRAddresses(ctx, resolver, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ipAddrs, func(i, j int) bool {
+		pi, pj := addressPriority(ipAddrs[i]), addressPriority(ipAddrs[j])
+		if pi != pj {
+			return pi < pj
 		}
-		ipAddrs = append(ipAddrs, ipAddr)
+		if c := bytes.Compare(ipAddrs[i].IP.To16(), ipAddrs[j].IP.To16()); c != 0 {
+			return c < 0
+		}
+		return ipAddrs[i].Port < ipAddrs[j].Port
+	})
+
+	return ipAddrs, nil
+}
+
+func selectMostExternalAddress(ctx context.Context, resolver *madns.Resolver, addresses []ma.Multiaddr) (*net.TCPAddr, error) {
+	ipAddrs, err := selectExternalAddresses(ctx, resolver, addresses)
+	if err != nil {
+		return nil, err
 	}
+	return ipAddrs[0], nil
+}
 
-	externalIPs := filterIP(ipAddrs, isExternal)
-	if len(externalIPs) > 0 {
-		return externalIPs[0], nil
+// selectAdvertisedAddresses returns the IP addresses of the advertised addresses that can be used
+// for the ENR. External addresses still come first, but addresses of the same kind keep the order
+// in which they were configured, since that order is the priority chosen by the operator
+func selectAdvertisedAddresses(ctx context.Context, resolver *madns.Resolver, addresses []ma.Multiaddr) ([]*net.TCPAddr, error) {
+	ipAddrs, err := collectENRAddresses(ctx, resolver, addresses)
+	if err != nil {
+		return nil, err
 	}
 
-	privateIPs := filterIP(ipAddrs, isPrivate)
-	if len(privateIPs) > 0 {
-		return privateIPs[0], nil
+	sort.SliceStable(ipAddrs, func(i, j int) bool {
+		return addressPriority(ipAddrs[i]) < addressPriority(ipAddrs[j])
+	})
+
+	return ipAddrs, nil
+}
+
+// collectENRAddresses extracts the IP addresses that can be used for the ENR from a list of
+// multiaddresses, without duplicates and in the order in which they were found
+func collectENRAddresses(ctx context.Context, resolver *madns.Resolver, addresses []ma.Multiaddr) ([]*net.TCPAddr, error) {
+	seen := make(map[string]struct{})
+	var ipAddrs []*net.TCPAddr
+	for _, addr := range addresses {
+		addrs, err := extractIPAddressesForENR(ctx, resolver, addr)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if addressPriority(a) > 2 {
+				continue
+			}
+			if _, ok := seen[a.String()]; ok {
+				continue
+			}
+			seen[a.String()] = struct{}{}
+			ipAddrs = append(ipAddrs, a)
+		}
 	}
 
-	loopback := filterIP(ipAddrs, isLoopback)
-	if len(loopback) > 0 {
-		return loopback[0], nil
+	if len(ipAddrs) == 0 {
+		return nil, errors.New("could not obtain ip address")
 	}
 
-	return nil, errors.New("could not obtain ip address")
+	return ipAddrs, nil
 }
 
 func decapsulateP2P(addr ma.Multiaddr) (ma.Multiaddr, error) {
@@ -239,6 +491,36 @@ func selectWSListenAddresses(addresses []ma.Multiaddr) ([]ma.Multiaddr, error) {
 	return result, nil
 }
 
+// selectQUICListenAddresses returns the QUIC listen addresses. There is no ENR key for QUIC,
+// so these are added to the multiaddr key
+func selectQUICListenAddresses(addresses []ma.Multiaddr) ([]ma.Multiaddr, error) {
+	var result []ma.Multiaddr
+	for _, addr := range addresses {
+		// It's a p2p-circuit address. We dont use these at this stage yet
+		_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
+		if err == nil {
+			continue
+		}
+
+		if !isQUICMultiaddr(addr) {
+			continue
+		}
+
+		addr, err = decapsulateP2P(addr)
+		if err == nil {
+			result = append(result, addr)
+		}
+	}
+
+	return result, nil
+}
+
+func isQUICMultiaddr(addr ma.Multiaddr) bool {
+	_, noQUIC := addr.ValueForProtocol(ma.P_QUIC)
+	_, noQUICV1 := addr.ValueForProtocol(ma.P_QUIC_V1)
+	return noQUIC == nil || noQUICV1 == nil
+}
+
 func selectCircuitRelayListenAddresses(ctx context.Context, addresses []ma.Multiaddr) ([]ma.Multiaddr, error) {
 	var result []ma.Multiaddr
 
@@ -268,6 +550,18 @@ func filter0Port(addresses []ma.Multiaddr) ([]ma.Multiaddr, error) {
 			return nil, err
 		}
 
+		if portStr == "" {
+			// QUIC addresses use an UDP port
+			portStr, err = addr.ValueForProtocol(ma.P_UDP)
+			if err != nil {
+				if errors.Is(err, multiaddr.ErrProtocolNotFound) {
+					result = append(result, addr)
+					continue
+				}
+				return nil, err
+			}
+		}
+
 		port, err := strconv.Atoi(portStr)
 		if err != nil {
 			return nil, err
@@ -281,12 +575,36 @@ func filter0Port(addresses []ma.Multiaddr) ([]ma.Multiaddr, error) {
 	return result, nil
 }
 
+// resolver returns the resolver used for the addresses of the ENR, which caches the resolution results
+func (w *WakuNode) resolver() *madns.Resolver {
+	if w.dnsResolver == nil {
+		return madns.DefaultResolver
+	}
+	return w.dnsResolver
+}
+
+// RefreshDNSCache drops the cached DNS resolution results, so the addresses of the ENR are resolved
+// again the next time it is updated
+func (w *WakuNode) RefreshDNSCache() {
+	if w.dnsCache != nil {
+		w.dnsCache.Refresh()
+	}
+}
+
 func (w *WakuNode) getENRAddresses(ctx context.Context, addrs []ma.Multiaddr) (extAddr *net.TCPAddr, multiaddr []ma.Multiaddr, err error) {
-	extAddr, err = selectMostExternalAddress(addrs)
+	var extAddrs []*net.TCPAddr
+	if w.opts.advertiseAddrs != nil {
+		extAddrs, err = selectAdvertisedAddresses(ctx, w.resolver(), w.opts.advertiseAddrs)
+	} else {
+		extAddrs, err = selectExternalAddresses(ctx, w.resolver(), addrs)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// The primary address goes in the ENR default keys
+	extAddr = extAddrs[0]
+
 	wssAddrs, err := selectWSListenAddresses(addrs)
 	if err != nil {
 		return nil, nil, err
@@ -297,12 +615,33 @@ func (w *WakuNode) getENRAddresses(ctx context.Context, addrs []ma.Multiaddr) (e
 		return nil, nil, err
 	}
 
+	quicAddrs, err := selectQUICListenAddresses(addrs)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if len(circuitAddrs) != 0 {
 		// Node is unreachable, hence why we have circuit relay multiaddr
-		// We prefer these instead of any ws/s address
+		// We prefer these instead of any ws/s or quic address
 		multiaddr = append(multiaddr, circuitAddrs...)
 	} else {
 		multiaddr = append(multiaddr, wssAddrs...)
+		multiaddr = append(multiaddr, quicAddrs...)
+	}
+
+	// The remaining addresses of the same kind as the primary address are also
+	// reachable, so they're added to the multiaddr key. They go last since they
+	// are the first ones to be dropped if the ENR does not have enough space
+	for _, addr := range extAddrs[1:] {
+		if addressPriority(addr) != addressPriority(extAddr) {
+			break
+		}
+
+		maddr, err := manet.FromNetAddr(addr)
+		if err != nil {
+			continue
+		}
+		multiaddr = append(multiaddr, maddr)
 	}
 
 	multiaddr, err = filter0Port(multiaddr)
@@ -310,9 +649,83 @@ func (w *WakuNode) getENRAddresses(ctx context.Context, addrs []ma.Multiaddr) (e
 		return nil, nil, err
 	}
 
+	multiaddr = prioritizeENRMultiaddrs(multiaddr)
+
 	return
 }
 
+func isDNSMultiaddr(addr ma.Multiaddr) bool {
+	for _, code := range []int{ma.P_DNS, ma.P_DNS4, ma.P_DNS6, ma.P_DNSADDR} {
+		if _, err := addr.ValueForProtocol(code); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// multiaddrENRPriority ranks a multiaddress for the ENR multiaddr key. Lower values are preferred
+func multiaddrENRPriority(addr ma.Multiaddr) int {
+	_, wssErr := addr.ValueForProtocol(ma.P_WSS)
+	_, wsErr := addr.ValueForProtocol(ma.P_WS)
+	switch {
+	case wssErr == nil && isDNSMultiaddr(addr):
+		// Can be used by browsers
+		return 0
+	case wssErr == nil || wsErr == nil:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// prioritizeENRMultiaddrs removes duplicated multiaddresses and sorts them by how useful they are:
+// wss addresses with a domain name first, then the rest of ws and wss addresses, and finally any other
+// address. Among those, addresses from relay nodes that were not seen yet come first, so that if
+// the list is truncated, the ENR contains as many different relay hops as possible
+func prioritizeENRMultiaddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	seen := make(map[string]struct{})
+	var unique []ma.Multiaddr
+	for _, addr := range addrs {
+		if _, ok := seen[addr.String()]; ok {
+			continue
+		}
+		seen[addr.String()] = struct{}{}
+		unique = append(unique, addr)
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return multiaddrENRPriority(unique[i]) < multiaddrENRPriority(unique[j])
+	})
+
+	relays := make(map[string]struct{})
+	var result []ma.Multiaddr
+	var repeatedRelays []ma.Multiaddr
+	for _, addr := range unique {
+		relay, err := addr.ValueForProtocol(ma.P_P2P)
+		if err == nil {
+			if _, ok := relays[relay]; ok {
+				repeatedRelays = append(repeatedRelays, addr)
+				continue
+			}
+			relays[relay] = struct{}{}
+		}
+		result = append(result, addr)
+	}
+
+	return append(result, repeatedRelays...)
+}
+
+// boundENRMultiaddrs limits the number of multiaddresses written in the ENR. A maximum of 0 means no limit
+func (w *WakuNode) boundENRMultiaddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	if w.opts.maxENRMultiaddrs == 0 || len(addrs) <= w.opts.maxENRMultiaddrs {
+		return addrs
+	}
+
+	w.log.Debug("dropping multiaddresses from the ENR", logging.MultiAddrs("dropped", addrs[w.opts.maxENRMultiaddrs:]...))
+
+	return addrs[:w.opts.maxENRMultiaddrs]
+}
+
 func (w *WakuNode) setupENR(ctx context.Context, addrs []ma.Multiaddr) error {
 	ipAddr, multiaddresses, err := w.getENRAddresses(ctx, addrs)
 	if err != nil {
@@ -320,23 +733,88 @@ func (w *WakuNode) setupENR(ctx context.Context, addrs []ma.Multiaddr) error {
 		return err
 	}
 
-	err = w.updateLocalNode(w.localNode, multiaddresses, ipAddr, w.opts.udpPort, w.wakuFlag, w.opts.advertiseAddrs, w.opts.discV5autoUpdate)
+	w.log.Debug("selected address for ENR", zap.Stringer("address", ipAddr), zap.String("kind", addressKind(ipAddr)))
+
+	multiaddresses = w.boundENRMultiaddrs(multiaddresses)
+
+	updated, err := w.updateLocalNode(ctx, w.localNode, multiaddresses, ipAddr, w.opts.udpPort, w.wakuFlag, w.opts.advertiseAddrs, w.opts.discV5autoUpdate)
 	if err != nil {
 		w.log.Error("updating localnode ENR record", zap.Error(err))
 		return err
 	}
 
-	if w.Relay() != nil {
+	// setupENR runs again on every listen addresses update, but the topic shards
+	// only need to be watched once
+	if w.Relay() != nil && w.watchingTopicShards.CompareAndSwap(false, true) {
 		err = w.watchTopicShards(ctx)
 		if err != nil {
+			w.watchingTopicShards.Store(false)
 			return err
 		}
 	}
 
-	w.enrChangeCh <- struct{}{}
+	if updated {
+		w.enrChangeCh <- struct{}{}
+	}
+
+	return nil
+
+}
+
+// customENRFields returns the options to write the application specific ENR fields, sorted by key
+func (w *WakuNode) customENRFields() []wenr.ENROption {
+	w.enrFieldsMu.Lock()
+	defer w.enrFieldsMu.Unlock()
+
+	keys := make([]string, 0, len(w.opts.enrFields))
+	for key := range w.opts.enrFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var options []wenr.ENROption
+	for _, key := range keys {
+		options = append(options, wenr.WithCustomField(key, w.opts.enrFields[key]))
+	}
+	return options
+}
+
+// SetENRField adds an application specific field to the ENR of the node, or replaces its value
+// if it already exists. Keys used by Waku or by the ENR spec are refused, and ErrENRTooLarge is
+// returned if the field does not fit in the ENR
+func (w *WakuNode) SetENRField(key string, value []byte) error {
+	if err := wenr.ValidateCustomField(key); err != nil {
+		return err
+	}
+
+	err := wenr.Update(w.log, w.localNode, wenr.WithCustomField(key, value))
+	if err != nil {
+		return err
+	}
+
+	w.enrFieldsMu.Lock()
+	defer w.enrFieldsMu.Unlock()
+
+	// Keeping the field so it's written again when the ENR is rebuilt
+	if w.opts.enrFields == nil {
+		w.opts.enrFields = make(map[string][]byte)
+	}
+	w.opts.enrFields[key] = value
+
+	if w.cancel != nil {
+		select {
+		case w.enrChangeCh <- struct{}{}:
+		default:
+		}
+	}
 
 	return nil
+}
 
+// GetENRField returns the value of an application specific field of the ENR of the node,
+// or nil if the field is not present
+func (w *WakuNode) GetENRField(key string) ([]byte, error) {
+	return wenr.GetENRField(w.localNode.Node(), key)
 }
 
 func (w *WakuNode) SetRelayShards(rs protocol.RelayShards) error {
@@ -3,6 +3,7 @@ package node
 import (
 	"fmt"
 
+	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-libp2p/p2p/metricshelper"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -33,11 +34,29 @@ var peerStoreSize = prometheus.NewGauge(
 		Help: "Size of Peer Store",
 	})
 
+var openStreams = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "waku_open_streams",
+		Help: "Number of inbound streams being handled per protocol",
+	},
+	[]string{"protocol"},
+)
+
+var rejectedStreams = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "waku_rejected_streams",
+		Help: "Number of inbound streams rejected due to the protocol stream limit",
+	},
+	[]string{"protocol"},
+)
+
 var collectors = []prometheus.Collector{
 	gitVersion,
 	peerDials,
 	connectedPeers,
 	peerStoreSize,
+	openStreams,
+	rejectedStreams,
 }
 
 // Metrics exposes the functions required to update prometheus metrics for the waku node
@@ -47,6 +66,8 @@ type Metrics interface {
 	RecordPeerConnected()
 	RecordPeerDisconnected()
 	SetPeerStoreSize(int)
+	SetOpenStreams(p protocol.ID, num int)
+	RecordRejectedStream(p protocol.ID)
 }
 
 type metricsImpl struct {
@@ -84,3 +105,13 @@ func (m *metricsImpl) RecordPeerDisconnected() {
 func (m *metricsImpl) SetPeerStoreSize(size int) {
 	peerStoreSize.Set(float64(size))
 }
+
+// SetOpenStreams sets the number of inbound streams being handled for a protocol
+func (m *metricsImpl) SetOpenStreams(p protocol.ID, num int) {
+	openStreams.WithLabelValues(string(p)).Set(float64(num))
+}
+
+// RecordRejectedStream increases the counter of streams rejected for a protocol
+func (m *metricsImpl) RecordRejectedStream(p protocol.ID) {
+	rejectedStreams.WithLabelValues(string(p)).Inc()
+}
@@ -0,0 +1,97 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"go.uber.org/zap"
+)
+
+// streamLimiter keeps track of the number of inbound streams being handled
+// for each protocol, and rejects new streams once the protocol limit is reached
+type streamLimiter struct {
+	sync.Mutex
+	limits  map[protocol.ID]int
+	open    map[protocol.ID]int
+	metrics Metrics
+	log     *zap.Logger
+}
+
+func newStreamLimiter(limits map[protocol.ID]int, metrics Metrics, log *zap.Logger) *streamLimiter {
+	l := &streamLimiter{
+		limits:  make(map[protocol.ID]int),
+		open:    make(map[protocol.ID]int),
+		metrics: metrics,
+		log:     log,
+	}
+	for p, max := range limits {
+		l.limits[p] = max
+	}
+	return l
+}
+
+func (l *streamLimiter) acquire(p protocol.ID) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if max, ok := l.limits[p]; ok && max > 0 && l.open[p] >= max {
+		return false
+	}
+
+	l.open[p]++
+	l.metrics.SetOpenStreams(p, l.open[p])
+	return true
+}
+
+func (l *streamLimiter) release(p protocol.ID) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.open[p]--
+	l.metrics.SetOpenStreams(p, l.open[p])
+}
+
+// OpenStreams returns the number of inbound streams currently being handled per protocol
+func (l *streamLimiter) OpenStreams() map[protocol.ID]int {
+	l.Lock()
+	defer l.Unlock()
+
+	result := make(map[protocol.ID]int, len(l.open))
+	for p, n := range l.open {
+		result[p] = n
+	}
+	return result
+}
+
+func (l *streamLimiter) wrap(p protocol.ID, handler network.StreamHandler) network.StreamHandler {
+	return func(stream network.Stream) {
+		if !l.acquire(p) {
+			// rejections are counted by the metrics, so they are only logged for debugging
+			l.log.Debug("stream limit reached, rejecting stream", zap.String("protocol", string(p)), zap.Stringer("peer", stream.Conn().RemotePeer()))
+			l.metrics.RecordRejectedStream(p)
+			if err := stream.Reset(); err != nil {
+				l.log.Error("resetting connection", zap.Error(err))
+			}
+			return
+		}
+		defer l.release(p)
+
+		handler(stream)
+	}
+}
+
+// limitedHost is a host whose stream handlers are subject to the limits of a streamLimiter
+type limitedHost struct {
+	host.Host
+	limiter *streamLimiter
+}
+
+func (h *limitedHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.limiter.wrap(pid, handler))
+}
+
+func (h *limitedHost) SetStreamHandlerMatch(pid protocol.ID, m func(protocol.ID) bool, handler network.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, m, h.limiter.wrap(pid, handler))
+}
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	testProtocol := protocol.ID("/vac/waku/test/1.0.0")
+
+	host1, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+	defer host1.Close()
+	host2, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+	defer host2.Close()
+
+	limiter := newStreamLimiter(map[protocol.ID]int{testProtocol: 1}, newMetrics(prometheus.NewRegistry()), utils.Logger())
+	limited := &limitedHost{Host: host1, limiter: limiter}
+
+	release := make(chan struct{})
+	handled := make(chan struct{}, 2)
+	limited.SetStreamHandler(testProtocol, func(s network.Stream) {
+		handled <- struct{}{}
+		<-release
+		s.Close()
+	})
+
+	host2.Peerstore().AddAddrs(host1.ID(), host1.Addrs(), peerstore.PermanentAddrTTL)
+
+	// First stream is accepted and kept open by the handler
+	stream1, err := host2.NewStream(ctx, host1.ID(), testProtocol)
+	require.NoError(t, err)
+	_, err = stream1.Write([]byte{1})
+	require.NoError(t, err)
+	<-handled
+	require.Equal(t, 1, limiter.OpenStreams()[testProtocol])
+
+	// Second stream exceeds the limit and is reset
+	stream2, err := host2.NewStream(ctx, host1.ID(), testProtocol)
+	require.NoError(t, err)
+	_, _ = stream2.Write([]byte{1})
+	_, err = stream2.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.Len(t, handled, 0)
+
+	// Once the first stream is done, new streams are accepted again
+	close(release)
+	require.Eventually(t, func() bool {
+		return limiter.OpenStreams()[testProtocol] == 0
+	}, 2*time.Second, 50*time.Millisecond)
+
+	stream3, err := host2.NewStream(ctx, host1.ID(), testProtocol)
+	require.NoError(t, err)
+	_, err = stream3.Write([]byte{1})
+	require.NoError(t, err)
+	select {
+	case <-handled:
+	case <-ctx.Done():
+		require.Fail(t, "stream was not handled")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	backoffv4 "github.com/cenkalti/backoff/v4"
@@ -24,9 +25,11 @@ import (
 	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
 	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/libp2p/go-libp2p/p2p/muxer/yamux"
 	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/proto"
 	ws "github.com/libp2p/go-libp2p/p2p/transport/websocket"
 	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
 
 	"github.com/waku-org/go-waku/logging"
 	"github.com/waku-org/go-waku/waku/v2/discv5"
@@ -73,10 +76,24 @@ type IdentityCredential = struct {
 
 type SpamHandler = func(message *pb.WakuMessage, topic string) error
 
+// SpamEvidence contains the metadata of the proofs of two messages sent in the same epoch by the
+// same RLN member. It mirrors rln.SpamEvidence so it can be used in builds without RLN
+type SpamEvidence struct {
+	Nullifier         byte32
+	ExternalNullifier byte32
+	ShareX            byte32
+	ShareY            byte32
+	PreviousShareX    byte32
+	PreviousShareY    byte32
+}
+
+type SlashingSpamHandler = func(message *pb.WakuMessage, topic string, evidence SpamEvidence) error
+
 type RLNRelay interface {
 	IdentityCredential() (IdentityCredential, error)
 	MembershipIndex() uint
 	AppendRLNProof(msg *pb.WakuMessage, senderEpochTime time.Time) error
+	AppendRLNProofAsync(msg *pb.WakuMessage, senderEpochTime time.Time) (<-chan error, error)
 	Validator(spamHandler SpamHandler) func(ctx context.Context, message *pb.WakuMessage, topic string) bool
 	Start(ctx context.Context) error
 	Stop() error
@@ -85,6 +102,7 @@ type RLNRelay interface {
 
 type WakuNode struct {
 	host       host.Host
+	streams    *streamLimiter
 	opts       *WakuNodeParameters
 	log        *zap.Logger
 	timesource timesource.Timesource
@@ -104,11 +122,17 @@ type WakuNode struct {
 	legacyStore     ReceptorService
 	store           *store.WakuStore
 	rlnRelay        RLNRelay
+	rlnUnavailable  bool
 
 	wakuFlag          enr.WakuEnrBitfield
 	circuitRelayNodes chan peer.AddrInfo
 
-	localNode *enode.LocalNode
+	localNode   *enode.LocalNode
+	enrFieldsMu sync.Mutex
+	dnsCache    *dnsCache
+	dnsResolver *madns.Resolver
+
+	watchingTopicShards atomic.Bool
 
 	bcaster relay.Broadcaster
 
@@ -125,7 +149,7 @@ type WakuNode struct {
 }
 
 func defaultStoreFactory(w *WakuNode) legacy_store.Store {
-	return legacy_store.NewWakuStore(w.opts.messageProvider, w.peermanager, w.timesource, w.opts.prometheusReg, w.log)
+	return legacy_store.NewWakuStore(w.opts.messageProvider, w.peermanager, w.timesource, w.opts.prometheusReg, w.log, w.opts.storeOpts...)
 }
 
 // New is used to instantiate a WakuNode using a set of WakuNodeOptions
@@ -142,6 +166,10 @@ func New(opts ...WakuNodeOption) (*WakuNode, error) {
 		}
 	}
 
+	if err := validateAdvertiseAddrs(params.advertiseAddrs, params.advertisePrivateIPs); err != nil {
+		return nil, err
+	}
+
 	if params.logger == nil {
 		params.logger = utils.Logger()
 		//golog.SetPrimaryCore(params.logger.Core())
@@ -181,6 +209,27 @@ func New(opts ...WakuNodeOption) (*WakuNode, error) {
 
 	params.libP2POpts = append(params.libP2POpts, params.Identity())
 
+	if params.connManager != nil {
+		params.libP2POpts = append(params.libP2POpts, func(cfg *libp2p.Config) error {
+			cfg.ConnManager = params.connManager
+			return nil
+		})
+	}
+
+	if params.connKeepAliveInterval > 0 {
+		muxer := *yamux.DefaultTransport
+		muxer.EnableKeepAlive = true
+		muxer.KeepAliveInterval = params.connKeepAliveInterval
+		params.libP2POpts = append(params.libP2POpts, func(cfg *libp2p.Config) error {
+			for i := range cfg.Muxers {
+				if cfg.Muxers[i].ID == yamux.ID {
+					cfg.Muxers[i].Muxer = &muxer
+				}
+			}
+			return nil
+		})
+	}
+
 	if params.addressFactory != nil {
 		params.libP2POpts = append(params.libP2POpts, libp2p.AddrsFactory(params.addressFactory))
 	}
@@ -192,6 +241,12 @@ func New(opts ...WakuNodeOption) (*WakuNode, error) {
 	w.wg = &sync.WaitGroup{}
 	w.wakuFlag = enr.NewWakuEnrBitfield(w.opts.enableLightPush, w.opts.enableFilterFullNode, w.opts.enableStore, w.opts.enableRelay)
 	w.circuitRelayNodes = make(chan peer.AddrInfo)
+
+	w.dnsCache = newDNSCache(net.DefaultResolver, params.dnsCacheTTL, params.dnsCacheGracePeriod)
+	w.dnsResolver, err = madns.NewResolver(madns.WithDefaultResolver(w.dnsCache))
+	if err != nil {
+		return nil, err
+	}
 	w.metrics = newMetrics(params.prometheusReg)
 	w.metrics.RecordVersion(Version, GitCommit)
 
@@ -253,7 +308,8 @@ func New(opts ...WakuNodeOption) (*WakuNode, error) {
 
 	relay := relay.NewWakuRelay(w.bcaster, w.opts.minRelayPeersToPublish, w.timesource, w.opts.prometheusReg, w.log,
 		relay.WithPubSubOptions(w.opts.pubsubOpts),
-		relay.WithMaxMsgSize(w.opts.maxMsgSizeBytes))
+		relay.WithMaxMsgSize(w.opts.maxMsgSizeBytes),
+		relay.WithMessageAgeBounds(w.opts.relayMaxMessageAge, w.opts.relayMaxFutureDrift))
 
 	w.relay = relay
 
@@ -289,7 +345,7 @@ func New(opts ...WakuNodeOption) (*WakuNode, error) {
 	w.opts.filterOpts = append(w.opts.filterOpts, filter.WithPeerManager(w.peermanager))
 
 	w.filterFullNode = filter.NewWakuFilterFullNode(w.timesource, w.opts.prometheusReg, w.log, w.opts.filterOpts...)
-	w.filterLightNode = filter.NewWakuFilterLightNode(w.bcaster, w.peermanager, w.timesource, w.opts.onlineChecker, w.opts.prometheusReg, w.log)
+	w.filterLightNode = filter.NewWakuFilterLightNode(w.bcaster, w.peermanager, w.timesource, w.opts.onlineChecker, w.opts.prometheusReg, w.log, w.opts.filterLightNodeOpts...)
 	w.lightPush = lightpush.NewWakuLightPush(w.Relay(), w.peermanager, w.opts.prometheusReg, w.log, w.opts.lightpushOpts...)
 
 	w.store = store.NewWakuStore(w.peermanager, w.timesource, w.log, w.opts.storeRateLimit)
@@ -313,6 +369,13 @@ func (w *WakuNode) watchMultiaddressChanges(ctx context.Context) {
 
 	addrsSet := utils.MultiAddrSet(w.ListenAddresses()...)
 
+	// Address updates usually come in bursts (i.e. when NAT mappings and identify
+	// observations settle), so the ENR is rebuilt once no updates are received
+	// for the debounce period. Updates that keep coming do not delay it for more
+	// than maxENRUpdateDebounces debounce periods after the first one
+	var debounceCh <-chan time.Time
+	var maxWaitCh <-chan time.Time
+
 	first := make(chan struct{}, 1)
 	first <- struct{}{}
 	for {
@@ -323,20 +386,37 @@ func (w *WakuNode) watchMultiaddressChanges(ctx context.Context) {
 			addr := maps.Values(addrsSet)
 			w.log.Info("listening", logging.MultiAddrs("multiaddr", addr...))
 		case <-w.addressChangesSub.Out():
-			newAddrs := utils.MultiAddrSet(w.ListenAddresses()...)
-			if !utils.MultiAddrSetEquals(addrsSet, newAddrs) {
-				addrsSet = newAddrs
-				addrs := maps.Values(addrsSet)
-				w.log.Info("listening addresses update received", logging.MultiAddrs("multiaddr", addrs...))
-				err := w.setupENR(ctx, addrs)
-				if err != nil {
-					w.log.Warn("could not update ENR", zap.Error(err))
-				}
+			debounceCh = time.After(w.opts.enrUpdateDebounce)
+			if maxWaitCh == nil {
+				maxWaitCh = time.After(maxENRUpdateDebounces * w.opts.enrUpdateDebounce)
 			}
+		case <-debounceCh:
+			debounceCh, maxWaitCh = nil, nil
+			addrsSet = w.updateListenAddresses(ctx, addrsSet)
+		case <-maxWaitCh:
+			debounceCh, maxWaitCh = nil, nil
+			addrsSet = w.updateListenAddresses(ctx, addrsSet)
 		}
 	}
 }
 
+// updateListenAddresses rebuilds the ENR if the listen addresses of the node differ from
+// addrsSet, returning the current set of listen addresses
+func (w *WakuNode) updateListenAddresses(ctx context.Context, addrsSet map[string]ma.Multiaddr) map[string]ma.Multiaddr {
+	newAddrs := utils.MultiAddrSet(w.ListenAddresses()...)
+	if utils.MultiAddrSetEquals(addrsSet, newAddrs) {
+		return addrsSet
+	}
+
+	addrs := maps.Values(newAddrs)
+	w.log.Info("listening addresses update received", logging.MultiAddrs("multiaddr", addrs...))
+	err := w.setupENR(ctx, addrs)
+	if err != nil {
+		w.log.Warn("could not update ENR", zap.Error(err))
+	}
+	return newAddrs
+}
+
 // Start initializes all the protocols that were setup in the WakuNode
 func (w *WakuNode) Start(ctx context.Context) error {
 	connGater := peermanager.NewConnectionGater(w.opts.maxConnectionsPerIP, w.log)
@@ -359,6 +439,10 @@ func (w *WakuNode) Start(ctx context.Context) error {
 
 	w.host = host
 
+	// Protocols serving requests use a host that enforces the stream limits
+	w.streams = newStreamLimiter(w.opts.maxStreams, w.metrics, w.log)
+	protocolHost := &limitedHost{Host: host, limiter: w.streams}
+
 	if w.addressChangesSub, err = host.EventBus().Subscribe(new(event.EvtLocalAddressesUpdated)); err != nil {
 		return err
 	}
@@ -379,7 +463,7 @@ func (w *WakuNode) Start(ctx context.Context) error {
 		return err
 	}
 
-	w.metadata.SetHost(host)
+	w.metadata.SetHost(protocolHost)
 	err = w.metadata.Start(ctx)
 	if err != nil {
 		return err
@@ -422,7 +506,7 @@ func (w *WakuNode) Start(ctx context.Context) error {
 	w.peermanager.Start(ctx)
 
 	w.legacyStore = w.storeFactory(w)
-	w.legacyStore.SetHost(host)
+	w.legacyStore.SetHost(protocolHost)
 	if w.opts.enableStore {
 		sub := w.bcaster.RegisterForAll()
 		err := w.startStore(ctx, sub)
@@ -434,14 +518,14 @@ func (w *WakuNode) Start(ctx context.Context) error {
 
 	w.store.SetHost(host)
 
-	w.lightPush.SetHost(host)
+	w.lightPush.SetHost(protocolHost)
 	if w.opts.enableLightPush {
 		if err := w.lightPush.Start(ctx); err != nil {
 			return err
 		}
 	}
 
-	w.filterFullNode.SetHost(host)
+	w.filterFullNode.SetHost(protocolHost)
 	if w.opts.enableFilterFullNode {
 		sub := w.bcaster.RegisterForAll()
 		err := w.filterFullNode.Start(ctx, sub)
@@ -452,7 +536,7 @@ func (w *WakuNode) Start(ctx context.Context) error {
 
 	}
 
-	w.filterLightNode.SetHost(host)
+	w.filterLightNode.SetHost(protocolHost)
 
 	err = w.setupENR(ctx, w.ListenAddresses())
 	if err != nil {
@@ -483,7 +567,12 @@ func (w *WakuNode) Start(ctx context.Context) error {
 		go w.startKeepAlive(ctx, w.opts.keepAliveRandomPeersInterval, w.opts.keepAliveAllPeersInterval)
 	}
 
-	w.peerExchange.SetHost(host)
+	if w.opts.idleConnTimeout > time.Duration(0) {
+		w.wg.Add(1)
+		go w.closeIdleConnections(ctx, w.opts.idleConnTimeout)
+	}
+
+	w.peerExchange.SetHost(protocolHost)
 	if w.opts.enablePeerExchange {
 		err := w.peerExchange.Start(ctx)
 		if err != nil {
@@ -549,6 +638,14 @@ func (w *WakuNode) Host() host.Host {
 	return w.host
 }
 
+// OpenStreams returns the number of inbound streams currently being handled per protocol
+func (w *WakuNode) OpenStreams() map[protocol.ID]int {
+	if w.streams == nil {
+		return nil
+	}
+	return w.streams.OpenStreams()
+}
+
 // ID returns the base58 encoded ID from the host
 func (w *WakuNode) ID() string {
 	return w.host.ID().String()
@@ -650,6 +747,32 @@ func (w *WakuNode) DiscV5() *discv5.DiscoveryV5 {
 	return nil
 }
 
+// DiscV5RoutingTableSize returns the number of nodes in the discv5 routing table.
+// A low number might indicate that discovery is not working as expected
+func (w *WakuNode) DiscV5RoutingTableSize() int {
+	if d := w.DiscV5(); d != nil {
+		return d.RoutingTableSize()
+	}
+	return 0
+}
+
+// DiscV5BucketSizes returns the number of nodes stored in each bucket of the discv5 routing table
+func (w *WakuNode) DiscV5BucketSizes() []int {
+	if d := w.DiscV5(); d != nil {
+		return d.BucketSizes()
+	}
+	return nil
+}
+
+// DiscV5NodesWithCapabilities returns the number of nodes in the discv5 routing table that support
+// the protocols specified in flags
+func (w *WakuNode) DiscV5NodesWithCapabilities(flags enr.WakuEnrBitfield) int {
+	if d := w.DiscV5(); d != nil {
+		return d.NodesWithCapabilities(flags)
+	}
+	return 0
+}
+
 // PeerExchange is used to access any operation related to Peer Exchange
 func (w *WakuNode) PeerExchange() *peer_exchange.WakuPeerExchange {
 	if result, ok := w.peerExchange.(*peer_exchange.WakuPeerExchange); ok {
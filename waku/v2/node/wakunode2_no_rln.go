@@ -3,13 +3,31 @@
 
 package node
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // RLNRelay is used to access any operation related to Waku RLN protocol
 func (w *WakuNode) RLNRelay() RLNRelay {
 	return nil
 }
 
+// RLNAvailable indicates whether RLN can be used in this platform
+func (w *WakuNode) RLNAvailable() bool {
+	return false
+}
+
+// ReloadRLNStaticGroup reloads the members of the static RLN group from the group file the node was configured with
+func (w *WakuNode) ReloadRLNStaticGroup() error {
+	return errors.New("rln relay is not enabled")
+}
+
+// AddRLNMerkleRoot adds a merkle root of the RLN group to the roots accepted when validating the messages
+func (w *WakuNode) AddRLNMerkleRoot(blockNumber uint64, root [32]byte) error {
+	return errors.New("rln relay is not enabled")
+}
+
 func (w *WakuNode) setupRLNRelay() error {
 	return nil
 }
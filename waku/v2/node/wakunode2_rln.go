@@ -8,19 +8,46 @@ import (
 	"context"
 	"errors"
 
+	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager/dynamic"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager/static"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager/validation"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/keystore"
 	r "github.com/waku-org/go-zerokit-rln/rln"
+	"go.uber.org/zap"
 )
 
+// getRLNInstanceAndRootTracker and getValidationRLNInstanceAndRootTracker create the RLN instance of the node
+var getRLNInstanceAndRootTracker = rln.GetRLNInstanceAndRootTrackerWithWindowSize
+var getValidationRLNInstanceAndRootTracker = rln.GetValidationRLNInstanceAndRootTracker
+
 // RLNRelay is used to access any operation related to Waku RLN protocol
 func (w *WakuNode) RLNRelay() RLNRelay {
 	return w.rlnRelay
 }
 
+// RLNAvailable indicates whether RLN can be used in this platform, i.e. false if the RLN native
+// library failed to create the RLN instance of the node
+func (w *WakuNode) RLNAvailable() bool {
+	return !w.rlnUnavailable
+}
+
+// handleRLNUnavailable handles RLN being requested while the native library is not available, either
+// returning an error, or disabling RLN if the node was configured to run without it
+func (w *WakuNode) handleRLNUnavailable(err error) error {
+	w.rlnUnavailable = true
+
+	if !w.opts.rlnFallback {
+		return err
+	}
+
+	w.log.Warn("rln is not available, running without rln", zap.Error(err))
+	w.opts.enableRLN = false
+	return nil
+}
+
 func (w *WakuNode) setupRLNRelay() error {
 	var err error
 
@@ -34,11 +61,35 @@ func (w *WakuNode) setupRLNRelay() error {
 
 	var groupManager group_manager.GroupManager
 
-	rlnInstance, rootTracker, err := rln.GetRLNInstanceAndRootTracker(w.opts.rlnTreePath)
+	rootWindowSize := w.opts.rlnAcceptableRootWindowSize
+	if rootWindowSize == 0 {
+		rootWindowSize = rln.DefaultAcceptableRootWindowSize
+	}
+
+	var rlnInstance *r.RLN
+	var rootTracker *group_manager.MerkleRootTracker
+	if w.opts.rlnRelayValidationOnly {
+		// The node does not hold the Merkle tree, so no tree is stored in rlnTreePath
+		rlnInstance, rootTracker, err = getValidationRLNInstanceAndRootTracker(rootWindowSize)
+	} else {
+		rlnInstance, rootTracker, err = getRLNInstanceAndRootTracker(w.opts.rlnTreePath, rootWindowSize)
+	}
+	if errors.Is(err, rln.ErrRLNUnavailable) {
+		return w.handleRLNUnavailable(err)
+	}
 	if err != nil {
 		return err
 	}
-	if !w.opts.rlnRelayDynamic {
+
+	if w.opts.rlnRelayValidationOnly {
+		w.log.Info("setting up waku-rln-relay in validation only mode")
+
+		if len(w.opts.rlnMemberships) != 0 {
+			return errors.New("rln memberships cannot be used in validation only mode")
+		}
+
+		groupManager = validation.NewValidationGroupManager(rootTracker, w.log)
+	} else if !w.opts.rlnRelayDynamic {
 		w.log.Info("setting up waku-rln-relay in off-chain mode")
 
 		index := uint(0)
@@ -47,18 +98,58 @@ func (w *WakuNode) setupRLNRelay() error {
 		}
 
 		// set up rln relay inputs
-		groupKeys, idCredential, err := static.Setup(index)
-		if err != nil {
-			return err
+		var groupKeys []r.IDCommitment
+		var idCredential r.IdentityCredential
+		if w.opts.rlnStaticGroupFile != "" {
+			if w.opts.rlnIdentityCredential == nil {
+				return errors.New("identity credential is required when loading the rln group from a file")
+			}
+
+			groupKeys, err = static.LoadGroupFromFile(w.opts.rlnStaticGroupFile)
+			if err != nil {
+				return err
+			}
+
+			if index >= uint(len(groupKeys)) {
+				return errors.New("wrong membership index")
+			}
+
+			idCredential = *w.opts.rlnIdentityCredential
+		} else {
+			groupKeys, idCredential, err = static.Setup(index)
+			if err != nil {
+				return err
+			}
 		}
 
-		groupManager, err = static.NewStaticGroupManager(groupKeys, idCredential, index, rlnInstance, rootTracker, w.log)
+		var staticGroupManager *static.StaticGroupManager
+		staticGroupManager, err = static.NewStaticGroupManager(groupKeys, idCredential, index, rlnInstance, rootTracker, w.log)
 		if err != nil {
 			return err
 		}
+
+		for _, m := range w.opts.rlnMemberships {
+			err = staticGroupManager.AddMembership(m.identityCredential, m.index, m.contentTopics...)
+			if err != nil {
+				return err
+			}
+		}
+
+		groupManager = staticGroupManager
+
+		if w.opts.rlnTreeSnapshotPath != "" {
+			err = groupManager.Restore(w.opts.rlnTreeSnapshotPath)
+			if err != nil {
+				w.log.Warn("could not restore rln tree snapshot, rebuilding the tree", zap.Error(err))
+			}
+		}
 	} else {
 		w.log.Info("setting up waku-rln-relay in on-chain mode")
 
+		if len(w.opts.rlnMemberships) != 0 {
+			return errors.New("additional rln memberships are only supported in off-chain mode")
+		}
+
 		var appKeystore *keystore.AppKeystore
 		if w.opts.keystorePath != "" {
 			appKeystore, err = keystore.New(w.opts.keystorePath, dynamic.RLNAppInfo, w.log)
@@ -88,10 +179,34 @@ func (w *WakuNode) setupRLNRelay() error {
 		RootTracker:  rootTracker,
 		RLN:          rlnInstance,
 	}, w.timesource, w.opts.prometheusReg, w.log)
+	rlnRelay.SetNullifierLogPath(w.opts.rlnNullifierLogPath)
+	if w.opts.rlnMaxClockGap != nil {
+		err = rlnRelay.SetMaxClockGap(*w.opts.rlnMaxClockGap)
+		if err != nil {
+			return err
+		}
+	}
+	err = rlnRelay.SetProofQueueSize(w.opts.rlnProofQueueSize)
+	if err != nil {
+		return err
+	}
+	if w.opts.rlnSignalVersion != 0 {
+		err = rlnRelay.SetSignalVersion(rln.SignalVersion(w.opts.rlnSignalVersion))
+		if err != nil {
+			return err
+		}
+	}
 
 	w.rlnRelay = rlnRelay
 
-	w.Relay().RegisterDefaultValidator(w.rlnRelay.Validator(w.opts.rlnSpamHandler))
+	if w.opts.rlnSlashingSpamHandler != nil {
+		spamHandler := w.opts.rlnSlashingSpamHandler
+		w.Relay().RegisterDefaultValidator(rlnRelay.ValidatorWithEvidence(func(msg *pb.WakuMessage, topic string, evidence rln.SpamEvidence) error {
+			return spamHandler(msg, topic, SpamEvidence(evidence))
+		}))
+	} else {
+		w.Relay().RegisterDefaultValidator(w.rlnRelay.Validator(w.opts.rlnSpamHandler))
+	}
 
 	return nil
 }
@@ -104,10 +219,13 @@ func (w *WakuNode) startRlnRelay(ctx context.Context) error {
 		return err
 	}
 
-	if !w.opts.rlnRelayDynamic {
+	if !w.opts.rlnRelayDynamic && !w.opts.rlnRelayValidationOnly && w.opts.rlnStaticGroupFile == "" {
 		// check the correct construction of the tree by comparing the calculated root against the expected root
 		// no error should happen as it is already captured in the unit tests
+		rlnLock := rlnRelay.RootTracker.RLNLock()
+		rlnLock.Lock()
 		root, err := rlnRelay.RLN.GetMerkleRoot()
+		rlnLock.Unlock()
 		if err != nil {
 			return err
 		}
@@ -127,9 +245,59 @@ func (w *WakuNode) startRlnRelay(ctx context.Context) error {
 	return nil
 }
 
-func (w *WakuNode) stopRlnRelay() error {
-	if w.rlnRelay != nil {
-		return w.rlnRelay.Stop()
+// ReloadRLNStaticGroup reloads the members of the static RLN group from the group file the node was configured
+// with, so the group can be updated without restarting the node
+func (w *WakuNode) ReloadRLNStaticGroup() error {
+	if w.rlnRelay == nil {
+		return errors.New("rln relay is not enabled")
+	}
+
+	if w.opts.rlnRelayDynamic || w.opts.rlnStaticGroupFile == "" {
+		return errors.New("rln relay is not using a static group file")
+	}
+
+	groupManager, ok := w.rlnRelay.(*rln.WakuRLNRelay).GroupManager.(*static.StaticGroupManager)
+	if !ok {
+		return errors.New("rln relay is not using a static group")
+	}
+
+	group, err := static.LoadGroupFromFile(w.opts.rlnStaticGroupFile)
+	if err != nil {
+		return err
 	}
+
+	return groupManager.ReloadGroup(group)
+}
+
+// AddRLNMerkleRoot adds a merkle root of the RLN group to the roots accepted when validating the messages.
+// It can only be used by nodes running RLN in validation only mode, which do not hold the Merkle tree
+func (w *WakuNode) AddRLNMerkleRoot(blockNumber uint64, root [32]byte) error {
+	if w.rlnRelay == nil {
+		return errors.New("rln relay is not enabled")
+	}
+
+	groupManager, ok := w.rlnRelay.(*rln.WakuRLNRelay).GroupManager.(*validation.ValidationGroupManager)
+	if !ok {
+		return errors.New("rln relay is not in validation only mode")
+	}
+
+	groupManager.AddRoot(blockNumber, root)
+
 	return nil
 }
+
+func (w *WakuNode) stopRlnRelay() error {
+	if w.rlnRelay == nil {
+		return nil
+	}
+
+	if w.opts.rlnTreeSnapshotPath != "" {
+		rlnRelay := w.rlnRelay.(*rln.WakuRLNRelay)
+		err := rlnRelay.GroupManager.Persist(w.opts.rlnTreeSnapshotPath)
+		if err != nil {
+			w.log.Error("persisting rln tree snapshot", zap.Error(err))
+		}
+	}
+
+	return w.rlnRelay.Stop()
+}
@@ -0,0 +1,63 @@
+//go:build !gowaku_no_rln
+// +build !gowaku_no_rln
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/tests"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
+	r "github.com/waku-org/go-zerokit-rln/rln"
+)
+
+func TestRLNUnavailable(t *testing.T) {
+	originalGetRLNInstanceAndRootTracker := getRLNInstanceAndRootTracker
+	getRLNInstanceAndRootTracker = func(treePath string, acceptableRootWindowSize int) (*r.RLN, *group_manager.MerkleRootTracker, error) {
+		return nil, nil, fmt.Errorf("%w: could not create the rln instance", rln.ErrRLNUnavailable)
+	}
+	defer func() {
+		getRLNInstanceAndRootTracker = originalGetRLNInstanceAndRootTracker
+	}()
+
+	hostAddr, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
+
+	key, err := tests.RandomHex(32)
+	require.NoError(t, err)
+	prvKey, err := crypto.HexToECDSA(key)
+	require.NoError(t, err)
+
+	index := r.MembershipIndex(0)
+
+	// RLN was requested but it is not available
+	_, err = New(
+		WithPrivateKey(prvKey),
+		WithHostAddress(hostAddr),
+		WithWakuRelay(),
+		WithStaticRLNRelay(&index, nil),
+	)
+	require.ErrorIs(t, err, rln.ErrRLNUnavailable)
+
+	// Node starts without RLN if the fallback is allowed
+	wakuNode, err := New(
+		WithPrivateKey(prvKey),
+		WithHostAddress(hostAddr),
+		WithWakuRelay(),
+		WithStaticRLNRelay(&index, nil),
+		WithRLNFallback(),
+	)
+	require.NoError(t, err)
+	require.False(t, wakuNode.RLNAvailable())
+
+	err = wakuNode.Start(context.Background())
+	require.NoError(t, err)
+	defer wakuNode.Stop()
+
+	require.Nil(t, wakuNode.RLNRelay())
+}
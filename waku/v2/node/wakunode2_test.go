@@ -600,3 +600,38 @@ func TestPeerExchangeRatelimit(t *testing.T) {
 	err = wakuNode1.PeerExchange().Request(ctx, 1)
 	require.NoError(t, err)
 }
+
+func TestWithProtocolsENRConsistency(t *testing.T) {
+	hostAddr, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
+
+	key, err := tests.RandomHex(32)
+	require.NoError(t, err)
+	prvKey, err := crypto.HexToECDSA(key)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	wakuNode, err := New(
+		WithPrivateKey(prvKey),
+		WithHostAddress(hostAddr),
+		WithWakuRelay(),
+		WithWakuStore(),
+		WithProtocols(true, false, false, true),
+	)
+	require.NoError(t, err)
+
+	err = wakuNode.Start(ctx)
+	require.NoError(t, err)
+	defer wakuNode.Stop()
+
+	protocols := wakuNode.Host().Mux().Protocols()
+	require.Contains(t, protocols, relay.WakuRelayID_v200)
+	require.NotContains(t, protocols, legacy_store.StoreID_v20beta4)
+
+	enrField, err := wenr.GetWakuEnrBitField(wakuNode.ENR())
+	require.NoError(t, err)
+	require.Equal(t, wenr.NewWakuEnrBitfield(true, false, false, true), enrField)
+
+	storeFlag := wenr.NewWakuEnrBitfield(false, false, true, false)
+	require.Zero(t, enrField&storeFlag)
+}
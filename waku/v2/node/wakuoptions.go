@@ -16,6 +16,7 @@ import (
 	"github.com/libp2p/go-libp2p/config"
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peerstore"
+	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
 	basichost "github.com/libp2p/go-libp2p/p2p/host/basic"
 	"github.com/libp2p/go-libp2p/p2p/muxer/yamux"
 	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
@@ -28,6 +29,7 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/onlinechecker"
 	"github.com/waku-org/go-waku/waku/v2/peermanager"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
+	wenr "github.com/waku-org/go-waku/waku/v2/protocol/enr"
 	"github.com/waku-org/go-waku/waku/v2/protocol/filter"
 	"github.com/waku-org/go-waku/waku/v2/protocol/legacy_store"
 	"github.com/waku-org/go-waku/waku/v2/protocol/lightpush"
@@ -51,6 +53,22 @@ const DefaultMaxConnectionsPerIP = 5
 const DefaultMaxConnections = 300
 const DefaultMaxPeerStoreCapacity = 300
 
+// DefaultENRUpdateDebounce is the time the node waits after the last listen addresses update before rebuilding its ENR
+const DefaultENRUpdateDebounce = 2 * time.Second
+
+// maxENRUpdateDebounces is the maximum number of debounce periods the ENR update is delayed by
+// listen addresses updates that keep coming
+const maxENRUpdateDebounces = 5
+
+// DefaultMaxENRMultiaddrs is the maximum number of multiaddresses written in the ENR multiaddr key
+const DefaultMaxENRMultiaddrs = 5
+
+type rlnMembership struct {
+	identityCredential IdentityCredential
+	index              uint
+	contentTopics      []string
+}
+
 type WakuNodeParameters struct {
 	hostAddr            *net.TCPAddr
 	maxConnectionsPerIP int
@@ -58,6 +76,14 @@ type WakuNodeParameters struct {
 	shards              *protocol.RelayShards
 	dns4Domain          string
 	advertiseAddrs      []multiaddr.Multiaddr
+	advertisePrivateIPs bool
+	strictENRSize       bool
+	enrFields           map[string][]byte
+	dnsCacheTTL         time.Duration
+	dnsCacheGracePeriod time.Duration
+	enrUpdateDebounce   time.Duration
+	maxENRMultiaddrs    int
+	enrFallbackIP       net.IP
 	multiAddr           []multiaddr.Multiaddr
 	addressFactory      basichost.AddrsFactory
 	privKey             *ecdsa.PrivateKey
@@ -86,13 +112,17 @@ type WakuNodeParameters struct {
 	enableFilterLightNode bool
 	enableFilterFullNode  bool
 	filterOpts            []filter.Option
+	filterLightNodeOpts   []filter.LightNodeOption
 	pubsubOpts            []pubsub.Option
 	lightpushOpts         []lightpush.Option
 
 	minRelayPeersToPublish int
 	maxMsgSizeBytes        int
+	relayMaxMessageAge     time.Duration
+	relayMaxFutureDrift    time.Duration
 
 	enableStore     bool
+	storeOpts       []legacy_store.Option
 	messageProvider legacy_store.MessageProvider
 
 	storeRateLimit rate.Limit
@@ -103,6 +133,13 @@ type WakuNodeParameters struct {
 	maxPeerConnections int
 	peerStoreCapacity  int
 
+	maxStreams map[libp2pProtocol.ID]int
+
+	connManager *connmgr.BasicConnMgr
+
+	connKeepAliveInterval time.Duration
+	idleConnTimeout       time.Duration
+
 	enableDiscV5     bool
 	udpPort          uint
 	discV5bootnodes  []*enode.Node
@@ -112,13 +149,25 @@ type WakuNodeParameters struct {
 	peerExchangeOptions []peer_exchange.Option
 
 	enableRLN                    bool
+	rlnFallback                  bool
 	rlnRelayMemIndex             *uint
 	rlnRelayDynamic              bool
+	rlnRelayValidationOnly       bool
+	rlnStaticGroupFile           string
+	rlnIdentityCredential        *IdentityCredential
 	rlnSpamHandler               func(message *pb.WakuMessage, topic string) error
 	rlnETHClientAddress          string
 	keystorePath                 string
 	keystorePassword             string
 	rlnTreePath                  string
+	rlnAcceptableRootWindowSize  int
+	rlnNullifierLogPath          string
+	rlnTreeSnapshotPath          string
+	rlnSlashingSpamHandler       SlashingSpamHandler
+	rlnMaxClockGap               *time.Duration
+	rlnProofQueueSize            int
+	rlnMemberships               []rlnMembership
+	rlnSignalVersion             int
 	rlnMembershipContractAddress common.Address
 
 	keepAliveRandomPeersInterval time.Duration
@@ -143,6 +192,9 @@ var DefaultWakuNodeOptions = []WakuNodeOption{
 	WithPeerStoreCapacity(DefaultMaxPeerStoreCapacity),
 	WithOnlineChecker(onlinechecker.NewDefaultOnlineChecker(true)),
 	WithWakuStoreRateLimit(8), // Value currently set in status.staging
+	WithDNSCache(DefaultDNSCacheTTL, DefaultDNSCacheGracePeriod),
+	WithENRUpdateDebounce(DefaultENRUpdateDebounce),
+	WithMaxENRMultiaddrs(DefaultMaxENRMultiaddrs),
 }
 
 // MultiAddresses return the list of multiaddresses configured in the node
@@ -251,7 +303,9 @@ func WithHostAddress(hostAddr *net.TCPAddr) WakuNodeOption {
 	}
 }
 
-// WithAdvertiseAddresses is a WakuNodeOption that allows overriding the address used in the waku node with custom value
+// WithAdvertiseAddresses is a WakuNodeOption that allows overriding the address used in the waku node with custom value.
+// The addresses are listed by priority: the first external one is written in the ENR ip and tcp keys, and the
+// rest are added to the multiaddr key. Advertising any address disables the discv5 endpoint prediction
 func WithAdvertiseAddresses(advertiseAddrs ...multiaddr.Multiaddr) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
 		params.advertiseAddrs = advertiseAddrs
@@ -259,6 +313,98 @@ func WithAdvertiseAddresses(advertiseAddrs ...multiaddr.Multiaddr) WakuNodeOptio
 	}
 }
 
+// WithNonRoutableAdvertiseAddresses is a WakuNodeOption that accepts loopback, link-local and private IP
+// addresses in WithAdvertiseAddresses. It is meant for test setups and private networks
+func WithNonRoutableAdvertiseAddresses() WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.advertisePrivateIPs = true
+		return nil
+	}
+}
+
+// WithStrictENRSize is a WakuNodeOption that makes the node fail to update its ENR when the multiaddresses
+// do not fit in it, instead of dropping the lowest priority ones
+func WithStrictENRSize() WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.strictENRSize = true
+		return nil
+	}
+}
+
+// WithENRField is a WakuNodeOption that adds an application specific field to the ENR of the node.
+// Keys used by Waku or by the ENR spec are refused, as well as values that do not fit in the ENR
+func WithENRField(key string, value []byte) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if err := wenr.ValidateCustomField(key); err != nil {
+			return err
+		}
+
+		if err := wenr.CheckCustomFieldSize(key, value); err != nil {
+			return err
+		}
+
+		if params.enrFields == nil {
+			params.enrFields = make(map[string][]byte)
+		}
+		params.enrFields[key] = value
+		return nil
+	}
+}
+
+// WithDNSCache is a WakuNodeOption that sets how long the DNS resolution results of the addresses
+// used for the ENR are cached, and for how long after they expire they are still used when the
+// resolution fails
+func WithDNSCache(ttl time.Duration, gracePeriod time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if ttl < 0 || gracePeriod < 0 {
+			return errors.New("dns cache ttl and grace period cannot be negative")
+		}
+		params.dnsCacheTTL = ttl
+		params.dnsCacheGracePeriod = gracePeriod
+		return nil
+	}
+}
+
+// WithENRUpdateDebounce is a WakuNodeOption that sets how long the node waits after the last
+// listen addresses update before rebuilding its ENR
+func WithENRUpdateDebounce(d time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if d < 0 {
+			return errors.New("enr update debounce cannot be negative")
+		}
+		params.enrUpdateDebounce = d
+		return nil
+	}
+}
+
+// WithMaxENRMultiaddrs is a WakuNodeOption that sets the maximum number of multiaddresses written in the
+// ENR multiaddr key. The least useful ones are dropped. A value of 0 means no limit
+func WithMaxENRMultiaddrs(max int) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if max < 0 {
+			return errors.New("max enr multiaddrs cannot be negative")
+		}
+		params.maxENRMultiaddrs = max
+		return nil
+	}
+}
+
+// WithENRFallbackIP is a WakuNodeOption that sets the IP written in the ENR when discv5 cannot predict the
+// external address of the node and the listen address is unspecified, i.e. the pod IP of a node running in
+// Kubernetes. By default no IP is written in this situation
+func WithENRFallbackIP(ip net.IP) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+			return errors.New("invalid enr fallback ip")
+		}
+		if ip.IsUnspecified() {
+			return errors.New("enr fallback ip cannot be unspecified")
+		}
+		params.enrFallbackIP = ip
+		return nil
+	}
+}
+
 // WithExternalIP is a WakuNodeOption that allows overriding the advertised external IP used in the waku node with custom value
 func WithExternalIP(ip net.IP) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
@@ -427,6 +573,19 @@ func WithMaxMsgSize(maxMsgSizeBytes int) WakuNodeOption {
 	}
 }
 
+// WithRelayMessageAgeBounds is a WakuNodeOption that specifies the window of timestamps accepted when
+// publishing a message with a timestamp set by the caller. See relay.WithMessageAgeBounds
+func WithRelayMessageAgeBounds(maxMessageAge time.Duration, maxFutureDrift time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if maxMessageAge < 0 || maxFutureDrift < 0 {
+			return errors.New("message age bounds cannot be negative")
+		}
+		params.relayMaxMessageAge = maxMessageAge
+		params.relayMaxFutureDrift = maxFutureDrift
+		return nil
+	}
+}
+
 func WithMaxPeerConnections(maxPeers int) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
 		params.maxPeerConnections = maxPeers
@@ -434,6 +593,55 @@ func WithMaxPeerConnections(maxPeers int) WakuNodeOption {
 	}
 }
 
+// WithMaxStreams is a WakuNodeOption used to limit the number of inbound streams
+// that are handled concurrently for a protocol. Streams received once the limit
+// is reached are reset
+func WithMaxStreams(p libp2pProtocol.ID, maxStreams int) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if params.maxStreams == nil {
+			params.maxStreams = make(map[libp2pProtocol.ID]int)
+		}
+		params.maxStreams[p] = maxStreams
+		return nil
+	}
+}
+
+// WithConnectionLimits is a WakuNodeOption used to limit the number of connections of the node.
+// Once the number of connections exceeds highWatermark, connections are closed until only
+// lowWatermark connections remain. Connections younger than gracePeriod are not closed, and
+// neither are the connections to peers protected by Waku, such as service peers and peers
+// with active filter subscriptions. This overrides the connection manager set in the libp2p options
+func WithConnectionLimits(lowWatermark int, highWatermark int, gracePeriod time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		mgr, err := connmgr.NewConnManager(lowWatermark, highWatermark, connmgr.WithGracePeriod(gracePeriod))
+		if err != nil {
+			return err
+		}
+		params.connManager = mgr
+		return nil
+	}
+}
+
+// WithConnectionKeepAlive is a WakuNodeOption used to send keep-alive probes every interval
+// on the connections of the node, regardless of the transport being used (TCP or websockets).
+// This prevents NATs and load balancers from silently dropping long-lived connections
+func WithConnectionKeepAlive(interval time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.connKeepAliveInterval = interval
+		return nil
+	}
+}
+
+// WithIdleConnectionTimeout is a WakuNodeOption used to close the connections that had no
+// open streams for longer than timeout. Connections to peers protected by Waku, such as
+// service peers and peers with active filter subscriptions, are never closed as idle
+func WithIdleConnectionTimeout(timeout time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.idleConnTimeout = timeout
+		return nil
+	}
+}
+
 func WithPeerStoreCapacity(capacity int) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
 		params.peerStoreCapacity = capacity
@@ -462,9 +670,11 @@ func WithPeerExchange(options ...peer_exchange.Option) WakuNodeOption {
 }
 
 // WithWakuFilter enables the Waku Filter V2 protocol for lightnode functionality
-func WithWakuFilterLightNode() WakuNodeOption {
+// This WakuNodeOption accepts a list of light node options to setup the protocol
+func WithWakuFilterLightNode(opts ...filter.LightNodeOption) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
 		params.enableFilterLightNode = true
+		params.filterLightNodeOpts = opts
 		return nil
 	}
 }
@@ -491,9 +701,10 @@ func WithWakuStoreRateLimit(value rate.Limit) WakuNodeOption {
 
 // WithWakuStore enables the Waku V2 Store protocol and if the messages should
 // be stored or not in a message provider.
-func WithWakuStore() WakuNodeOption {
+func WithWakuStore(opts ...legacy_store.Option) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
 		params.enableStore = true
+		params.storeOpts = opts
 		return nil
 	}
 }
@@ -529,6 +740,20 @@ func WithLightPush(lightpushOpts ...lightpush.Option) WakuNodeOption {
 	}
 }
 
+// WithProtocols is a WakuNodeOption used to enable or disable the relay, store, filter (full node)
+// and lightpush protocols at once. Only the enabled protocols are mounted, and the capabilities
+// advertised in the node's ENR are derived from these same flags. Protocol specific options set
+// with WithWakuRelay, WithWakuStore, WithWakuFilterFullNode or WithLightPush are preserved
+func WithProtocols(relay, store, filter, lightpush bool) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.enableRelay = relay
+		params.enableStore = store
+		params.enableFilterFullNode = filter
+		params.enableLightPush = lightpush
+		return nil
+	}
+}
+
 // WithKeepAlive is a WakuNodeOption used to set the interval of time when
 // each peer will be ping to keep the TCP connection alive. Option accepts two
 // intervals, the `randomPeersInterval`, which will be used to ping full mesh
@@ -4,6 +4,9 @@
 package node
 
 import (
+	"errors"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln"
 	r "github.com/waku-org/go-zerokit-rln/rln"
@@ -14,17 +17,35 @@ func WithStaticRLNRelay(memberIndex *r.MembershipIndex, spamHandler rln.SpamHand
 	return func(params *WakuNodeParameters) error {
 		params.enableRLN = true
 		params.rlnRelayDynamic = false
+		params.rlnRelayValidationOnly = false
 		params.rlnRelayMemIndex = memberIndex
 		params.rlnSpamHandler = spamHandler
 		return nil
 	}
 }
 
+// WithStaticRLNRelayGroupFile enables the Waku V2 RLN protocol in offchain mode, using a group
+// loaded from a file instead of the hardcoded static group. The file can contain either a JSON
+// array or a line delimited list of hex encoded IDCommitments
+func WithStaticRLNRelayGroupFile(groupFilePath string, identityCredential IdentityCredential, memberIndex r.MembershipIndex, spamHandler rln.SpamHandler) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.enableRLN = true
+		params.rlnRelayDynamic = false
+		params.rlnRelayValidationOnly = false
+		params.rlnStaticGroupFile = groupFilePath
+		params.rlnIdentityCredential = &identityCredential
+		params.rlnRelayMemIndex = &memberIndex
+		params.rlnSpamHandler = spamHandler
+		return nil
+	}
+}
+
 // WithDynamicRLNRelay enables the Waku V2 RLN protocol in onchain mode.
 func WithDynamicRLNRelay(keystorePath string, keystorePassword string, treePath string, membershipContract common.Address, membershipIndex *uint, spamHandler rln.SpamHandler, ethClientAddress string) WakuNodeOption {
 	return func(params *WakuNodeParameters) error {
 		params.enableRLN = true
 		params.rlnRelayDynamic = true
+		params.rlnRelayValidationOnly = false
 		params.keystorePassword = keystorePassword
 		params.keystorePath = keystorePath
 		params.rlnSpamHandler = spamHandler
@@ -35,3 +56,122 @@ func WithDynamicRLNRelay(keystorePath string, keystorePassword string, treePath
 		return nil
 	}
 }
+
+// WithValidationOnlyRLNRelay enables the Waku V2 RLN protocol to validate the proofs of the relayed messages
+// without holding the Merkle tree of the group. The accepted merkle roots of the group must be provided with
+// AddRLNMerkleRoot. The node has no membership, so it cannot generate proofs for the messages it publishes
+func WithValidationOnlyRLNRelay(spamHandler rln.SpamHandler) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.enableRLN = true
+		params.rlnRelayDynamic = false
+		params.rlnRelayValidationOnly = true
+		params.rlnSpamHandler = spamHandler
+		return nil
+	}
+}
+
+// WithRLNFallback allows the node to start without RLN if it was requested but the
+// RLN native library fails to create an RLN instance in this platform. By default,
+// the node fails to start with rln.ErrRLNUnavailable in this situation
+func WithRLNFallback() WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.rlnFallback = true
+		return nil
+	}
+}
+
+// WithRLNAcceptableRootWindowSize sets how many of the latest merkle roots are accepted when validating the
+// proofs of incoming messages. A larger window tolerates proofs generated against older roots in groups whose
+// membership changes often, at the cost of accepting proofs from removed members for longer
+func WithRLNAcceptableRootWindowSize(size int) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if size <= 0 {
+			return errors.New("acceptable root window size must be positive")
+		}
+		params.rlnAcceptableRootWindowSize = size
+		return nil
+	}
+}
+
+// WithRLNNullifierLogPath persists the RLN nullifier log in a database stored in path, so that messages
+// exceeding the rate limit are still detected after the node restarts
+func WithRLNNullifierLogPath(path string) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.rlnNullifierLogPath = path
+		return nil
+	}
+}
+
+// WithRLNTreeSnapshot makes a node using a static RLN group store a snapshot of its Merkle tree state in
+// path when it stops, and restore it when it starts, so only the members added after the snapshot need
+// to be inserted. If the snapshot is missing or does not match the tree, the tree is rebuilt
+func WithRLNTreeSnapshot(path string) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.rlnTreeSnapshotPath = path
+		return nil
+	}
+}
+
+// WithRLNSlashingSpamHandler sets a spam handler that also receives the proofs of the conflicting messages,
+// so the secret of the spammer can be recovered to slash its membership. It replaces the spam handler
+// passed when enabling RLN
+func WithRLNSlashingSpamHandler(spamHandler SlashingSpamHandler) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		params.rlnSlashingSpamHandler = spamHandler
+		return nil
+	}
+}
+
+// WithRLNMaxClockGap sets the maximum clock difference between peers tolerated when validating the epoch of
+// the proofs of incoming messages. Messages whose epoch is further away from the current epoch are considered
+// invalid. By default rln.DefaultMaxClockGap is used
+func WithRLNMaxClockGap(maxClockGap time.Duration) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if maxClockGap < 0 {
+			return errors.New("max clock gap cannot be negative")
+		}
+		params.rlnMaxClockGap = &maxClockGap
+		return nil
+	}
+}
+
+// WithRLNProofQueueSize sets how many of the messages passed to AppendRLNProofAsync can wait for their RLN
+// proof to be generated before new messages are rejected. A value of 0 uses rln.DefaultProofQueueSize
+func WithRLNProofQueueSize(queueSize int) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if queueSize < 0 {
+			return errors.New("proof queue size cannot be negative")
+		}
+		params.rlnProofQueueSize = queueSize
+		return nil
+	}
+}
+
+// WithRLNMembership adds another membership of the node in a static RLN group, whose credential is used to
+// generate the proofs of the messages published in contentTopics. The proofs of messages published in other
+// content topics are generated with the membership passed when enabling RLN
+func WithRLNMembership(identityCredential IdentityCredential, memberIndex r.MembershipIndex, contentTopics ...string) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if len(contentTopics) == 0 {
+			return errors.New("at least one content topic is required")
+		}
+		params.rlnMemberships = append(params.rlnMemberships, rlnMembership{
+			identityCredential: identityCredential,
+			index:              memberIndex,
+			contentTopics:      contentTopics,
+		})
+		return nil
+	}
+}
+
+// WithRLNSignalVersion sets which fields of the messages are included in the signal of their RLN proofs.
+// All the nodes of a network must use the same version. By default rln.DefaultSignalVersion is used
+func WithRLNSignalVersion(version rln.SignalVersion) WakuNodeOption {
+	return func(params *WakuNodeParameters) error {
+		if version != rln.SignalV1 && version != rln.SignalV2 {
+			return errors.New("unsupported rln signal version")
+		}
+		params.rlnSignalVersion = int(version)
+		return nil
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/peermanager"
 	"github.com/waku-org/go-waku/waku/v2/protocol/legacy_store"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln"
 	r "github.com/waku-org/go-zerokit-rln/rln"
 	"go.uber.org/zap"
 
@@ -61,6 +62,7 @@ func TestWakuOptions(t *testing.T) {
 		WithKeepAlive(time.Minute, time.Hour),
 		WithTopicHealthStatusChannel(topicHealthStatusChan),
 		WithWakuStoreFactory(storeFactory),
+		WithENRUpdateDebounce(time.Second),
 	}
 
 	params := new(WakuNodeParameters)
@@ -72,6 +74,14 @@ func TestWakuOptions(t *testing.T) {
 	require.NotNil(t, params.multiAddr)
 	require.NotNil(t, params.privKey)
 	require.NotNil(t, params.topicHealthNotifCh)
+	require.Equal(t, time.Second, params.enrUpdateDebounce)
+
+	require.Error(t, WithENRUpdateDebounce(-time.Second)(params))
+
+	require.NoError(t, WithRelayMessageAgeBounds(time.Hour, time.Minute)(params))
+	require.Equal(t, time.Hour, params.relayMaxMessageAge)
+	require.Equal(t, time.Minute, params.relayMaxFutureDrift)
+	require.Error(t, WithRelayMessageAgeBounds(-time.Hour, time.Minute)(params))
 }
 
 func TestWakuRLNOptions(t *testing.T) {
@@ -151,6 +161,10 @@ func TestWakuRLNOptions(t *testing.T) {
 		WithTopicHealthStatusChannel(topicHealthStatusChan),
 		WithWakuStoreFactory(storeFactory),
 		WithDynamicRLNRelay(keystorePath, keystorePassword, rlnTreePath, common.HexToAddress(contractAddress), &index, handleSpam, ethClientAddress),
+		WithRLNAcceptableRootWindowSize(10),
+		WithRLNMaxClockGap(5 * time.Second),
+		WithRLNProofQueueSize(50),
+		WithRLNSignalVersion(rln.SignalV2),
 	}
 
 	params2 := new(WakuNodeParameters)
@@ -167,5 +181,22 @@ func TestWakuRLNOptions(t *testing.T) {
 	require.Equal(t, ethClientAddress, params2.rlnETHClientAddress)
 	require.Equal(t, common.HexToAddress(contractAddress), params2.rlnMembershipContractAddress)
 	require.Equal(t, rlnTreePath, params2.rlnTreePath)
-
+	require.Equal(t, 10, params2.rlnAcceptableRootWindowSize)
+
+	require.Equal(t, 5*time.Second, *params2.rlnMaxClockGap)
+	require.Equal(t, 50, params2.rlnProofQueueSize)
+	require.Equal(t, int(rln.SignalV2), params2.rlnSignalVersion)
+
+	require.Error(t, WithRLNAcceptableRootWindowSize(0)(params2))
+	require.Error(t, WithRLNMaxClockGap(-time.Second)(params2))
+	require.Error(t, WithRLNProofQueueSize(-1)(params2))
+	require.Error(t, WithRLNSignalVersion(rln.SignalVersion(3))(params2))
+
+	// Test WithValidationOnlyRLNRelay
+	params3 := new(WakuNodeParameters)
+	require.NoError(t, WithValidationOnlyRLNRelay(handleSpam)(params3))
+	require.True(t, params3.enableRLN)
+	require.True(t, params3.rlnRelayValidationOnly)
+	require.False(t, params3.rlnRelayDynamic)
+	require.NotNil(t, params3.rlnSpamHandler)
 }
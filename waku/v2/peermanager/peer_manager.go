@@ -96,6 +96,9 @@ type PeerSelection int
 const (
 	Automatic PeerSelection = iota
 	LowestRTT
+	// LowestLatency selects the peers with the lowest latency observed by the peerstore,
+	// without pinging them. Peers with the same latency are selected randomly
+	LowestLatency
 )
 
 const maxFailedAttempts = 5
@@ -105,6 +108,9 @@ const maxConnsToPeerRatio = 3
 const badPeersCleanupInterval = 1 * time.Minute
 const maxDialFailures = 2
 
+// serviceSlotProtectionTag is used to protect the connections to service peers from being trimmed
+const serviceSlotProtectionTag = "waku-service-peer"
+
 // 80% relay peers 20% service peers
 func relayAndServicePeers(maxConnections int) (int, int) {
 	return maxConnections - maxConnections/5, maxConnections / 5
@@ -722,10 +728,14 @@ func (pm *PeerManager) Connect(pData *service.PeerData) {
 // RemovePeer deletes peer from the peerStore after disconnecting it.
 // It also removes the peer from serviceSlot.
 func (pm *PeerManager) RemovePeer(peerID peer.ID) {
-	pm.host.Peerstore().RemovePeer(peerID)
 	//Search if this peer is in serviceSlot and if so, remove it from there
 	// TODO:Add another peer which is statically configured to the serviceSlot.
 	pm.serviceSlots.removePeer(peerID)
+	if pm.host == nil {
+		return
+	}
+	pm.host.Peerstore().RemovePeer(peerID)
+	pm.host.ConnManager().Unprotect(peerID, serviceSlotProtectionTag)
 }
 
 // addPeerToServiceSlot adds a peerID to serviceSlot.
@@ -743,6 +753,10 @@ func (pm *PeerManager) addPeerToServiceSlot(proto protocol.ID, peerID peer.ID) {
 		zap.String("service", string(proto)))
 	// getPeers returns nil for WakuRelayIDv200 protocol, but we don't run this ServiceSlot code for WakuRelayIDv200 protocol
 	pm.serviceSlots.getPeers(proto).add(peerID)
+	// Service peers are not closed when the connection manager trims connections
+	if pm.host != nil {
+		pm.host.ConnManager().Protect(peerID, serviceSlotProtectionTag)
+	}
 }
 
 func (pm *PeerManager) HandleDialError(err error, peerID peer.ID) {
@@ -9,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
@@ -218,6 +219,13 @@ func TestAdditionAndRemovalOfPeer(t *testing.T) {
 	require.Error(t, err, utils.ErrNoPeersAvailable)
 }
 
+func TestRemovePeerWithoutHost(t *testing.T) {
+	pm := NewPeerManager(10, 20, nil, nil, true, utils.Logger())
+	require.NotPanics(t, func() {
+		pm.RemovePeer(peer.ID("peer"))
+	})
+}
+
 func TestConnectToRelayPeers(t *testing.T) {
 
 	ctx, pm, deferFn := initTest(t)
@@ -322,3 +330,138 @@ func TestOnDemandPeerDiscovery(t *testing.T) {
 	require.Equal(t, host1.ID(), peerIDs[0])
 
 }
+
+func TestPeerSelectionExcludesSelf(t *testing.T) {
+	ctx, pm, deferFn := initTest(t)
+	defer deferFn()
+
+	protocol := libp2pProtocol.ID("test/protocol")
+
+	// The node itself is found in the peerstore and in the service slot supporting the protocol
+	_, err := pm.AddPeer(tests.GetAddr(pm.host), wps.Static, []string{"/waku/2/rs/2/1"}, protocol)
+	require.NoError(t, err)
+
+	_, err = pm.SelectPeers(PeerSelectionCriteria{SelectionType: Automatic, Proto: protocol})
+	require.ErrorIs(t, err, utils.ErrNoPeersAvailable)
+
+	_, err = pm.SelectPeers(PeerSelectionCriteria{SelectionType: Automatic, Proto: protocol, SpecificPeers: peer.IDSlice{pm.host.ID()}})
+	require.ErrorIs(t, err, utils.ErrNoPeersAvailable)
+
+	h2, err := tests.MakeHost(ctx, 0, rand.Reader)
+	require.NoError(t, err)
+	defer h2.Close()
+
+	_, err = pm.AddPeer(tests.GetAddr(h2), wps.Static, []string{"/waku/2/rs/2/1"}, protocol)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		peerIDs, err := pm.SelectPeers(PeerSelectionCriteria{SelectionType: Automatic, Proto: protocol, MaxPeers: 2})
+		require.NoError(t, err)
+		require.Equal(t, peer.IDSlice{h2.ID()}, peerIDs)
+
+		peerIDs, err = pm.SelectPeers(PeerSelectionCriteria{SelectionType: Automatic, Proto: protocol, PubsubTopics: []string{"/waku/2/rs/2/1"}, MaxPeers: 2})
+		require.NoError(t, err)
+		require.Equal(t, peer.IDSlice{h2.ID()}, peerIDs)
+	}
+}
+
+func TestLowestLatencyPeerSelection(t *testing.T) {
+	ctx, pm, deferFn := initTest(t)
+	defer deferFn()
+
+	protocol := libp2pProtocol.ID("test/protocol")
+	pubsubTopic := "/waku/2/rs/2/1"
+
+	var hosts []host.Host
+	for i := 0; i < 3; i++ {
+		h, err := tests.MakeHost(ctx, 0, rand.Reader)
+		require.NoError(t, err)
+		defer h.Close()
+		_, err = pm.AddPeer(tests.GetAddr(h), wps.Static, []string{pubsubTopic}, protocol)
+		require.NoError(t, err)
+		hosts = append(hosts, h)
+	}
+
+	// A peer that does not track the pubsub topic is never selected
+	h4, err := tests.MakeHost(ctx, 0, rand.Reader)
+	require.NoError(t, err)
+	defer h4.Close()
+	_, err = pm.AddPeer(tests.GetAddr(h4), wps.Static, []string{"/waku/2/rs/2/2"}, protocol)
+	require.NoError(t, err)
+	pm.host.Peerstore().RecordLatency(h4.ID(), time.Millisecond)
+
+	// A peer whose pubsub topics are unknown is selected after the peers that track the pubsub topic
+	h5, err := tests.MakeHost(ctx, 0, rand.Reader)
+	require.NoError(t, err)
+	defer h5.Close()
+	_, err = pm.AddPeer(tests.GetAddr(h5), wps.Static, nil, protocol)
+	require.NoError(t, err)
+	pm.host.Peerstore().RecordLatency(h5.ID(), time.Millisecond)
+
+	pm.host.Peerstore().RecordLatency(hosts[1].ID(), 10*time.Millisecond)
+	pm.host.Peerstore().RecordLatency(hosts[2].ID(), 50*time.Millisecond)
+
+	peerIDs, err := pm.SelectPeers(PeerSelectionCriteria{SelectionType: LowestLatency, Proto: protocol, PubsubTopics: []string{pubsubTopic}})
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{hosts[1].ID()}, peerIDs)
+
+	// Peers with unknown latency are selected last among the peers that track the pubsub topic
+	peerIDs, err = pm.SelectPeers(PeerSelectionCriteria{SelectionType: LowestLatency, Proto: protocol, PubsubTopics: []string{pubsubTopic}, MaxPeers: 5})
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{hosts[1].ID(), hosts[2].ID(), hosts[0].ID(), h5.ID()}, peerIDs)
+
+	// A custom strategy overrides the selection type
+	strategy := func(ctx context.Context, candidates peer.IDSlice, maxPeers int) (peer.IDSlice, error) {
+		require.Len(t, candidates, 3)
+		for _, p := range candidates {
+			if p == hosts[0].ID() {
+				return peer.IDSlice{p}, nil
+			}
+		}
+		return nil, nil
+	}
+	peerIDs, err = pm.SelectPeers(PeerSelectionCriteria{SelectionType: LowestLatency, Proto: protocol, PubsubTopics: []string{pubsubTopic}, Strategy: strategy})
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{hosts[0].ID()}, peerIDs)
+
+	_, err = pm.SelectPeers(PeerSelectionCriteria{SelectionType: LowestLatency, Proto: protocol, PubsubTopics: []string{"/waku/2/rs/2/3"}})
+	require.ErrorIs(t, err, utils.ErrNoPeersAvailable)
+}
+
+func TestLowestLatencyPeerSelectionWithLiveness(t *testing.T) {
+	ctx, pm, deferFn := initTest(t)
+	defer deferFn()
+
+	protocol := libp2pProtocol.ID("test/protocol")
+	pubsubTopic := "/waku/2/rs/2/1"
+
+	var hosts []host.Host
+	for i := 0; i < 2; i++ {
+		h, err := tests.MakeHost(ctx, 0, rand.Reader)
+		require.NoError(t, err)
+		defer h.Close()
+
+		_, err = pm.AddPeer(tests.GetAddr(h), wps.Static, []string{pubsubTopic}, protocol)
+		require.NoError(t, err)
+		hosts = append(hosts, h)
+	}
+
+	// The peer with the lowest latency does not serve the protocol anymore
+	pm.host.Peerstore().RecordLatency(hosts[0].ID(), 10*time.Millisecond)
+	pm.host.Peerstore().RecordLatency(hosts[1].ID(), 50*time.Millisecond)
+	hosts[1].SetStreamHandler(protocol, func(s network.Stream) { s.Close() })
+
+	criteria := PeerSelectionCriteria{SelectionType: LowestLatency, Proto: protocol, PubsubTopics: []string{pubsubTopic}, Ctx: ctx}
+	peerIDs, err := pm.SelectPeers(criteria)
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{hosts[0].ID()}, peerIDs)
+
+	criteria.CheckLiveness = true
+	peerIDs, err = pm.SelectPeers(criteria)
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{hosts[1].ID()}, peerIDs)
+
+	hosts[1].RemoveStreamHandler(protocol)
+	_, err = pm.SelectPeers(criteria)
+	require.ErrorIs(t, err, utils.ErrNoPeersAvailable)
+}
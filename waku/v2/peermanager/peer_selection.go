@@ -4,11 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
 	wps "github.com/waku-org/go-waku/waku/v2/peerstore"
 	waku_proto "github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/liveness"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"go.uber.org/zap"
 	"golang.org/x/exp/maps"
@@ -57,6 +63,7 @@ func (pm *PeerManager) SelectRandom(criteria PeerSelectionCriteria) (peer.IDSlic
 	// This will require us to check for various factors such as:
 	//  - which topics they track
 	//  - latency?
+	criteria.ExcludePeers = pm.excludeSelf(criteria.ExcludePeers)
 	peerIDs, err := pm.selectServicePeer(criteria)
 	if err == nil && len(peerIDs) == criteria.MaxPeers {
 		return maps.Keys(peerIDs), nil
@@ -87,6 +94,21 @@ func (pm *PeerManager) SelectRandom(criteria PeerSelectionCriteria) (peer.IDSlic
 	return maps.Keys(peerIDs), nil
 }
 
+// excludeSelf returns a copy of excludePeers that also contains the ID of this node, so it is
+// never selected even if it is found in the peerstore supporting the protocol
+func (pm *PeerManager) excludeSelf(excludePeers PeerSet) PeerSet {
+	if PeerInSet(excludePeers, pm.host.ID()) {
+		return excludePeers
+	}
+
+	result := make(PeerSet, len(excludePeers)+1)
+	for p := range excludePeers {
+		result[p] = struct{}{}
+	}
+	result[pm.host.ID()] = struct{}{}
+	return result
+}
+
 func getRandom(filter PeerSet, count int, excludePeers PeerSet) (PeerSet, error) {
 	i := 0
 	selectedPeers := make(PeerSet)
@@ -170,8 +192,23 @@ type PeerSelectionCriteria struct {
 	MaxPeers      int             `json:"maxPeerCount"`
 	Ctx           context.Context `json:"-"`
 	ExcludePeers  PeerSet         `json:"excludePeers"`
+	// CheckLiveness indicates that the selected peers must be probed to confirm they are
+	// responsive and still serve the protocol. Unresponsive peers are skipped
+	CheckLiveness bool `json:"checkLiveness"`
+	// Strategy overrides the selection type with a custom heuristic
+	Strategy PeerSelectionStrategy `json:"-"`
 }
 
+// PeerSelectionStrategy selects up to maxPeers from the candidates, which are the peers
+// that support the protocol and track the pubsub topics of the selection criteria
+type PeerSelectionStrategy func(ctx context.Context, candidates peer.IDSlice, maxPeers int) (peer.IDSlice, error)
+
+// livenessProbeTimeout is the time a peer has to reply to a liveness probe during peer selection
+const livenessProbeTimeout = 3 * time.Second
+
+// maxLivenessAttempts is the number of times peers are selected again when all the selected peers are unresponsive
+const maxLivenessAttempts = 2
+
 func (psc PeerSelectionCriteria) String() string {
 	pscJson, err := json.Marshal(psc)
 	if err != nil {
@@ -192,8 +229,15 @@ func (pm *PeerManager) SelectPeers(criteria PeerSelectionCriteria) (peer.IDSlice
 		excPeer = excPeers[0]
 	}
 	pm.logger.Debug("Select Peers", zap.Stringer("selectionCriteria", criteria), zap.Stringer("excludedPeers", excPeer))
+	criteria.ExcludePeers = pm.excludeSelf(criteria.ExcludePeers)
+	if criteria.Strategy != nil {
+		return pm.selectWithStrategy(criteria)
+	}
 	switch criteria.SelectionType {
 	case Automatic:
+		if criteria.CheckLiveness {
+			return pm.selectLivePeers(criteria)
+		}
 		return pm.SelectRandom(criteria)
 	case LowestRTT:
 		peerID, err := pm.SelectPeerWithLowestRTT(criteria)
@@ -202,11 +246,77 @@ func (pm *PeerManager) SelectPeers(criteria PeerSelectionCriteria) (peer.IDSlice
 		}
 		//TODO: Update this once peer Ping cache PR is merged into this code.
 		return []peer.ID{peerID}, nil
+	case LowestLatency:
+		return pm.SelectPeersWithLowestLatency(criteria)
 	default:
 		return nil, errors.New("unknown peer selection type specified")
 	}
 }
 
+// selectLivePeers selects random peers, skipping those that do not reply to a liveness probe
+func (pm *PeerManager) selectLivePeers(criteria PeerSelectionCriteria) (peer.IDSlice, error) {
+	ctx := criteria.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	excludePeers := make(PeerSet)
+	for p := range criteria.ExcludePeers {
+		excludePeers[p] = struct{}{}
+	}
+	criteria.ExcludePeers = excludePeers
+
+	for attempt := 0; attempt < maxLivenessAttempts; attempt++ {
+		peers, err := pm.SelectRandom(criteria)
+		if err != nil {
+			return nil, err
+		}
+
+		livePeers, unresponsivePeers := pm.probePeers(ctx, peers, criteria.Proto)
+		for _, p := range unresponsivePeers {
+			criteria.ExcludePeers[p] = struct{}{}
+		}
+
+		if len(livePeers) != 0 {
+			return livePeers, nil
+		}
+	}
+
+	return nil, utils.ErrNoPeersAvailable
+}
+
+// probePeers concurrently probes each peer for the protocol, and returns the responsive and the
+// unresponsive peers, in the same order they were received
+func (pm *PeerManager) probePeers(ctx context.Context, peers peer.IDSlice, proto protocol.ID) (peer.IDSlice, peer.IDSlice) {
+	live := make([]bool, len(peers))
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		wg.Add(1)
+		go func(i int, p peer.ID) {
+			defer utils.LogOnPanic()
+			defer wg.Done()
+			err := liveness.Probe(ctx, pm.host, p, proto, livenessProbeTimeout)
+			if err == nil {
+				live[i] = true
+				return
+			}
+			pm.logger.Debug("skipping unresponsive peer", zap.Stringer("peer", p), zap.String("protocol", string(proto)), zap.Error(err))
+		}(i, p)
+	}
+	wg.Wait()
+
+	var livePeers, unresponsivePeers peer.IDSlice
+	for i, p := range peers {
+		if live[i] {
+			livePeers = append(livePeers, p)
+		} else {
+			unresponsivePeers = append(unresponsivePeers, p)
+		}
+	}
+
+	return livePeers, unresponsivePeers
+}
+
 // SelectPeerWithLowestRTT will select a peer that supports a specific protocol with the lowest reply time
 // If a list of specific peers is passed, the peer will be chosen from that list assuming
 // it supports the chosen protocol, otherwise it will chose a peer from the node peerstore
@@ -219,6 +329,7 @@ func (pm *PeerManager) SelectPeerWithLowestRTT(criteria PeerSelectionCriteria) (
 		pm.logger.Warn("context is not passed for peerSelectionwithRTT, using background context")
 		criteria.Ctx = context.Background()
 	}
+	criteria.ExcludePeers = pm.excludeSelf(criteria.ExcludePeers)
 
 	if len(criteria.PubsubTopics) == 0 || (len(criteria.PubsubTopics) == 1 && criteria.PubsubTopics[0] == "") {
 		peers = pm.host.Peerstore().(wps.WakuPeerstore).PeersByPubSubTopics(criteria.PubsubTopics, criteria.SpecificPeers...)
@@ -232,6 +343,154 @@ func (pm *PeerManager) SelectPeerWithLowestRTT(criteria PeerSelectionCriteria) (
 	return pm.rttCache.FastestPeer(criteria.Ctx, peers)
 }
 
+// SelectPeersWithLowestLatency selects the peers that support a protocol with the lowest latency observed
+// by the peerstore. Peers do not advertise the content topics they serve, so content topics are matched
+// through the pubsub topics they map to: peers known to track the pubsub topics of the criteria are
+// preferred over peers whose pubsub topics are unknown, and peers known to track other pubsub topics only
+// are never selected. Peers whose latency is unknown are selected last, and peers with the same latency
+// are selected randomly. If CheckLiveness is set, unresponsive peers are skipped in favor of the next ones
+func (pm *PeerManager) SelectPeersWithLowestLatency(criteria PeerSelectionCriteria) (peer.IDSlice, error) {
+	criteria.ExcludePeers = pm.excludeSelf(criteria.ExcludePeers)
+
+	peers, err := pm.FilterPeersByProto(criteria.SpecificPeers, criteria.ExcludePeers, criteria.Proto)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := make(map[peer.ID]int, len(peers))
+	var candidates peer.IDSlice
+	for _, p := range peers {
+		r, ok := pm.pubsubTopicsRank(p, criteria.PubsubTopics)
+		if !ok {
+			continue
+		}
+		rank[p] = r
+		candidates = append(candidates, p)
+	}
+
+	if len(candidates) == 0 {
+		return nil, utils.ErrNoPeersAvailable
+	}
+
+	latency := func(p peer.ID) time.Duration {
+		if l := pm.host.Peerstore().LatencyEWMA(p); l != 0 {
+			return l
+		}
+		return math.MaxInt64
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if rank[candidates[i]] != rank[candidates[j]] {
+			return rank[candidates[i]] < rank[candidates[j]]
+		}
+		return latency(candidates[i]) < latency(candidates[j])
+	})
+
+	if !criteria.CheckLiveness {
+		if len(candidates) > criteria.MaxPeers {
+			candidates = candidates[:criteria.MaxPeers]
+		}
+		return candidates, nil
+	}
+
+	ctx := criteria.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Candidates are probed in order of preference until enough of them are responsive
+	var selectedPeers peer.IDSlice
+	for len(candidates) != 0 && len(selectedPeers) < criteria.MaxPeers {
+		n := criteria.MaxPeers - len(selectedPeers)
+		if n > len(candidates) {
+			n = len(candidates)
+		}
+		livePeers, _ := pm.probePeers(ctx, candidates[:n], criteria.Proto)
+		selectedPeers = append(selectedPeers, livePeers...)
+		candidates = candidates[n:]
+	}
+
+	if len(selectedPeers) == 0 {
+		return nil, utils.ErrNoPeersAvailable
+	}
+
+	return selectedPeers, nil
+}
+
+// pubsubTopicsRank returns 0 for a peer known to track all the pubsub topics, and 1 for a peer whose
+// pubsub topics are unknown. False is returned for a peer known to track other pubsub topics only
+func (pm *PeerManager) pubsubTopicsRank(p peer.ID, pubsubTopics []string) (int, bool) {
+	if len(pubsubTopics) == 0 {
+		return 0, true
+	}
+
+	peerTopics, err := pm.host.Peerstore().(wps.WakuPeerstore).PubSubTopics(p)
+	if err != nil {
+		return 0, false
+	}
+
+	if len(peerTopics) == 0 {
+		return 1, true
+	}
+
+	for _, t := range pubsubTopics {
+		if _, ok := peerTopics[t]; !ok {
+			return 0, false
+		}
+	}
+
+	return 0, true
+}
+
+// selectWithStrategy selects peers among the candidates using the custom strategy of the criteria
+func (pm *PeerManager) selectWithStrategy(criteria PeerSelectionCriteria) (peer.IDSlice, error) {
+	ctx := criteria.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	candidates, err := pm.candidatePeers(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := criteria.Strategy(ctx, candidates, criteria.MaxPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(peers) == 0 {
+		return nil, utils.ErrNoPeersAvailable
+	}
+
+	if len(peers) > criteria.MaxPeers {
+		peers = peers[:criteria.MaxPeers]
+	}
+
+	return peers, nil
+}
+
+// candidatePeers returns the peers that support the protocol and track the pubsub topics
+// of the selection criteria, excluding the peers that must not be selected
+func (pm *PeerManager) candidatePeers(criteria PeerSelectionCriteria) (peer.IDSlice, error) {
+	peers, err := pm.FilterPeersByProto(criteria.SpecificPeers, criteria.ExcludePeers, criteria.Proto)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty list of peers would make the peerstore consider all its peers
+	if len(peers) != 0 && len(criteria.PubsubTopics) > 0 {
+		peers = pm.host.Peerstore().(wps.WakuPeerstore).PeersByPubSubTopics(criteria.PubsubTopics, peers...)
+	}
+
+	if len(peers) == 0 {
+		return nil, utils.ErrNoPeersAvailable
+	}
+
+	return peers, nil
+}
+
 // FilterPeersByProto filters list of peers that support specified protocols.
 // If specificPeers is nil, all peers in the host's peerStore are considered for filtering.
 func (pm *PeerManager) FilterPeersByProto(specificPeers peer.IDSlice, excludePeers PeerSet, proto ...protocol.ID) (peer.IDSlice, error) {
@@ -25,6 +25,51 @@ const ShardingIndicesListEnrField = "rs"
 
 const ShardingBitVectorEnrField = "rsv"
 
+// ErrReservedENRField is returned when trying to set a custom ENR field whose key is used by Waku or by the ENR spec
+var ErrReservedENRField = errors.New("reserved ENR field")
+
+var reservedENRFields = map[string]struct{}{
+	"id":                        {},
+	"secp256k1":                 {},
+	"ip":                        {},
+	"ip6":                       {},
+	"tcp":                       {},
+	"tcp6":                      {},
+	"udp":                       {},
+	"udp6":                      {},
+	WakuENRField:                {},
+	MultiaddrENRField:           {},
+	ShardingIndicesListEnrField: {},
+	ShardingBitVectorEnrField:   {},
+}
+
+// ValidateCustomField checks that a key can be used for a custom ENR field
+func ValidateCustomField(key string) error {
+	if key == "" {
+		return errors.New("empty ENR field key")
+	}
+
+	if _, ok := reservedENRFields[key]; ok {
+		return fmt.Errorf("%w: %s", ErrReservedENRField, key)
+	}
+
+	return nil
+}
+
+// GetENRField returns the value of a custom ENR field, or nil if the field is not present
+func GetENRField(node *enode.Node, key string) ([]byte, error) {
+	var value []byte
+	err := node.Record().Load(enr.WithEntry(key, &value))
+	if err != nil {
+		if enr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return value, nil
+}
+
 // WakuEnrBitfield is a8-bit flag field to indicate Waku capabilities. Only the 4 LSBs are currently defined according to RFC31 (https://rfc.vac.dev/spec/31/).
 type WakuEnrBitfield = uint8
 
@@ -68,6 +113,33 @@ func NewWakuEnrBitfield(lightpush, filter, store, relay bool) WakuEnrBitfield {
 	return v
 }
 
+// WakuCapabilities are the protocols a node advertises in the waku2 ENR field
+type WakuCapabilities struct {
+	Lightpush bool
+	Filter    bool
+	Store     bool
+	Relay     bool
+}
+
+// ParseWakuEnrBitfield decodes a WakuEnrBitfield into the protocols it advertises. It's the counterpart of NewWakuEnrBitfield
+func ParseWakuEnrBitfield(v WakuEnrBitfield) WakuCapabilities {
+	return WakuCapabilities{
+		Lightpush: v&(1<<3) != 0,
+		Filter:    v&(1<<2) != 0,
+		Store:     v&(1<<1) != 0,
+		Relay:     v&(1<<0) != 0,
+	}
+}
+
+// GetWakuCapabilities returns the protocols advertised in the waku2 ENR field of a node
+func GetWakuCapabilities(node *enode.Node) (WakuCapabilities, error) {
+	v, err := GetWakuEnrBitField(node)
+	if err != nil {
+		return WakuCapabilities{}, err
+	}
+	return ParseWakuEnrBitfield(v), nil
+}
+
 // EnodeToMultiaddress converts an enode into a multiaddress
 func enodeToMultiAddr(node *enode.Node) (multiaddr.Multiaddr, error) {
 	pubKey := utils.EcdsaPubKeyToSecp256k1PublicKey(node.Pubkey())
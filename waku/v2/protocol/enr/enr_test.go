@@ -1,6 +1,8 @@
 package enr
 
 import (
+	"bytes"
+	"fmt"
 	"net"
 	"testing"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/v2/protocol"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 )
 
@@ -69,6 +72,84 @@ func updateLocalNode(localnode *enode.LocalNode, multiaddrs []ma.Multiaddr, ipAd
 	return Update(utils.Logger(), localnode, options...)
 }
 
+func TestWithIPv6(t *testing.T) {
+	key, _ := gcrypto.GenerateKey()
+	db, _ := enode.OpenDB("")
+	localNode := enode.NewLocalNode(db, key)
+
+	ip6 := net.ParseIP("2001:db8::1")
+	err := Update(utils.Logger(), localNode, WithIP(&net.TCPAddr{IP: ip6, Port: 60000}))
+	require.NoError(t, err)
+
+	var tcp6 enr.TCP6
+	require.NoError(t, localNode.Node().Record().Load(&tcp6))
+	require.Equal(t, enr.TCP6(60000), tcp6)
+	require.True(t, localNode.Node().IP().Equal(ip6))
+
+	var tcp enr.TCP
+	require.Error(t, localNode.Node().Record().Load(&tcp))
+}
+
+func TestRelaySharding(t *testing.T) {
+	key, _ := gcrypto.GenerateKey()
+	db, _ := enode.OpenDB("")
+	localNode := enode.NewLocalNode(db, key)
+
+	rs, err := protocol.NewRelayShards(16, 1, 1023)
+	require.NoError(t, err)
+	require.NoError(t, Update(utils.Logger(), localNode, WithWakuRelaySharding(rs)))
+
+	decoded, err := RelaySharding(localNode.Node().Record())
+	require.NoError(t, err)
+	require.Equal(t, rs.ClusterID, decoded.ClusterID)
+	require.ElementsMatch(t, rs.ShardIDs, decoded.ShardIDs)
+	require.True(t, ContainsShard(localNode.Node().Record(), 16, 1023))
+
+	// Shard indices out of range are rejected
+	invalid := protocol.RelayShards{ClusterID: 16, ShardIDs: []uint16{1024}}
+	require.ErrorIs(t, Update(utils.Logger(), localNode, WithWakuRelaySharding(invalid)), protocol.ErrInvalidShard)
+	require.ErrorIs(t, Update(utils.Logger(), localNode, WithWakuRelayShardingBitVector(invalid)), protocol.ErrInvalidShard)
+
+	// The field is omitted if there are no shards
+	require.NoError(t, Update(utils.Logger(), localNode, WithWakuRelaySharding(protocol.RelayShards{ClusterID: 16})))
+	decoded, err = RelaySharding(localNode.Node().Record())
+	require.NoError(t, err)
+	require.Nil(t, decoded)
+}
+
+func TestMultiaddrENRSize(t *testing.T) {
+	key, _ := gcrypto.GenerateKey()
+	db, _ := enode.OpenDB("")
+	localNode := enode.NewLocalNode(db, key)
+
+	var multiaddrs []ma.Multiaddr
+	for i := 0; i < 10; i++ {
+		addr, err := ma.NewMultiaddr(fmt.Sprintf("/dns4/node-%02d.status.im/tcp/443/wss", i))
+		require.NoError(t, err)
+		multiaddrs = append(multiaddrs, addr)
+	}
+
+	// Not all of them fit
+	err := Update(utils.Logger(), localNode, WithAllMultiaddresses(multiaddrs...))
+	require.ErrorIs(t, err, ErrENRTooLarge)
+
+	// The lowest priority ones are dropped
+	err = Update(utils.Logger(), localNode, WithMultiaddress(multiaddrs...))
+	require.NoError(t, err)
+
+	var field []byte
+	require.NoError(t, localNode.Node().Record().Load(enr.WithEntry(MultiaddrENRField, &field)))
+	all := marshalMultiaddress(multiaddrs)
+	require.Less(t, len(field), len(all))
+	require.True(t, bytes.HasPrefix(all, field))
+
+	// All of them are written when they fit
+	err = Update(utils.Logger(), localNode, WithAllMultiaddresses(multiaddrs[0:2]...))
+	require.NoError(t, err)
+	require.NoError(t, localNode.Node().Record().Load(enr.WithEntry(MultiaddrENRField, &field)))
+	require.Equal(t, marshalMultiaddress(multiaddrs[0:2]), field)
+}
+
 func TestMultiaddr(t *testing.T) {
 
 	key, _ := gcrypto.GenerateKey()
@@ -94,3 +175,51 @@ func TestMultiaddr(t *testing.T) {
 	_, _, err = Multiaddress(localNode.Node())
 	require.NoError(t, err)
 }
+
+func TestCustomField(t *testing.T) {
+	key, _ := gcrypto.GenerateKey()
+	db, _ := enode.OpenDB("")
+	localNode := enode.NewLocalNode(db, key)
+
+	require.NoError(t, Update(utils.Logger(), localNode, WithCustomField("app", []byte{0x01, 0x02})))
+
+	value, err := GetENRField(localNode.Node(), "app")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x02}, value)
+
+	value, err = GetENRField(localNode.Node(), "missing")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	err = Update(utils.Logger(), localNode, WithCustomField(WakuENRField, []byte{0x01}))
+	require.ErrorIs(t, err, ErrReservedENRField)
+	err = Update(utils.Logger(), localNode, WithCustomField("tcp", []byte{0x01}))
+	require.ErrorIs(t, err, ErrReservedENRField)
+
+	// A field that does not fit in the ENR is not written
+	err = Update(utils.Logger(), localNode, WithCustomField("big", make([]byte, 400)))
+	require.ErrorIs(t, err, ErrENRTooLarge)
+	value, err = GetENRField(localNode.Node(), "big")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	require.ErrorIs(t, CheckCustomFieldSize("big", make([]byte, 400)), ErrENRTooLarge)
+	require.NoError(t, CheckCustomFieldSize("app", []byte{0x01}))
+}
+
+func TestWakuEnrBitfield(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		lightpush, filter, store, relay := i&8 != 0, i&4 != 0, i&2 != 0, i&1 != 0
+		bitfield := NewWakuEnrBitfield(lightpush, filter, store, relay)
+		require.Equal(t, WakuCapabilities{Lightpush: lightpush, Filter: filter, Store: store, Relay: relay}, ParseWakuEnrBitfield(bitfield))
+	}
+
+	key, _ := gcrypto.GenerateKey()
+	db, _ := enode.OpenDB("")
+	localNode := enode.NewLocalNode(db, key)
+	require.NoError(t, Update(utils.Logger(), localNode, WithCapabilities(false, true, false, true)))
+
+	capabilities, err := GetWakuCapabilities(localNode.Node())
+	require.NoError(t, err)
+	require.Equal(t, WakuCapabilities{Filter: true, Relay: true}, capabilities)
+}
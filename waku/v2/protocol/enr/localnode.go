@@ -4,8 +4,8 @@ import (
 	"crypto/ecdsa"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math"
-	"math/rand"
 	"net"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -25,47 +25,60 @@ func NewLocalnode(priv *ecdsa.PrivateKey) (*enode.LocalNode, error) {
 
 type ENROption func(*enode.LocalNode) error
 
+// ErrENRTooLarge is returned when the multiaddresses or a custom field do not fit in the ENR
+var ErrENRTooLarge = errors.New("enr exceeds the maximum size of 300 bytes")
+
+// WithMultiaddress writes as many multiaddresses as fit in the ENR, in order of priority.
+// The multiaddresses that would make the ENR exceed its maximum size are dropped, and the
+// field is removed if none of them fit
 func WithMultiaddress(multiaddrs ...multiaddr.Multiaddr) ENROption {
-	return func(localnode *enode.LocalNode) (err error) {
-		// Randomly shuffle multiaddresses
-		rand.Shuffle(len(multiaddrs), func(i, j int) { multiaddrs[i], multiaddrs[j] = multiaddrs[j], multiaddrs[i] })
+	return withMultiaddress(true, multiaddrs)
+}
+
+// WithAllMultiaddresses writes all the multiaddresses in the ENR, and returns ErrENRTooLarge
+// instead of dropping the ones that would make the ENR exceed its maximum size
+func WithAllMultiaddresses(multiaddrs ...multiaddr.Multiaddr) ENROption {
+	return withMultiaddress(false, multiaddrs)
+}
 
+// checkENRSize returns an error if the ENR of the localnode would exceed its maximum size once
+// entry is written. It simulates what the localnode does when signing the enr, so writing an
+// entry that does not fit is detected without causing a panic
+func checkENRSize(localnode *enode.LocalNode, privk *ecdsa.PrivateKey, entry enr.Entry) error {
+	cpy := localnode.Node().Record() // Record() creates a copy
+	// Copy all the entries that might not have been written in the ENR record due to the
+	// async nature of localnode.Set
+	for _, e := range localnode.Entries() {
+		cpy.Set(e)
+	}
+	cpy.Set(entry)
+	cpy.SetSeq(localnode.Seq() + 1)
+	return enode.SignV4(cpy, privk)
+}
+
+func withMultiaddress(truncate bool, multiaddrs []multiaddr.Multiaddr) ENROption {
+	return func(localnode *enode.LocalNode) (err error) {
 		// Testing how many multiaddresses we can write before we exceed the limit
-		// By simulating what the localnode does when signing the enr, but without
-		// causing a panic
 
 		privk, err := crypto.GenerateKey()
 		if err != nil {
 			return err
 		}
 
-		// Adding extra multiaddresses. Should probably not exceed the enr max size of 300bytes
-		failedOnceWritingENR := false
-		couldWriteENRatLeastOnce := false
-		successIdx := -1
 		for i := len(multiaddrs); i > 0; i-- {
-			cpy := localnode.Node().Record() // Record() creates a copy for the current iteration
-			// Copy all the entries that might not have been written in the ENR record due to the
-			// async nature of localnode.Set
-			for _, entry := range localnode.Entries() {
-				cpy.Set(entry)
-			}
-			cpy.Set(enr.WithEntry(MultiaddrENRField, marshalMultiaddress(multiaddrs[0:i])))
-			cpy.SetSeq(localnode.Seq() + 1)
-			err = enode.SignV4(cpy, privk)
+			err = checkENRSize(localnode, privk, enr.WithEntry(MultiaddrENRField, marshalMultiaddress(multiaddrs[0:i])))
 			if err == nil {
-				couldWriteENRatLeastOnce = true
-				successIdx = i
-				break
+				writeMultiaddressField(localnode, multiaddrs[0:i])
+				return nil
 			}
-			failedOnceWritingENR = true
-		}
 
-		if failedOnceWritingENR && couldWriteENRatLeastOnce {
-			// Could write a subset of multiaddresses but not all
-			writeMultiaddressField(localnode, multiaddrs[0:successIdx])
+			if !truncate {
+				return fmt.Errorf("%w: cannot write %d multiaddresses: %v", ErrENRTooLarge, len(multiaddrs), err)
+			}
 		}
 
+		// None of the multiaddresses fit in the ENR
+		DeleteField(localnode, MultiaddrENRField)
 		return nil
 	}
 }
@@ -84,14 +97,59 @@ func WithWakuBitfield(flags WakuEnrBitfield) ENROption {
 	}
 }
 
+// WithCustomField writes an application specific field in the ENR. Reserved keys are refused, and
+// ErrENRTooLarge is returned if the field does not fit in the ENR
+func WithCustomField(key string, value []byte) ENROption {
+	return func(localnode *enode.LocalNode) (err error) {
+		if err := ValidateCustomField(key); err != nil {
+			return err
+		}
+
+		privk, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		entry := enr.WithEntry(key, value)
+		if err := checkENRSize(localnode, privk, entry); err != nil {
+			return fmt.Errorf("%w: cannot write field %s: %v", ErrENRTooLarge, key, err)
+		}
+
+		localnode.Set(entry)
+		return nil
+	}
+}
+
+// CheckCustomFieldSize returns ErrENRTooLarge if an application specific field does not fit in
+// an ENR, even without any other field than the ones required by the ENR spec
+func CheckCustomFieldSize(key string, value []byte) error {
+	privk, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	var record enr.Record
+	record.Set(enr.WithEntry(key, value))
+	if err := enode.SignV4(&record, privk); err != nil {
+		return fmt.Errorf("%w: cannot write field %s: %v", ErrENRTooLarge, key, err)
+	}
+	return nil
+}
+
 func WithIP(ipAddr *net.TCPAddr) ENROption {
 	return func(localnode *enode.LocalNode) (err error) {
 		if ipAddr.Port == 0 {
 			return ErrNoPortAvailable
 		}
 
+		// SetStaticIP sets the ip or ip6 field depending on the address family,
+		// so the matching tcp or tcp6 field is set as well
 		localnode.SetStaticIP(ipAddr.IP)
-		localnode.Set(enr.TCP(uint16(ipAddr.Port))) // TODO: ipv6?
+		if ipAddr.IP.To4() != nil {
+			localnode.Set(enr.TCP(uint16(ipAddr.Port)))
+		} else {
+			localnode.Set(enr.TCP6(uint16(ipAddr.Port)))
+		}
 		return nil
 	}
 }
@@ -27,14 +27,25 @@ func WithWakuRelayShardList(rs protocol.RelayShards) ENROption {
 
 func WithWakuRelayShardingBitVector(rs protocol.RelayShards) ENROption {
 	return func(localnode *enode.LocalNode) error {
+		if err := rs.Validate(); err != nil {
+			return err
+		}
 		deleteShardingENREntries(localnode)
 		localnode.Set(enr.WithEntry(ShardingBitVectorEnrField, rs.BitVector()))
 		return nil
 	}
 }
 
+// WithWakuRelaySharding sets the shards the node participates in, using the rs field
+// or, for 64 shards or more, the more compact rsv field. The sharding fields are
+// removed if there are no shards
 func WithWakuRelaySharding(rs protocol.RelayShards) ENROption {
 	return func(localnode *enode.LocalNode) error {
+		if len(rs.ShardIDs) == 0 {
+			deleteShardingENREntries(localnode)
+			return nil
+		}
+
 		if len(rs.ShardIDs) >= 64 {
 			return WithWakuRelayShardingBitVector(rs)(localnode)
 		}
@@ -15,11 +15,19 @@ type Envelope struct {
 	index *pb.Index
 }
 
+// HashMessage calculates the deterministic hash of a WakuMessage published in a pubsub topic, as
+// defined in https://rfc.vac.dev/spec/14/#deterministic-message-hashing, over the pubsub topic,
+// payload, content topic, meta and timestamp of the message. It is the same hash returned by
+// Envelope.Hash, and can be used to identify a message for deduplication, storage or acknowledgements
+func HashMessage(pubsubTopic string, msg *wpb.WakuMessage) wpb.MessageHash {
+	return msg.Hash(pubsubTopic)
+}
+
 // NewEnvelope creates a new Envelope that contains a WakuMessage
 // It's used as a way to know to which Pubsub topic belongs a WakuMessage
 // as well as generating a hash based on the bytes that compose the message
 func NewEnvelope(msg *wpb.WakuMessage, receiverTime int64, pubSubTopic string) *Envelope {
-	messageHash := msg.Hash(pubSubTopic)
+	messageHash := HashMessage(pubSubTopic, msg)
 	digest := hash.SHA256([]byte(msg.ContentTopic), msg.Payload)
 	return &Envelope{
 		msg:  msg,
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/utils"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestEnvelope(t *testing.T) {
@@ -28,3 +29,75 @@ func TestEnvelope(t *testing.T) {
 		hash,
 	)
 }
+
+func TestHashMessage(t *testing.T) {
+	pubsubTopic := "/waku/2/default-waku/proto"
+	meta := []byte("\x73\x75\x70\x65\x72\x2d\x73\x65\x63\x72\x65\x74")
+
+	testCases := []struct {
+		name     string
+		msg      *pb.WakuMessage
+		expected string
+	}{
+		{
+			name: "empty meta",
+			msg: &pb.WakuMessage{
+				ContentTopic: "/waku/2/default-content/proto",
+				Payload:      []byte("\x01\x02\x03\x04TEST\x05\x06\x07\x08"),
+				Meta:         []byte{},
+				Timestamp:    proto.Int64(123456789123456789),
+				Version:      proto.Uint32(1),
+			},
+			expected: "0xf0183c2e370e473ff471bbe1028d0d8a940949c02f3007a1ccd21fed356852a0",
+		},
+		{
+			name: "12 byte meta",
+			msg: &pb.WakuMessage{
+				ContentTopic: "/waku/2/default-content/proto",
+				Payload:      []byte("\x01\x02\x03\x04TEST\x05\x06\x07\x08"),
+				Meta:         meta,
+				Timestamp:    proto.Int64(123456789123456789),
+				Version:      proto.Uint32(1),
+			},
+			expected: "0xf673cd2c9c973d685b52ca74c2559e001733a3a31a49ffc7b6e8713decba5a55",
+		},
+		{
+			name: "zero length payload",
+			msg: &pb.WakuMessage{
+				ContentTopic: "/waku/2/default-content/proto",
+				Payload:      []byte{},
+				Meta:         meta,
+				Timestamp:    proto.Int64(123456789123456789),
+				Version:      proto.Uint32(1),
+			},
+			expected: "0x978ccc9a665029f9829d42d84e3a49ad3a4791cce53fb5a8b581ef43ad6b4d2f",
+		},
+		{
+			name: "no timestamp",
+			msg: &pb.WakuMessage{
+				ContentTopic: "/waku/2/default-content/proto",
+				Payload:      []byte{},
+				Meta:         meta,
+				Version:      proto.Uint32(1),
+			},
+			expected: "0x58e2fc032a82c4adeb967a8b87086d0d6fb304912f120d4404e6236add8f1f56",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, HashMessage(pubsubTopic, tc.msg).String())
+
+			// The envelope of the message has the same hash
+			e := NewEnvelope(tc.msg, *utils.GetUnixEpoch(), pubsubTopic)
+			require.Equal(t, tc.expected, e.Hash().String())
+		})
+	}
+
+	// The version is not part of the hash, while the pubsub topic is
+	msg := &pb.WakuMessage{ContentTopic: "/waku/2/default-content/proto", Payload: []byte{1}, Version: proto.Uint32(0)}
+	hash := HashMessage(pubsubTopic, msg)
+	msg.Version = proto.Uint32(1)
+	require.Equal(t, hash, HashMessage(pubsubTopic, msg))
+	require.NotEqual(t, hash, HashMessage("/waku/2/rs/1/0", msg))
+}
@@ -5,17 +5,19 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"math"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-msgio/pbio"
+	msmux "github.com/multiformats/go-multistream"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/waku-org/go-waku/logging"
 	"github.com/waku-org/go-waku/waku/v2/onlinechecker"
@@ -38,12 +40,27 @@ import (
 // filter service nodes to push messages matching registered subscriptions to this client.
 const FilterPushID_v20beta1 = libp2pProtocol.ID("/vac/waku/filter-push/2.0.0-beta1")
 
+// filterProtectionTag is used to protect the connections to the full nodes with active subscriptions
+const filterProtectionTag = "waku-filter-subscription"
+
 var (
-	ErrNoPeersAvailable     = errors.New("no suitable remote peers")
-	ErrSubscriptionNotFound = errors.New("subscription not found")
-	ErrNoPeersSpecified     = errors.New("no peers specified to unsubscribe")
+	ErrNoPeersAvailable        = errors.New("no suitable remote peers")
+	ErrSubscriptionNotFound    = errors.New("subscription not found")
+	ErrNoPeersSpecified        = errors.New("no peers specified to unsubscribe")
+	ErrMaxSubscriptionsReached = errors.New("maximum number of subscriptions reached")
+	ErrPeerNotConnected        = errors.New("subscription peer is not connected")
+	ErrFilterNotSupported      = errors.New("peer does not support the filter protocol")
 )
 
+// noPeersAvailable wraps the error returned by the peer selection, if any, so it matches
+// both ErrNoPeersAvailable and the original cause
+func noPeersAvailable(err error) error {
+	if err == nil || errors.Is(err, ErrNoPeersAvailable) {
+		return ErrNoPeersAvailable
+	}
+	return fmt.Errorf("%w: %w", ErrNoPeersAvailable, err)
+}
+
 type WakuFilterLightNode struct {
 	*service.CommonService
 	h                host.Host
@@ -55,6 +72,14 @@ type WakuFilterLightNode struct {
 	subscriptions    *subscription.SubscriptionsMap
 	pm               *peermanager.PeerManager
 	peerPingInterval time.Duration
+	pingJitter       float64
+
+	maxSubscriptions     int
+	subscriptionsLimitMu sync.Mutex
+	pendingSubscriptions int
+
+	seenMessages *lru.Cache
+	readerLimit  int
 }
 
 type WakuFilterPushError struct {
@@ -89,7 +114,13 @@ func NewWakuFilterLightNode(
 	onlineChecker onlinechecker.OnlineChecker,
 	reg prometheus.Registerer,
 	log *zap.Logger,
+	opts ...LightNodeOption,
 ) *WakuFilterLightNode {
+	params := &LightNodeParameters{pingInterval: DefaultPingInterval, pingJitter: utils.DefaultJitterFraction, readerLimit: DefaultReaderLimit}
+	for _, opt := range opts {
+		opt(params)
+	}
+
 	wf := new(WakuFilterLightNode)
 	wf.log = log.Named("filterv2-lightnode")
 	wf.broadcaster = broadcaster
@@ -98,7 +129,14 @@ func NewWakuFilterLightNode(
 	wf.pm = pm
 	wf.CommonService = service.NewCommonService()
 	wf.metrics = newMetrics(reg)
-	wf.peerPingInterval = 1 * time.Minute
+	wf.peerPingInterval = params.pingInterval
+	wf.pingJitter = params.pingJitter
+	wf.maxSubscriptions = params.maxSubscriptions
+	wf.readerLimit = params.readerLimit
+	if params.dedupCacheSize > 0 {
+		// lru.New only fails if the size is not positive
+		wf.seenMessages, _ = lru.New(params.dedupCacheSize)
+	}
 	return wf
 }
 
@@ -113,6 +151,11 @@ func (wf *WakuFilterLightNode) Start(ctx context.Context) error {
 
 func (wf *WakuFilterLightNode) start() error {
 	wf.subscriptions = subscription.NewSubscriptionMap(wf.log)
+	// Connections to the full nodes we are subscribed to are not closed when the connection manager trims connections
+	wf.subscriptions.SetPeerCallbacks(
+		func(peerID peer.ID) { wf.h.ConnManager().Protect(peerID, filterProtectionTag) },
+		func(peerID peer.ID) { wf.h.ConnManager().Unprotect(peerID, filterProtectionTag) },
+	)
 	wf.h.SetStreamHandlerMatch(FilterPushID_v20beta1, protocol.PrefixTextMatch(string(FilterPushID_v20beta1)), wf.onRequest(wf.Context()))
 	//Start Filter liveness check
 	wf.CommonService.WaitGroup().Add(1)
@@ -127,24 +170,22 @@ func (wf *WakuFilterLightNode) Stop() {
 	wf.CommonService.Stop(func() {
 		wf.h.RemoveStreamHandler(FilterPushID_v20beta1)
 		if wf.subscriptions.Count() > 0 {
-			go func() {
-				defer utils.LogOnPanic()
-				defer func() {
-					_ = recover()
-				}()
-				res, err := wf.unsubscribeAll(wf.Context())
-				if err != nil {
-					wf.log.Warn("unsubscribing from full nodes", zap.Error(err))
-				}
+			// The context of the service is already cancelled, so a new one is used to
+			// notify the full nodes. The subscriptions are closed before returning
+			ctx, cancel := context.WithTimeout(context.Background(), unsubscribeOnStopTimeout)
+			defer cancel()
 
+			res, err := wf.unsubscribeAll(ctx)
+			if err != nil {
+				wf.log.Warn("unsubscribing from full nodes", zap.Error(err))
+			} else {
 				for _, r := range res.Errors() {
 					if r.Err != nil {
 						wf.log.Warn("unsubscribing from full nodes", zap.Error(r.Err), logging.HostID("peerID", r.PeerID))
 					}
-
 				}
-				wf.subscriptions.Clear()
-			}()
+			}
+			wf.subscriptions.Clear()
 		}
 	})
 }
@@ -165,61 +206,87 @@ func (wf *WakuFilterLightNode) onRequest(ctx context.Context) func(network.Strea
 			return
 		}
 
-		reader := pbio.NewDelimitedReader(stream, math.MaxInt32)
-
-		messagePush := &pb.MessagePush{}
-		err := reader.ReadMsg(messagePush)
-		if err != nil {
-			logger.Error("reading message push", zap.Error(err))
-			wf.metrics.RecordError(decodeRPCFailure)
-			if err := stream.Reset(); err != nil {
-				wf.log.Error("resetting connection", zap.Error(err))
-			}
-			return
-		}
-
-		stream.Close()
-
-		if err = messagePush.Validate(); err != nil {
-			logger.Warn("received invalid messagepush")
-			return
-		}
+		reader := pbio.NewDelimitedReader(stream, wf.readerLimit)
 
-		pubSubTopic := ""
-		//For now returning failure, this will get addressed with autosharding changes for filter.
-		if messagePush.PubsubTopic == nil {
-			pubSubTopic, err = protocol.GetPubSubTopicFromContentTopic(messagePush.WakuMessage.ContentTopic)
+		// Full nodes may push several messages in the same stream
+		for read := 0; ; read++ {
+			messagePush := &pb.MessagePush{}
+			err := reader.ReadMsg(messagePush)
 			if err != nil {
-				logger.Error("could not derive pubSubTopic from contentTopic", zap.Error(err))
+				if read != 0 && errors.Is(err, io.EOF) {
+					break
+				}
+				err = readerLimitError(err, wf.readerLimit)
+				logger.Error("reading message push", zap.Error(err))
 				wf.metrics.RecordError(decodeRPCFailure)
 				if err := stream.Reset(); err != nil {
 					wf.log.Error("resetting connection", zap.Error(err))
 				}
 				return
 			}
-		} else {
-			pubSubTopic = *messagePush.PubsubTopic
-		}
 
-		logger = messagePush.WakuMessage.Logger(logger, pubSubTopic)
-		cf := protocol.NewContentFilter(pubSubTopic, messagePush.WakuMessage.ContentTopic)
-		if !wf.subscriptions.Has(peerID, cf) {
-			logger.Warn("received messagepush with invalid subscription parameters")
-			wf.metrics.RecordError(invalidSubscriptionMessage)
-			return
+			if err := wf.handleMessagePush(ctx, logger, peerID, messagePush); err != nil {
+				if err := stream.Reset(); err != nil {
+					wf.log.Error("resetting connection", zap.Error(err))
+				}
+				return
+			}
 		}
 
-		wf.metrics.RecordMessage()
+		// The stream is closed once the messages are handled, so a full node waiting
+		// for it to be closed can push the next messages in order
+		stream.Close()
+	}
+}
 
-		wf.notify(ctx, peerID, pubSubTopic, messagePush.WakuMessage)
+// handleMessagePush notifies the subscriptions matching a message pushed by a full node. Invalid
+// messages are skipped, while an error is returned if the stream must be reset
+func (wf *WakuFilterLightNode) handleMessagePush(ctx context.Context, logger *zap.Logger, peerID peer.ID, messagePush *pb.MessagePush) error {
+	if err := messagePush.Validate(); err != nil {
+		logger.Warn("received invalid messagepush")
+		return nil
+	}
 
-		logger.Info("received message push")
+	pubSubTopic := ""
+	//For now returning failure, this will get addressed with autosharding changes for filter.
+	if messagePush.PubsubTopic == nil {
+		var err error
+		pubSubTopic, err = protocol.GetPubSubTopicFromContentTopic(messagePush.WakuMessage.ContentTopic)
+		if err != nil {
+			logger.Error("could not derive pubSubTopic from contentTopic", zap.Error(err))
+			wf.metrics.RecordError(decodeRPCFailure)
+			return err
+		}
+	} else {
+		pubSubTopic = *messagePush.PubsubTopic
+	}
+
+	logger = messagePush.WakuMessage.Logger(logger, pubSubTopic)
+	cf := protocol.NewContentFilter(pubSubTopic, messagePush.WakuMessage.ContentTopic)
+	if !wf.subscriptions.Has(peerID, cf) {
+		logger.Warn("received messagepush with invalid subscription parameters")
+		wf.metrics.RecordError(invalidSubscriptionMessage)
+		return nil
 	}
+
+	wf.metrics.RecordMessage()
+
+	wf.notify(ctx, peerID, pubSubTopic, messagePush.WakuMessage)
+
+	logger.Info("received message push")
+	return nil
 }
 
 func (wf *WakuFilterLightNode) notify(ctx context.Context, remotePeerID peer.ID, pubsubTopic string, msg *wpb.WakuMessage) {
 	envelope := protocol.NewEnvelope(msg, wf.timesource.Now().UnixNano(), pubsubTopic)
 
+	if wf.seenMessages != nil {
+		if seen, _ := wf.seenMessages.ContainsOrAdd(envelope.Hash(), struct{}{}); seen {
+			wf.log.Debug("skipping duplicate message push", logging.Hash(envelope.Hash()), logging.HostID("peerID", remotePeerID))
+			return
+		}
+	}
+
 	if wf.broadcaster != nil {
 		// Broadcasting message so it's stored
 		wf.broadcaster.Submit(envelope)
@@ -228,8 +295,10 @@ func (wf *WakuFilterLightNode) notify(ctx context.Context, remotePeerID peer.ID,
 	wf.subscriptions.Notify(ctx, remotePeerID, envelope)
 }
 
+// request sends a filter subscribe request to a peer. matchAll allows empty content filters, and
+// must only be set when subscribing with WithMatchAll or when unsubscribing from such a subscription
 func (wf *WakuFilterLightNode) request(ctx context.Context, requestID []byte,
-	reqType pb.FilterSubscribeRequest_FilterSubscribeType, contentFilter protocol.ContentFilter, peerID peer.ID) error {
+	reqType pb.FilterSubscribeRequest_FilterSubscribeType, contentFilter protocol.ContentFilter, peerID peer.ID, matchAll bool) error {
 	request := &pb.FilterSubscribeRequest{
 		RequestId:           hex.EncodeToString(requestID),
 		FilterSubscribeType: reqType,
@@ -237,7 +306,12 @@ func (wf *WakuFilterLightNode) request(ctx context.Context, requestID []byte,
 		ContentTopics:       contentFilter.ContentTopicsList(),
 	}
 
-	err := request.Validate()
+	validate := request.Validate
+	if matchAll && request.IsMatchAll() {
+		validate = request.ValidateMatchAll
+	}
+
+	err := validate()
 	if err != nil {
 		return err
 	}
@@ -247,14 +321,29 @@ func (wf *WakuFilterLightNode) request(ctx context.Context, requestID []byte,
 	stream, err := wf.h.NewStream(ctx, peerID, FilterSubscribeID_v20beta1)
 	if err != nil {
 		wf.metrics.RecordError(dialFailure)
+		var notSupported msmux.ErrNotSupported[libp2pProtocol.ID]
+		if errors.As(err, &notSupported) {
+			return fmt.Errorf("%w: %w", ErrFilterNotSupported, err)
+		}
 		if wf.pm != nil {
 			wf.pm.HandleDialError(err, peerID)
 		}
 		return err
 	}
 
+	// The request must not outlive the context, even if the peer stops replying
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+
+	// Cancelling the context aborts the blocked writes and reads, resetting the stream so it is not leaked
+	stopAbort := context.AfterFunc(ctx, func() {
+		_ = stream.Reset()
+	})
+	defer stopAbort()
+
 	writer := pbio.NewDelimitedWriter(stream)
-	reader := pbio.NewDelimitedReader(stream, math.MaxInt32)
+	reader := pbio.NewDelimitedReader(stream, wf.readerLimit)
 
 	logger.Debug("sending FilterSubscribeRequest", zap.Stringer("request", request))
 	err = writer.WriteMsg(request)
@@ -264,17 +353,24 @@ func (wf *WakuFilterLightNode) request(ctx context.Context, requestID []byte,
 		if err := stream.Reset(); err != nil {
 			logger.Error("resetting connection", zap.Error(err))
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 
 	filterSubscribeResponse := &pb.FilterSubscribeResponse{}
 	err = reader.ReadMsg(filterSubscribeResponse)
 	if err != nil {
+		err = readerLimitError(err, wf.readerLimit)
 		logger.Error("receiving FilterSubscribeResponse", zap.Error(err))
 		wf.metrics.RecordError(decodeRPCFailure)
 		if err := stream.Reset(); err != nil {
 			logger.Error("resetting connection", zap.Error(err))
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 
@@ -318,11 +414,33 @@ func (wf *WakuFilterLightNode) handleFilterSubscribeOptions(ctx context.Context,
 		}
 	}
 
+	if params.matchAll && (contentFilter.PubsubTopic == "" || len(contentFilter.ContentTopics) != 0) {
+		return nil, nil, errors.New("subscribing to all messages requires a pubsub topic and no content topics")
+	}
+
 	pubSubTopicMap, err := protocol.ContentFilterToPubSubTopicMap(contentFilter)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Invalid content filters are rejected before selecting any peer
+	for pubsubTopic, cTopics := range pubSubTopicMap {
+		pubsubTopic := pubsubTopic
+		request := &pb.FilterSubscribeRequest{
+			RequestId:           hex.EncodeToString(params.requestID),
+			FilterSubscribeType: pb.FilterSubscribeRequest_SUBSCRIBE,
+			PubsubTopic:         &pubsubTopic,
+			ContentTopics:       cTopics,
+		}
+		validate := request.Validate
+		if params.matchAll {
+			validate = request.ValidateMatchAll
+		}
+		if err := validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	//Add Peer to peerstore.
 	if params.pm != nil && params.peerAddr != nil {
 		pData, err := wf.pm.AddPeer(params.peerAddr, peerstore.Static, maps.Keys(pubSubTopicMap), FilterSubscribeID_v20beta1)
@@ -345,11 +463,17 @@ func (wf *WakuFilterLightNode) handleFilterSubscribeOptions(ctx context.Context,
 				SpecificPeers: params.preferredPeers,
 				MaxPeers:      reqPeerCount,
 				Ctx:           ctx,
+				CheckLiveness: params.checkLiveness,
 				ExcludePeers:  params.peersToExclude,
+				Strategy:      params.peerSelector,
 			},
 		)
 		if err != nil {
 			wf.log.Error("peer selection returned err", zap.Error(err))
+			if errors.Is(err, utils.ErrNoPeersAvailable) {
+				wf.metrics.RecordError(peerNotFoundFailure)
+				return nil, nil, noPeersAvailable(err)
+			}
 			return nil, nil, err
 		}
 	}
@@ -362,6 +486,8 @@ func (wf *WakuFilterLightNode) handleFilterSubscribeOptions(ctx context.Context,
 // If contentTopics passed result in different pubSub topics (due to Auto/Static sharding), then multiple subscription requests are sent to the peer.
 // This may change if Filterv2 protocol is updated to handle such a scenario in a single request.
 // Note: In case of partial failure, results are returned for successful subscriptions along with error indicating failed contentTopics.
+// If no filter peer could be selected for a pubsub topic, the error returned matches ErrNoPeersAvailable
+// with errors.Is, so the subscription can be retried once peers are discovered.
 func (wf *WakuFilterLightNode) Subscribe(ctx context.Context, contentFilter protocol.ContentFilter, opts ...FilterSubscribeOption) ([]*subscription.SubscriptionDetails, error) {
 	wf.RLock()
 	defer wf.RUnlock()
@@ -369,12 +495,35 @@ func (wf *WakuFilterLightNode) Subscribe(ctx context.Context, contentFilter prot
 		return nil, err
 	}
 
+	return wf.subscribe(ctx, contentFilter, opts...)
+}
+
+// SubscribeWithPeer setups a subscription with a specific full node, skipping the peer selection.
+// The peer is dialed first, and ErrFilterNotSupported is returned if it does not support the
+// filter protocol. The options selecting peers are ignored
+func (wf *WakuFilterLightNode) SubscribeWithPeer(ctx context.Context, peerID peer.ID, contentFilter protocol.ContentFilter, opts ...FilterSubscribeOption) ([]*subscription.SubscriptionDetails, error) {
+	wf.RLock()
+	defer wf.RUnlock()
+	if err := wf.ErrOnNotRunning(); err != nil {
+		return nil, err
+	}
+
+	if err := wf.dialFilterPeer(ctx, peerID); err != nil {
+		return nil, err
+	}
+
+	return wf.subscribe(ctx, contentFilter, append(slices.Clip(opts), withOnlyPeer(peerID))...)
+}
+
+func (wf *WakuFilterLightNode) subscribe(ctx context.Context, contentFilter protocol.ContentFilter, opts ...FilterSubscribeOption) ([]*subscription.SubscriptionDetails, error) {
 	params, pubSubTopicMap, err := wf.handleFilterSubscribeOptions(ctx, contentFilter, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	failedContentTopics := []string{}
+	var failures []error
+	limitReached := false
 	subscriptions := make([]*subscription.SubscriptionDetails, 0)
 	for pubSubTopic, cTopics := range pubSubTopicMap {
 		var selectedPeers peer.IDSlice
@@ -390,7 +539,9 @@ func (wf *WakuFilterLightNode) Subscribe(ctx context.Context, contentFilter prot
 					SpecificPeers: params.preferredPeers,
 					MaxPeers:      params.maxPeers - params.selectedPeers.Len(),
 					Ctx:           ctx,
+					CheckLiveness: params.checkLiveness,
 					ExcludePeers:  params.peersToExclude,
+					Strategy:      params.peerSelector,
 				},
 			)
 		} else {
@@ -401,6 +552,7 @@ func (wf *WakuFilterLightNode) Subscribe(ctx context.Context, contentFilter prot
 			wf.log.Error("selecting peer", zap.String("pubSubTopic", pubSubTopic), zap.Strings("contentTopics", cTopics),
 				zap.Error(err))
 			failedContentTopics = append(failedContentTopics, cTopics...)
+			failures = append(failures, noPeersAvailable(err))
 			continue
 		}
 		var cFilter protocol.ContentFilter
@@ -410,27 +562,41 @@ func (wf *WakuFilterLightNode) Subscribe(ctx context.Context, contentFilter prot
 		paramsCopy := params.Copy()
 		paramsCopy.selectedPeers = selectedPeers
 		var wg sync.WaitGroup
+		var failedMu sync.Mutex
 		reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
 		tmpSubs := make([]*subscription.SubscriptionDetails, len(selectedPeers))
 		for i, peerID := range selectedPeers {
+			if !wf.reserveSubscription() {
+				wf.metrics.RecordError(maxSubscriptionsFailure)
+				wf.log.Warn("maximum number of subscriptions reached", zap.String("pubSubTopic", pubSubTopic), zap.Strings("contentTopics", cTopics), zap.Int("maxSubscriptions", wf.maxSubscriptions))
+				failedContentTopics = append(failedContentTopics, cTopics...)
+				limitReached = true
+				break
+			}
+
 			wg.Add(1)
 			go func(index int, ID peer.ID) {
 				defer utils.LogOnPanic()
 				defer wg.Done()
+				defer wf.releaseSubscription()
 				err := wf.request(
 					reqCtx,
 					params.requestID,
 					pb.FilterSubscribeRequest_SUBSCRIBE,
 					cFilter,
-					ID)
+					ID,
+					params.matchAll)
 				if err != nil {
 					wf.log.Error("Failed to subscribe", zap.String("pubSubTopic", pubSubTopic), zap.Strings("contentTopics", cTopics),
 						zap.Error(err))
+					failedMu.Lock()
 					failedContentTopics = append(failedContentTopics, cTopics...)
+					failures = append(failures, err)
+					failedMu.Unlock()
 				} else {
 					wf.log.Debug("subscription successful", zap.String("pubSubTopic", pubSubTopic), zap.Strings("contentTopics", cTopics), zap.Stringer("peer", ID))
-					tmpSubs[index] = wf.subscriptions.NewSubscription(ID, cFilter)
+					tmpSubs[index] = wf.subscriptions.NewSubscriptionWithHandler(ID, cFilter, params.onMessage)
 				}
 			}(i, peerID)
 		}
@@ -442,13 +608,55 @@ func (wf *WakuFilterLightNode) Subscribe(ctx context.Context, contentFilter prot
 		}
 	}
 
-	if len(failedContentTopics) > 0 {
-		return subscriptions, fmt.Errorf("subscriptions failed for contentTopics: %s", strings.Join(failedContentTopics, ","))
+	if limitReached {
+		return subscriptions, fmt.Errorf("%w: subscriptions failed for contentTopics: %s", ErrMaxSubscriptionsReached, strings.Join(failedContentTopics, ","))
+	} else if len(failures) > 0 {
+		// Match all subscriptions have no content topics, so the failures are counted instead
+		// The errors returned by the full nodes (i.e. *FilterError) can be inspected with errors.As
+		return subscriptions, fmt.Errorf("subscriptions failed for contentTopics: %s: %w", strings.Join(failedContentTopics, ","), errors.Join(failures...))
 	} else {
 		return subscriptions, nil
 	}
 }
 
+// reserveSubscription reserves a slot for a new subscription, returning false if doing
+// so would exceed the maximum number of subscriptions. The slot must be released with
+// releaseSubscription once the subscription is stored or has failed
+func (wf *WakuFilterLightNode) reserveSubscription() bool {
+	if wf.maxSubscriptions <= 0 {
+		return true
+	}
+
+	wf.subscriptionsLimitMu.Lock()
+	defer wf.subscriptionsLimitMu.Unlock()
+
+	if wf.subscriptions.SubscriptionCount()+wf.pendingSubscriptions >= wf.maxSubscriptions {
+		return false
+	}
+	wf.pendingSubscriptions++
+	return true
+}
+
+func (wf *WakuFilterLightNode) releaseSubscription() {
+	if wf.maxSubscriptions <= 0 {
+		return
+	}
+
+	wf.subscriptionsLimitMu.Lock()
+	defer wf.subscriptionsLimitMu.Unlock()
+	wf.pendingSubscriptions--
+}
+
+// ActiveSubscriptions returns the number of subscriptions currently active in the light node
+func (wf *WakuFilterLightNode) ActiveSubscriptions() int {
+	wf.RLock()
+	defer wf.RUnlock()
+	if wf.subscriptions == nil {
+		return 0
+	}
+	return wf.subscriptions.SubscriptionCount()
+}
+
 // FilterSubscription is used to obtain an object from which you could receive messages received via filter protocol
 func (wf *WakuFilterLightNode) FilterSubscription(peerID peer.ID, contentFilter protocol.ContentFilter) (*subscription.SubscriptionDetails, error) {
 	wf.RLock()
@@ -497,10 +705,13 @@ func (wf *WakuFilterLightNode) Ping(ctx context.Context, peerID peer.ID, opts ..
 		params.requestID,
 		pb.FilterSubscribeRequest_SUBSCRIBER_PING,
 		protocol.ContentFilter{},
-		peerID)
+		peerID,
+		false)
 }
 
-// Unsubscribe is used to stop receiving messages from specified peers for the content filter
+// Unsubscribe is used to stop receiving messages from specified peers for the content filter.
+// If no peers are specified and the light node has no subscriptions, there is no peer to
+// unsubscribe from and ErrNoPeersAvailable is returned
 func (wf *WakuFilterLightNode) Unsubscribe(ctx context.Context, contentFilter protocol.ContentFilter, opts ...FilterSubscribeOption) (*WakuFilterPushResult, error) {
 	wf.RLock()
 	defer wf.RUnlock()
@@ -508,6 +719,49 @@ func (wf *WakuFilterLightNode) Unsubscribe(ctx context.Context, contentFilter pr
 		return nil, err
 	}
 
+	return wf.unsubscribe(ctx, contentFilter, opts...)
+}
+
+// UnsubscribeWithPeer stops receiving messages from a specific full node for the content filter.
+// The peer is dialed first, and ErrFilterNotSupported is returned if it does not support the
+// filter protocol. The options selecting peers are ignored
+func (wf *WakuFilterLightNode) UnsubscribeWithPeer(ctx context.Context, peerID peer.ID, contentFilter protocol.ContentFilter, opts ...FilterSubscribeOption) (*WakuFilterPushResult, error) {
+	wf.RLock()
+	defer wf.RUnlock()
+	if err := wf.ErrOnNotRunning(); err != nil {
+		return nil, err
+	}
+
+	if err := wf.dialFilterPeer(ctx, peerID); err != nil {
+		return nil, err
+	}
+
+	return wf.unsubscribe(ctx, contentFilter, append(slices.Clip(opts), withOnlyPeer(peerID))...)
+}
+
+// dialFilterPeer connects to a peer so its protocols are identified, and checks that it
+// supports the filter protocol
+func (wf *WakuFilterLightNode) dialFilterPeer(ctx context.Context, peerID peer.ID) error {
+	err := wf.h.Connect(ctx, wf.h.Peerstore().PeerInfo(peerID))
+	if err != nil {
+		wf.metrics.RecordError(dialFailure)
+		if wf.pm != nil {
+			wf.pm.HandleDialError(err, peerID)
+		}
+		return err
+	}
+
+	// Peers whose protocols are not known yet are checked when opening the stream
+	if supported, err := wf.h.Peerstore().SupportsProtocols(peerID, FilterSubscribeID_v20beta1); err == nil && len(supported) == 0 {
+		if protocols, err := wf.h.Peerstore().GetProtocols(peerID); err == nil && len(protocols) != 0 {
+			return ErrFilterNotSupported
+		}
+	}
+
+	return nil
+}
+
+func (wf *WakuFilterLightNode) unsubscribe(ctx context.Context, contentFilter protocol.ContentFilter, opts ...FilterSubscribeOption) (*WakuFilterPushResult, error) {
 	if len(contentFilter.ContentTopics) == 0 {
 		return nil, errors.New("at least one content topic is required")
 	}
@@ -528,19 +782,16 @@ func (wf *WakuFilterLightNode) Unsubscribe(ctx context.Context, contentFilter pr
 	if err != nil {
 		return nil, err
 	}
+	if params.selectedPeers.Len() == 0 && len(wf.subscriptions.GetAllSubscriptions()) == 0 {
+		wf.metrics.RecordError(peerNotFoundFailure)
+		return nil, ErrNoPeersAvailable
+	}
 	result := &WakuFilterPushResult{}
 	for pTopic, cTopics := range pubSubTopicMap {
 		cFilter := protocol.NewContentFilter(pTopic, cTopics...)
 		var subs []*subscription.SubscriptionDetails
 		if params.selectedPeers.Len() == 0 {
 			subs = wf.subscriptions.GetAllSubscriptions()
-			if len(subs) == 0 {
-				result.Add(WakuFilterPushError{
-					Err:    ErrSubscriptionNotFound,
-					PeerID: "",
-				})
-				continue
-			}
 		}
 		for _, peer := range params.selectedPeers {
 			subsForPeer := wf.subscriptions.GetSubscriptionsForPeer(peer, cFilter)
@@ -574,7 +825,7 @@ func (wf *WakuFilterLightNode) Unsubscribe(ctx context.Context, contentFilter pr
 						params.wg.Done()
 					}
 				}()
-				err := wf.unsubscribeFromServer(ctx, params.requestID, peerID, cFilter)
+				err := wf.unsubscribeFromServer(ctx, params.requestID, peerID, cFilter, false)
 
 				if params.wg != nil {
 					result.Add(WakuFilterPushError{
@@ -597,6 +848,17 @@ func (wf *WakuFilterLightNode) Subscriptions() []*subscription.SubscriptionDetai
 	return subs
 }
 
+// SubscriptionsInfo returns a snapshot of the subscriptions of the light node, including the last
+// time each of them received a message, so stale subscriptions can be detected
+func (wf *WakuFilterLightNode) SubscriptionsInfo() []subscription.SubscriptionInfo {
+	subs := wf.subscriptions.GetAllSubscriptions()
+	result := make([]subscription.SubscriptionInfo, 0, len(subs))
+	for _, sub := range subs {
+		result = append(result, sub.Info())
+	}
+	return result
+}
+
 func (wf *WakuFilterLightNode) IsListening(pubsubTopic, contentTopic string) bool {
 	return wf.subscriptions.IsListening(pubsubTopic, contentTopic)
 
@@ -625,7 +887,9 @@ func (wf *WakuFilterLightNode) UnsubscribeWithSubscription(ctx context.Context,
 	wf.log.Debug("unsubscribing subscription", zap.String("sub-id", sub.ID), zap.Stringer("content-filter", sub.ContentFilter))
 	if !wf.subscriptions.Has(sub.PeerID, sub.ContentFilter) {
 		// Last sub for this [peer, contentFilter] pair
-		err = wf.unsubscribeFromServer(ctx, params.requestID, sub.PeerID, sub.ContentFilter)
+		// Subscriptions without content topics can only be created with WithMatchAll
+		matchAll := len(sub.ContentFilter.ContentTopics) == 0
+		err = wf.unsubscribeFromServer(ctx, params.requestID, sub.PeerID, sub.ContentFilter, matchAll)
 		result.Add(WakuFilterPushError{
 			Err:    err,
 			PeerID: sub.PeerID,
@@ -637,8 +901,16 @@ func (wf *WakuFilterLightNode) UnsubscribeWithSubscription(ctx context.Context,
 
 }
 
-func (wf *WakuFilterLightNode) unsubscribeFromServer(ctx context.Context, requestID []byte, peer peer.ID, cFilter protocol.ContentFilter) error {
-	err := wf.request(ctx, requestID, pb.FilterSubscribeRequest_UNSUBSCRIBE, cFilter, peer)
+// unsubscribeFromServer sends the unsubscribe request to the peer the subscription was established
+// with. Full nodes drop the subscriptions of the peers that disconnect, so disconnected peers are
+// not dialed again and an error is returned instead
+func (wf *WakuFilterLightNode) unsubscribeFromServer(ctx context.Context, requestID []byte, peer peer.ID, cFilter protocol.ContentFilter, matchAll bool) error {
+	if wf.h.Network().Connectedness(peer) != network.Connected {
+		wf.log.Warn("not unsubscribing from disconnected peer", logging.HostID("peerID", peer))
+		return ErrPeerNotConnected
+	}
+
+	err := wf.request(ctx, requestID, pb.FilterSubscribeRequest_UNSUBSCRIBE, cFilter, peer, matchAll)
 	if err != nil {
 		ferr, ok := err.(*FilterError)
 		if ok && ferr.Code == http.StatusNotFound {
@@ -703,7 +975,7 @@ func (wf *WakuFilterLightNode) unsubscribeAll(ctx context.Context, opts ...Filte
 				ctx,
 				params.requestID,
 				pb.FilterSubscribeRequest_UNSUBSCRIBE_ALL,
-				protocol.ContentFilter{}, peerID)
+				protocol.ContentFilter{}, peerID, false)
 			if err != nil {
 				wf.log.Error("could not unsubscribe from peer", logging.HostID("peerID", peerID), zap.Error(err))
 			}
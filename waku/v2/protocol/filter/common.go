@@ -1,8 +1,14 @@
 package filter
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"time"
+
+	"github.com/multiformats/go-varint"
+	"google.golang.org/protobuf/proto"
 )
 
 const DefaultMaxSubscribers = 20
@@ -10,6 +16,52 @@ const MaxCriteriaPerSubscription = 1000
 const MaxContentTopicsPerRequest = 100
 const MessagePushTimeout = 20 * time.Second
 const DefaultIdleSubscriptionTimeout = 5 * time.Minute
+const DefaultPingInterval = 1 * time.Minute
+
+// DefaultPushBatchMaxBytes is the default maximum size of the messages pushed to a subscriber in a single stream
+const DefaultPushBatchMaxBytes = 1024 * 1024
+
+// DefaultReaderLimit is the default maximum size of a single protobuffer read from a filter stream
+const DefaultReaderLimit = math.MaxInt32
+
+// matchAllContentTopic is used by full nodes to track the subscriptions to all the
+// messages of a pubsub topic. It cannot be used by regular subscriptions as empty
+// content topics are not valid
+const matchAllContentTopic = ""
+
+// unsubscribeOnStopTimeout is the time the full nodes have to acknowledge the
+// removal of the subscriptions when the light node stops
+const unsubscribeOnStopTimeout = 5 * time.Second
+
+// ErrMessageTooLarge is returned when reading a protobuffer that exceeds the reader limit
+var ErrMessageTooLarge = errors.New("message exceeds the reader limit")
+
+// ErrMalformedRPC is returned when reading a protobuffer that cannot be decoded
+var ErrMalformedRPC = errors.New("malformed rpc")
+
+// readerLimitError describes the error returned when reading a protobuffer that exceeds the reader limit
+func readerLimitError(err error, limit int) error {
+	if errors.Is(err, io.ErrShortBuffer) {
+		return fmt.Errorf("%w of %d bytes: %w", ErrMessageTooLarge, limit, err)
+	}
+	return err
+}
+
+// readError classifies the errors returned when reading a protobuffer from a stream. io.EOF is returned
+// as is when the stream was closed before sending anything, oversized messages are reported with
+// ErrMessageTooLarge, and undecodable ones with ErrMalformedRPC. Other errors come from the stream itself
+func readError(err error, limit int) error {
+	switch {
+	case errors.Is(err, io.EOF):
+		return err
+	case errors.Is(err, io.ErrShortBuffer):
+		return readerLimitError(err, limit)
+	case errors.Is(err, proto.Error), errors.Is(err, varint.ErrOverflow), errors.Is(err, varint.ErrNotMinimal):
+		return fmt.Errorf("%w: %w", ErrMalformedRPC, err)
+	default:
+		return err
+	}
+}
 
 type FilterError struct {
 	Code    int
@@ -45,14 +45,15 @@ func (wf *WakuFilterLightNode) PingPeer(peer peer.ID) {
 func (wf *WakuFilterLightNode) FilterHealthCheckLoop() {
 	defer utils.LogOnPanic()
 	defer wf.WaitGroup().Done()
-	ticker := time.NewTicker(wf.peerPingInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(utils.Jitter(wf.peerPingInterval, wf.pingJitter))
+	defer timer.Stop()
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
 			if wf.onlineChecker.IsOnline() {
 				wf.PingPeers()
 			}
+			timer.Reset(utils.Jitter(wf.peerPingInterval, wf.pingJitter))
 		case <-wf.CommonService.Context().Done():
 			return
 		}
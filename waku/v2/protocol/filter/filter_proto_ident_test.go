@@ -5,13 +5,17 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"strings"
 	"sync"
+	"testing"
 	"time"
 
 	"github.com/libp2p/go-msgio/pbio"
+	"github.com/multiformats/go-varint"
+	"github.com/stretchr/testify/require"
 	"github.com/waku-org/go-waku/waku/v2/peermanager"
 	"github.com/waku-org/go-waku/waku/v2/protocol/filter/pb"
 	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
@@ -19,6 +23,7 @@ import (
 
 	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
 
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/peerstore"
 	"github.com/waku-org/go-waku/tests"
@@ -26,6 +31,7 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/protocol/subscription"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 func (s *FilterTestSuite) TestCreateSubscription() {
@@ -288,3 +294,87 @@ func (s *FilterTestSuite) TestIncorrectPushIdentifier() {
 	_, err = s.LightNode.UnsubscribeAll(s.ctx)
 	s.Require().NoError(err)
 }
+
+func (s *FilterTestSuite) TestDecodeFailuresPerPeer() {
+	s.ctx, s.ctxCancel = context.WithTimeout(context.Background(), 10*time.Second) // Test can't exceed 10 seconds
+
+	nodeData := s.GetWakuFilterFullNode(s.TestTopic, false, WithMaxDecodeFailures(2))
+	fullNode := nodeData.FullNode
+	defer fullNode.Stop()
+
+	s.LightNodeHost.Peerstore().AddAddr(nodeData.FullNodeHost.ID(), tests.GetHostAddress(nodeData.FullNodeHost), peerstore.PermanentAddrTTL)
+
+	sendMalformedFrame := func() {
+		stream, err := s.LightNodeHost.NewStream(s.ctx, nodeData.FullNodeHost.ID(), FilterSubscribeID_v20beta1)
+		s.Require().NoError(err)
+		// Length prefix followed by bytes that are not a valid FilterSubscribeRequest
+		_, err = stream.Write([]byte{0x03, 0xff, 0xff, 0xff})
+		s.Require().NoError(err)
+		_ = stream.Close()
+	}
+
+	for i := 1; i <= 2; i++ {
+		sendMalformedFrame()
+		s.Require().Eventually(func() bool {
+			return fullNode.DecodeFailures(s.LightNodeHost.ID()) == i
+		}, 2*time.Second, 10*time.Millisecond)
+	}
+
+	s.Require().Equal(network.Connected, nodeData.FullNodeHost.Network().Connectedness(s.LightNodeHost.ID()))
+
+	// Exceeding the maximum number of decode failures disconnects the peer,
+	// and the failures are no longer tracked once it is disconnected
+	sendMalformedFrame()
+	s.Require().Eventually(func() bool {
+		return nodeData.FullNodeHost.Network().Connectedness(s.LightNodeHost.ID()) != network.Connected
+	}, 2*time.Second, 10*time.Millisecond)
+	s.Require().Equal(0, fullNode.DecodeFailures(s.LightNodeHost.ID()))
+}
+
+func (s *FilterTestSuite) TestReadRequestErrors() {
+	s.ctx, s.ctxCancel = context.WithTimeout(context.Background(), 10*time.Second) // Test can't exceed 10 seconds
+
+	s.LightNodeHost.Peerstore().AddAddr(s.FullNodeHost.ID(), tests.GetHostAddress(s.FullNodeHost), peerstore.PermanentAddrTTL)
+	s.FullNode.readerLimit = 16
+
+	send := func(frame []byte) {
+		stream, err := s.LightNodeHost.NewStream(s.ctx, s.FullNodeHost.ID(), FilterSubscribeID_v20beta1)
+		s.Require().NoError(err)
+		if len(frame) != 0 {
+			_, err = stream.Write(frame)
+			s.Require().NoError(err)
+		}
+		_ = stream.Close()
+	}
+
+	// Closing the stream without sending a request is not a decode failure
+	send(nil)
+	s.Require().Never(func() bool {
+		return s.FullNode.DecodeFailures(s.LightNodeHost.ID()) != 0
+	}, 500*time.Millisecond, 10*time.Millisecond)
+
+	// Oversized request
+	send([]byte{0x20})
+	s.Require().Eventually(func() bool {
+		return s.FullNode.DecodeFailures(s.LightNodeHost.ID()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Malformed request
+	send([]byte{0x03, 0xff, 0xff, 0xff})
+	s.Require().Eventually(func() bool {
+		return s.FullNode.DecodeFailures(s.LightNodeHost.ID()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestReadError(t *testing.T) {
+	require.Equal(t, io.EOF, readError(io.EOF, 10))
+	require.ErrorIs(t, readError(io.ErrShortBuffer, 10), ErrMessageTooLarge)
+	require.ErrorIs(t, readError(varint.ErrOverflow, 10), ErrMalformedRPC)
+
+	err := proto.Unmarshal([]byte{0xff, 0xff, 0xff}, &pb.FilterSubscribeRequest{})
+	require.Error(t, err)
+	require.ErrorIs(t, readError(err, 10), ErrMalformedRPC)
+
+	streamErr := errors.New("stream reset")
+	require.Equal(t, streamErr, readError(streamErr, 10))
+}
@@ -6,9 +6,13 @@ import (
 	"time"
 
 	"github.com/waku-org/go-waku/tests"
+	"github.com/waku-org/go-waku/waku/v2/protocol"
+	wpb "github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
+	"github.com/waku-org/go-waku/waku/v2/protocol/subscription"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
 func (s *FilterTestSuite) TestValidPayloadsASCII() {
@@ -150,3 +154,140 @@ func (s *FilterTestSuite) TestFuturePayloadEncryptionVersion() {
 	_, err = s.LightNode.UnsubscribeAll(s.ctx)
 	s.Require().NoError(err)
 }
+
+func (s *FilterTestSuite) TestMessageHandler() {
+	received := make(chan subscription.MessageMetadata, 10)
+	handler := func(subscriptionID string, msg *wpb.WakuMessage, meta subscription.MessageMetadata) {
+		received <- meta
+	}
+
+	contentFilter := protocol.ContentFilter{PubsubTopic: s.TestTopic, ContentTopics: protocol.NewContentTopicSet(s.TestContentTopic)}
+	subDetails, err := s.LightNode.Subscribe(s.ctx, contentFilter, WithPeer(s.FullNodeHost.ID()), WithMessageHandler(handler))
+	s.Require().NoError(err)
+	s.Require().Len(subDetails, 1)
+	s.subDetails = subDetails
+	s.ContentFilter = contentFilter
+
+	time.Sleep(1 * time.Second)
+
+	// The handler is invoked for each message delivered
+	for i := 0; i < 3; i++ {
+		s.waitForMsg(&WakuMsg{s.TestTopic, s.TestContentTopic, strconv.Itoa(i)})
+
+		select {
+		case meta := <-received:
+			s.Require().Equal(s.TestTopic, meta.PubsubTopic)
+			s.Require().Equal(s.FullNodeHost.ID(), meta.PeerID)
+			s.Require().False(meta.ReceivedAt.IsZero())
+		case <-time.After(1 * time.Second):
+			s.Require().Fail("message handler was not invoked")
+		}
+	}
+
+	_, err = s.LightNode.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+}
+
+func (s *FilterTestSuite) TestOrderedPush() {
+	// Pushes are ordered by default
+	s.Require().True(s.FullNode.orderedPush)
+
+	// Subscribe
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+
+	const numMessages = 50
+	var messages []WakuMsg
+	for i := 0; i < numMessages; i++ {
+		messages = append(messages, WakuMsg{s.TestTopic, s.TestContentTopic, strconv.Itoa(i)})
+	}
+	s.publishMessages(messages)
+
+	// Messages should be received in the same order they were published
+	for i := 0; i < numMessages; i++ {
+		select {
+		case env := <-s.subDetails[0].C:
+			s.Require().Equal(strconv.Itoa(i), string(env.Message().Payload))
+		case <-time.After(5 * time.Second):
+			s.Require().Fail("Message timeout")
+		}
+	}
+
+	_, err := s.LightNode.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+}
+
+func (s *FilterTestSuite) TestMessageDeduplication() {
+	lightNodeData := s.GetWakuFilterLightNode(WithMessageDeduplication(10))
+	lightNode := lightNodeData.LightNode
+	s.Require().NoError(lightNode.Start(s.ctx))
+	defer lightNode.Stop()
+	defer lightNodeData.LightNodeHost.Close()
+
+	sub := lightNode.subscriptions.NewSubscription(s.FullNodeHost.ID(), protocol.NewContentFilter(s.TestTopic, s.TestContentTopic))
+
+	msg := tests.CreateWakuMessage(s.TestContentTopic, utils.GetUnixEpoch(), "test_payload")
+	lightNode.notify(s.ctx, s.FullNodeHost.ID(), s.TestTopic, msg)
+	// The same message pushed again is skipped
+	lightNode.notify(s.ctx, s.FullNodeHost.ID(), s.TestTopic, msg)
+
+	otherMsg := tests.CreateWakuMessage(s.TestContentTopic, utils.GetUnixEpoch(), "other_payload")
+	lightNode.notify(s.ctx, s.FullNodeHost.ID(), s.TestTopic, otherMsg)
+
+	s.Require().Len(sub.C, 2)
+	s.Require().Equal(msg.Payload, (<-sub.C).Message().Payload)
+	s.Require().Equal(otherMsg.Payload, (<-sub.C).Message().Payload)
+}
+
+func (s *FilterTestSuite) TestPushBatching() {
+	// Small batches so some of them are flushed before the window elapses
+	s.FullNode.pushBatchWindow = 100 * time.Millisecond
+	s.FullNode.pushBatchMaxBytes = 1024
+
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+
+	// All messages should be received, in batches
+	messages := s.prepareData(50, false, false, true, tests.GenerateRandomASCIIString)
+	s.waitForMessages(messages)
+
+	_, err := s.LightNode.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+}
+
+func (s *FilterTestSuite) TestPushRateLimit() {
+	// Only a burst of two messages is allowed
+	s.FullNode.pushRateLimit = rate.Every(time.Hour)
+	s.FullNode.pushRateBurst = 2
+
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+
+	for i := 0; i < 5; i++ {
+		s.PublishMsg(&WakuMsg{PubSubTopic: s.TestTopic, ContentTopic: s.TestContentTopic, Payload: strconv.Itoa(i)})
+	}
+
+	received := 0
+	timeout := time.After(2 * time.Second)
+	for done := false; !done; {
+		select {
+		case <-s.subDetails[0].C:
+			received++
+		case <-timeout:
+			done = true
+		}
+	}
+	s.Require().Equal(2, received)
+
+	_, err := s.LightNode.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+
+	// Subscribing again does not reset the rate limit
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+	s.PublishMsg(&WakuMsg{PubSubTopic: s.TestTopic, ContentTopic: s.TestContentTopic, Payload: "again"})
+	select {
+	case <-s.subDetails[0].C:
+		s.Require().Fail("rate limit was reset")
+	case <-time.After(1 * time.Second):
+	}
+
+	_, err = s.LightNode.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/waku-org/go-waku/waku/v2/protocol/filter/pb"
 
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peerstore"
 	"github.com/waku-org/go-waku/tests"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
@@ -173,6 +175,41 @@ func (s *FilterTestSuite) TestSubscriptionRefresh() {
 
 }
 
+func (s *FilterTestSuite) TestDuplicateSubscriptionSingleDelivery() {
+	// Subscribe twice to the same content topic
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+	first := s.subDetails[0]
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+	second := s.subDetails[0]
+
+	subs, ok := s.FullNode.subscriptions.Get(s.LightNodeHost.ID())
+	s.Require().True(ok)
+	s.Require().Len(subs[s.TestTopic], 1)
+
+	// Each push is delivered to every subscription of the light node, so a duplicate
+	// push would show up as a second copy of the message in each channel
+	s.PublishMsg(&WakuMsg{s.TestTopic, s.TestContentTopic, "first"})
+	for _, ch := range []chan *protocol.Envelope{first.C, second.C} {
+		received := 0
+		timeout := time.After(1 * time.Second)
+	loop:
+		for {
+			select {
+			case env := <-ch:
+				if string(env.Message().Payload) == "first" {
+					received++
+				}
+			case <-timeout:
+				break loop
+			}
+		}
+		s.Require().Equal(1, received)
+	}
+
+	_, err := s.LightNode.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+}
+
 func (s *FilterTestSuite) TestContentTopicsLimit() {
 	var maxContentTopics = pb.MaxContentTopicsPerRequest
 
@@ -313,6 +350,53 @@ func (s *FilterTestSuite) TestSubscribeMultipleLightNodes() {
 
 }
 
+func (s *FilterTestSuite) TestMaxSubscriptions() {
+	maxSubscriptions := 3
+
+	// Create test context
+	s.ctx, s.ctxCancel = context.WithTimeout(context.Background(), 10*time.Second) // Test can't exceed 10 seconds
+
+	lightNodeData := s.GetWakuFilterLightNode(WithMaxSubscriptions(maxSubscriptions))
+	lightNode2 := lightNodeData.LightNode
+	err := lightNode2.Start(context.Background())
+	s.Require().NoError(err)
+	defer lightNode2.Stop()
+
+	lightNode2.h.Peerstore().AddAddr(s.FullNodeHost.ID(), tests.GetHostAddress(s.FullNodeHost), peerstore.PermanentAddrTTL)
+
+	messages := s.prepareData(maxSubscriptions+1, false, true, false, nil)
+
+	// Subscriptions up to the limit should succeed
+	for i, m := range messages[:maxSubscriptions] {
+		contentFilter := protocol.ContentFilter{PubsubTopic: m.PubSubTopic, ContentTopics: protocol.NewContentTopicSet(m.ContentTopic)}
+		_, err = lightNode2.Subscribe(s.ctx, contentFilter, WithPeer(s.FullNodeHost.ID()))
+		s.Require().NoError(err)
+		s.Require().Equal(i+1, lightNode2.ActiveSubscriptions())
+	}
+
+	// Subscribing beyond the limit should fail
+	last := messages[maxSubscriptions]
+	contentFilter := protocol.ContentFilter{PubsubTopic: last.PubSubTopic, ContentTopics: protocol.NewContentTopicSet(last.ContentTopic)}
+	subs, err := lightNode2.Subscribe(s.ctx, contentFilter, WithPeer(s.FullNodeHost.ID()))
+	s.Require().ErrorIs(err, ErrMaxSubscriptionsReached)
+	s.Require().Empty(subs)
+	s.Require().Equal(maxSubscriptions, lightNode2.ActiveSubscriptions())
+
+	// Removing a subscription frees a slot
+	first := messages[0]
+	_, err = lightNode2.Unsubscribe(s.ctx, protocol.ContentFilter{PubsubTopic: first.PubSubTopic, ContentTopics: protocol.NewContentTopicSet(first.ContentTopic)}, WithPeer(s.FullNodeHost.ID()))
+	s.Require().NoError(err)
+	s.Require().Equal(maxSubscriptions-1, lightNode2.ActiveSubscriptions())
+
+	_, err = lightNode2.Subscribe(s.ctx, contentFilter, WithPeer(s.FullNodeHost.ID()))
+	s.Require().NoError(err)
+	s.Require().Equal(maxSubscriptions, lightNode2.ActiveSubscriptions())
+
+	_, err = lightNode2.UnsubscribeAll(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Equal(0, lightNode2.ActiveSubscriptions())
+}
+
 func (s *FilterTestSuite) TestSubscribeFullNode2FullNode() {
 
 	var (
@@ -408,3 +492,200 @@ func (s *FilterTestSuite) TestHandleFilterSubscribeOptions() {
 	s.Require().Error(err)
 
 }
+
+func (s *FilterTestSuite) TestSubscribeUnresponsivePeer() {
+	// Peer that accepts filter requests but never replies to them
+	port, err := tests.FindFreePort(s.T(), "", 5)
+	s.Require().NoError(err)
+	unresponsiveHost, err := tests.MakeHost(s.ctx, port, rand.Reader)
+	s.Require().NoError(err)
+	defer unresponsiveHost.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	unresponsiveHost.SetStreamHandler(FilterSubscribeID_v20beta1, func(stream network.Stream) {
+		<-release
+		_ = stream.Reset()
+	})
+	s.LightNodeHost.Peerstore().AddAddr(unresponsiveHost.ID(), tests.GetHostAddress(unresponsiveHost), peerstore.PermanentAddrTTL)
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Second)
+	defer cancel()
+
+	start := time.Now()
+	subs, err := s.LightNode.Subscribe(ctx, s.ContentFilter, WithPeer(unresponsiveHost.ID()))
+	s.Require().Error(err)
+	s.Require().Empty(subs)
+	s.Require().Less(time.Since(start), 5*time.Second)
+	s.Require().Empty(s.LightNode.Subscriptions())
+}
+
+func (s *FilterTestSuite) TestSubscribersRemovedOnDisconnect() {
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+	s.Require().True(s.FullNode.HasSubscriber(s.LightNodeHost.ID()))
+
+	s.Require().NoError(s.LightNodeHost.Network().ClosePeer(s.FullNodeHost.ID()))
+
+	s.Require().Eventually(func() bool {
+		return s.FullNode.subscriptions.Count() == 0
+	}, 5*time.Second, 100*time.Millisecond)
+	s.Require().False(s.FullNode.HasSubscriber(s.LightNodeHost.ID()))
+}
+
+func (s *FilterTestSuite) TestSubscribeRejected() {
+	s.FullNode.maxSubscriptions = 0
+	s.ContentFilter = protocol.ContentFilter{PubsubTopic: s.TestTopic, ContentTopics: protocol.NewContentTopicSet(s.TestContentTopic)}
+
+	subs, err := s.LightNode.Subscribe(s.ctx, s.ContentFilter, WithPeer(s.FullNodeHost.ID()))
+	s.Require().Error(err)
+	s.Require().Empty(subs)
+
+	// The status returned by the full node is surfaced to the caller
+	var filterErr *FilterError
+	s.Require().ErrorAs(err, &filterErr)
+	s.Require().Equal(http.StatusServiceUnavailable, filterErr.Code)
+}
+
+func (s *FilterTestSuite) TestSubscribeMatchAll() {
+	matchAll := protocol.ContentFilter{PubsubTopic: s.TestTopic}
+
+	// Empty content filters are rejected unless explicitly requested
+	_, err := s.LightNode.Subscribe(s.ctx, matchAll, WithPeer(s.FullNodeHost.ID()))
+	s.Require().Error(err)
+
+	// Full nodes reject them by default
+	_, err = s.LightNode.Subscribe(s.ctx, matchAll, WithPeer(s.FullNodeHost.ID()), WithMatchAll())
+	var filterErr *FilterError
+	s.Require().ErrorAs(err, &filterErr)
+	s.Require().Equal(http.StatusBadRequest, filterErr.Code)
+
+	s.FullNode.allowMatchAll = true
+	subs, err := s.LightNode.Subscribe(s.ctx, matchAll, WithPeer(s.FullNodeHost.ID()), WithMatchAll())
+	s.Require().NoError(err)
+	s.Require().Len(subs, 1)
+
+	// Messages with any content topic are delivered
+	msg := &WakuMsg{PubSubTopic: s.TestTopic, ContentTopic: "/test/10/other-topic/proto", Payload: "match_all"}
+	s.PublishMsg(msg)
+	select {
+	case env := <-subs[0].C:
+		s.Require().Equal(msg.ContentTopic, env.Message().ContentTopic)
+	case <-time.After(1 * time.Second):
+		s.Require().Fail("Message timeout")
+	}
+
+	// Unsubscribe requests only accept empty content filters for match all subscriptions
+	err = s.LightNode.request(s.ctx, protocol.GenerateRequestID(), pb.FilterSubscribeRequest_UNSUBSCRIBE, matchAll, s.FullNodeHost.ID(), false)
+	s.Require().Error(err)
+
+	_, err = s.LightNode.UnsubscribeWithSubscription(s.ctx, subs[0])
+	s.Require().NoError(err)
+}
+
+func (s *FilterTestSuite) TestUnsubscribeDisconnectedPeer() {
+	s.ContentFilter = protocol.ContentFilter{PubsubTopic: s.TestTopic, ContentTopics: protocol.NewContentTopicSet(s.TestContentTopic)}
+	subs, err := s.LightNode.Subscribe(s.ctx, s.ContentFilter, WithPeer(s.FullNodeHost.ID()))
+	s.Require().NoError(err)
+	s.Require().Len(subs, 1)
+	s.Require().Equal(s.FullNodeHost.ID(), subs[0].PeerID)
+
+	s.Require().NoError(s.LightNodeHost.Network().ClosePeer(s.FullNodeHost.ID()))
+
+	// The unsubscribe is not routed to any other peer
+	_, err = s.LightNode.UnsubscribeWithSubscription(s.ctx, subs[0])
+	s.Require().ErrorIs(err, ErrPeerNotConnected)
+	s.Require().True(subs[0].Closed)
+}
+
+func (s *FilterTestSuite) TestSubscribeExceedsReaderLimit() {
+	s.FullNode.readerLimit = 10
+	s.ContentFilter = protocol.ContentFilter{PubsubTopic: s.TestTopic, ContentTopics: protocol.NewContentTopicSet(s.TestContentTopic)}
+
+	_, err := s.LightNode.Subscribe(s.ctx, s.ContentFilter, WithPeer(s.FullNodeHost.ID()))
+	s.Require().Error(err)
+
+	s.FullNode.readerLimit = DefaultReaderLimit
+	_, err = s.LightNode.Subscribe(s.ctx, s.ContentFilter, WithPeer(s.FullNodeHost.ID()))
+	s.Require().NoError(err)
+}
+
+func (s *FilterTestSuite) TestNoPeersAvailable() {
+	// Light node with an empty peerstore
+	lightNodeData := s.GetWakuFilterLightNode()
+	lightNode := lightNodeData.LightNode
+	s.Require().NoError(lightNode.Start(s.ctx))
+	defer lightNode.Stop()
+
+	contentFilter := protocol.NewContentFilter(s.TestTopic, s.TestContentTopic)
+
+	subs, err := lightNode.Subscribe(s.ctx, contentFilter)
+	s.Require().ErrorIs(err, ErrNoPeersAvailable)
+	s.Require().Empty(subs)
+	s.Require().Empty(lightNode.Subscriptions())
+
+	result, err := lightNode.Unsubscribe(s.ctx, contentFilter)
+	s.Require().ErrorIs(err, ErrNoPeersAvailable)
+	s.Require().Nil(result)
+}
+
+func (s *FilterTestSuite) TestSubscribeWithPeer() {
+	contentFilter := protocol.NewContentFilter(s.TestTopic, s.TestContentTopic)
+
+	subs, err := s.LightNode.SubscribeWithPeer(s.ctx, s.FullNodeHost.ID(), contentFilter)
+	s.Require().NoError(err)
+	s.Require().Len(subs, 1)
+	s.Require().Equal(s.FullNodeHost.ID(), subs[0].PeerID)
+	s.Require().True(s.FullNode.HasSubscriber(s.LightNodeHost.ID()))
+
+	result, err := s.LightNode.UnsubscribeWithPeer(s.ctx, s.FullNodeHost.ID(), contentFilter)
+	s.Require().NoError(err)
+	for _, e := range result.Errors() {
+		s.Require().NoError(e.Err)
+	}
+	s.Require().Empty(s.LightNode.Subscriptions())
+
+	// Peer that does not mount the filter protocol
+	port, err := tests.FindFreePort(s.T(), "", 5)
+	s.Require().NoError(err)
+	otherHost, err := tests.MakeHost(s.ctx, port, rand.Reader)
+	s.Require().NoError(err)
+	defer otherHost.Close()
+	s.LightNodeHost.Peerstore().AddAddr(otherHost.ID(), tests.GetHostAddress(otherHost), peerstore.PermanentAddrTTL)
+
+	subs, err = s.LightNode.SubscribeWithPeer(s.ctx, otherHost.ID(), contentFilter)
+	s.Require().ErrorIs(err, ErrFilterNotSupported)
+	s.Require().Empty(subs)
+
+	_, err = s.LightNode.UnsubscribeWithPeer(s.ctx, otherHost.ID(), contentFilter)
+	s.Require().ErrorIs(err, ErrFilterNotSupported)
+}
+
+func (s *FilterTestSuite) TestSubscribeCancelled() {
+	// Peer that accepts filter requests but never replies to them
+	port, err := tests.FindFreePort(s.T(), "", 5)
+	s.Require().NoError(err)
+	unresponsiveHost, err := tests.MakeHost(s.ctx, port, rand.Reader)
+	s.Require().NoError(err)
+	defer unresponsiveHost.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	unresponsiveHost.SetStreamHandler(FilterSubscribeID_v20beta1, func(stream network.Stream) {
+		<-release
+		_ = stream.Reset()
+	})
+	s.LightNodeHost.Peerstore().AddAddr(unresponsiveHost.ID(), tests.GetHostAddress(unresponsiveHost), peerstore.PermanentAddrTTL)
+
+	// The context has no deadline, so only its cancellation can abort the request
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	contentFilter := protocol.NewContentFilter(s.TestTopic, s.TestContentTopic)
+
+	start := time.Now()
+	subs, err := s.LightNode.Subscribe(ctx, contentFilter, WithPeer(unresponsiveHost.ID()))
+	s.Require().ErrorIs(err, context.Canceled)
+	s.Require().Empty(subs)
+	s.Require().Less(time.Since(start), 5*time.Second)
+	s.Require().Empty(s.LightNode.Subscriptions())
+}
@@ -89,6 +89,22 @@ func (s *FilterTestSuite) TestStartStop() {
 	wg.Wait()
 }
 
+func (s *FilterTestSuite) TestStopClosesSubscriptions() {
+	s.subscribe(s.TestTopic, s.TestContentTopic, s.FullNodeHost.ID())
+	s.Require().True(s.FullNode.HasSubscriber(s.LightNodeHost.ID()))
+
+	s.LightNode.Stop()
+
+	// The channels of the subscriptions are closed once the light node stops
+	for _, sub := range s.subDetails {
+		_, ok := <-sub.C
+		s.Require().False(ok)
+	}
+
+	// and the full node is notified
+	s.Require().False(s.FullNode.HasSubscriber(s.LightNodeHost.ID()))
+}
+
 func (s *FilterTestSuite) TestAutoShard() {
 
 	//Workaround as could not find a way to reuse setup test with params
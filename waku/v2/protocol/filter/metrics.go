@@ -13,6 +13,18 @@ var filterMessages = prometheus.NewCounter(
 		Help: "The number of messages received via filter protocol",
 	})
 
+var filterMessagesPushed = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "waku_filter_messages_pushed",
+		Help: "The number of messages pushed to filter subscribers",
+	})
+
+var filterMessagesDropped = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "waku_filter_messages_dropped",
+		Help: "The number of messages queued for an ordered push to filter subscribers that were dropped",
+	})
+
 var filterErrors = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "waku_filter_errors",
@@ -51,6 +63,8 @@ var filterSubscriptions = prometheus.NewGauge(
 
 var collectors = []prometheus.Collector{
 	filterMessages,
+	filterMessagesPushed,
+	filterMessagesDropped,
 	filterErrors,
 	filterRequests,
 	filterSubscriptions,
@@ -63,6 +77,8 @@ type Metrics interface {
 	RecordMessage()
 	RecordRequest(requestType string, duration time.Duration)
 	RecordPushDuration(duration time.Duration)
+	RecordMessagePushed()
+	RecordMessagesDropped(num int)
 	RecordSubscriptions(num int)
 	RecordError(err metricsErrCategory)
 }
@@ -95,7 +111,13 @@ var (
 	errorResponse              metricsErrCategory = "error_response"
 	peerNotFoundFailure        metricsErrCategory = "peer_not_found_failure"
 	writeResponseFailure       metricsErrCategory = "write_response_failure"
+	writePushFailure           metricsErrCategory = "write_push_failure"
 	pushTimeoutFailure         metricsErrCategory = "push_timeout_failure"
+	pushQueueFullFailure       metricsErrCategory = "push_queue_full_failure"
+	pushRateLimitFailure       metricsErrCategory = "push_rate_limit_failure"
+	maxSubscriptionsFailure    metricsErrCategory = "max_subscriptions_failure"
+	maxSubscribersFailure      metricsErrCategory = "max_subscribers_failure"
+	maxCriteriaFailure         metricsErrCategory = "max_criteria_failure"
 )
 
 // RecordError increases the counter for different error types
@@ -114,6 +136,16 @@ func (m *metricsImpl) RecordPushDuration(duration time.Duration) {
 	filterHandleMessageDurationSeconds.Observe(duration.Seconds())
 }
 
+// RecordMessagePushed increases the counter for the number of messages pushed to filter subscribers
+func (m *metricsImpl) RecordMessagePushed() {
+	filterMessagesPushed.Inc()
+}
+
+// RecordMessagesDropped increases the counter for the number of queued messages that were not pushed to filter subscribers
+func (m *metricsImpl) RecordMessagesDropped(num int) {
+	filterMessagesDropped.Add(float64(num))
+}
+
 // RecordSubscriptions track the current number of filter subscriptions
 func (m *metricsImpl) RecordSubscriptions(num int) {
 	filterSubscriptions.Set(float64(num))
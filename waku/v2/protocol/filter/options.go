@@ -10,13 +10,16 @@ import (
 	"github.com/multiformats/go-multiaddr"
 	"github.com/waku-org/go-waku/waku/v2/peermanager"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/subscription"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
 func (old *FilterSubscribeParameters) Copy() *FilterSubscribeParameters {
 	return &FilterSubscribeParameters{
 		selectedPeers: old.selectedPeers,
 		requestID:     old.requestID,
+		onMessage:     old.onMessage,
 	}
 }
 
@@ -38,15 +41,19 @@ type (
 		selectedPeers     peer.IDSlice
 		peerAddr          multiaddr.Multiaddr
 		peerSelectionType peermanager.PeerSelection
+		peerSelector      peermanager.PeerSelectionStrategy
 		preferredPeers    peer.IDSlice
 		peersToExclude    peermanager.PeerSet
 		maxPeers          int
+		checkLiveness     bool
+		matchAll          bool
 		requestID         []byte
 		log               *zap.Logger
 
 		// Subscribe-specific
-		host host.Host
-		pm   *peermanager.PeerManager
+		host      host.Host
+		pm        *peermanager.PeerManager
+		onMessage subscription.MessageHandler
 
 		// Unsubscribe-specific
 		unsubscribeAll bool
@@ -57,13 +64,40 @@ type (
 		Timeout        time.Duration
 		MaxSubscribers int
 		pm             *peermanager.PeerManager
+		orderedPush    bool
+
+		pushBatchWindow   time.Duration
+		pushBatchMaxBytes int
+
+		pushRateLimit rate.Limit
+		pushRateBurst int
+
+		maxCriteriaPerPeer  int
+		evictOldestCriteria bool
+		allowMatchAll       bool
+
+		maxDecodeFailures int
+		readerLimit       int
 	}
 
 	Option func(*FilterParameters)
 
+	LightNodeParameters struct {
+		maxSubscriptions int
+		pingInterval     time.Duration
+		pingJitter       float64
+		dedupCacheSize   int
+		readerLimit      int
+	}
+
+	LightNodeOption func(*LightNodeParameters)
+
 	FilterSubscribeOption func(*FilterSubscribeParameters) error
 )
 
+// WithTimeout sets the time after which the subscriptions of a peer that did not
+// subscribe or ping again are removed from the full node. Subscriptions never expire
+// if the timeout is not positive
 func WithTimeout(timeout time.Duration) Option {
 	return func(params *FilterParameters) {
 		params.Timeout = timeout
@@ -95,6 +129,17 @@ func WithPeerAddr(pAddr multiaddr.Multiaddr) FilterSubscribeOption {
 	}
 }
 
+// withOnlyPeer targets a single peer, overriding the options that select the peers
+func withOnlyPeer(p peer.ID) FilterSubscribeOption {
+	return func(params *FilterSubscribeParameters) error {
+		params.selectedPeers = peer.IDSlice{p}
+		params.peerAddr = nil
+		params.preferredPeers = nil
+		params.maxPeers = 1
+		return nil
+	}
+}
+
 func WithMaxPeersPerContentFilter(numPeers int) FilterSubscribeOption {
 	return func(params *FilterSubscribeParameters) error {
 		params.maxPeers = numPeers
@@ -110,6 +155,15 @@ func WithPeersToExclude(peers ...peer.ID) FilterSubscribeOption {
 	}
 }
 
+// WithLivenessCheck is an option used to probe the peers selected from the peer store by negotiating
+// the filter protocol, skipping those that do not respond. It adds a round trip per selected peer
+func WithLivenessCheck() FilterSubscribeOption {
+	return func(params *FilterSubscribeParameters) error {
+		params.checkLiveness = true
+		return nil
+	}
+}
+
 // WithAutomaticPeerSelection is an option used to randomly select a peer from the peer store.
 // If a list of specific peers is passed, the peer will be chosen from that list assuming it
 // supports the chosen protocol, otherwise it will chose a peer from the node peerstore
@@ -132,6 +186,35 @@ func WithFastestPeerSelection(fromThesePeers ...peer.ID) FilterSubscribeOption {
 	}
 }
 
+// WithLowestLatencyPeerSelection is an option used to select the peers with the lowest
+// latency observed by the peerstore, preferring those that track the pubsub topic of the
+// subscription. If a list of specific peers is passed, the peers will be chosen from that list
+func WithLowestLatencyPeerSelection(fromThesePeers ...peer.ID) FilterSubscribeOption {
+	return func(params *FilterSubscribeParameters) error {
+		params.peerSelectionType = peermanager.LowestLatency
+		params.preferredPeers = fromThesePeers
+		return nil
+	}
+}
+
+// WithPeerSelectionStrategy is an option used to override the peer selection heuristic with a
+// custom strategy, which receives the peers that support filter and track the pubsub topic
+func WithPeerSelectionStrategy(strategy peermanager.PeerSelectionStrategy) FilterSubscribeOption {
+	return func(params *FilterSubscribeParameters) error {
+		params.peerSelector = strategy
+		return nil
+	}
+}
+
+// WithMatchAll is an option used to subscribe to all the messages of the pubsub topic of the
+// content filter, which must not contain any content topic. The full node must allow it
+func WithMatchAll() FilterSubscribeOption {
+	return func(params *FilterSubscribeParameters) error {
+		params.matchAll = true
+		return nil
+	}
+}
+
 // WithRequestID is an option to set a specific request ID to be used when
 // creating/removing a filter subscription
 func WithRequestID(requestID []byte) FilterSubscribeOption {
@@ -141,6 +224,16 @@ func WithRequestID(requestID []byte) FilterSubscribeOption {
 	}
 }
 
+// WithMessageHandler is an option to set a callback that is invoked, in addition to delivering the
+// message in the subscription channel, each time a message is received for the subscriptions created.
+// The handler is called synchronously when the message is pushed, so it should not block
+func WithMessageHandler(handler subscription.MessageHandler) FilterSubscribeOption {
+	return func(params *FilterSubscribeParameters) error {
+		params.onMessage = handler
+		return nil
+	}
+}
+
 // WithAutomaticRequestID is an option to automatically generate a request ID
 // when creating a filter subscription
 func WithAutomaticRequestID() FilterSubscribeOption {
@@ -196,6 +289,77 @@ func WithMaxSubscribers(maxSubscribers int) Option {
 	}
 }
 
+// WithOrderedPush indicates whether the messages pushed to a subscriber must be delivered in the
+// same order they were received by the full node. When enabled, pushes to the same subscriber are
+// serialized, while pushes to different subscribers are still done in parallel. Messages that do not
+// fit in the queue of a subscriber are dropped. Enabled by default
+func WithOrderedPush(ordered bool) Option {
+	return func(params *FilterParameters) {
+		params.orderedPush = ordered
+	}
+}
+
+// WithPushBatching coalesces the messages pushed to the same subscriber within the flush window, so
+// they are written to a single stream instead of opening one stream per message. A batch is flushed
+// early once its messages exceed maxBytes. Light nodes read every message pushed in a stream, but
+// older ones only read the first one, so batching is disabled by default. Messages are written
+// separately, so maxBytes is not bound by the reader limit of the light nodes (see WithPushReaderLimit)
+func WithPushBatching(window time.Duration, maxBytes int) Option {
+	return func(params *FilterParameters) {
+		params.pushBatchWindow = window
+		params.pushBatchMaxBytes = maxBytes
+		if params.pushBatchMaxBytes <= 0 {
+			params.pushBatchMaxBytes = DefaultPushBatchMaxBytes
+		}
+	}
+}
+
+// WithPushRateLimit limits the rate of the messages pushed to each subscriber to r messages per second,
+// with bursts of up to b messages. Messages exceeding the rate of a subscriber are dropped and counted
+// in the metrics, which protects the bandwidth of the full node from content topic floods. Disabled by default
+func WithPushRateLimit(r rate.Limit, b int) Option {
+	return func(params *FilterParameters) {
+		params.pushRateLimit = r
+		params.pushRateBurst = b
+	}
+}
+
+// WithReaderLimit sets the maximum size of the subscribe requests read by the full node. Requests
+// exceeding it are rejected and counted as decode failures
+func WithReaderLimit(limit int) Option {
+	return func(params *FilterParameters) {
+		params.readerLimit = limit
+	}
+}
+
+// WithMaxCriteriaPerPeer sets the maximum number of content topics a single peer can be subscribed to.
+// If evictOldest is true, the content topics the peer subscribed to first are removed to make room for
+// the new ones, otherwise the subscribe requests exceeding the limit are rejected. A maxCriteria of 0
+// removes the limit
+func WithMaxCriteriaPerPeer(maxCriteria int, evictOldest bool) Option {
+	return func(params *FilterParameters) {
+		params.maxCriteriaPerPeer = maxCriteria
+		params.evictOldestCriteria = evictOldest
+	}
+}
+
+// WithMatchAllSubscriptions allows peers to subscribe to all the messages of a pubsub topic by not
+// specifying any content topic. This is expensive for the full node, so it is disabled by default
+// and subscriptions without content topics are rejected
+func WithMatchAllSubscriptions(allow bool) Option {
+	return func(params *FilterParameters) {
+		params.allowMatchAll = allow
+	}
+}
+
+// WithMaxDecodeFailures sets the number of malformed requests accepted from a peer before
+// the full node disconnects from it and removes it from the peer store. 0 disables it
+func WithMaxDecodeFailures(maxDecodeFailures int) Option {
+	return func(params *FilterParameters) {
+		params.maxDecodeFailures = maxDecodeFailures
+	}
+}
+
 func WithPeerManager(pm *peermanager.PeerManager) Option {
 	return func(params *FilterParameters) {
 		params.pm = pm
@@ -206,5 +370,54 @@ func DefaultOptions() []Option {
 	return []Option{
 		WithTimeout(DefaultIdleSubscriptionTimeout),
 		WithMaxSubscribers(DefaultMaxSubscribers),
+		WithMaxCriteriaPerPeer(MaxCriteriaPerSubscription, false),
+		WithReaderLimit(DefaultReaderLimit),
+		WithOrderedPush(true),
+	}
+}
+
+// WithPingInterval sets the interval between the keep-alive pings sent to the subscribed peers,
+// which refresh the subscriptions on the full nodes. It should be shorter than the idle
+// subscription timeout of the full nodes. Defaults to DefaultPingInterval
+func WithPingInterval(interval time.Duration) LightNodeOption {
+	return func(params *LightNodeParameters) {
+		params.pingInterval = interval
+	}
+}
+
+// WithPingJitter randomizes the interval between the keep-alive pings sent to the
+// subscribed peers by the given fraction, so light nodes do not ping at the same time.
+// Defaults to utils.DefaultJitterFraction
+func WithPingJitter(fraction float64) LightNodeOption {
+	return func(params *LightNodeParameters) {
+		params.pingJitter = fraction
+	}
+}
+
+// WithMaxSubscriptions limits the number of simultaneous subscriptions a light node
+// can have. Subscriptions beyond this limit fail with ErrMaxSubscriptionsReached.
+// A value of 0 disables the limit
+func WithMaxSubscriptions(maxSubscriptions int) LightNodeOption {
+	return func(params *LightNodeParameters) {
+		params.maxSubscriptions = maxSubscriptions
+	}
+}
+
+// WithPushReaderLimit sets the maximum size of each message pushed to the light node, and of the
+// responses to its requests. Full nodes batching pushes write every message separately, so the limit
+// applies to each message of a batch and not to the maximum size of the batch, but it should still be
+// larger than the largest message relayed by the full nodes. Defaults to DefaultReaderLimit
+func WithPushReaderLimit(limit int) LightNodeOption {
+	return func(params *LightNodeParameters) {
+		params.readerLimit = limit
+	}
+}
+
+// WithMessageDeduplication skips the messages pushed more than once, i.e. by the different full nodes a
+// light node is subscribed to or after resubscribing. The hashes of the last cacheSize messages received
+// are remembered. A value of 0 disables it, which is the default
+func WithMessageDeduplication(cacheSize int) LightNodeOption {
+	return func(params *LightNodeParameters) {
+		params.dedupCacheSize = cacheSize
 	}
 }
@@ -37,6 +37,11 @@ func TestFilterOption(t *testing.T) {
 	require.Equal(t, host, params.host)
 	require.NotEqual(t, 0, params.selectedPeers)
 
+	// Liveness checks are opt-in
+	require.False(t, params.checkLiveness)
+	require.NoError(t, WithLivenessCheck()(params))
+	require.True(t, params.checkLiveness)
+
 	// Unsubscribe options
 	options2 := []FilterSubscribeOption{
 		WithAutomaticRequestID(),
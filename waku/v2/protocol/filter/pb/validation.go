@@ -9,12 +9,16 @@ import (
 
 const MaxContentTopicsPerRequest = 100
 
+// MaxContentTopicSize is the maximum length in bytes of a content topic
+const MaxContentTopicSize = 256
+
 var (
 	errMissingRequestID   = errors.New("missing RequestId field")
 	errMissingPubsubTopic = errors.New("missing PubsubTopic field")
 	errNoContentTopics    = errors.New("at least one contenttopic should be specified")
 	errMaxContentTopics   = fmt.Errorf("exceeds maximum content topics: %d", MaxContentTopicsPerRequest)
 	errEmptyContentTopics = errors.New("one or more content topics specified is empty")
+	errContentTopicSize   = fmt.Errorf("content topic exceeds maximum size: %d bytes", MaxContentTopicSize)
 	errMissingMessage     = errors.New("missing WakuMessage field")
 )
 
@@ -39,6 +43,33 @@ func (x *FilterSubscribeRequest) Validate() error {
 		if len(x.ContentTopics) > MaxContentTopicsPerRequest {
 			return errMaxContentTopics
 		}
+
+		for _, c := range x.ContentTopics {
+			if len(c) > MaxContentTopicSize {
+				return errContentTopicSize
+			}
+		}
+	}
+
+	return nil
+}
+
+// IsMatchAll indicates that the request subscribes to (or unsubscribes from) all the messages
+// of a pubsub topic, regardless of their content topic
+func (x *FilterSubscribeRequest) IsMatchAll() bool {
+	return (x.FilterSubscribeType == FilterSubscribeRequest_SUBSCRIBE || x.FilterSubscribeType == FilterSubscribeRequest_UNSUBSCRIBE) &&
+		len(x.ContentTopics) == 0
+}
+
+// ValidateMatchAll validates a request that subscribes to (or unsubscribes from) all the
+// messages of a pubsub topic. Only nodes that support it should accept these requests
+func (x *FilterSubscribeRequest) ValidateMatchAll() error {
+	if x.RequestId == "" {
+		return errMissingRequestID
+	}
+
+	if x.PubsubTopic == nil || *x.PubsubTopic == "" {
+		return errMissingPubsubTopic
 	}
 
 	return nil
@@ -1,6 +1,8 @@
 package pb
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -22,6 +24,63 @@ func TestValidateRequest(t *testing.T) {
 	require.NoError(t, request.Validate())
 }
 
+func TestValidateRequestContentTopics(t *testing.T) {
+	tooMany := make([]string, MaxContentTopicsPerRequest+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("/test/1/topic-%d/proto", i)
+	}
+
+	tests := []struct {
+		name          string
+		contentTopics []string
+		err           error
+	}{
+		{"no content topics", nil, errNoContentTopics},
+		{"empty content topic", []string{"/test/1/topic/proto", ""}, errEmptyContentTopics},
+		{"too many content topics", tooMany, errMaxContentTopics},
+		{"oversized content topic", []string{strings.Repeat("a", MaxContentTopicSize+1)}, errContentTopicSize},
+		{"maximum content topics", tooMany[:MaxContentTopicsPerRequest], nil},
+		{"maximum content topic size", []string{strings.Repeat("a", MaxContentTopicSize)}, nil},
+	}
+
+	pubsubTopic := "test"
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, requestType := range []FilterSubscribeRequest_FilterSubscribeType{FilterSubscribeRequest_SUBSCRIBE, FilterSubscribeRequest_UNSUBSCRIBE} {
+				request := &FilterSubscribeRequest{
+					RequestId:           "test",
+					FilterSubscribeType: requestType,
+					PubsubTopic:         &pubsubTopic,
+					ContentTopics:       tc.contentTopics,
+				}
+				if tc.err == nil {
+					require.NoError(t, request.Validate())
+				} else {
+					require.ErrorIs(t, request.Validate(), tc.err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMatchAll(t *testing.T) {
+	request := &FilterSubscribeRequest{RequestId: "test", FilterSubscribeType: FilterSubscribeRequest_SUBSCRIBE}
+	require.True(t, request.IsMatchAll())
+	require.ErrorIs(t, request.ValidateMatchAll(), errMissingPubsubTopic)
+
+	pubsubTopic := "test"
+	request.PubsubTopic = &pubsubTopic
+	require.NoError(t, request.ValidateMatchAll())
+	// Empty filters are still rejected by default
+	require.ErrorIs(t, request.Validate(), errNoContentTopics)
+
+	request.ContentTopics = []string{"test"}
+	require.False(t, request.IsMatchAll())
+
+	request = &FilterSubscribeRequest{RequestId: "test", FilterSubscribeType: FilterSubscribeRequest_UNSUBSCRIBE_ALL}
+	require.False(t, request.IsMatchAll())
+}
+
 func TestValidateResponse(t *testing.T) {
 	response := FilterSubscribeResponse{}
 	require.ErrorIs(t, response.Validate(), errMissingRequestID)
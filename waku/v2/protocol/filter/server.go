@@ -3,8 +3,9 @@ package filter
 import (
 	"context"
 	"errors"
-	"math"
+	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/host"
@@ -22,6 +23,8 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/timesource"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
 )
 
 // FilterSubscribeID_v20beta1 is the current Waku Filter protocol identifier for servers to
@@ -40,10 +43,44 @@ type (
 		subscriptions *SubscribersMap
 		pm            *peermanager.PeerManager
 
-		maxSubscriptions int
+		maxSubscriptions    int
+		maxCriteriaPerPeer  int
+		evictOldestCriteria bool
+		allowMatchAll       bool
+
+		orderedPush       bool
+		pushBatchWindow   time.Duration
+		pushBatchMaxBytes int
+		pushQueuesLock    sync.Mutex
+		pushQueues        map[peer.ID]chan pushItem
+
+		pushRateLimit    rate.Limit
+		pushRateBurst    int
+		pushLimitersLock sync.Mutex
+		pushLimiters     map[peer.ID]*rate.Limiter
+
+		readerLimit int
+
+		maxDecodeFailures  int
+		decodeFailuresLock sync.Mutex
+		decodeFailures     map[peer.ID]int
+
+		disconnectNotif *network.NotifyBundle
+	}
+
+	pushItem struct {
+		envelope *protocol.Envelope
+		logger   *zap.Logger
 	}
 )
 
+// pushQueueSize is the maximum number of messages waiting to be pushed to a subscriber
+const pushQueueSize = 1024
+
+// pushQueueIdleTimeout is the time after which the worker pushing messages to a subscriber
+// stops if there are no new messages for it
+const pushQueueIdleTimeout = 30 * time.Second
+
 // NewWakuFilterFullNode returns a new instance of Waku Filter struct setup according to the chosen parameter and options
 func NewWakuFilterFullNode(timesource timesource.Timesource, reg prometheus.Registerer, log *zap.Logger, opts ...Option) *WakuFilterFullNode {
 	wf := new(WakuFilterFullNode)
@@ -59,7 +96,23 @@ func NewWakuFilterFullNode(timesource timesource.Timesource, reg prometheus.Regi
 	wf.CommonService = service.NewCommonService()
 	wf.metrics = newMetrics(reg)
 	wf.subscriptions = NewSubscribersMap(params.Timeout)
+	// The push rate limit of a peer is only forgotten once it disconnects or its subscriptions
+	// expire, so unsubscribing and subscribing again does not reset it
+	wf.subscriptions.OnExpired(wf.removePushLimiter)
 	wf.maxSubscriptions = params.MaxSubscribers
+	wf.maxCriteriaPerPeer = params.maxCriteriaPerPeer
+	wf.evictOldestCriteria = params.evictOldestCriteria
+	wf.allowMatchAll = params.allowMatchAll
+	wf.orderedPush = params.orderedPush
+	wf.pushBatchWindow = params.pushBatchWindow
+	wf.pushBatchMaxBytes = params.pushBatchMaxBytes
+	wf.pushQueues = make(map[peer.ID]chan pushItem)
+	wf.pushRateLimit = params.pushRateLimit
+	wf.pushRateBurst = params.pushRateBurst
+	wf.pushLimiters = make(map[peer.ID]*rate.Limiter)
+	wf.maxDecodeFailures = params.maxDecodeFailures
+	wf.readerLimit = params.readerLimit
+	wf.decodeFailures = make(map[peer.ID]int)
 	if params.pm != nil {
 		params.pm.RegisterWakuProtocol(FilterSubscribeID_v20beta1, FilterSubscribeENRField)
 		wf.pm = params.pm
@@ -87,21 +140,60 @@ func (wf *WakuFilterFullNode) start(sub *relay.Subscription) error {
 
 	wf.subscriptions.Start(wf.Context())
 
+	wf.disconnectNotif = &network.NotifyBundle{
+		DisconnectedF: func(n network.Network, c network.Conn) {
+			wf.onPeerDisconnected(n, c.RemotePeer())
+		},
+	}
+	wf.h.Network().Notify(wf.disconnectNotif)
+
 	wf.log.Info("filter-subscriber protocol started")
 	return nil
 }
 
+// onPeerDisconnected removes the subscriptions of a peer once all its connections are closed,
+// since messages can no longer be pushed to it
+func (wf *WakuFilterFullNode) onPeerDisconnected(n network.Network, peerID peer.ID) {
+	if n.Connectedness(peerID) == network.Connected {
+		return
+	}
+
+	wf.removePushLimiter(peerID)
+	wf.resetDecodeFailures(peerID)
+
+	if err := wf.subscriptions.DeleteAll(peerID); err != nil {
+		return
+	}
+
+	wf.metrics.RecordSubscriptions(wf.subscriptions.Count())
+	wf.log.Debug("removed subscriptions of disconnected peer", logging.HostID("peer", peerID))
+}
+
 func (wf *WakuFilterFullNode) onRequest(ctx context.Context) func(network.Stream) {
 	return func(stream network.Stream) {
 		logger := wf.log.With(logging.HostID("peer", stream.Conn().RemotePeer()))
 
-		reader := pbio.NewDelimitedReader(stream, math.MaxInt32)
+		reader := pbio.NewDelimitedReader(stream, wf.readerLimit)
 
 		subscribeRequest := &pb.FilterSubscribeRequest{}
 		err := reader.ReadMsg(subscribeRequest)
 		if err != nil {
-			wf.metrics.RecordError(decodeRPCFailure)
-			logger.Error("reading request", zap.Error(err))
+			err = readError(err, wf.readerLimit)
+			switch {
+			case errors.Is(err, io.EOF):
+				// the stream was closed without sending a request
+				logger.Debug("stream closed before reading request")
+				_ = stream.Close()
+				return
+			case errors.Is(err, ErrMessageTooLarge):
+				logger.Error("reading request", zap.Error(err))
+				wf.recordDecodeFailure(stream.Conn().RemotePeer(), logger)
+			case errors.Is(err, ErrMalformedRPC):
+				logger.Warn("decoding request", zap.Error(err))
+				wf.recordDecodeFailure(stream.Conn().RemotePeer(), logger)
+			default:
+				logger.Debug("reading request", zap.Error(err))
+			}
 			if err := stream.Reset(); err != nil {
 				wf.log.Error("resetting connection", zap.Error(err))
 			}
@@ -112,7 +204,14 @@ func (wf *WakuFilterFullNode) onRequest(ctx context.Context) func(network.Stream
 
 		start := time.Now()
 
-		if err := subscribeRequest.Validate(); err != nil {
+		validate := subscribeRequest.Validate
+		if wf.allowMatchAll && subscribeRequest.IsMatchAll() {
+			validate = subscribeRequest.ValidateMatchAll
+			// Subscribers to all the messages of a pubsub topic are tracked with a wildcard content topic
+			subscribeRequest.ContentTopics = []string{matchAllContentTopic}
+		}
+
+		if err := validate(); err != nil {
 			wf.reply(ctx, stream, subscribeRequest, http.StatusBadRequest, err.Error())
 		} else {
 			switch subscribeRequest.FilterSubscribeType {
@@ -135,6 +234,59 @@ func (wf *WakuFilterFullNode) onRequest(ctx context.Context) func(network.Stream
 	}
 }
 
+// recordDecodeFailure keeps track of the malformed requests received from a peer, disconnecting
+// from it once the maximum number of decode failures is exceeded
+func (wf *WakuFilterFullNode) recordDecodeFailure(peerID peer.ID, logger *zap.Logger) {
+	wf.metrics.RecordError(decodeRPCFailure)
+
+	wf.decodeFailuresLock.Lock()
+	wf.decodeFailures[peerID]++
+	failures := wf.decodeFailures[peerID]
+	wf.decodeFailuresLock.Unlock()
+
+	if wf.maxDecodeFailures <= 0 || failures <= wf.maxDecodeFailures {
+		return
+	}
+
+	logger.Warn("disconnecting from peer due to recurring decode failures", zap.Int("failures", failures))
+	wf.resetDecodeFailures(peerID)
+	if wf.pm != nil {
+		wf.pm.RemovePeer(peerID)
+	}
+	if err := wf.h.Network().ClosePeer(peerID); err != nil {
+		logger.Error("closing connection", zap.Error(err))
+	}
+}
+
+func (wf *WakuFilterFullNode) resetDecodeFailures(peerID peer.ID) {
+	wf.decodeFailuresLock.Lock()
+	defer wf.decodeFailuresLock.Unlock()
+	delete(wf.decodeFailures, peerID)
+}
+
+// DecodeFailures returns the number of malformed requests received from a peer
+func (wf *WakuFilterFullNode) DecodeFailures(peerID peer.ID) int {
+	wf.decodeFailuresLock.Lock()
+	defer wf.decodeFailuresLock.Unlock()
+	return wf.decodeFailures[peerID]
+}
+
+// HasSubscriber returns true if a peer has active filter subscriptions in the full node
+func (wf *WakuFilterFullNode) HasSubscriber(peerID peer.ID) bool {
+	return wf.subscriptions.Has(peerID)
+}
+
+// Subscribers returns a snapshot of the peers subscribed to the full node, with their content
+// topics and the last time they subscribed or pinged
+func (wf *WakuFilterFullNode) Subscribers() []SubscriberInfo {
+	return wf.subscriptions.Subscribers()
+}
+
+// FilterTopicDistribution returns the number of active filter subscribers per pubsub topic
+func (wf *WakuFilterFullNode) FilterTopicDistribution() map[string]int {
+	return wf.subscriptions.TopicDistribution()
+}
+
 func (wf *WakuFilterFullNode) reply(ctx context.Context, stream network.Stream, request *pb.FilterSubscribeRequest, statusCode int, description ...string) {
 	response := &pb.FilterSubscribeResponse{
 		RequestId:  request.RequestId,
@@ -172,27 +324,25 @@ func (wf *WakuFilterFullNode) ping(ctx context.Context, stream network.Stream, r
 }
 
 func (wf *WakuFilterFullNode) subscribe(ctx context.Context, stream network.Stream, request *pb.FilterSubscribeRequest) {
-	if wf.subscriptions.Count() >= wf.maxSubscriptions {
+	peerID := stream.Conn().RemotePeer()
+
+	// Peers that are already subscribed can modify their subscription
+	if !wf.subscriptions.Has(peerID) && wf.subscriptions.Count() >= wf.maxSubscriptions {
+		wf.metrics.RecordError(maxSubscribersFailure)
 		wf.reply(ctx, stream, request, http.StatusServiceUnavailable, "node has reached maximum number of subscriptions")
 		return
 	}
 
-	peerID := stream.Conn().RemotePeer()
-
-	if totalSubs, exists := wf.subscriptions.Get(peerID); exists {
-		ctTotal := 0
-		for _, contentTopicSet := range totalSubs {
-			ctTotal += len(contentTopicSet)
-		}
-
-		if ctTotal+len(request.ContentTopics) > MaxCriteriaPerSubscription {
-			wf.reply(ctx, stream, request, http.StatusServiceUnavailable, "peer has reached maximum number of filter criteria")
-			return
-		}
+	// Content topics the peer is already subscribed to (i.e. a light node repeating a
+	// subscription after reconnecting) are merged into the existing subscription, and
+	// do not count towards the limit
+	err := wf.subscriptions.SetWithLimit(peerID, *request.PubsubTopic, request.ContentTopics, wf.maxCriteriaPerPeer, wf.evictOldestCriteria)
+	if err != nil {
+		wf.metrics.RecordError(maxCriteriaFailure)
+		wf.reply(ctx, stream, request, http.StatusServiceUnavailable, "peer has reached maximum number of filter criteria")
+		return
 	}
 
-	wf.subscriptions.Set(peerID, *request.PubsubTopic, request.ContentTopics)
-
 	wf.metrics.RecordSubscriptions(wf.subscriptions.Count())
 	wf.reply(ctx, stream, request, http.StatusOK)
 }
@@ -234,21 +384,26 @@ func (wf *WakuFilterFullNode) filterListener(ctx context.Context) {
 
 		// Each subscriber is a light node that earlier on invoked
 		// a FilterRequest on this node
-		for subscriber := range wf.subscriptions.Items(pubsubTopic, msg.ContentTopic) {
+		for subscriber := range wf.subscribers(pubsubTopic, msg.ContentTopic) {
 			logger := logger.With(logging.HostID("peer", subscriber))
+			if !wf.allowPush(subscriber) {
+				wf.metrics.RecordError(pushRateLimitFailure)
+				logger.Debug("push rate limit exceeded, dropping message")
+				continue
+			}
+
 			// Do a message push to light node
 			logger.Debug("pushing message to light node")
+			if wf.orderedPush || wf.pushBatchWindow > 0 {
+				wf.enqueuePush(ctx, subscriber, pushItem{envelope: envelope, logger: logger})
+				continue
+			}
+
 			wf.WaitGroup().Add(1)
 			go func(subscriber peer.ID) {
 				defer utils.LogOnPanic()
 				defer wf.WaitGroup().Done()
-				start := time.Now()
-				err := wf.pushMessage(ctx, logger, subscriber, envelope)
-				if err != nil {
-					logger.Error("pushing message", zap.Error(err))
-					return
-				}
-				wf.metrics.RecordPushDuration(time.Since(start))
+				wf.push(ctx, logger, subscriber, envelope)
 			}(subscriber)
 		}
 
@@ -262,19 +417,160 @@ func (wf *WakuFilterFullNode) filterListener(ctx context.Context) {
 	}
 }
 
-func (wf *WakuFilterFullNode) pushMessage(ctx context.Context, logger *zap.Logger, peerID peer.ID, env *protocol.Envelope) error {
-	pubSubTopic := env.PubsubTopic()
-	messagePush := &pb.MessagePush{
-		PubsubTopic: &pubSubTopic,
-		WakuMessage: env.Message(),
+// allowPush determines whether a message can be pushed to a subscriber without exceeding its push rate limit
+func (wf *WakuFilterFullNode) allowPush(subscriber peer.ID) bool {
+	if wf.pushRateLimit == 0 {
+		return true
 	}
 
+	wf.pushLimitersLock.Lock()
+	defer wf.pushLimitersLock.Unlock()
+
+	limiter, ok := wf.pushLimiters[subscriber]
+	if !ok {
+		limiter = rate.NewLimiter(wf.pushRateLimit, wf.pushRateBurst)
+		wf.pushLimiters[subscriber] = limiter
+	}
+
+	return limiter.Allow()
+}
+
+func (wf *WakuFilterFullNode) removePushLimiter(subscriber peer.ID) {
+	wf.pushLimitersLock.Lock()
+	defer wf.pushLimitersLock.Unlock()
+	delete(wf.pushLimiters, subscriber)
+}
+
+// subscribers returns the peers subscribed to a content topic, including those subscribed
+// to all the messages of the pubsub topic
+func (wf *WakuFilterFullNode) subscribers(pubsubTopic string, contentTopic string) PeerSet {
+	result := make(PeerSet)
+	for subscriber := range wf.subscriptions.Items(pubsubTopic, contentTopic) {
+		result[subscriber] = struct{}{}
+	}
+	if wf.allowMatchAll {
+		for subscriber := range wf.subscriptions.Items(pubsubTopic, matchAllContentTopic) {
+			result[subscriber] = struct{}{}
+		}
+	}
+	return result
+}
+
+func (wf *WakuFilterFullNode) push(ctx context.Context, logger *zap.Logger, subscriber peer.ID, envelopes ...*protocol.Envelope) {
+	start := time.Now()
+	err := wf.pushMessage(ctx, logger, subscriber, envelopes...)
+	if err != nil {
+		logger.Error("pushing message", zap.Error(err))
+		return
+	}
+	for range envelopes {
+		wf.metrics.RecordMessagePushed()
+	}
+	wf.metrics.RecordPushDuration(time.Since(start))
+}
+
+// enqueuePush adds a message to the queue of messages to push to a subscriber, starting
+// a worker that pushes them in order if there is none running for this subscriber
+func (wf *WakuFilterFullNode) enqueuePush(ctx context.Context, subscriber peer.ID, item pushItem) {
+	wf.pushQueuesLock.Lock()
+	defer wf.pushQueuesLock.Unlock()
+
+	queue, ok := wf.pushQueues[subscriber]
+	if !ok {
+		queue = make(chan pushItem, pushQueueSize)
+		wf.pushQueues[subscriber] = queue
+		wf.WaitGroup().Add(1)
+		go wf.pushWorker(ctx, subscriber, queue)
+	}
+
+	select {
+	case queue <- item:
+	default:
+		wf.metrics.RecordError(pushQueueFullFailure)
+		item.logger.Debug("push queue is full, dropping message")
+	}
+}
+
+// pushWorker pushes the messages queued for a subscriber in order, one at a time or in batches
+func (wf *WakuFilterFullNode) pushWorker(ctx context.Context, subscriber peer.ID, queue chan pushItem) {
+	defer utils.LogOnPanic()
+	defer wf.WaitGroup().Done()
+
+	idleTimer := time.NewTimer(pushQueueIdleTimeout)
+	defer idleTimer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if dropped := len(queue); dropped > 0 {
+				wf.metrics.RecordMessagesDropped(dropped)
+				wf.log.Debug("stopping push worker, dropping queued messages", logging.HostID("peer", subscriber), zap.Int("dropped", dropped))
+			}
+			return
+		case item := <-queue:
+			for next := &item; next != nil; {
+				var batch []*protocol.Envelope
+				batch, next = wf.collectBatch(ctx, queue, *next)
+				wf.push(ctx, item.logger, subscriber, batch...)
+			}
+			if !idleTimer.Stop() {
+				select {
+				case <-idleTimer.C:
+				default:
+				}
+			}
+			idleTimer.Reset(pushQueueIdleTimeout)
+		case <-idleTimer.C:
+			wf.pushQueuesLock.Lock()
+			if len(queue) == 0 {
+				delete(wf.pushQueues, subscriber)
+				wf.pushQueuesLock.Unlock()
+				return
+			}
+			wf.pushQueuesLock.Unlock()
+			idleTimer.Reset(pushQueueIdleTimeout)
+		}
+	}
+}
+
+// collectBatch waits for the flush window to collect the messages queued for a subscriber after the
+// first one. The message that would exceed the maximum size of the batch is returned separately, so
+// it is pushed in the next batch
+func (wf *WakuFilterFullNode) collectBatch(ctx context.Context, queue chan pushItem, first pushItem) ([]*protocol.Envelope, *pushItem) {
+	batch := []*protocol.Envelope{first.envelope}
+	if wf.pushBatchWindow <= 0 {
+		return batch, nil
+	}
+
+	size := proto.Size(first.envelope.Message())
+	timer := time.NewTimer(wf.pushBatchWindow)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return batch, nil
+		case <-timer.C:
+			return batch, nil
+		case item := <-queue:
+			itemSize := proto.Size(item.envelope.Message())
+			if size+itemSize > wf.pushBatchMaxBytes {
+				return batch, &item
+			}
+			batch = append(batch, item.envelope)
+			size += itemSize
+		}
+	}
+}
+
+// pushMessage writes the messages to a single stream, in order
+func (wf *WakuFilterFullNode) pushMessage(ctx context.Context, logger *zap.Logger, peerID peer.ID, envelopes ...*protocol.Envelope) error {
 	ctx, cancel := context.WithTimeout(ctx, MessagePushTimeout)
 	defer cancel()
 
 	stream, err := wf.h.NewStream(ctx, peerID, FilterPushID_v20beta1)
 	if err != nil {
-		if errors.Is(context.DeadlineExceeded, err) {
+		if errors.Is(err, context.DeadlineExceeded) {
 			wf.metrics.RecordError(pushTimeoutFailure)
 		} else {
 			wf.metrics.RecordError(dialFailure)
@@ -287,18 +583,34 @@ func (wf *WakuFilterFullNode) pushMessage(ctx context.Context, logger *zap.Logge
 	}
 
 	writer := pbio.NewDelimitedWriter(stream)
-	err = writer.WriteMsg(messagePush)
-	if err != nil {
-		if errors.Is(context.DeadlineExceeded, err) {
-			wf.metrics.RecordError(pushTimeoutFailure)
-		} else {
-			wf.metrics.RecordError(writeResponseFailure)
+	for _, env := range envelopes {
+		pubSubTopic := env.PubsubTopic()
+		messagePush := &pb.MessagePush{
+			PubsubTopic: &pubSubTopic,
+			WakuMessage: env.Message(),
 		}
-		logger.Error("pushing messages to peer", zap.Error(err))
-		if err := stream.Reset(); err != nil {
-			wf.log.Error("resetting connection", zap.Error(err))
+
+		err = writer.WriteMsg(messagePush)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				wf.metrics.RecordError(pushTimeoutFailure)
+			} else {
+				wf.metrics.RecordError(writePushFailure)
+			}
+			logger.Error("pushing messages to peer", zap.Error(err))
+			if err := stream.Reset(); err != nil {
+				wf.log.Error("resetting connection", zap.Error(err))
+			}
+			return err
+		}
+	}
+
+	if wf.orderedPush {
+		// Wait for the light node to finish handling the message before pushing the next one
+		if err := stream.CloseWrite(); err == nil {
+			_ = stream.SetReadDeadline(time.Now().Add(MessagePushTimeout))
+			_, _ = io.Copy(io.Discard, stream)
 		}
-		return nil
 	}
 
 	stream.Close()
@@ -311,6 +623,7 @@ func (wf *WakuFilterFullNode) pushMessage(ctx context.Context, logger *zap.Logge
 // Stop unmounts the filter protocol
 func (wf *WakuFilterFullNode) Stop() {
 	wf.CommonService.Stop(func() {
+		wf.h.Network().StopNotify(wf.disconnectNotif)
 		wf.h.RemoveStreamHandler(FilterSubscribeID_v20beta1)
 		wf.msgSub.Unsubscribe()
 	})
@@ -17,10 +17,29 @@ type PeerSet map[peer.ID]struct{}
 
 type PubsubTopics map[string]protocol.ContentTopicSet // pubsubTopic => contentTopics
 
+// SubscriberInfo is a snapshot of the subscriptions of a peer
+type SubscriberInfo struct {
+	PeerID   peer.ID             `json:"peerID"`
+	Topics   map[string][]string `json:"topics"` // pubsubTopic => contentTopics, empty if subscribed to all the messages
+	LastSeen time.Time           `json:"lastSeen"`
+}
+
 var errNotFound = errors.New("not found")
 
+// errMaxCriteria is returned when a subscription would exceed the maximum number of content topics of a peer
+var errMaxCriteria = errors.New("maximum number of criteria reached")
+
 const cleanupInterval = time.Minute
 
+// minCleanupInterval bounds how often subscribers are checked for expiration with very short timeouts
+const minCleanupInterval = 100 * time.Millisecond
+
+// criteria is a content topic a peer is subscribed to
+type criteria struct {
+	pubsubTopic  string
+	contentTopic string
+}
+
 type SubscribersMap struct {
 	sync.RWMutex
 
@@ -28,6 +47,8 @@ type SubscribersMap struct {
 	interestMap map[string]PeerSet // key: sha256(pubsubTopic-contentTopic) => peers
 	timeout     time.Duration
 	lastSeen    map[peer.ID]time.Time
+	order       map[peer.ID][]criteria // criteria of each peer, from the oldest to the newest
+	onExpired   func(peer.ID)
 }
 
 func NewSubscribersMap(timeout time.Duration) *SubscribersMap {
@@ -36,11 +57,31 @@ func NewSubscribersMap(timeout time.Duration) *SubscribersMap {
 		interestMap: make(map[string]PeerSet),
 		timeout:     timeout,
 		lastSeen:    make(map[peer.ID]time.Time),
+		order:       make(map[peer.ID][]criteria),
 	}
 }
 
+// Start removes periodically the subscribers that did not subscribe or ping within the timeout.
+// Subscriptions never expire if the timeout is not positive
 func (sub *SubscribersMap) Start(ctx context.Context) {
-	go sub.cleanUp(ctx, cleanupInterval)
+	if sub.timeout <= 0 {
+		return
+	}
+
+	interval := cleanupInterval
+	if sub.timeout/2 < interval {
+		interval = sub.timeout / 2
+	}
+	if interval < minCleanupInterval {
+		interval = minCleanupInterval
+	}
+	go sub.cleanUp(ctx, interval)
+}
+
+// OnExpired sets a function that is called with each peer whose subscriptions are removed
+// because it did not subscribe or ping within the timeout. It must be set before Start
+func (sub *SubscribersMap) OnExpired(fn func(peerID peer.ID)) {
+	sub.onExpired = fn
 }
 
 func (sub *SubscribersMap) Clear() {
@@ -50,12 +91,65 @@ func (sub *SubscribersMap) Clear() {
 	sub.items = make(map[peer.ID]PubsubTopics)
 	sub.interestMap = make(map[string]PeerSet)
 	sub.lastSeen = make(map[peer.ID]time.Time)
+	sub.order = make(map[peer.ID][]criteria)
 }
 
 func (sub *SubscribersMap) Set(peerID peer.ID, pubsubTopic string, contentTopics []string) {
 	sub.Lock()
 	defer sub.Unlock()
 
+	sub.set(peerID, pubsubTopic, contentTopics)
+}
+
+// SetWithLimit subscribes a peer to content topics like Set, ensuring atomically that the peer does not
+// end up subscribed to more than maxCriteria content topics. Content topics the peer is already subscribed
+// to do not count towards the limit. If evictOldest is true, the content topics the peer subscribed to first
+// are removed to make room for the new ones, otherwise errMaxCriteria is returned. A maxCriteria of 0
+// means there is no limit
+func (sub *SubscribersMap) SetWithLimit(peerID peer.ID, pubsubTopic string, contentTopics []string, maxCriteria int, evictOldest bool) error {
+	sub.Lock()
+	defer sub.Unlock()
+
+	if maxCriteria <= 0 {
+		sub.set(peerID, pubsubTopic, contentTopics)
+		return nil
+	}
+
+	if len(contentTopics) > maxCriteria {
+		return errMaxCriteria
+	}
+
+	toEvict := 0
+	if pubsubTopicMap, exists := sub.items[peerID]; exists {
+		total := 0
+		for _, contentTopicSet := range pubsubTopicMap {
+			total += len(contentTopicSet)
+		}
+
+		newContentTopics := 0
+		for _, c := range contentTopics {
+			if _, ok := pubsubTopicMap[pubsubTopic][c]; !ok {
+				newContentTopics++
+			}
+		}
+
+		if total+newContentTopics > maxCriteria {
+			if !evictOldest {
+				return errMaxCriteria
+			}
+			toEvict = total + newContentTopics - maxCriteria
+		}
+	}
+
+	sub.set(peerID, pubsubTopic, contentTopics)
+
+	// The content topics that were just set are the newest ones, so they are never evicted
+	sub.evictOldest(peerID, toEvict)
+
+	return nil
+}
+
+func (sub *SubscribersMap) set(peerID peer.ID, pubsubTopic string, contentTopics []string) {
 	sub.lastSeen[peerID] = time.Now()
 
 	pubsubTopicMap, ok := sub.items[peerID]
@@ -69,6 +163,11 @@ func (sub *SubscribersMap) Set(peerID peer.ID, pubsubTopic string, contentTopics
 	}
 
 	for _, c := range contentTopics {
+		if _, ok := contentTopicsMap[c]; ok {
+			// Content topics subscribed to again become the newest ones
+			sub.removeFromOrder(peerID, pubsubTopic, c)
+		}
+		sub.order[peerID] = append(sub.order[peerID], criteria{pubsubTopic: pubsubTopic, contentTopic: c})
 		contentTopicsMap[c] = struct{}{}
 	}
 
@@ -114,10 +213,34 @@ func (sub *SubscribersMap) Delete(peerID peer.ID, pubsubTopic string, contentTop
 		return errNotFound
 	}
 
+	// Content topics the peer is not subscribed to are ignored, but at least one of
+	// them must match an existing subscription
+	matched := false
+	for _, c := range contentTopics {
+		if _, ok := contentTopicsMap[c]; ok {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return errNotFound
+	}
+
 	// Updating first the lastSeen since this is a valid activity
 	// (it will still get deleted if all content topics are removed)
 	sub.lastSeen[peerID] = time.Now()
 
+	sub.deleteCriteria(peerID, pubsubTopic, contentTopics)
+
+	return nil
+}
+
+// deleteCriteria removes content topics from the subscription of a peer, removing
+// the peer completely if it is not subscribed to any content topic anymore
+func (sub *SubscribersMap) deleteCriteria(peerID peer.ID, pubsubTopic string, contentTopics []string) {
+	pubsubTopicMap := sub.items[peerID]
+	contentTopicsMap := pubsubTopicMap[pubsubTopic]
+
 	// Removing content topics individually
 	for _, c := range contentTopics {
 		c := c
@@ -125,6 +248,10 @@ func (sub *SubscribersMap) Delete(peerID peer.ID, pubsubTopic string, contentTop
 		sub.removeFromInterestMap(peerID, pubsubTopic, c)
 	}
 
+	for _, c := range contentTopics {
+		sub.removeFromOrder(peerID, pubsubTopic, c)
+	}
+
 	pubsubTopicMap[pubsubTopic] = contentTopicsMap
 
 	// No more content topics available. Removing content topic completely
@@ -137,9 +264,23 @@ func (sub *SubscribersMap) Delete(peerID peer.ID, pubsubTopic string, contentTop
 	if len(sub.items[peerID]) == 0 {
 		delete(sub.items, peerID)
 		delete(sub.lastSeen, peerID)
+		delete(sub.order, peerID)
 	}
+}
 
-	return nil
+// EvictOldest removes the n content topics a peer subscribed to first
+func (sub *SubscribersMap) EvictOldest(peerID peer.ID, n int) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	sub.evictOldest(peerID, n)
+}
+
+func (sub *SubscribersMap) evictOldest(peerID peer.ID, n int) {
+	for i := 0; i < n && len(sub.order[peerID]) != 0; i++ {
+		oldest := sub.order[peerID][0]
+		sub.deleteCriteria(peerID, oldest.pubsubTopic, []string{oldest.contentTopic})
+	}
 }
 
 func (sub *SubscribersMap) deleteAll(peerID peer.ID) error {
@@ -157,6 +298,7 @@ func (sub *SubscribersMap) deleteAll(peerID peer.ID) error {
 
 	delete(sub.items, peerID)
 	delete(sub.lastSeen, peerID)
+	delete(sub.order, peerID)
 
 	return nil
 }
@@ -174,6 +316,7 @@ func (sub *SubscribersMap) RemoveAll() {
 
 	sub.items = make(map[peer.ID]PubsubTopics)
 	sub.lastSeen = make(map[peer.ID]time.Time)
+	sub.order = make(map[peer.ID][]criteria)
 }
 
 func (sub *SubscribersMap) Count() int {
@@ -183,24 +326,62 @@ func (sub *SubscribersMap) Count() int {
 	return len(sub.items)
 }
 
-func (sub *SubscribersMap) Items(pubsubTopic string, contentTopic string) <-chan peer.ID {
-	c := make(chan peer.ID)
+// TopicDistribution returns the number of subscribers interested in each pubsub topic
+func (sub *SubscribersMap) TopicDistribution() map[string]int {
+	sub.RLock()
+	defer sub.RUnlock()
 
-	key := getKey(pubsubTopic, contentTopic)
+	result := make(map[string]int)
+	for _, pubsubTopics := range sub.items {
+		for pubsubTopic := range pubsubTopics {
+			result[pubsubTopic]++
+		}
+	}
 
-	f := func() {
-		defer utils.LogOnPanic()
-		sub.RLock()
-		defer sub.RUnlock()
+	return result
+}
+
+// Subscribers returns a snapshot of the subscriptions of each peer
+func (sub *SubscribersMap) Subscribers() []SubscriberInfo {
+	sub.RLock()
+	defer sub.RUnlock()
 
-		if peers, ok := sub.interestMap[key]; ok {
-			for p := range peers {
-				c <- p
+	result := make([]SubscriberInfo, 0, len(sub.items))
+	for peerID, pubsubTopics := range sub.items {
+		info := SubscriberInfo{
+			PeerID:   peerID,
+			Topics:   make(map[string][]string),
+			LastSeen: sub.lastSeen[peerID],
+		}
+		for pubsubTopic, contentTopics := range pubsubTopics {
+			info.Topics[pubsubTopic] = []string{}
+			for contentTopic := range contentTopics {
+				if contentTopic != matchAllContentTopic {
+					info.Topics[pubsubTopic] = append(info.Topics[pubsubTopic], contentTopic)
+				}
 			}
 		}
-		close(c)
+		result = append(result, info)
+	}
+
+	return result
+}
+
+// Items returns the peers subscribed to a content topic. The subscribers are
+// snapshotted under the read lock, so the lock is not held while the caller
+// pushes messages to them, and the caller can stop reading at any time
+func (sub *SubscribersMap) Items(pubsubTopic string, contentTopic string) <-chan peer.ID {
+	key := getKey(pubsubTopic, contentTopic)
+
+	sub.RLock()
+	defer sub.RUnlock()
+
+	peers := sub.interestMap[key]
+	c := make(chan peer.ID, len(peers))
+	for p := range peers {
+		c <- p
 	}
-	go f()
+	close(c)
 
 	return c
 }
@@ -220,6 +401,19 @@ func (sub *SubscribersMap) removeFromInterestMap(peerID peer.ID, pubsubTopic str
 	_, exists := sub.interestMap[key]
 	if exists {
 		delete(sub.interestMap[key], peerID)
+		if len(sub.interestMap[key]) == 0 {
+			delete(sub.interestMap, key)
+		}
+	}
+}
+
+func (sub *SubscribersMap) removeFromOrder(peerID peer.ID, pubsubTopic string, contentTopic string) {
+	order := sub.order[peerID]
+	for i, c := range order {
+		if c.pubsubTopic == pubsubTopic && c.contentTopic == contentTopic {
+			sub.order[peerID] = append(order[:i], order[i+1:]...)
+			return
+		}
 	}
 }
 
@@ -247,15 +441,24 @@ func (sub *SubscribersMap) cleanUp(ctx context.Context, cleanupInterval time.Dur
 		case <-ctx.Done():
 			return
 		case <-t.C:
+			var expired []peer.ID
 			sub.Lock()
 			for peerID, lastSeen := range sub.lastSeen {
+				// Subscribers that did not subscribe or ping within the timeout are removed
 				elapsedTime := time.Since(lastSeen)
-				if elapsedTime < sub.timeout {
+				if elapsedTime >= sub.timeout {
 					_ = sub.deleteAll(peerID)
+					expired = append(expired, peerID)
 				}
 
 			}
 			sub.Unlock()
+
+			if sub.onExpired != nil {
+				for _, peerID := range expired {
+					sub.onExpired(peerID)
+				}
+			}
 		}
 	}
 }
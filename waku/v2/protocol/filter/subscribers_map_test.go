@@ -2,6 +2,7 @@ package filter
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -107,12 +108,118 @@ func TestRemoveBogus(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestRemoveNoMatch(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	peerId := createPeerID(t)
+
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1"})
+
+	// None of the content topics match, so the subscription is left untouched
+	err := subs.Delete(peerId, PUBSUB_TOPIC, []string{"does not exist"})
+	require.ErrorIs(t, err, errNotFound)
+	require.NotEmpty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic1"))
+
+	err = subs.Delete(createPeerID(t), PUBSUB_TOPIC, []string{"topic1"})
+	require.ErrorIs(t, err, errNotFound)
+
+	// Removing the last content topic drops the subscriber and its interest entries
+	err = subs.Delete(peerId, PUBSUB_TOPIC, []string{"topic1"})
+	require.NoError(t, err)
+	require.False(t, subs.Has(peerId))
+	require.Empty(t, subs.interestMap)
+}
+
+func TestItemsDoesNotHoldLock(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	for i := 0; i < 5; i++ {
+		subs.Set(createPeerID(t), PUBSUB_TOPIC, []string{"topic1"})
+	}
+
+	// Stop reading before all the subscribers are retrieved
+	require.NotEmpty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic1"))
+
+	done := make(chan struct{})
+	go func() {
+		subs.Set(createPeerID(t), PUBSUB_TOPIC, []string{"topic1"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "subscribers map is still locked")
+	}
+
+	count := 0
+	for range subs.Items(PUBSUB_TOPIC, "topic1") {
+		count++
+	}
+	require.Equal(t, 6, count)
+}
+
+func TestEvictOldest(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	peerId := createPeerID(t)
+
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1", "topic2"})
+	subs.Set(peerId, PUBSUB_TOPIC+"2", []string{"topic3"})
+	// Subscribing again makes the content topic the newest one
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1"})
+
+	subs.EvictOldest(peerId, 2)
+	require.Empty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic2"))
+	require.Empty(t, firstSubscriber(subs, PUBSUB_TOPIC+"2", "topic3"))
+	require.NotEmpty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic1"))
+
+	pubsubTopics, ok := subs.Get(peerId)
+	require.True(t, ok)
+	require.Len(t, pubsubTopics, 1)
+
+	subs.EvictOldest(peerId, 5)
+	require.False(t, subs.Has(peerId))
+	require.Empty(t, subs.order)
+}
+
+func TestSetWithLimit(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	peerId := createPeerID(t)
+
+	require.ErrorIs(t, subs.SetWithLimit(peerId, PUBSUB_TOPIC, []string{"topic1", "topic2", "topic3"}, 2, false), errMaxCriteria)
+	require.False(t, subs.Has(peerId))
+
+	require.NoError(t, subs.SetWithLimit(peerId, PUBSUB_TOPIC, []string{"topic1", "topic2"}, 2, false))
+	// Content topics the peer is already subscribed to do not count towards the limit
+	require.NoError(t, subs.SetWithLimit(peerId, PUBSUB_TOPIC, []string{"topic1"}, 2, false))
+	require.ErrorIs(t, subs.SetWithLimit(peerId, PUBSUB_TOPIC, []string{"topic3"}, 2, false), errMaxCriteria)
+	require.Empty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic3"))
+
+	// The oldest content topic is evicted to make room for the new one
+	require.NoError(t, subs.SetWithLimit(peerId, PUBSUB_TOPIC, []string{"topic3"}, 2, true))
+	require.Empty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic2"))
+	require.NotEmpty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic1"))
+	require.NotEmpty(t, firstSubscriber(subs, PUBSUB_TOPIC, "topic3"))
+
+	// No limit
+	require.NoError(t, subs.SetWithLimit(peerId, PUBSUB_TOPIC, []string{"topic4", "topic5", "topic6"}, 0, false))
+	pubsubTopics, ok := subs.Get(peerId)
+	require.True(t, ok)
+	require.Len(t, pubsubTopics[PUBSUB_TOPIC], 5)
+}
+
 func TestCleanup(t *testing.T) {
 	subs := NewSubscribersMap(2 * time.Second)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	var expired []peer.ID
+	var expiredLock sync.Mutex
+	subs.OnExpired(func(peerID peer.ID) {
+		expiredLock.Lock()
+		defer expiredLock.Unlock()
+		expired = append(expired, peerID)
+	})
+
 	go subs.cleanUp(ctx, 500*time.Millisecond)
 
 	peerId := createPeerID(t)
@@ -125,6 +232,12 @@ func TestCleanup(t *testing.T) {
 	_, exists := subs.Get(peerId)
 	require.True(t, exists)
 
+	// Refreshed subscribers are kept
+	time.Sleep(1500 * time.Millisecond)
+	subs.Refresh(peerId)
+	time.Sleep(1500 * time.Millisecond)
+	require.True(t, subs.Has(peerId))
+
 	time.Sleep(2 * time.Second)
 
 	hasSubs = subs.Has(peerId)
@@ -132,4 +245,107 @@ func TestCleanup(t *testing.T) {
 
 	_, exists = subs.Get(peerId)
 	require.False(t, exists)
+
+	expiredLock.Lock()
+	defer expiredLock.Unlock()
+	require.Equal(t, []peer.ID{peerId}, expired)
+}
+
+func TestCleanupWithoutTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscriptions never expire without a timeout
+	subs := NewSubscribersMap(0)
+	subs.Start(ctx)
+
+	peerId := createPeerID(t)
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1"})
+	time.Sleep(200 * time.Millisecond)
+	require.True(t, subs.Has(peerId))
+
+	// Very short timeouts do not make the cleanup panic
+	subs = NewSubscribersMap(time.Nanosecond)
+	subs.Start(ctx)
+
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1"})
+	require.Eventually(t, func() bool {
+		return !subs.Has(peerId)
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+func TestDuplicateSubscription(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	peerId := createPeerID(t)
+
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1", "topic2"})
+	subs.Set(peerId, PUBSUB_TOPIC, []string{"topic1"})
+
+	var peers []peer.ID
+	for p := range subs.Items(PUBSUB_TOPIC, "topic1") {
+		peers = append(peers, p)
+	}
+	require.Equal(t, []peer.ID{peerId}, peers)
+
+	pubsubTopics, ok := subs.Get(peerId)
+	require.True(t, ok)
+	require.Len(t, pubsubTopics[PUBSUB_TOPIC], 2)
+}
+
+func TestSubscribers(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	peerId1 := createPeerID(t)
+	peerId2 := createPeerID(t)
+
+	subs.Set(peerId1, PUBSUB_TOPIC+"1", []string{"topic1"})
+	subs.Set(peerId1, PUBSUB_TOPIC+"2", []string{matchAllContentTopic})
+	subs.Set(peerId2, PUBSUB_TOPIC+"1", []string{"topic2"})
+
+	subscribers := subs.Subscribers()
+	require.Len(t, subscribers, 2)
+	for _, info := range subscribers {
+		require.False(t, info.LastSeen.IsZero())
+		switch info.PeerID {
+		case peerId1:
+			require.Equal(t, map[string][]string{PUBSUB_TOPIC + "1": {"topic1"}, PUBSUB_TOPIC + "2": {}}, info.Topics)
+		case peerId2:
+			require.Equal(t, map[string][]string{PUBSUB_TOPIC + "1": {"topic2"}}, info.Topics)
+		default:
+			require.Fail(t, "unexpected subscriber")
+		}
+	}
+
+	// The snapshot is not affected by later changes
+	err := subs.DeleteAll(peerId1)
+	require.NoError(t, err)
+	require.Len(t, subscribers, 2)
+	require.Len(t, subs.Subscribers(), 1)
+}
+
+func TestTopicDistribution(t *testing.T) {
+	subs := NewSubscribersMap(5 * time.Second)
+	peerId1 := createPeerID(t)
+	peerId2 := createPeerID(t)
+	peerId3 := createPeerID(t)
+
+	subs.Set(peerId1, PUBSUB_TOPIC+"1", []string{"topic1", "topic2"})
+	subs.Set(peerId1, PUBSUB_TOPIC+"2", []string{"topic1"})
+	subs.Set(peerId2, PUBSUB_TOPIC+"1", []string{"topic3"})
+	subs.Set(peerId3, PUBSUB_TOPIC+"1", []string{"topic1"})
+	subs.Set(peerId3, PUBSUB_TOPIC+"3", []string{"topic1"})
+
+	require.Equal(t, map[string]int{
+		PUBSUB_TOPIC + "1": 3,
+		PUBSUB_TOPIC + "2": 1,
+		PUBSUB_TOPIC + "3": 1,
+	}, subs.TopicDistribution())
+
+	err := subs.Delete(peerId3, PUBSUB_TOPIC+"3", []string{"topic1"})
+	require.NoError(t, err)
+	err = subs.DeleteAll(peerId1)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]int{
+		PUBSUB_TOPIC + "1": 2,
+	}, subs.TopicDistribution())
 }
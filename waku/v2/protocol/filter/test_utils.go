@@ -129,11 +129,11 @@ func (s *FilterTestSuite) GetWakuRelay(topic string) FullNodeData {
 	return FullNodeData{relay, sub[0], host, broadcaster, nil}
 }
 
-func (s *FilterTestSuite) GetWakuFilterFullNode(topic string, withRegisterAll bool) FullNodeData {
+func (s *FilterTestSuite) GetWakuFilterFullNode(topic string, withRegisterAll bool, opts ...Option) FullNodeData {
 
 	nodeData := s.GetWakuRelay(topic)
 
-	node2Filter := NewWakuFilterFullNode(timesource.NewDefaultClock(), prometheus.DefaultRegisterer, s.Log)
+	node2Filter := NewWakuFilterFullNode(timesource.NewDefaultClock(), prometheus.DefaultRegisterer, s.Log, opts...)
 	node2Filter.SetHost(nodeData.FullNodeHost)
 
 	var sub *relay.Subscription
@@ -157,7 +157,7 @@ func (s *FilterTestSuite) MakeWakuFilterFullNode(topic string, withRegisterAll b
 	s.FullNodeData = nodeData
 }
 
-func (s *FilterTestSuite) GetWakuFilterLightNode() LightNodeData {
+func (s *FilterTestSuite) GetWakuFilterLightNode(opts ...LightNodeOption) LightNodeData {
 	port, err := tests.FindFreePort(s.T(), "", 5)
 	s.Require().NoError(err)
 
@@ -166,7 +166,7 @@ func (s *FilterTestSuite) GetWakuFilterLightNode() LightNodeData {
 	b := relay.NewBroadcaster(10)
 	s.Require().NoError(b.Start(context.Background()))
 	pm := peermanager.NewPeerManager(5, 5, nil, nil, true, s.Log)
-	filterPush := NewWakuFilterLightNode(b, pm, timesource.NewDefaultClock(), onlinechecker.NewDefaultOnlineChecker(true), prometheus.DefaultRegisterer, s.Log)
+	filterPush := NewWakuFilterLightNode(b, pm, timesource.NewDefaultClock(), onlinechecker.NewDefaultOnlineChecker(true), prometheus.DefaultRegisterer, s.Log, opts...)
 	filterPush.SetHost(host)
 	pm.SetHost(host)
 	return LightNodeData{filterPush, host}
@@ -19,15 +19,31 @@ var storeErrors = prometheus.NewCounterVec(
 	[]string{"error_type"},
 )
 
+var storeActiveQueries = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "waku_store_active_queries",
+		Help: "The number of store queries being processed",
+	})
+
+var storeQueuedQueries = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "waku_store_queued_queries",
+		Help: "The number of store queries waiting to be processed",
+	})
+
 var collectors = []prometheus.Collector{
 	storeQueries,
 	storeErrors,
+	storeActiveQueries,
+	storeQueuedQueries,
 }
 
 // Metrics exposes the functions required to update prometheus metrics for store protocol
 type Metrics interface {
 	RecordQuery()
 	RecordError(err metricsErrCategory)
+	SetActiveQueries(num int)
+	SetQueuedQueries(num int)
 }
 
 type metricsImpl struct {
@@ -48,16 +64,28 @@ func (m *metricsImpl) RecordQuery() {
 type metricsErrCategory string
 
 var (
-	dialFailure          metricsErrCategory = "dial_failure"
-	decodeRPCFailure     metricsErrCategory = "decode_rpc_failure"
-	writeRequestFailure  metricsErrCategory = "write_request_failure"
-	writeResponseFailure metricsErrCategory = "write_response_failure"
-	storeFailure         metricsErrCategory = "store_failure"
-	emptyRPCQueryFailure metricsErrCategory = "empty_rpc_query_failure"
-	peerNotFoundFailure  metricsErrCategory = "peer_not_found_failure"
+	dialFailure             metricsErrCategory = "dial_failure"
+	decodeRPCFailure        metricsErrCategory = "decode_rpc_failure"
+	writeRequestFailure     metricsErrCategory = "write_request_failure"
+	writeResponseFailure    metricsErrCategory = "write_response_failure"
+	storeFailure            metricsErrCategory = "store_failure"
+	emptyRPCQueryFailure    metricsErrCategory = "empty_rpc_query_failure"
+	peerNotFoundFailure     metricsErrCategory = "peer_not_found_failure"
+	serviceBusyFailure      metricsErrCategory = "service_busy_failure"
+	invalidTimeRangeFailure metricsErrCategory = "invalid_time_range_failure"
 )
 
 // RecordError increases the counter for different error types
 func (m *metricsImpl) RecordError(err metricsErrCategory) {
 	storeErrors.WithLabelValues(string(err)).Inc()
 }
+
+// SetActiveQueries sets the number of store queries being processed
+func (m *metricsImpl) SetActiveQueries(num int) {
+	storeActiveQueries.Set(float64(num))
+}
+
+// SetQueuedQueries sets the number of store queries waiting to be processed
+func (m *metricsImpl) SetQueuedQueries(num int) {
+	storeQueuedQueries.Set(float64(num))
+}
@@ -0,0 +1,108 @@
+package legacy_store
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/waku-org/go-waku/waku/v2/protocol/legacy_store/pb"
+)
+
+// ResumeProgress describes how far a history resume has advanced
+type ResumeProgress struct {
+	// PeerID is the store node being queried
+	PeerID peer.ID
+	// Pages is the number of history pages retrieved so far
+	Pages int
+	// Messages is the number of messages retrieved so far
+	Messages int
+	// Bytes is the size of the payloads of the messages retrieved so far
+	Bytes int
+	// Percentage is an estimation of the progress of the resume, based on the time
+	// window covered by the messages retrieved so far
+	Percentage float64
+	// Done indicates that the resume finished
+	Done bool
+}
+
+type resumeParameters struct {
+	progressFn func(ResumeProgress)
+}
+
+// ResumeOption is an optional setting that can be used when resuming the message history
+type ResumeOption func(*resumeParameters)
+
+// WithResumeProgress registers a callback that is invoked every time a page of
+// history is processed during a resume, and once more when the resume finishes
+func WithResumeProgress(fn func(ResumeProgress)) ResumeOption {
+	return func(params *resumeParameters) {
+		params.progressFn = fn
+	}
+}
+
+// resumeProgressTracker keeps track of the progress of a resume across all the peers queried
+type resumeProgressTracker struct {
+	progress   ResumeProgress
+	fn         func(ResumeProgress)
+	numPeers   int
+	peersDone  int
+	startTime  int64
+	endTime    int64
+	oldestSeen int64
+}
+
+func newResumeProgressTracker(fn func(ResumeProgress), numPeers int, query *pb.HistoryQuery) *resumeProgressTracker {
+	return &resumeProgressTracker{
+		fn:        fn,
+		numPeers:  numPeers,
+		startTime: query.GetStartTime(),
+		endTime:   query.GetEndTime(),
+	}
+}
+
+// startPeer resets the time window covered when a new peer is queried
+func (t *resumeProgressTracker) startPeer(peerID peer.ID) {
+	t.progress.PeerID = peerID
+	t.oldestSeen = t.endTime
+}
+
+// pageProcessed updates the progress with a page of history retrieved from the current
+// peer. Pages are retrieved backwards, so the time window covered goes from the oldest
+// message retrieved to the end of the query
+func (t *resumeProgressTracker) pageProcessed(response *pb.HistoryResponse) {
+	t.progress.Pages++
+	for _, msg := range response.Messages {
+		t.progress.Messages++
+		t.progress.Bytes += len(msg.Payload)
+		if msg.GetTimestamp() != 0 && msg.GetTimestamp() < t.oldestSeen {
+			t.oldestSeen = msg.GetTimestamp()
+		}
+	}
+
+	peerProgress := 0.0
+	if window := t.endTime - t.startTime; window > 0 {
+		peerProgress = float64(t.endTime-t.oldestSeen) / float64(window)
+		if peerProgress > 1 {
+			peerProgress = 1
+		}
+	}
+
+	t.progress.Percentage = 100 * (float64(t.peersDone) + peerProgress) / float64(t.numPeers)
+	t.notify()
+}
+
+// peerDone is called once all the history from the current peer was retrieved
+func (t *resumeProgressTracker) peerDone() {
+	t.peersDone++
+	t.progress.Percentage = 100 * float64(t.peersDone) / float64(t.numPeers)
+}
+
+// done is called once the resume finishes
+func (t *resumeProgressTracker) done() {
+	t.progress.Percentage = 100
+	t.progress.Done = true
+	t.notify()
+}
+
+func (t *resumeProgressTracker) notify() {
+	if t.fn != nil {
+		t.fn(t.progress)
+	}
+}
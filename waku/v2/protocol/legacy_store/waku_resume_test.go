@@ -145,6 +145,15 @@ func TestResumeWithListOfPeers(t *testing.T) {
 	allMsgs, err := s2.msgProvider.GetAll()
 	require.NoError(t, err)
 	require.Len(t, allMsgs, 1)
+
+	// The history is only retrieved from the first peer that succeeds
+	var last ResumeProgress
+	_, err = s2.Resume(ctx, "test", []peer.ID{host1.ID(), host1.ID()}, WithResumeProgress(func(p ResumeProgress) {
+		last = p
+	}))
+	require.NoError(t, err)
+	require.True(t, last.Done)
+	require.Equal(t, 1, last.Pages)
 }
 
 func TestResumeWithoutSpecifyingPeer(t *testing.T) {
@@ -186,3 +195,64 @@ func TestResumeWithoutSpecifyingPeer(t *testing.T) {
 	_, err = s2.Resume(ctx, "test", []peer.ID{})
 	require.Error(t, err)
 }
+
+func TestResumeProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	host1, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+
+	s1 := NewWakuStore(MemoryDB(t), nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+	s1.SetHost(host1)
+	err = s1.Start(ctx, relay.NewSubscription(protocol.NewContentFilter(relay.DefaultWakuTopic)))
+	require.NoError(t, err)
+	defer s1.Stop()
+
+	// Enough messages to require several pages of history
+	numMessages := 2*DefaultPageSize + 5
+	now := *utils.GetUnixEpoch()
+	for i := 0; i < numMessages; i++ {
+		wakuMessage := tests.CreateWakuMessage("1", proto.Int64(now+int64(i)+1))
+		_ = s1.storeMessage(protocol.NewEnvelope(wakuMessage, *utils.GetUnixEpoch(), "test"))
+	}
+
+	host2, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+
+	s2 := NewWakuStore(MemoryDB(t), nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+	s2.SetHost(host2)
+	err = s2.Start(ctx, relay.NewSubscription(protocol.NewContentFilter(relay.DefaultWakuTopic)))
+	require.NoError(t, err)
+	defer s2.Stop()
+
+	host2.Peerstore().AddAddr(host1.ID(), tests.GetHostAddress(host1), peerstore.PermanentAddrTTL)
+	err = host2.Peerstore().AddProtocols(host1.ID(), StoreID_v20beta4)
+	require.NoError(t, err)
+
+	var updates []ResumeProgress
+	msgCount, err := s2.Resume(ctx, "test", []peer.ID{host1.ID()}, WithResumeProgress(func(p ResumeProgress) {
+		updates = append(updates, p)
+	}))
+	require.NoError(t, err)
+	require.Equal(t, numMessages, msgCount)
+
+	// One update per page, and a final one once the resume is done
+	require.Len(t, updates, 4)
+	for i := 1; i < len(updates); i++ {
+		require.Equal(t, host1.ID(), updates[i].PeerID)
+		require.GreaterOrEqual(t, updates[i].Messages, updates[i-1].Messages)
+		require.GreaterOrEqual(t, updates[i].Percentage, updates[i-1].Percentage)
+	}
+
+	last := updates[len(updates)-1]
+	require.True(t, last.Done)
+	require.Equal(t, float64(100), last.Percentage)
+	require.Equal(t, 3, last.Pages)
+	require.Equal(t, numMessages, last.Messages)
+	require.Equal(t, numMessages*3, last.Bytes)
+	for _, u := range updates[:len(updates)-1] {
+		require.False(t, u.Done)
+		require.Less(t, u.Percentage, float64(100))
+	}
+}
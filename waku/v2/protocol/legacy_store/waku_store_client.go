@@ -24,6 +24,13 @@ type Query struct {
 	ContentTopics []string
 	StartTime     *int64
 	EndTime       *int64
+
+	// MinVersion restricts the results to messages whose version is greater
+	// or equal than this value. It is not part of the store v2beta4 protocol and
+	// only go-waku store nodes apply it, so the messages with a lower version
+	// returned by other store nodes are discarded by the client. Pages may then
+	// contain fewer messages than requested
+	MinVersion *uint32
 }
 
 // Result represents a valid response from a store node
@@ -341,6 +348,7 @@ func (store *WakuStore) Query(ctx context.Context, query Query, opts ...HistoryR
 			ContentFilters: []*pb.ContentFilter{},
 			StartTime:      query.StartTime,
 			EndTime:        query.EndTime,
+			MinVersion:     query.MinVersion,
 			PagingInfo:     &pb.PagingInfo{},
 		},
 	}
@@ -392,9 +400,17 @@ func (store *WakuStore) Query(ctx context.Context, query Query, opts ...HistoryR
 		return nil, errors.New("invalid cursor")
 	}
 
+	if response.Error == pb.HistoryResponse_SERVICE_UNAVAILABLE {
+		return nil, ErrServiceBusy
+	}
+
+	if response.Error == pb.HistoryResponse_INVALID_TIME_RANGE {
+		return nil, ErrInvalidTimeRange
+	}
+
 	result := &Result{
 		store:    store,
-		Messages: response.Messages,
+		Messages: filterMinVersion(historyRequest.Query, response.Messages),
 		query:    historyRequest.Query,
 		peerID:   params.selectedPeer,
 	}
@@ -406,6 +422,22 @@ func (store *WakuStore) Query(ctx context.Context, query Query, opts ...HistoryR
 	return result, nil
 }
 
+// filterMinVersion discards the messages whose version is lower than the minimum version of
+// the query, as store nodes that do not support it return every message
+func filterMinVersion(query *pb.HistoryQuery, messages []*wpb.WakuMessage) []*wpb.WakuMessage {
+	if query.MinVersion == nil {
+		return messages
+	}
+
+	result := make([]*wpb.WakuMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.GetVersion() >= query.GetMinVersion() {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
 // Find the first message that matches a criteria. criteriaCB is a function that will be invoked for each message and returns true if the message matches the criteria
 func (store *WakuStore) Find(ctx context.Context, query Query, cb CriteriaFN, opts ...HistoryRequestOption) (*wpb.WakuMessage, error) {
 	if cb == nil {
@@ -473,10 +505,18 @@ func (store *WakuStore) Next(ctx context.Context, r *Result) (*Result, error) {
 		return nil, errors.New("invalid cursor")
 	}
 
+	if response.Error == pb.HistoryResponse_SERVICE_UNAVAILABLE {
+		return nil, ErrServiceBusy
+	}
+
+	if response.Error == pb.HistoryResponse_INVALID_TIME_RANGE {
+		return nil, ErrInvalidTimeRange
+	}
+
 	result := &Result{
 		started:  true,
 		store:    store,
-		Messages: response.Messages,
+		Messages: filterMinVersion(historyRequest.Query, response.Messages),
 		query:    historyRequest.Query,
 		peerID:   r.PeerID(),
 	}
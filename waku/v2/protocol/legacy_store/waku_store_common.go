@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/host"
 	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/waku-org/go-waku/waku/v2/peermanager"
+	"github.com/waku-org/go-waku/waku/v2/protocol/legacy_store/pb"
 	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
 	"github.com/waku-org/go-waku/waku/v2/timesource"
 	"go.uber.org/zap"
@@ -32,6 +35,14 @@ var (
 	// ErrFailedToResumeHistory is returned when the node attempted to retrieve historic
 	// messages to fill its own message history but for some reason it failed
 	ErrFailedToResumeHistory = errors.New("failed to resume the history")
+
+	// ErrServiceBusy is returned when the store node is handling too many queries
+	// and could not accept the request
+	ErrServiceBusy = errors.New("store service is busy")
+
+	// ErrInvalidTimeRange is returned when the time range of a query is wider than
+	// the maximum span accepted by the store node
+	ErrInvalidTimeRange = errors.New("query time range exceeds the maximum allowed span")
 )
 
 type WakuSwap interface {
@@ -53,12 +64,43 @@ type WakuStore struct {
 	msgProvider MessageProvider
 	h           host.Host
 	pm          *peermanager.PeerManager
+
+	maxConcurrentQueries int
+	maxQueuedQueries     int
+	querySlots           chan struct{}
+	activeQueries        atomic.Int64
+	queuedQueries        atomic.Int64
+
+	maxQueryTimeRange time.Duration
+}
+
+// Option is an optional setting that can be used to configure the WakuStore
+type Option func(*WakuStore)
+
+// WithQueryConcurrency limits the number of history queries handled at the same time.
+// Up to maxQueued queries will wait for their turn, and any query received once the
+// queue is full is answered with a busy response. A zero maxConcurrent disables the limit
+func WithQueryConcurrency(maxConcurrent int, maxQueued int) Option {
+	return func(store *WakuStore) {
+		store.maxConcurrentQueries = maxConcurrent
+		store.maxQueuedQueries = maxQueued
+	}
+}
+
+// WithMaxQueryTimeRange limits how wide the time range of a single history query can be.
+// Queries whose start and end times are more than maxTimeRange apart are rejected with
+// ErrInvalidTimeRange, so clients have to paginate across smaller windows. Queries
+// missing either bound are not limited. By default no limit is enforced
+func WithMaxQueryTimeRange(maxTimeRange time.Duration) Option {
+	return func(store *WakuStore) {
+		store.maxQueryTimeRange = maxTimeRange
+	}
 }
 
 // NewWakuStore creates a WakuStore using an specific MessageProvider for storing the messages
 // Takes an optional peermanager if WakuStore is being created along with WakuNode.
 // If using libp2p host, then pass peermanager as nil
-func NewWakuStore(p MessageProvider, pm *peermanager.PeerManager, timesource timesource.Timesource, reg prometheus.Registerer, log *zap.Logger) *WakuStore {
+func NewWakuStore(p MessageProvider, pm *peermanager.PeerManager, timesource timesource.Timesource, reg prometheus.Registerer, log *zap.Logger, opts ...Option) *WakuStore {
 	wakuStore := new(WakuStore)
 	wakuStore.msgProvider = p
 	wakuStore.wg = &sync.WaitGroup{}
@@ -67,5 +109,13 @@ func NewWakuStore(p MessageProvider, pm *peermanager.PeerManager, timesource tim
 	wakuStore.pm = pm
 	wakuStore.metrics = newMetrics(reg)
 
+	for _, opt := range opts {
+		opt(wakuStore)
+	}
+
+	if wakuStore.maxConcurrentQueries > 0 {
+		wakuStore.querySlots = make(chan struct{}, wakuStore.maxConcurrentQueries)
+	}
+
 	return wakuStore
 }
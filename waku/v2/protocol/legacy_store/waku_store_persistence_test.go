@@ -5,6 +5,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/persistence"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/timesource"
@@ -41,7 +42,12 @@ func TestStorePersistence(t *testing.T) {
 	require.Len(t, allMsgs, 1)
 	require.True(t, proto.Equal(msg, allMsgs[0].Message))
 
-	// Storing a duplicated message should not crash. It's okay to generate an error log in this case
+	// Storing a duplicated message is ignored
 	err = s1.storeMessage(protocol.NewEnvelope(msg, *utils.GetUnixEpoch(), defaultPubSubTopic))
-	require.Error(t, err)
+	require.ErrorIs(t, err, persistence.ErrDuplicateMessage)
+	require.Equal(t, 1, db.DuplicateMessages())
+
+	allMsgs, err = db.GetAll()
+	require.NoError(t, err)
+	require.Len(t, allMsgs, 1)
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/hex"
 	"errors"
 	"math"
-	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/host"
@@ -13,6 +12,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-msgio/pbio"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/waku-org/go-waku/logging"
 	"github.com/waku-org/go-waku/waku/persistence"
@@ -89,7 +89,7 @@ type Store interface {
 	Query(ctx context.Context, query Query, opts ...HistoryRequestOption) (*Result, error)
 	Find(ctx context.Context, query Query, cb CriteriaFN, opts ...HistoryRequestOption) (*wpb.WakuMessage, error)
 	Next(ctx context.Context, r *Result) (*Result, error)
-	Resume(ctx context.Context, pubsubTopic string, peerList []peer.ID) (int, error)
+	Resume(ctx context.Context, pubsubTopic string, peerList []peer.ID, opts ...ResumeOption) (int, error)
 	Stop()
 }
 
@@ -150,6 +150,9 @@ func (store *WakuStore) storeMessage(env *protocol.Envelope) error {
 	}
 
 	err = store.msgProvider.Put(env)
+	if errors.Is(err, persistence.ErrDuplicateMessage) {
+		return err
+	}
 	if err != nil {
 		store.log.Error("storing message", zap.Error(err))
 		store.metrics.RecordError(storeFailure)
@@ -216,7 +219,19 @@ func (store *WakuStore) onRequest(stream network.Stream) {
 
 	historyResponseRPC := &pb.HistoryRPC{}
 	historyResponseRPC.RequestId = historyRPCRequest.RequestId
-	historyResponseRPC.Response = store.FindMessages(historyRPCRequest.Query)
+
+	if err := store.validateTimeRange(historyRPCRequest.Query); err != nil {
+		logger.Warn("rejecting request", zap.Error(err))
+		store.metrics.RecordError(invalidTimeRangeFailure)
+		historyResponseRPC.Response = &pb.HistoryResponse{Error: pb.HistoryResponse_INVALID_TIME_RANGE}
+	} else if store.acquireQuerySlot() {
+		historyResponseRPC.Response = store.FindMessages(historyRPCRequest.Query)
+		store.releaseQuerySlot()
+	} else {
+		logger.Warn("too many queries, rejecting request")
+		store.metrics.RecordError(serviceBusyFailure)
+		historyResponseRPC.Response = &pb.HistoryResponse{Error: pb.HistoryResponse_SERVICE_UNAVAILABLE}
+	}
 
 	logger = logger.With(zap.Int("messages", len(historyResponseRPC.Response.Messages)))
 	err = writer.WriteMsg(historyResponseRPC)
@@ -233,6 +248,74 @@ func (store *WakuStore) onRequest(stream network.Stream) {
 	stream.Close()
 }
 
+// validateTimeRange checks that the time range of a query does not exceed the maximum span
+func (store *WakuStore) validateTimeRange(query *pb.HistoryQuery) error {
+	if store.maxQueryTimeRange <= 0 {
+		return nil
+	}
+
+	if query.StartTime == nil || query.EndTime == nil {
+		return nil
+	}
+
+	if *query.EndTime-*query.StartTime > store.maxQueryTimeRange.Nanoseconds() {
+		return ErrInvalidTimeRange
+	}
+
+	return nil
+}
+
+// acquireQuerySlot waits until the query can be processed according to the concurrency
+// limit. It returns false if the query could not be queued because the queue is full
+func (store *WakuStore) acquireQuerySlot() bool {
+	if store.querySlots == nil {
+		store.metrics.SetActiveQueries(int(store.activeQueries.Add(1)))
+		return true
+	}
+
+	select {
+	case store.querySlots <- struct{}{}:
+		store.metrics.SetActiveQueries(int(store.activeQueries.Add(1)))
+		return true
+	default:
+	}
+
+	if store.queuedQueries.Add(1) > int64(store.maxQueuedQueries) {
+		store.queuedQueries.Add(-1)
+		return false
+	}
+	store.metrics.SetQueuedQueries(int(store.queuedQueries.Load()))
+
+	defer func() {
+		store.metrics.SetQueuedQueries(int(store.queuedQueries.Add(-1)))
+	}()
+
+	select {
+	case store.querySlots <- struct{}{}:
+		store.metrics.SetActiveQueries(int(store.activeQueries.Add(1)))
+		return true
+	case <-store.ctx.Done():
+		return false
+	}
+}
+
+func (store *WakuStore) releaseQuerySlot() {
+	store.metrics.SetActiveQueries(int(store.activeQueries.Add(-1)))
+	if store.querySlots != nil {
+		<-store.querySlots
+	}
+}
+
+// ActiveQueries returns the number of history queries currently being processed
+func (store *WakuStore) ActiveQueries() int {
+	return int(store.activeQueries.Load())
+}
+
+// QueuedQueries returns the number of history queries waiting to be processed
+func (store *WakuStore) QueuedQueries() int {
+	return int(store.queuedQueries.Load())
+}
+
 // Stop closes the store message channel and removes the protocol stream handler
 func (store *WakuStore) Stop() {
 	if store.cancel == nil {
@@ -262,50 +345,65 @@ type queryLoopCandidateResponse struct {
 	err      error
 }
 
-func (store *WakuStore) queryLoop(ctx context.Context, query *pb.HistoryQuery, candidateList []peer.ID) ([]*queryLoopCandidateResponse, error) {
+func (store *WakuStore) queryLoop(ctx context.Context, query *pb.HistoryQuery, candidateList []peer.ID, progress *resumeProgressTracker) ([]*queryLoopCandidateResponse, error) {
 	err := query.Validate()
 	if err != nil {
 		return nil, err
 	}
 
-	queryWg := sync.WaitGroup{}
-	queryWg.Add(len(candidateList))
-
-	resultChan := make(chan *queryLoopCandidateResponse, len(candidateList))
+	var queryLoopResults []*queryLoopCandidateResponse
 
-	// loops through the candidateList in order and sends the query to each until one of the query gets resolved successfully
-	// returns the number of retrieved messages, or error if all the requests fail
+	// loops through the candidateList in order and sends the query to each, retrieving all the pages of history.
+	// The loop stops at the first peer that returns its history successfully, so the history is not downloaded
+	// again from every candidate
 	for _, peer := range candidateList {
-		func() {
-			defer queryWg.Done()
-
-			historyRequest := &pb.HistoryRPC{
-				RequestId: hex.EncodeToString(protocol.GenerateRequestID()),
-				Query:     query,
-			}
+		result := func() *queryLoopCandidateResponse {
+			progress.startPeer(peer)
+			defer progress.peerDone()
 
 			result := &queryLoopCandidateResponse{
-				peerID: peer,
+				peerID:   peer,
+				response: new(pb.HistoryResponse),
 			}
 
-			response, err := store.queryFrom(ctx, historyRequest, peer)
-			if err != nil {
-				store.log.Error("resuming history", logging.HostID("peer", peer), zap.Error(err))
-				result.err = err
-			} else {
-				result.response = response
+			pageQuery := proto.Clone(query).(*pb.HistoryQuery)
+			for {
+				historyRequest := &pb.HistoryRPC{
+					RequestId: hex.EncodeToString(protocol.GenerateRequestID()),
+					Query:     pageQuery,
+				}
+
+				response, err := store.queryFrom(ctx, historyRequest, peer)
+				if err != nil {
+					store.log.Error("resuming history", logging.HostID("peer", peer), zap.Error(err))
+					result.err = err
+					break
+				}
+
+				if response.GetError() != pb.HistoryResponse_NONE {
+					result.response = response
+					break
+				}
+
+				result.response.Messages = append(result.response.Messages, response.Messages...)
+				progress.pageProcessed(response)
+
+				cursor := response.GetPagingInfo().GetCursor()
+				if cursor == nil || len(response.Messages) == 0 {
+					break
+				}
+
+				pageQuery.PagingInfo.Cursor = cursor
 			}
 
-			resultChan <- result
+			return result
 		}()
-	}
 
-	queryWg.Wait()
-	close(resultChan)
-
-	var queryLoopResults []*queryLoopCandidateResponse
-	for result := range resultChan {
 		queryLoopResults = append(queryLoopResults, result)
+
+		if result.err == nil && result.response.GetError() == pb.HistoryResponse_NONE {
+			break
+		}
 	}
 
 	return queryLoopResults, nil
@@ -330,7 +428,8 @@ func max(x, y int64) int64 {
 // peerList indicates the list of peers to query from. The history is fetched from the first available peer in this list. Such candidates should be found through a discovery method (to be developed).
 // if no peerList is passed, one of the peers in the underlying peer manager unit of the store protocol is picked randomly to fetch the history from. The history gets fetched successfully if the dialed peer has been online during the queried time window.
 // the resume proc returns the number of retrieved messages if no error occurs, otherwise returns the error string
-func (store *WakuStore) Resume(ctx context.Context, pubsubTopic string, peerList []peer.ID) (int, error) {
+// the progress of the resume can be followed with the `WithResumeProgress` option
+func (store *WakuStore) Resume(ctx context.Context, pubsubTopic string, peerList []peer.ID, opts ...ResumeOption) (int, error) {
 	if !store.started {
 		return 0, errors.New("can't resume: store has not started")
 	}
@@ -358,7 +457,14 @@ func (store *WakuStore) Resume(ctx context.Context, pubsubTopic string, peerList
 		return -1, ErrNoPeersAvailable
 	}
 
-	queryLoopResults, err := store.queryLoop(ctx, rpc, peerList)
+	params := new(resumeParameters)
+	for _, opt := range opts {
+		opt(params)
+	}
+
+	progress := newResumeProgressTracker(params.progressFn, len(peerList), rpc)
+
+	queryLoopResults, err := store.queryLoop(ctx, rpc, peerList, progress)
 	if err != nil {
 		store.log.Error("resuming history", zap.Error(err))
 		return -1, ErrFailedToResumeHistory
@@ -366,7 +472,7 @@ func (store *WakuStore) Resume(ctx context.Context, pubsubTopic string, peerList
 
 	msgCount := 0
 	for _, r := range queryLoopResults {
-		if err == nil && r.response.GetError() != pb.HistoryResponse_NONE {
+		if r.err == nil && r.response.GetError() != pb.HistoryResponse_NONE {
 			r.err = errors.New("invalid cursor")
 		}
 
@@ -376,12 +482,15 @@ func (store *WakuStore) Resume(ctx context.Context, pubsubTopic string, peerList
 		}
 
 		for _, msg := range r.response.Messages {
-			if err = store.storeMessage(protocol.NewEnvelope(msg, store.timesource.Now().UnixNano(), pubsubTopic)); err == nil {
+			// Messages that were already stored are skipped by the message provider
+			if storeErr := store.storeMessage(protocol.NewEnvelope(msg, store.timesource.Now().UnixNano(), pubsubTopic)); storeErr == nil {
 				msgCount++
 			}
 		}
 	}
 
+	progress.done()
+
 	store.log.Info("retrieved messages since the last online time", zap.Int("messages", msgCount))
 
 	return msgCount, nil
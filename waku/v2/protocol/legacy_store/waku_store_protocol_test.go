@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/waku-org/go-waku/tests"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
+	storepb "github.com/waku-org/go-waku/waku/v2/protocol/legacy_store/pb"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
 	"github.com/waku-org/go-waku/waku/v2/timesource"
@@ -509,3 +510,152 @@ func TestWakuStoreWithStaticSharding(t *testing.T) {
 	}
 
 }
+
+// slowMessageProvider blocks the queries until it is released
+type slowMessageProvider struct {
+	*persistence.DBStore
+	release chan struct{}
+}
+
+func (p *slowMessageProvider) Query(query *storepb.HistoryQuery) (*storepb.Index, []persistence.StoredMessage, error) {
+	<-p.release
+	return p.DBStore.Query(query)
+}
+
+func TestWakuStoreProtocolQueryConcurrency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	host1, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+
+	provider := &slowMessageProvider{DBStore: MemoryDB(t), release: make(chan struct{})}
+	s1 := NewWakuStore(provider, nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger(), WithQueryConcurrency(1, 1))
+	s1.SetHost(host1)
+	err = s1.Start(ctx, relay.NewSubscription(protocol.NewContentFilter(relay.DefaultWakuTopic)))
+	require.NoError(t, err)
+	defer s1.Stop()
+
+	s2 := NewWakuStore(MemoryDB(t), nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+	host2, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+	s2.SetHost(host2)
+	err = s2.Start(ctx, relay.NewSubscription(protocol.NewContentFilter(relay.DefaultWakuTopic)))
+	require.NoError(t, err)
+	defer s2.Stop()
+
+	host2.Peerstore().AddAddr(host1.ID(), tests.GetHostAddress(host1), peerstore.PermanentAddrTTL)
+	err = host2.Peerstore().AddProtocols(host1.ID(), StoreID_v20beta4)
+	require.NoError(t, err)
+
+	q := Query{
+		PubsubTopic:   "topic1",
+		ContentTopics: []string{"1"},
+	}
+
+	const numQueries = 5
+	results := make(chan error, numQueries)
+	for i := 0; i < numQueries; i++ {
+		go func() {
+			_, err := s2.Query(ctx, q, WithPeer(host1.ID()))
+			results <- err
+		}()
+	}
+
+	// One query is processed, another one is queued and the rest are rejected
+	for i := 0; i < numQueries-2; i++ {
+		select {
+		case err := <-results:
+			require.ErrorIs(t, err, ErrServiceBusy)
+		case <-ctx.Done():
+			require.Fail(t, "expected busy response")
+		}
+	}
+	require.Equal(t, 1, s1.ActiveQueries())
+	require.Equal(t, 1, s1.QueuedQueries())
+
+	close(provider.release)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-results:
+			require.NoError(t, err)
+		case <-ctx.Done():
+			require.Fail(t, "expected query to succeed")
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		return s1.ActiveQueries() == 0 && s1.QueuedQueries() == 0
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+func TestWakuStoreProtocolMaxQueryTimeRange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	host1, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+
+	s1 := NewWakuStore(MemoryDB(t), nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger(), WithMaxQueryTimeRange(24*time.Hour))
+	s1.SetHost(host1)
+	err = s1.Start(ctx, relay.NewSubscription(protocol.NewContentFilter(relay.DefaultWakuTopic)))
+	require.NoError(t, err)
+	defer s1.Stop()
+
+	now := utils.GetUnixEpoch()
+	msg := tests.CreateWakuMessage("1", now)
+	_ = s1.storeMessage(protocol.NewEnvelope(msg, *utils.GetUnixEpoch(), "topic1"))
+
+	s2 := NewWakuStore(MemoryDB(t), nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+	host2, err := libp2p.New(libp2p.DefaultTransports, libp2p.ListenAddrStrings("/ip4/0.0.0.0/tcp/0"))
+	require.NoError(t, err)
+	s2.SetHost(host2)
+	err = s2.Start(ctx, relay.NewSubscription(protocol.NewContentFilter(relay.DefaultWakuTopic)))
+	require.NoError(t, err)
+	defer s2.Stop()
+
+	host2.Peerstore().AddAddr(host1.ID(), tests.GetHostAddress(host1), peerstore.PermanentAddrTTL)
+	err = host2.Peerstore().AddProtocols(host1.ID(), StoreID_v20beta4)
+	require.NoError(t, err)
+
+	// Query within the max span
+	startTime := *now - (time.Hour).Nanoseconds()
+	endTime := *now + (time.Hour).Nanoseconds()
+	result, err := s2.Query(ctx, Query{
+		PubsubTopic:   "topic1",
+		ContentTopics: []string{"1"},
+		StartTime:     &startTime,
+		EndTime:       &endTime,
+	}, WithPeer(host1.ID()))
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+
+	// Query without an end time
+	result, err = s2.Query(ctx, Query{
+		PubsubTopic:   "topic1",
+		ContentTopics: []string{"1"},
+		StartTime:     &startTime,
+	}, WithPeer(host1.ID()))
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+
+	// Query exceeding the max span
+	startTime = *now - (48 * time.Hour).Nanoseconds()
+	_, err = s2.Query(ctx, Query{
+		PubsubTopic:   "topic1",
+		ContentTopics: []string{"1"},
+		StartTime:     &startTime,
+		EndTime:       &endTime,
+	}, WithPeer(host1.ID()))
+	require.ErrorIs(t, err, ErrInvalidTimeRange)
+
+	// Query missing a bound is not limited
+	result, err = s2.Query(ctx, Query{
+		PubsubTopic:   "topic1",
+		ContentTopics: []string{"1"},
+		EndTime:       &endTime,
+	}, WithPeer(host1.ID()))
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+}
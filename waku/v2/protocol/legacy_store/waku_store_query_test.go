@@ -293,3 +293,68 @@ func TestSetMessageProvider(t *testing.T) {
 	require.True(t, proto.Equal(msg, response2.Messages[0]))
 
 }
+
+func TestStoreQueryMinVersion(t *testing.T) {
+	defaultPubSubTopic := "test"
+	defaultContentTopic := "1"
+
+	s := NewWakuStore(MemoryDB(t), nil, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+
+	// Mixed dataset with messages of versions 0, 1 and 2
+	var messages []*wpb.WakuMessage
+	for i := 0; i < 6; i++ {
+		msg := tests.CreateWakuMessage(defaultContentTopic, proto.Int64(int64(i+1)))
+		msg.Version = proto.Uint32(uint32(i % 3))
+		messages = append(messages, msg)
+		_ = s.storeMessage(protocol.NewEnvelope(msg, *utils.GetUnixEpoch(), defaultPubSubTopic))
+	}
+
+	query := &pb.HistoryQuery{
+		ContentFilters: []*pb.ContentFilter{
+			{
+				ContentTopic: defaultContentTopic,
+			},
+		},
+	}
+
+	// Without a version filter all messages are returned
+	response := s.FindMessages(query)
+	require.Len(t, response.Messages, 6)
+
+	query.MinVersion = proto.Uint32(1)
+	response = s.FindMessages(query)
+	require.Len(t, response.Messages, 4)
+	for _, msg := range response.Messages {
+		require.GreaterOrEqual(t, msg.GetVersion(), uint32(1))
+	}
+
+	query.MinVersion = proto.Uint32(2)
+	response = s.FindMessages(query)
+	require.Len(t, response.Messages, 2)
+	require.True(t, proto.Equal(messages[2], response.Messages[0]))
+	require.True(t, proto.Equal(messages[5], response.Messages[1]))
+
+	query.MinVersion = proto.Uint32(3)
+	response = s.FindMessages(query)
+	require.Len(t, response.Messages, 0)
+}
+
+func TestFilterMinVersion(t *testing.T) {
+	var messages []*wpb.WakuMessage
+	for i := 0; i < 3; i++ {
+		msg := tests.CreateWakuMessage("1", proto.Int64(int64(i+1)))
+		msg.Version = proto.Uint32(uint32(i))
+		messages = append(messages, msg)
+	}
+
+	// Without a version filter the messages are kept
+	query := &pb.HistoryQuery{}
+	require.Len(t, filterMinVersion(query, messages), 3)
+
+	// Messages below the minimum version returned by store nodes that ignore it are discarded
+	query.MinVersion = proto.Uint32(1)
+	result := filterMinVersion(query, messages)
+	require.Len(t, result, 2)
+	require.True(t, proto.Equal(messages[1], result[0]))
+	require.True(t, proto.Equal(messages[2], result[1]))
+}
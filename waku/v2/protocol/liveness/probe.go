@@ -0,0 +1,52 @@
+package liveness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
+	msmux "github.com/multiformats/go-multistream"
+)
+
+// ErrProtocolNotServed indicates that the peer is alive but does not serve the protocol anymore
+var ErrProtocolNotServed = errors.New("protocol not served by peer")
+
+// Probe checks within the timeout that a peer is responsive and still serves a protocol.
+// A stream is opened and the protocol is negotiated with the peer, then the stream is reset
+// without sending a request, so it works with any service protocol and does not require
+// support from the peer beyond the protocol itself
+func Probe(ctx context.Context, h host.Host, peerID peer.ID, proto libp2pProtocol.ID, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := h.Connect(ctx, peer.AddrInfo{ID: peerID}); err != nil {
+		return err
+	}
+
+	// The stream is negotiated here instead of using the host, which skips the negotiation
+	// round trip for the protocols already known to be supported by the peer
+	stream, err := h.Network().NewStream(ctx, peerID)
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+
+	err = msmux.SelectProtoOrFail(proto, stream)
+	_ = stream.Reset()
+	if err != nil {
+		var notSupported msmux.ErrNotSupported[libp2pProtocol.ID]
+		if errors.As(err, &notSupported) {
+			return fmt.Errorf("%w: %s", ErrProtocolNotServed, proto)
+		}
+		return err
+	}
+
+	return nil
+}
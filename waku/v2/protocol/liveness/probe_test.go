@@ -0,0 +1,58 @@
+package liveness
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	libp2pProtocol "github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/tests"
+)
+
+const testServiceID = libp2pProtocol.ID("/vac/waku/test-service/1.0.0")
+
+func makeHost(t *testing.T) host.Host {
+	port, err := tests.FindFreePort(t, "", 5)
+	require.NoError(t, err)
+	h, err := tests.MakeHost(context.Background(), port, rand.Reader)
+	require.NoError(t, err)
+	return h
+}
+
+func TestProbe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := makeHost(t)
+	defer client.Close()
+
+	// Live service peer
+	service := makeHost(t)
+	defer service.Close()
+	service.SetStreamHandler(testServiceID, func(s network.Stream) { s.Close() })
+
+	client.Peerstore().AddAddrs(service.ID(), service.Addrs(), peerstore.PermanentAddrTTL)
+	require.NoError(t, Probe(ctx, client, service.ID(), testServiceID, time.Second))
+
+	// The protocol is negotiated even if the peerstore already knows it is supported
+	require.NoError(t, client.Peerstore().AddProtocols(service.ID(), testServiceID))
+	service.RemoveStreamHandler(testServiceID)
+	require.ErrorIs(t, Probe(ctx, client, service.ID(), testServiceID, time.Second), ErrProtocolNotServed)
+
+	// Dead service peer
+	dead := makeHost(t)
+	dead.SetStreamHandler(testServiceID, func(s network.Stream) { s.Close() })
+	client.Peerstore().AddAddrs(dead.ID(), dead.Addrs(), peerstore.PermanentAddrTTL)
+	require.NoError(t, dead.Close())
+
+	start := time.Now()
+	err := Probe(ctx, client, dead.ID(), testServiceID, time.Second)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrProtocolNotServed)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
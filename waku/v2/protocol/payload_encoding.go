@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Encodings of the payload that can be specified in the last segment of a content topic
+const (
+	EncodingProto = "proto"
+	EncodingJSON  = "json"
+	EncodingRLP   = "rlp"
+)
+
+// ErrEncodingMismatch is returned when a payload can not be decoded with the encoding specified in its content topic
+var ErrEncodingMismatch = errors.New("payload does not match content topic encoding")
+
+// ContentTopicEncoding extracts the encoding segment from a content topic that follows the
+// format `/{application-name}/{version}/{content-topic-name}/{encoding}`
+func ContentTopicEncoding(contentTopic string) (string, error) {
+	ct, err := StringToContentTopic(contentTopic)
+	if err != nil {
+		return "", err
+	}
+	return ct.Encoding, nil
+}
+
+// ValidatePayloadEncoding verifies that a payload can be decoded with the specified encoding.
+// Encodings other than proto, json and rlp can not be verified and are always considered valid
+func ValidatePayloadEncoding(encoding string, payload []byte) error {
+	var valid bool
+	switch encoding {
+	case EncodingProto:
+		valid = isValidProtobuf(payload)
+	case EncodingJSON:
+		valid = json.Valid(payload)
+	case EncodingRLP:
+		valid = isValidRLP(payload)
+	default:
+		return nil
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: %s", ErrEncodingMismatch, encoding)
+	}
+
+	return nil
+}
+
+func isValidProtobuf(b []byte) bool {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || num <= 0 {
+			return false
+		}
+		b = b[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+	}
+	return true
+}
+
+func isValidRLP(b []byte) bool {
+	for len(b) > 0 {
+		_, _, rest, err := rlp.Split(b)
+		if err != nil {
+			return false
+		}
+		b = rest
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestContentTopicEncoding(t *testing.T) {
+	encoding, err := ContentTopicEncoding("/toychat/2/huilong/proto")
+	require.NoError(t, err)
+	require.Equal(t, EncodingProto, encoding)
+
+	encoding, err = ContentTopicEncoding("/0/toychat/2/huilong/json")
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, encoding)
+
+	_, err = ContentTopicEncoding("/toychat/2/huilong")
+	require.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = ContentTopicEncoding("/toychat/2/huilong/")
+	require.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = ContentTopicEncoding("toychat")
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestValidatePayloadEncoding(t *testing.T) {
+	protoPayload, err := proto.Marshal(&pb.WakuMessage{Payload: []byte{1, 2, 3}, ContentTopic: "abc"})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePayloadEncoding(EncodingProto, protoPayload))
+	require.ErrorIs(t, ValidatePayloadEncoding(EncodingProto, []byte{0xff, 0xff, 0xff}), ErrEncodingMismatch)
+
+	require.NoError(t, ValidatePayloadEncoding(EncodingJSON, []byte(`{"a": 1}`)))
+	require.ErrorIs(t, ValidatePayloadEncoding(EncodingJSON, []byte(`{"a": 1`)), ErrEncodingMismatch)
+
+	rlpPayload, err := rlp.EncodeToBytes([]string{"a", "b"})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePayloadEncoding(EncodingRLP, rlpPayload))
+	require.ErrorIs(t, ValidatePayloadEncoding(EncodingRLP, []byte{0xf8}), ErrEncodingMismatch)
+
+	// Unknown encodings can't be verified
+	require.NoError(t, ValidatePayloadEncoding("unknown", []byte{0xff}))
+}
@@ -109,7 +109,6 @@ func (w *WakuRelay) defaultPubsubOptions() []pubsub.Option {
 			},
 		),
 		pubsub.WithGossipSubParams(cfg),
-		pubsub.WithFloodPublish(true),
 		pubsub.WithSeenMessagesTTL(2 * time.Minute),
 		pubsub.WithPeerScore(w.peerScoreParams, w.peerScoreThresholds),
 		pubsub.WithPeerScoreInspect(w.peerScoreInspector, 6*time.Second),
@@ -29,16 +29,26 @@ var pubsubTopics = prometheus.NewGauge(
 		Help: "Number of PubSub Topics node is subscribed to",
 	})
 
+var payloadEncodingMismatches = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "waku_relay_payload_encoding_mismatches",
+		Help: "The number of messages whose payload does not match the encoding of their content topic",
+	},
+	[]string{"encoding"},
+)
+
 var collectors = []prometheus.Collector{
 	messages,
 	messageSize,
 	pubsubTopics,
+	payloadEncodingMismatches,
 }
 
 // Metrics exposes the functions required to update prometheus metrics for relay protocol
 type Metrics interface {
 	RecordMessage(envelope *waku_proto.Envelope)
 	SetPubSubTopics(int)
+	RecordPayloadEncodingMismatch(encoding string)
 }
 
 type metricsImpl struct {
@@ -70,3 +80,8 @@ func (m *metricsImpl) RecordMessage(envelope *waku_proto.Envelope) {
 func (m *metricsImpl) SetPubSubTopics(size int) {
 	pubsubTopics.Set(float64(size))
 }
+
+// RecordPayloadEncodingMismatch is used to increase the counter for messages whose payload can't be decoded with the encoding of their content topic
+func (m *metricsImpl) RecordPayloadEncodingMismatch(encoding string) {
+	payloadEncodingMismatches.WithLabelValues(encoding).Inc()
+}
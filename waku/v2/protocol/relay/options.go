@@ -1,9 +1,14 @@
 package relay
 
-import pubsub "github.com/libp2p/go-libp2p-pubsub"
+import (
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
 
 type publishParameters struct {
 	pubsubTopic string
+	loopback    bool
 }
 
 // PublishOption is the type of options accepted when publishing WakuMessages
@@ -23,9 +28,22 @@ func WithDefaultPubsubTopic() PublishOption {
 	}
 }
 
+// WithLocalLoopback is used to indicate that, if there are not enough peers to publish the message via gossipsub,
+// the message should still be delivered to the node's own subscribers
+func WithLocalLoopback() PublishOption {
+	return func(params *publishParameters) {
+		params.loopback = true
+	}
+}
+
 type relayParameters struct {
-	pubsubOpts      []pubsub.Option
-	maxMsgSizeBytes int
+	pubsubOpts            []pubsub.Option
+	maxMsgSizeBytes       int
+	floodPublish          bool
+	checkPayloadEncoding  bool
+	strictPayloadEncoding bool
+	maxMessageAge         time.Duration
+	maxFutureDrift        time.Duration
 }
 
 type RelayOption func(*relayParameters)
@@ -45,8 +63,41 @@ func WithMaxMsgSize(maxMsgSizeBytes int) RelayOption {
 	}
 }
 
+// WithFloodPublish indicates whether messages published by this node are sent to every peer subscribed
+// to the pubsub topic instead of only to the mesh peers. This reduces the latency for a message to reach
+// the network, which is useful in small networks, at the cost of a higher bandwidth usage for the
+// publisher, since each message is sent to all the topic peers. Enabled by default
+func WithFloodPublish(floodPublish bool) RelayOption {
+	return func(params *relayParameters) {
+		params.floodPublish = floodPublish
+	}
+}
+
+// WithPayloadEncodingCheck verifies that the payload of the messages received can be decoded
+// using the encoding specified in their content topic (proto, json or rlp). Mismatches are logged
+// and counted in a metric. If strict is true, messages that do not match are also rejected
+func WithPayloadEncodingCheck(strict bool) RelayOption {
+	return func(params *relayParameters) {
+		params.checkPayloadEncoding = true
+		params.strictPayloadEncoding = strict
+	}
+}
+
+// WithMessageAgeBounds specifies the window of timestamps accepted when publishing a message
+// with a timestamp set by the caller. Messages older than maxMessageAge, or with a timestamp
+// more than maxFutureDrift ahead of the current time are rejected. A zero value disables the
+// corresponding check. Messages published without a timestamp are always timestamped with the
+// current time
+func WithMessageAgeBounds(maxMessageAge time.Duration, maxFutureDrift time.Duration) RelayOption {
+	return func(params *relayParameters) {
+		params.maxMessageAge = maxMessageAge
+		params.maxFutureDrift = maxFutureDrift
+	}
+}
+
 func defaultOptions() []RelayOption {
 	return []RelayOption{
 		WithMaxMsgSize(defaultMaxMsgSizeBytes),
+		WithFloodPublish(true),
 	}
 }
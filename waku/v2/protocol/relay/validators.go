@@ -13,6 +13,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 
 	"github.com/waku-org/go-waku/waku/v2/hash"
+	waku_proto "github.com/waku-org/go-waku/waku/v2/protocol"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/timesource"
 	"go.uber.org/zap"
@@ -65,18 +66,45 @@ func (w *WakuRelay) topicValidator(topic string) func(ctx context.Context, peerI
 			return false
 		}
 
-		w.topicValidatorMutex.RLock()
-		validators := w.topicValidators[topic]
-		validators = append(validators, w.defaultTopicValidators...)
-		w.topicValidatorMutex.RUnlock()
-		exists := len(validators) > 0
-
-		if exists {
-			for _, v := range validators {
-				if !v(ctx, msg, topic) {
-					return false
-				}
-			}
+		return w.validateMessage(ctx, msg, topic)
+	}
+}
+
+// validateMessage runs the default and topic specific validators against a message
+func (w *WakuRelay) validateMessage(ctx context.Context, msg *pb.WakuMessage, topic string) bool {
+	w.topicValidatorMutex.RLock()
+	validators := w.topicValidators[topic]
+	validators = append(validators, w.defaultTopicValidators...)
+	w.topicValidatorMutex.RUnlock()
+
+	for _, v := range validators {
+		if !v(ctx, msg, topic) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// payloadEncodingValidator checks that a message payload matches the encoding of its content topic.
+// Encrypted payloads (version other than 0) are not checked, since the encoding applies to the plaintext
+func (w *WakuRelay) payloadEncodingValidator(strict bool) validatorFn {
+	return func(ctx context.Context, msg *pb.WakuMessage, topic string) bool {
+		if msg.GetVersion() != 0 {
+			return true
+		}
+
+		encoding, err := waku_proto.ContentTopicEncoding(msg.ContentTopic)
+		if err != nil {
+			// Content topic does not follow the structured format, so there's no encoding to check
+			return true
+		}
+
+		err = waku_proto.ValidatePayloadEncoding(encoding, msg.Payload)
+		if err != nil {
+			w.metrics.RecordPayloadEncodingMismatch(encoding)
+			w.log.Debug("payload does not match content topic encoding", zap.String("pubsubTopic", topic), zap.String("contentTopic", msg.ContentTopic))
+			return !strict
 		}
 
 		return true
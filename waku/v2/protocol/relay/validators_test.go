@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
+	"github.com/waku-org/go-waku/waku/v2/utils"
 	proto "google.golang.org/protobuf/proto"
 )
 
@@ -77,3 +79,19 @@ func TestMsgHash(t *testing.T) {
 	result = myValidator(context.Background(), msg, protectedPubSubTopic)
 	require.False(t, result)
 }
+
+func TestPayloadEncodingValidator(t *testing.T) {
+	w := &WakuRelay{log: utils.Logger(), metrics: newMetrics(prometheus.NewRegistry(), utils.Logger())}
+	validator := w.payloadEncodingValidator(true)
+
+	contentTopic := "/app/1/chat/json"
+
+	require.True(t, validator(context.Background(), &pb.WakuMessage{ContentTopic: contentTopic, Payload: []byte(`{"a": 1}`)}, "topic"))
+	require.False(t, validator(context.Background(), &pb.WakuMessage{ContentTopic: contentTopic, Payload: []byte(`{"a": 1`)}, "topic"))
+
+	// Encrypted payloads are not checked
+	require.True(t, validator(context.Background(), &pb.WakuMessage{ContentTopic: contentTopic, Payload: []byte{0xff, 0x01}, Version: proto.Uint32(1)}, "topic"))
+
+	// Mismatches are counted in both modes, but only the strict validator rejects the message
+	require.True(t, w.payloadEncodingValidator(false)(context.Background(), &pb.WakuMessage{ContentTopic: contentTopic, Payload: []byte(`{"a": 1`)}, "topic"))
+}
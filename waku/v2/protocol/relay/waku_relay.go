@@ -33,6 +33,13 @@ const defaultMaxMsgSizeBytes = 150 * 1024
 // DefaultWakuTopic is the default pubsub topic used across all Waku protocols
 var DefaultWakuTopic string = waku_proto.DefaultPubsubTopic{}.String()
 
+var (
+	// ErrMessageTooOld is returned when publishing a message whose timestamp is older than the maximum message age
+	ErrMessageTooOld = waku_proto.ErrMessageTooOld
+	// ErrFutureMessage is returned when publishing a message whose timestamp is too far in the future
+	ErrFutureMessage = waku_proto.ErrFutureMessage
+)
+
 // WakuRelay is the implementation of the Waku Relay protocol
 type WakuRelay struct {
 	host                host.Host
@@ -97,6 +104,11 @@ func NewWakuRelay(bcaster Broadcaster, minPeersToPublish int, timesource timesou
 	for _, opt := range options {
 		opt(w.relayParams)
 	}
+
+	if w.relayParams.checkPayloadEncoding {
+		w.RegisterDefaultValidator(w.payloadEncodingValidator(w.relayParams.strictPayloadEncoding))
+	}
+
 	w.log.Info("relay config", zap.Int("max-msg-size-bytes", w.relayParams.maxMsgSizeBytes),
 		zap.Int("min-peers-to-publish", w.minPeersToPublish))
 	return w
@@ -133,7 +145,9 @@ func (w *WakuRelay) start() error {
 	if w.bcaster == nil {
 		return errors.New("broadcaster not specified for relay")
 	}
-	ps, err := pubsub.NewGossipSub(w.Context(), w.host, w.relayParams.pubsubOpts...)
+	// Options set with WithPubSubOptions are applied last so they take precedence
+	opts := append([]pubsub.Option{pubsub.WithFloodPublish(w.relayParams.floodPublish)}, w.relayParams.pubsubOpts...)
+	ps, err := pubsub.NewGossipSub(w.Context(), w.host, opts...)
 	if err != nil {
 		return err
 	}
@@ -254,7 +268,9 @@ func (w *WakuRelay) subscribeToPubsubTopic(topic string) (*pubsubTopicSubscripti
 
 // Publish is used to broadcast a WakuMessage to a pubsub topic. The pubsubTopic is derived from contentTopic
 // specified in the message via autosharding. To publish to a specific pubsubTopic, the `WithPubSubTopic` option should
-// be provided
+// be provided. A timestamp set by the caller is preserved, and the message is rejected with ErrMessageTooOld or
+// ErrFutureMessage if it is outside of the configured age bounds. Messages without timestamp are published with
+// the current time, without modifying the message passed by the caller
 func (w *WakuRelay) Publish(ctx context.Context, message *pb.WakuMessage, opts ...PublishOption) (pb.MessageHash, error) {
 	// Publish a `WakuMessage` to a PubSub topic.
 	if w.pubsub == nil {
@@ -270,6 +286,13 @@ func (w *WakuRelay) Publish(ctx context.Context, message *pb.WakuMessage, opts .
 		return pb.MessageHash{}, err
 	}
 
+	if message.GetTimestamp() == 0 {
+		message = proto.Clone(message).(*pb.WakuMessage)
+		message.Timestamp = proto.Int64(w.timesource.Now().UnixNano())
+	} else if err := waku_proto.CheckTimestampBounds(message.GetTimestamp(), w.timesource.Now(), w.relayParams.maxMessageAge, w.relayParams.maxFutureDrift); err != nil {
+		return pb.MessageHash{}, err
+	}
+
 	params := new(publishParameters)
 	for _, opt := range opts {
 		opt(params)
@@ -286,7 +309,8 @@ func (w *WakuRelay) Publish(ctx context.Context, message *pb.WakuMessage, opts .
 		}
 	}
 
-	if !w.EnoughPeersToPublishToTopic(params.pubsubTopic) {
+	enoughPeers := w.EnoughPeersToPublishToTopic(params.pubsubTopic)
+	if !enoughPeers && !params.loopback {
 		return pb.MessageHash{}, errors.New("not enough peers to publish")
 	}
 
@@ -294,6 +318,10 @@ func (w *WakuRelay) Publish(ctx context.Context, message *pb.WakuMessage, opts .
 		return pb.MessageHash{}, errors.New("cannot publish to unsubscribed topic")
 	}
 
+	if !enoughPeers {
+		return w.publishLocally(ctx, message, params.pubsubTopic)
+	}
+
 	w.topicsMutex.Lock()
 	defer w.topicsMutex.Unlock()
 
@@ -323,6 +351,32 @@ func (w *WakuRelay) Publish(ctx context.Context, message *pb.WakuMessage, opts .
 	return hash, nil
 }
 
+// publishLocally delivers a message to the node's own subscribers without relying on gossipsub
+func (w *WakuRelay) publishLocally(ctx context.Context, message *pb.WakuMessage, pubsubTopic string) (pb.MessageHash, error) {
+	out, err := proto.Marshal(message)
+	if err != nil {
+		return pb.MessageHash{}, err
+	}
+
+	if len(out) > w.relayParams.maxMsgSizeBytes {
+		return pb.MessageHash{}, errors.New("message size exceeds gossipsub max message size")
+	}
+
+	if !w.validateMessage(ctx, message, pubsubTopic) {
+		return pb.MessageHash{}, errors.New("message failed validation")
+	}
+
+	envelope := waku_proto.NewEnvelope(message, w.timesource.Now().UnixNano(), pubsubTopic)
+	w.metrics.RecordMessage(envelope)
+	w.bcaster.Submit(envelope)
+
+	hash := envelope.Hash()
+
+	w.logMessages.Debug("waku.relay published locally", zap.String("pubsubTopic", pubsubTopic), logging.Hash(hash), zap.Int64("publishTime", w.timesource.Now().UnixNano()), zap.Int("payloadSizeBytes", len(message.Payload)))
+
+	return hash, nil
+}
+
 func (w *WakuRelay) getSubscription(contentFilter waku_proto.ContentFilter) (*Subscription, error) {
 	w.topicsMutex.RLock()
 	defer w.topicsMutex.RUnlock()
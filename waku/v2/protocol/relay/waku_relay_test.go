@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	pubsub_pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peerstore"
@@ -18,6 +19,7 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/timesource"
 	"github.com/waku-org/go-waku/waku/v2/utils"
+	"google.golang.org/protobuf/proto"
 )
 
 const defaultTestPubSubTopic = "/waku/2/go/relay/test"
@@ -483,3 +485,192 @@ func TestWakuRelayStaticSharding(t *testing.T) {
 	tests.WaitForMsg(t, 2*time.Second, &wg, subs1[0].Ch)
 
 }
+
+func TestWakuRelayLocalLoopback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	testTopic := defaultTestPubSubTopic
+
+	port, err := tests.FindFreePort(t, "", 5)
+	require.NoError(t, err)
+
+	host, err := tests.MakeHost(context.Background(), port, rand.Reader)
+	require.NoError(t, err)
+	bcaster := NewBroadcaster(10)
+	relay := NewWakuRelay(bcaster, 1, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+	relay.SetHost(host)
+	err = relay.Start(context.Background())
+	require.NoError(t, err)
+
+	err = bcaster.Start(context.Background())
+	require.NoError(t, err)
+	defer relay.Stop()
+
+	subs, err := relay.subscribe(context.Background(), protocol.NewContentFilter(testTopic, defaultTestContentTopic))
+	require.NoError(t, err)
+
+	msg := tests.CreateWakuMessage(defaultTestContentTopic, utils.GetUnixEpoch(), "test_payload")
+
+	// No peers are connected, so publishing via gossipsub should fail
+	_, err = relay.Publish(ctx, msg, WithPubSubTopic(testTopic))
+	require.Error(t, err)
+
+	// With loopback the message is delivered to the local subscription
+	hash, err := relay.Publish(ctx, msg, WithPubSubTopic(testTopic), WithLocalLoopback())
+	require.NoError(t, err)
+
+	select {
+	case env := <-subs[0].Ch:
+		require.Equal(t, hash, env.Hash())
+		require.Equal(t, testTopic, env.PubsubTopic())
+	case <-ctx.Done():
+		require.Fail(t, "message was not delivered locally")
+	}
+}
+
+func TestWakuRelayFloodPublish(t *testing.T) {
+	testTopic := defaultTestPubSubTopic
+
+	// Flood publishing is enabled by default
+	bcaster := NewBroadcaster(10)
+	relay := NewWakuRelay(bcaster, 0, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+	require.True(t, relay.relayParams.floodPublish)
+
+	// The heartbeat is delayed so the mesh is never formed. Without flood publishing,
+	// gossipsub only sends the messages published by a node to its mesh peers, so
+	// they only reach the other nodes when flood publishing is enabled
+	gossipSubParams := pubsub.DefaultGossipSubParams()
+	gossipSubParams.HeartbeatInitialDelay = time.Hour
+	gossipSubParams.HeartbeatInterval = time.Hour
+
+	for _, floodPublish := range []bool{true, false} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		var relays []*WakuRelay
+		var hosts []host.Host
+		var subs []*Subscription
+		for i := 0; i < 3; i++ {
+			port, err := tests.FindFreePort(t, "", 5)
+			require.NoError(t, err)
+
+			host, err := tests.MakeHost(context.Background(), port, rand.Reader)
+			require.NoError(t, err)
+			bcaster := NewBroadcaster(10)
+			relay := NewWakuRelay(bcaster, 0, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger(),
+				WithFloodPublish(floodPublish), WithPubSubOptions([]pubsub.Option{pubsub.WithGossipSubParams(gossipSubParams)}))
+			require.Equal(t, floodPublish, relay.relayParams.floodPublish)
+			relay.SetHost(host)
+			err = relay.Start(context.Background())
+			require.NoError(t, err)
+
+			err = bcaster.Start(context.Background())
+			require.NoError(t, err)
+
+			// Subscribing before connecting the nodes keeps the mesh empty, since
+			// the mesh peers are only picked when joining the topic or in the heartbeat
+			s, err := relay.subscribe(context.Background(), protocol.NewContentFilter(testTopic, defaultTestContentTopic))
+			require.NoError(t, err)
+
+			relays = append(relays, relay)
+			hosts = append(hosts, host)
+			subs = append(subs, s[0])
+		}
+
+		// Connect the publisher with the rest of the nodes
+		for _, h := range hosts[1:] {
+			hosts[0].Peerstore().AddAddr(h.ID(), tests.GetHostAddress(h), peerstore.PermanentAddrTTL)
+			err := hosts[0].Peerstore().AddProtocols(h.ID(), WakuRelayID_v200)
+			require.NoError(t, err)
+			err = hosts[0].Connect(ctx, hosts[0].Peerstore().PeerInfo(h.ID()))
+			require.NoError(t, err)
+		}
+
+		// Wait for the publisher to learn the subscriptions of the other nodes
+		require.Eventually(t, func() bool {
+			return len(relays[0].PubSub().ListPeers(testTopic)) == len(hosts)-1
+		}, 5*time.Second, 50*time.Millisecond)
+
+		msg := tests.CreateWakuMessage(defaultTestContentTopic, utils.GetUnixEpoch(), "test_payload")
+		_, err := relays[0].Publish(ctx, msg, WithPubSubTopic(testTopic))
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for _, s := range subs[1:] {
+			if floodPublish {
+				tests.WaitForMsg(t, 2*time.Second, &wg, s.Ch)
+			} else {
+				tests.WaitForTimeout(t, ctx, time.Second, &wg, s.Ch)
+			}
+		}
+
+		for _, r := range relays {
+			r.Stop()
+		}
+		cancel()
+	}
+}
+
+func TestWakuRelayPublishTimestamp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	testTopic := defaultTestPubSubTopic
+
+	port, err := tests.FindFreePort(t, "", 5)
+	require.NoError(t, err)
+
+	host, err := tests.MakeHost(context.Background(), port, rand.Reader)
+	require.NoError(t, err)
+	bcaster := NewBroadcaster(10)
+	relay := NewWakuRelay(bcaster, 1, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger(), WithMessageAgeBounds(24*time.Hour, time.Minute))
+	relay.SetHost(host)
+	err = relay.Start(context.Background())
+	require.NoError(t, err)
+
+	err = bcaster.Start(context.Background())
+	require.NoError(t, err)
+	defer relay.Stop()
+
+	subs, err := relay.subscribe(context.Background(), protocol.NewContentFilter(testTopic, defaultTestContentTopic))
+	require.NoError(t, err)
+
+	// An explicit timestamp is preserved
+	timestamp := time.Now().Add(-time.Hour).UnixNano()
+	msg := tests.CreateWakuMessage(defaultTestContentTopic, proto.Int64(timestamp), "archived")
+	hash, err := relay.Publish(ctx, msg, WithPubSubTopic(testTopic), WithLocalLoopback())
+	require.NoError(t, err)
+
+	select {
+	case env := <-subs[0].Ch:
+		require.Equal(t, hash, env.Hash())
+		require.Equal(t, timestamp, env.Message().GetTimestamp())
+	case <-ctx.Done():
+		require.Fail(t, "message was not delivered")
+	}
+
+	// Messages without timestamp are timestamped with the current time
+	before := time.Now().UnixNano()
+	msg = tests.CreateWakuMessage(defaultTestContentTopic, nil, "new")
+	_, err = relay.Publish(ctx, msg, WithPubSubTopic(testTopic), WithLocalLoopback())
+	require.NoError(t, err)
+	// The message of the caller is not modified
+	require.Nil(t, msg.Timestamp)
+
+	select {
+	case env := <-subs[0].Ch:
+		require.GreaterOrEqual(t, env.Message().GetTimestamp(), before)
+		require.LessOrEqual(t, env.Message().GetTimestamp(), time.Now().UnixNano())
+	case <-ctx.Done():
+		require.Fail(t, "message was not delivered")
+	}
+
+	// Timestamps outside of the age bounds are rejected
+	msg = tests.CreateWakuMessage(defaultTestContentTopic, proto.Int64(time.Now().Add(-48*time.Hour).UnixNano()), "too old")
+	_, err = relay.Publish(ctx, msg, WithPubSubTopic(testTopic), WithLocalLoopback())
+	require.ErrorIs(t, err, ErrMessageTooOld)
+
+	msg = tests.CreateWakuMessage(defaultTestContentTopic, proto.Int64(time.Now().Add(time.Hour).UnixNano()), "future")
+	_, err = relay.Publish(ctx, msg, WithPubSubTopic(testTopic), WithLocalLoopback())
+	require.ErrorIs(t, err, ErrFutureMessage)
+}
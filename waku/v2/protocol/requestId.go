@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"crypto/rand"
+	"fmt"
 	"sync"
 
 	"github.com/cruxic/go-hmac-drbg/hmacdrbg"
@@ -18,13 +19,47 @@ var brHmacDrbgPool = sync.Pool{New: func() interface{} {
 	return hmacdrbg.NewHmacDrbg(256, seed, nil)
 }}
 
+// DefaultRequestIDLength is the length in bytes of the request ids generated by GenerateRequestID
+const DefaultRequestIDLength = 32
+
+// MinRequestIDLength is the minimum length in bytes of a request id. Shorter ids would make
+// collisions between the request ids of a node likely enough to matter
+const MinRequestIDLength = 16
+
 // GenerateRequestID generates a random 32 byte slice that can be used for
-// creating requests inf the filter, store and lightpush protocols
+// creating requests inf the filter, store and lightpush protocols.
+//
+// The bytes are generated by an HMAC-DRBG (NIST SP 800-90A) seeded and periodically
+// reseeded with crypto/rand, so request ids are unpredictable, and the probability of
+// two of them colliding is negligible (2^-128 after 2^64 ids for the default length).
+// Since subscriptions are keyed by request id, ids must not be reused by callers
 func GenerateRequestID() []byte {
+	randData := make([]byte, DefaultRequestIDLength)
+	generateRandomBytes(randData)
+	return randData
+}
+
+// GenerateRequestIDWithLength generates a random request id like GenerateRequestID, of the
+// specified length in bytes. The length must be at least MinRequestIDLength
+func GenerateRequestIDWithLength(length int) ([]byte, error) {
+	if length < MinRequestIDLength {
+		return nil, fmt.Errorf("request id length must be at least %d bytes", MinRequestIDLength)
+	}
+
+	randData := make([]byte, length)
+	for offset := 0; offset < length; offset += hmacdrbg.MaxBytesPerGenerate {
+		end := min(offset+hmacdrbg.MaxBytesPerGenerate, length)
+		generateRandomBytes(randData[offset:end])
+	}
+
+	return randData, nil
+}
+
+// generateRandomBytes fills randData, which must not be larger than hmacdrbg.MaxBytesPerGenerate
+func generateRandomBytes(randData []byte) {
 	rng := brHmacDrbgPool.Get().(*hmacdrbg.HmacDrbg)
 	defer brHmacDrbgPool.Put(rng)
 
-	randData := make([]byte, 32)
 	if !rng.Generate(randData) {
 		//Reseed is required every 10,000 calls
 		seed := make([]byte, 48)
@@ -42,5 +77,4 @@ func GenerateRequestID() []byte {
 			utils.Logger().Error("could not generate random request id")
 		}
 	}
-	return randData
 }
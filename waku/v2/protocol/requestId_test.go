@@ -1,8 +1,10 @@
 package protocol
 
 import (
+	"encoding/hex"
 	"testing"
 
+	"github.com/cruxic/go-hmac-drbg/hmacdrbg"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,3 +15,30 @@ func TestGenerateRequestId(t *testing.T) {
 		require.Equal(t, 32, len(bytes))
 	}
 }
+
+func TestGenerateRequestIdUniqueness(t *testing.T) {
+	seen := make(map[string]struct{})
+	for i := 0; i < 10000; i++ {
+		id := hex.EncodeToString(GenerateRequestID())
+		_, exists := seen[id]
+		require.False(t, exists)
+		seen[id] = struct{}{}
+	}
+}
+
+func TestGenerateRequestIdWithLength(t *testing.T) {
+	for _, length := range []int{MinRequestIDLength, DefaultRequestIDLength, 64, 2000} {
+		id, err := GenerateRequestIDWithLength(length)
+		require.NoError(t, err)
+		require.Len(t, id, length)
+
+		// Ids larger than a single DRBG generation are fully random
+		require.NotEqual(t, make([]byte, length), id)
+		if length > hmacdrbg.MaxBytesPerGenerate {
+			require.NotEqual(t, make([]byte, length-hmacdrbg.MaxBytesPerGenerate), id[hmacdrbg.MaxBytesPerGenerate:])
+		}
+	}
+
+	_, err := GenerateRequestIDWithLength(MinRequestIDLength - 1)
+	require.Error(t, err)
+}
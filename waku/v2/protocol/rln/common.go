@@ -1,6 +1,11 @@
 package rln
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	rlnpb "github.com/waku-org/go-waku/waku/v2/protocol/rln/pb"
@@ -15,28 +20,175 @@ const (
 	validMessage
 	invalidMessage
 	spamMessage
+	duplicateMessage
 )
 
-// the maximum clock difference between peers in seconds
-const maxClockGapSeconds = 20
+func (r messageValidationResult) String() string {
+	switch r {
+	case validationError:
+		return "validation_error"
+	case validMessage:
+		return "valid"
+	case invalidMessage:
+		return "invalid"
+	case spamMessage:
+		return "spam"
+	case duplicateMessage:
+		return "duplicate"
+	default:
+		return "unknown"
+	}
+}
+
+// ValidationResult contains the outcome of validating a message with RLN. When a message
+// is not valid, Reason describes why it was rejected
+type ValidationResult struct {
+	Result messageValidationResult
+	Reason string
+	// EpochGap is the difference between the current epoch and the epoch of the message's proof.
+	// It is only set if the message contained a proof whose metadata could be extracted
+	EpochGap int64
+	// SpamEvidence is only set for spam messages
+	SpamEvidence *SpamEvidence
+}
+
+// IsValid indicates whether the message was accepted
+func (r ValidationResult) IsValid() bool {
+	return r.Result == validMessage
+}
+
+// IsInvalid indicates whether the message was rejected because its proof is missing or not valid
+func (r ValidationResult) IsInvalid() bool {
+	return r.Result == invalidMessage
+}
+
+// IsSpam indicates whether the message was rejected for exceeding the rate limit of its sender
+func (r ValidationResult) IsSpam() bool {
+	return r.Result == spamMessage
+}
+
+// IsDuplicate indicates whether the message was already received
+func (r ValidationResult) IsDuplicate() bool {
+	return r.Result == duplicateMessage
+}
+
+// IsError indicates whether the message could not be validated
+func (r ValidationResult) IsError() bool {
+	return r.Result == validationError
+}
 
-// maximum allowed gap between the epochs of messages' RateLimitProofs
-const maxEpochGap = int64(maxClockGapSeconds / rln.EPOCH_UNIT_SECONDS)
+// DefaultMaxClockGap is the default maximum clock difference between peers
+const DefaultMaxClockGap = 20 * time.Second
+
+// default maximum allowed gap between the epochs of messages' RateLimitProofs
+const defaultMaxEpochGap = int64(DefaultMaxClockGap/time.Second) / int64(rln.EPOCH_UNIT_SECONDS)
+
+// epochGapForClockGap returns the maximum allowed gap between the epochs of messages' RateLimitProofs
+// for a maximum clock difference between peers
+func epochGapForClockGap(maxClockGap time.Duration) int64 {
+	return int64(maxClockGap/time.Second) / int64(rln.EPOCH_UNIT_SECONDS)
+}
 
-// acceptable roots for merkle root validation of incoming messages
-const acceptableRootWindowSize = 5
+// DefaultAcceptableRootWindowSize is the default number of recent merkle roots accepted when validating
+// the proofs of incoming messages. A larger window accepts messages whose proofs were generated against
+// older roots, which helps on groups whose membership changes often, but it also keeps accepting proofs
+// from members that were removed from the group for longer
+const DefaultAcceptableRootWindowSize = 5
 
 type RegistrationHandler = func(tx *types.Transaction)
 
 type SpamHandler = func(msg *pb.WakuMessage, topic string) error
 
-func toRLNSignal(wakuMessage *pb.WakuMessage) []byte {
+// SpamEvidence contains the metadata of the proofs of two messages that were sent in the same epoch
+// by the same member. Their Shamir shares are enough to recover the secret of the member, i.e. to
+// slash its membership
+type SpamEvidence struct {
+	Nullifier         [32]byte
+	ExternalNullifier [32]byte
+	ShareX            [32]byte
+	ShareY            [32]byte
+	PreviousShareX    [32]byte
+	PreviousShareY    [32]byte
+}
+
+// SlashingSpamHandler is a SpamHandler that also receives the evidence of the double signaling
+type SlashingSpamHandler = func(msg *pb.WakuMessage, topic string, evidence SpamEvidence) error
+
+// AdaptSpamHandler converts a SpamHandler into a SlashingSpamHandler that ignores the evidence
+func AdaptSpamHandler(spamHandler SpamHandler) SlashingSpamHandler {
+	if spamHandler == nil {
+		return nil
+	}
+
+	return func(msg *pb.WakuMessage, topic string, _ SpamEvidence) error {
+		return spamHandler(msg, topic)
+	}
+}
+
+// SignalVersion identifies which fields of a message are included in the signal its RLN proof is
+// generated for. Publishers and validators must use the same version, otherwise the proofs are rejected
+type SignalVersion int
+
+const (
+	// SignalV1 is the legacy signal: payload || contentTopic
+	SignalV1 SignalVersion = iota + 1
+	// SignalV2 also includes the timestamp and meta of the message, so distinct messages with the same payload
+	// and content topic produce distinct signals: payload || contentTopic || timestamp (8 bytes, big endian) || meta
+	SignalV2
+)
+
+// DefaultSignalVersion is the signal version used unless a different one is configured
+const DefaultSignalVersion = SignalV1
+
+func (v SignalVersion) valid() bool {
+	return v == SignalV1 || v == SignalV2
+}
+
+func toRLNSignal(wakuMessage *pb.WakuMessage, version SignalVersion) []byte {
 	if wakuMessage == nil {
 		return []byte{}
 	}
 
-	contentTopicBytes := []byte(wakuMessage.ContentTopic)
-	return append(wakuMessage.Payload, contentTopicBytes...)
+	signal := make([]byte, 0, len(wakuMessage.Payload)+len(wakuMessage.ContentTopic)+8+len(wakuMessage.Meta))
+	signal = append(signal, wakuMessage.Payload...)
+	signal = append(signal, wakuMessage.ContentTopic...)
+
+	if version == SignalV2 {
+		signal = binary.BigEndian.AppendUint64(signal, uint64(wakuMessage.GetTimestamp()))
+		signal = append(signal, wakuMessage.Meta...)
+	}
+
+	return signal
+}
+
+// ErrMalformedRateLimitProof is returned when the fields of a RateLimitProof do not have the expected length
+var ErrMalformedRateLimitProof = errors.New("malformed rate limit proof")
+
+// validateRateLimitProof checks the length of each field of a RateLimitProof before they are
+// converted into fixed size arrays, as a proof crafted with wrong-length fields would otherwise
+// be truncated or cause a panic
+func validateRateLimitProof(proof *rlnpb.RateLimitProof) error {
+	fields := []struct {
+		name   string
+		value  []byte
+		length int
+	}{
+		{"proof", proof.Proof, 128},
+		{"merkle root", proof.MerkleRoot, 32},
+		{"epoch", proof.Epoch, 32},
+		{"share x", proof.ShareX, 32},
+		{"share y", proof.ShareY, 32},
+		{"nullifier", proof.Nullifier, 32},
+		{"rln identifier", proof.RlnIdentifier, 32},
+	}
+
+	for _, f := range fields {
+		if len(f.value) != f.length {
+			return fmt.Errorf("%w: %s has %d bytes, expected %d", ErrMalformedRateLimitProof, f.name, len(f.value), f.length)
+		}
+	}
+
+	return nil
 }
 
 // Bytres2RateLimitProof converts a slice of bytes into a RateLimitProof instance
@@ -51,6 +203,11 @@ func BytesToRateLimitProof(data []byte) (*rln.RateLimitProof, error) {
 		return nil, err
 	}
 
+	err = validateRateLimitProof(rateLimitProof)
+	if err != nil {
+		return nil, err
+	}
+
 	result := &rln.RateLimitProof{
 		Proof:         rln.ZKSNARK(rln.Bytes128(rateLimitProof.Proof)),
 		MerkleRoot:    rln.MerkleNode(rln.Bytes32(rateLimitProof.MerkleRoot)),
@@ -0,0 +1,49 @@
+package group_manager
+
+import (
+	"sync"
+
+	"github.com/waku-org/go-zerokit-rln/rln"
+	"go.uber.org/zap"
+)
+
+// Member is a member of the RLN group and its index in the Merkle tree
+type Member struct {
+	IDCommitment rln.IDCommitment
+	Index        rln.MembershipIndex
+}
+
+// MemberInsertedCallback is called after a member is inserted in the Merkle tree. An error
+// returned by the callback is logged, but does not affect the insertion
+type MemberInsertedCallback func(idCommitment rln.IDCommitment, index rln.MembershipIndex) error
+
+// MemberInsertedCallbacks keeps the callbacks registered to be notified of the inserted members.
+// It is meant to be embedded in the group managers
+type MemberInsertedCallbacks struct {
+	callbacksMutex sync.RWMutex
+	callbacks      []MemberInsertedCallback
+}
+
+// OnMemberInserted registers a callback to be called after each member inserted in the Merkle tree
+func (c *MemberInsertedCallbacks) OnMemberInserted(callback MemberInsertedCallback) {
+	c.callbacksMutex.Lock()
+	defer c.callbacksMutex.Unlock()
+
+	c.callbacks = append(c.callbacks, callback)
+}
+
+// NotifyMemberInserted calls the registered callbacks for each member. It must not be called
+// while holding the lock of the tree, so the callbacks can use the group manager
+func (c *MemberInsertedCallbacks) NotifyMemberInserted(log *zap.Logger, members []Member) {
+	c.callbacksMutex.RLock()
+	callbacks := c.callbacks
+	c.callbacksMutex.RUnlock()
+
+	for _, member := range members {
+		for _, callback := range callbacks {
+			if err := callback(member.IDCommitment, member.Index); err != nil {
+				log.Error("executing member inserted callback", zap.Uint("index", uint(member.Index)), zap.Error(err))
+			}
+		}
+	}
+}
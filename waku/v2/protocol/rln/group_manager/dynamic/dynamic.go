@@ -29,8 +29,13 @@ var RLNAppInfo = keystore.AppInfo{
 	Version:       "0.2",
 }
 
+// ErrMembersFromContract is returned when inserting members directly, since the members of a dynamic group
+// are inserted from the events of the membership contract
+var ErrMembersFromContract = errors.New("members of a dynamic rln group are inserted from the membership contract")
+
 type DynamicGroupManager struct {
 	MembershipFetcher
+	group_manager.MemberInsertedCallbacks
 	metrics Metrics
 
 	cancel context.CancelFunc
@@ -47,6 +52,15 @@ type DynamicGroupManager struct {
 }
 
 func (gm *DynamicGroupManager) handler(events []*contracts.RLNMemberRegistered, latestProcessBlock uint64) error {
+	inserted, err := gm.handleEvents(events, latestProcessBlock)
+
+	// the callbacks are executed once the lock is released, so they can't deadlock the manager
+	gm.NotifyMemberInserted(gm.log, inserted)
+
+	return err
+}
+
+func (gm *DynamicGroupManager) handleEvents(events []*contracts.RLNMemberRegistered, latestProcessBlock uint64) ([]group_manager.Member, error) {
 	gm.lastBlockProcessedMutex.Lock()
 	defer gm.lastBlockProcessedMutex.Unlock()
 
@@ -84,12 +98,12 @@ func (gm *DynamicGroupManager) handler(events []*contracts.RLNMemberRegistered,
 
 	err := gm.RemoveMembers(toRemoveTable)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = gm.InsertMembers(toInsertTable)
+	inserted, err := gm.insertMembers(toInsertTable)
 	if err != nil {
-		return err
+		return inserted, err
 	}
 
 	gm.lastBlockProcessed = lastBlockProcessed
@@ -106,7 +120,7 @@ func (gm *DynamicGroupManager) handler(events []*contracts.RLNMemberRegistered,
 		gm.log.Debug("rln metadata persisted", zap.Uint64("lastBlockProcessed", gm.lastBlockProcessed), zap.Uint64("chainID", gm.web3Config.ChainID.Uint64()), logging.HexBytes("contractAddress", gm.web3Config.RegistryContract.Address.Bytes()))
 	}
 
-	return nil
+	return inserted, nil
 }
 
 type RegistrationHandler = func(tx *types.Transaction)
@@ -189,11 +203,20 @@ func (gm *DynamicGroupManager) Start(ctx context.Context) error {
 		return err
 	}
 
-	gm.metrics.RecordRegisteredMembership(gm.rln.LeavesSet())
+	gm.metrics.RecordRegisteredMembership(gm.leavesSet())
 
 	return nil
 }
 
+// leavesSet returns the number of leaves set in the Merkle tree
+func (gm *DynamicGroupManager) leavesSet() uint {
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	defer rlnLock.Unlock()
+
+	return gm.rln.LeavesSet()
+}
+
 func (gm *DynamicGroupManager) loadCredential(ctx context.Context) error {
 	if gm.appKeystore == nil {
 		gm.log.Warn("no credentials were loaded. Node will only validate messages, but wont be able to generate proofs and attach them to messages")
@@ -229,7 +252,24 @@ func (gm *DynamicGroupManager) loadCredential(ctx context.Context) error {
 	return nil
 }
 
-func (gm *DynamicGroupManager) InsertMembers(toInsert *om.OrderedMap) error {
+// InsertRegisteredMembers inserts the members registered in each block, and returns the members that
+// were inserted along with the index of the leaf they were assigned. If an error occurs, the members
+// inserted before it are still returned
+func (gm *DynamicGroupManager) InsertRegisteredMembers(toInsert *om.OrderedMap) ([]group_manager.Member, error) {
+	inserted, err := gm.insertMembers(toInsert)
+	gm.NotifyMemberInserted(gm.log, inserted)
+	return inserted, err
+}
+
+// InsertMembers always returns ErrMembersFromContract. The Merkle tree must be the one of the membership
+// contract, so members are only inserted at the index assigned when they were registered in the contract
+func (gm *DynamicGroupManager) InsertMembers(idCommitments []rln.IDCommitment) error {
+	return ErrMembersFromContract
+}
+
+// insertMembers inserts the members registered in each block, and returns the members that were inserted
+func (gm *DynamicGroupManager) insertMembers(toInsert *om.OrderedMap) ([]group_manager.Member, error) {
+	var inserted []group_manager.Member
 	for pair := toInsert.Oldest(); pair != nil; pair = pair.Next() {
 		events := pair.Value.([]*contracts.RLNMemberRegistered) // TODO: should these be sortered by index? we assume all members arrive in order
 		var idCommitments []rln.IDCommitment
@@ -248,24 +288,34 @@ func (gm *DynamicGroupManager) InsertMembers(toInsert *om.OrderedMap) error {
 		// TODO: should we track indexes to identify missing?
 		startIndex := rln.MembershipIndex(uint(oldestIndexInBlock.Int64()))
 		start := time.Now()
+		rlnLock := gm.rootTracker.RLNLock()
+		rlnLock.Lock()
 		err := gm.rln.InsertMembers(startIndex, idCommitments)
+		rlnLock.Unlock()
 		if err != nil {
 			gm.log.Error("inserting members into merkletree", zap.Error(err))
-			return err
+			return inserted, err
 		}
 		gm.metrics.RecordMembershipInsertionDuration(time.Since(start))
 
-		gm.metrics.RecordRegisteredMembership(gm.rln.LeavesSet())
+		gm.metrics.RecordRegisteredMembership(gm.leavesSet())
 
 		gm.rootTracker.UpdateLatestRoot(pair.Key.(uint64))
+
+		for i, idCommitment := range idCommitments {
+			inserted = append(inserted, group_manager.Member{IDCommitment: idCommitment, Index: startIndex + rln.MembershipIndex(i)})
+		}
 	}
-	return nil
+	return inserted, nil
 }
 
 func (gm *DynamicGroupManager) RemoveMembers(toRemove *om.OrderedMap) error {
 	for pair := toRemove.Newest(); pair != nil; pair = pair.Prev() {
 		memberIndexes := pair.Value.([]uint)
+		rlnLock := gm.rootTracker.RLNLock()
+		rlnLock.Lock()
 		err := gm.rln.DeleteMembers(memberIndexes)
+		rlnLock.Unlock()
 		if err != nil {
 			gm.log.Error("deleting members", zap.Error(err))
 			return err
@@ -288,6 +338,21 @@ func (gm *DynamicGroupManager) MembershipIndex() rln.MembershipIndex {
 	return gm.membershipIndex
 }
 
+// CurrentRootHex returns the current merkle root of the tree as a hex string
+func (gm *DynamicGroupManager) CurrentRootHex() string {
+	return gm.rootTracker.CurrentRootHex()
+}
+
+// CurrentRoot returns the current merkle root of the tree
+func (gm *DynamicGroupManager) CurrentRoot() rln.MerkleNode {
+	return gm.rootTracker.CurrentRoot()
+}
+
+// AcceptedRoots returns a copy of the merkle roots accepted when validating the proofs of incoming messages
+func (gm *DynamicGroupManager) AcceptedRoots() []rln.MerkleNode {
+	return gm.rootTracker.AcceptedRoots()
+}
+
 // Stop stops all go-routines, eth client and closes the rln database
 func (gm *DynamicGroupManager) Stop() error {
 	if gm.cancel == nil {
@@ -296,7 +361,10 @@ func (gm *DynamicGroupManager) Stop() error {
 
 	gm.cancel()
 
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
 	err := gm.rln.Flush()
+	rlnLock.Unlock()
 	if err != nil {
 		return err
 	}
@@ -306,6 +374,18 @@ func (gm *DynamicGroupManager) Stop() error {
 	return nil
 }
 
+// Persist is a function created just to comply with the GroupManager interface (it does nothing).
+// The dynamic group manager already stores its state in the RLN database
+func (gm *DynamicGroupManager) Persist(path string) error {
+	return nil
+}
+
+// Restore is a function created just to comply with the GroupManager interface (it does nothing).
+// The dynamic group manager resumes from the state stored in the RLN database
+func (gm *DynamicGroupManager) Restore(path string) error {
+	return nil
+}
+
 func (gm *DynamicGroupManager) IsReady(ctx context.Context) (bool, error) {
 	latestBlockNumber, err := gm.latestBlockNumber(ctx)
 	if err != nil {
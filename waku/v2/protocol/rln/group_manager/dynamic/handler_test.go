@@ -2,6 +2,7 @@ package dynamic
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"testing"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/web3"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"github.com/waku-org/go-zerokit-rln/rln"
+	om "github.com/wk8/go-ordered-map"
 )
 
 func eventBuilder(blockNumber uint64, removed bool, idCommitment int64, index int64) *contracts.RLNMemberRegistered {
@@ -118,3 +120,121 @@ func TestHandler(t *testing.T) {
 	require.Len(t, roots, 5)
 
 }
+
+func TestHandlerMemberInsertedCallback(t *testing.T) {
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+
+	gm := &DynamicGroupManager{
+		MembershipFetcher: NewMembershipFetcher(
+			&web3.Config{
+				ChainID: big.NewInt(1),
+			},
+			rlnInstance,
+			rootTracker,
+			utils.Logger(),
+		),
+
+		metrics: newMetrics(prometheus.DefaultRegisterer),
+	}
+
+	var inserted []group_manager.Member
+	gm.OnMemberInserted(func(idCommitment rln.IDCommitment, index rln.MembershipIndex) error {
+		// The callback is executed once the lock is released
+		gm.lastBlockProcessedMutex.RLock()
+		defer gm.lastBlockProcessedMutex.RUnlock()
+
+		inserted = append(inserted, group_manager.Member{IDCommitment: idCommitment, Index: index})
+		return nil
+	})
+
+	// An error returned by a callback does not fail the insertion
+	gm.OnMemberInserted(func(idCommitment rln.IDCommitment, index rln.MembershipIndex) error {
+		return errors.New("callback error")
+	})
+
+	events := []*contracts.RLNMemberRegistered{
+		eventBuilder(1, false, 0xaaaa, 1),
+		eventBuilder(1, false, 0xbbbb, 2),
+		eventBuilder(2, false, 0xcccc, 3),
+	}
+
+	err = gm.handler(events, 2)
+	require.NoError(t, err)
+
+	require.Equal(t, []group_manager.Member{
+		{IDCommitment: rln.BigIntToBytes32(big.NewInt(0xaaaa)), Index: 1},
+		{IDCommitment: rln.BigIntToBytes32(big.NewInt(0xbbbb)), Index: 2},
+		{IDCommitment: rln.BigIntToBytes32(big.NewInt(0xcccc)), Index: 3},
+	}, inserted)
+}
+
+func TestInsertMembersIndices(t *testing.T) {
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+
+	gm := &DynamicGroupManager{
+		MembershipFetcher: NewMembershipFetcher(
+			&web3.Config{
+				ChainID: big.NewInt(1),
+			},
+			rlnInstance,
+			rootTracker,
+			utils.Logger(),
+		),
+
+		metrics: newMetrics(prometheus.DefaultRegisterer),
+	}
+
+	toInsert := om.New()
+	toInsert.Set(uint64(1), []*contracts.RLNMemberRegistered{
+		eventBuilder(1, false, 0xaaaa, 4),
+		eventBuilder(1, false, 0xbbbb, 5),
+	})
+	toInsert.Set(uint64(2), []*contracts.RLNMemberRegistered{
+		eventBuilder(2, false, 0xcccc, 6),
+	})
+
+	inserted, err := gm.InsertRegisteredMembers(toInsert)
+	require.NoError(t, err)
+	require.Equal(t, []group_manager.Member{
+		{IDCommitment: rln.BigIntToBytes32(big.NewInt(0xaaaa)), Index: 4},
+		{IDCommitment: rln.BigIntToBytes32(big.NewInt(0xbbbb)), Index: 5},
+		{IDCommitment: rln.BigIntToBytes32(big.NewInt(0xcccc)), Index: 6},
+	}, inserted)
+
+	for _, member := range inserted {
+		leaf, err := rlnInstance.GetLeaf(member.Index)
+		require.NoError(t, err)
+		require.Equal(t, member.IDCommitment, leaf)
+	}
+}
+
+func TestInsertMembersOutsideContract(t *testing.T) {
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+
+	gm := &DynamicGroupManager{
+		MembershipFetcher: NewMembershipFetcher(
+			&web3.Config{
+				ChainID: big.NewInt(1),
+			},
+			rlnInstance,
+			rootTracker,
+			utils.Logger(),
+		),
+
+		metrics: newMetrics(prometheus.DefaultRegisterer),
+	}
+
+	// Only the members registered in the contract are inserted, so the tree keeps matching the contract
+	err = gm.InsertMembers([]rln.IDCommitment{rln.BigIntToBytes32(big.NewInt(0xaaaa))})
+	require.ErrorIs(t, err, ErrMembersFromContract)
+	require.Equal(t, uint(0), rlnInstance.LeavesSet())
+}
@@ -238,7 +238,10 @@ func (mf *MembershipFetcher) fetchEvents(ctx context.Context, from uint64, to ui
 
 // GetMetadata retrieves metadata from the zerokit's RLN database
 func (mf *MembershipFetcher) GetMetadata() (RLNMetadata, error) {
+	rlnLock := mf.rootTracker.RLNLock()
+	rlnLock.Lock()
 	b, err := mf.rln.GetMetadata()
+	rlnLock.Unlock()
 	if err != nil {
 		return RLNMetadata{}, err
 	}
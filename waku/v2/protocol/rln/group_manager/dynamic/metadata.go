@@ -80,5 +80,10 @@ func (gm *DynamicGroupManager) SetMetadata(meta RLNMetadata) error {
 	if err != nil {
 		return err
 	}
+
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	defer rlnLock.Unlock()
+
 	return gm.rln.SetMetadata(b)
 }
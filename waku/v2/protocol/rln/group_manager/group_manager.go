@@ -10,8 +10,23 @@ type GroupManager interface {
 	Start(ctx context.Context) error
 	IdentityCredentials() (rln.IdentityCredential, error)
 	MembershipIndex() rln.MembershipIndex
+	CurrentRootHex() string
+	CurrentRoot() rln.MerkleNode
+	AcceptedRoots() []rln.MerkleNode
 	Stop() error
 	IsReady(ctx context.Context) (bool, error)
+	Persist(path string) error
+	Restore(path string) error
+	OnMemberInserted(callback MemberInsertedCallback)
+	// InsertMembers appends a batch of IDCommitments to the group, updating the merkle root once. Group
+	// managers whose members cannot be inserted directly return an error instead
+	InsertMembers(idCommitments []rln.IDCommitment) error
+}
+
+// CredentialSelector is implemented by the group managers holding several memberships of the node,
+// to select the membership used to generate the proof of a message published in a content topic
+type CredentialSelector interface {
+	CredentialFor(contentTopic string) (rln.IdentityCredential, rln.MembershipIndex, error)
 }
 
 type Details struct {
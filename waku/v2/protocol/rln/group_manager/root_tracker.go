@@ -2,6 +2,8 @@ package group_manager
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
 	"sync"
 
 	"github.com/waku-org/go-waku/waku/v2/utils"
@@ -21,13 +23,19 @@ type MerkleRootTracker struct {
 	sync.RWMutex
 
 	rln                      *rln.RLN
+	rlnMutex                 sync.Mutex
 	acceptableRootWindowSize int
 	validMerkleRoots         []RootsPerBlock
 	merkleRootBuffer         []RootsPerBlock
+
+	subscribers []chan rln.MerkleNode
 }
 
 const maxBufferSize = 20
 
+// ErrRootOnlyTracker is returned when syncing a root tracker that is not bound to the Merkle tree of an RLN instance
+var ErrRootOnlyTracker = errors.New("root tracker only tracks the merkle roots pushed to it")
+
 // NewMerkleRootTracker creates an instance of MerkleRootTracker
 func NewMerkleRootTracker(acceptableRootWindowSize int, rlnInstance *rln.RLN) *MerkleRootTracker {
 	result := &MerkleRootTracker{
@@ -40,11 +48,33 @@ func NewMerkleRootTracker(acceptableRootWindowSize int, rlnInstance *rln.RLN) *M
 	return result
 }
 
+// NewRootOnlyMerkleRootTracker creates an instance of MerkleRootTracker that is not bound to the Merkle tree
+// of an RLN instance. It starts without any valid root, and only tracks the roots added with PushRoot
+func NewRootOnlyMerkleRootTracker(acceptableRootWindowSize int) *MerkleRootTracker {
+	return &MerkleRootTracker{
+		acceptableRootWindowSize: acceptableRootWindowSize,
+	}
+}
+
+// RLNLock returns the mutex that must be held while calling the RLN instance shared by the group manager
+// and the relay using this root tracker. zerokit takes the instance mutably on every call, so it cannot be
+// used concurrently, e.g. to generate or verify a proof while members are inserted in the Merkle tree
+func (m *MerkleRootTracker) RLNLock() *sync.Mutex {
+	return &m.rlnMutex
+}
+
 // Backfill is used to pop merkle roots when there is a chain fork
 func (m *MerkleRootTracker) Backfill(fromBlockNumber uint64) {
 	m.Lock()
 	defer m.Unlock()
 
+	previousRoot, _ := m.latestRoot()
+	defer func() {
+		if root, ok := m.latestRoot(); ok && root != previousRoot {
+			m.notify(root)
+		}
+	}()
+
 	numBlocks := 0
 	for i := len(m.validMerkleRoots) - 1; i >= 0; i-- {
 		if m.validMerkleRoots[i].BlockNumber >= fromBlockNumber {
@@ -105,7 +135,13 @@ func (m *MerkleRootTracker) UpdateLatestRoot(blockNumber uint64) rln.MerkleNode
 	m.Lock()
 	defer m.Unlock()
 
+	if m.rln == nil {
+		utils.Logger().Named("root-tracker").Panic("could not retrieve merkle root", zap.Error(ErrRootOnlyTracker))
+	}
+
+	m.rlnMutex.Lock()
 	root, err := m.rln.GetMerkleRoot()
+	m.rlnMutex.Unlock()
 	if err != nil {
 		utils.Logger().Named("root-tracker").Panic("could not retrieve merkle root", zap.Error(err))
 	}
@@ -115,7 +151,52 @@ func (m *MerkleRootTracker) UpdateLatestRoot(blockNumber uint64) rln.MerkleNode
 	return root
 }
 
+// Sync pushes the current merkle root of the tree if it changed since the latest root tracked, i.e.
+// after members were inserted in the RLN instance directly. Unlike UpdateLatestRoot, an error is
+// returned if the merkle root cannot be retrieved
+func (m *MerkleRootTracker) Sync() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.rln == nil {
+		return ErrRootOnlyTracker
+	}
+
+	m.rlnMutex.Lock()
+	root, err := m.rln.GetMerkleRoot()
+	m.rlnMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var blockNumber uint64
+	if len(m.validMerkleRoots) != 0 {
+		latest := m.validMerkleRoots[len(m.validMerkleRoots)-1]
+		if latest.Root == root {
+			return nil
+		}
+		blockNumber = latest.BlockNumber
+	}
+
+	m.pushRoot(blockNumber, root)
+
+	return nil
+}
+
+// PushRoot adds a merkle root that was computed elsewhere, to track the roots of a group
+// whose Merkle tree is not held by the node
+func (m *MerkleRootTracker) PushRoot(blockNumber uint64, root rln.MerkleNode) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.pushRoot(blockNumber, root)
+}
+
 func (m *MerkleRootTracker) pushRoot(blockNumber uint64, root [32]byte) {
+	if previousRoot, ok := m.latestRoot(); !ok || previousRoot != root {
+		defer m.notify(root)
+	}
+
 	m.validMerkleRoots = append(m.validMerkleRoots, RootsPerBlock{
 		Root:        root,
 		BlockNumber: blockNumber,
@@ -146,6 +227,12 @@ func (m *MerkleRootTracker) Roots() []rln.MerkleNode {
 	return result
 }
 
+// AcceptedRoots returns a copy of the merkle roots currently in the acceptable window, i.e. the
+// roots that the proofs of incoming messages are verified against
+func (m *MerkleRootTracker) AcceptedRoots() []rln.MerkleNode {
+	return m.Roots()
+}
+
 // Buffer is used as a repository of older merkle roots that although
 // they were valid once, they have left the acceptable window of
 // merkle roots. We keep track of them in case a chain fork occurs
@@ -163,6 +250,19 @@ func (m *MerkleRootTracker) Buffer() []rln.MerkleNode {
 	return result
 }
 
+// BufferLen returns the number of merkle roots that left the acceptable window and are kept in the buffer
+func (m *MerkleRootTracker) BufferLen() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.merkleRootBuffer)
+}
+
+// WindowSize returns the maximum number of merkle roots that are accepted
+func (m *MerkleRootTracker) WindowSize() int {
+	return m.acceptableRootWindowSize
+}
+
 // ValidRootsPerBlock returns the current valid merkle roots and block numbers
 func (m *MerkleRootTracker) ValidRootsPerBlock() []RootsPerBlock {
 	m.RLock()
@@ -178,3 +278,62 @@ func (m *MerkleRootTracker) SetValidRootsPerBlock(roots []RootsPerBlock) {
 
 	m.validMerkleRoots = roots
 }
+
+func (m *MerkleRootTracker) latestRoot() (rln.MerkleNode, bool) {
+	if len(m.validMerkleRoots) == 0 {
+		return rln.MerkleNode{}, false
+	}
+	return m.validMerkleRoots[len(m.validMerkleRoots)-1].Root, true
+}
+
+func (m *MerkleRootTracker) notify(root rln.MerkleNode) {
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- root:
+		default:
+			// Subscriber is not keeping up. Skip the notification instead of blocking the tracker
+		}
+	}
+}
+
+// CurrentRoot returns the latest merkle root
+func (m *MerkleRootTracker) CurrentRoot() rln.MerkleNode {
+	m.RLock()
+	defer m.RUnlock()
+
+	root, _ := m.latestRoot()
+	return root
+}
+
+// CurrentRootHex returns the latest merkle root as a hex string
+func (m *MerkleRootTracker) CurrentRootHex() string {
+	root := m.CurrentRoot()
+	return hex.EncodeToString(root[:])
+}
+
+// Subscribe returns a channel that receives the new merkle root each time it changes,
+// and a function that must be called to stop receiving notifications
+func (m *MerkleRootTracker) Subscribe() (<-chan rln.MerkleNode, func()) {
+	m.Lock()
+	defer m.Unlock()
+
+	ch := make(chan rln.MerkleNode, 10)
+	m.subscribers = append(m.subscribers, ch)
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			m.Lock()
+			defer m.Unlock()
+			for i, c := range m.subscribers {
+				if c == ch {
+					m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+					break
+				}
+			}
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,76 @@
+package static
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/waku-org/go-waku/waku/v2/utils"
+	"github.com/waku-org/go-zerokit-rln/rln"
+)
+
+// LoadGroupFromFile reads the list of IDCommitments that compose a static RLN group
+// from a file. Two formats are supported:
+//   - a JSON array of hex encoded commitments
+//   - a line delimited list of hex encoded commitments. Empty lines and lines
+//     starting with `#` are ignored
+//
+// Commitments can optionally be prefixed with `0x`
+func LoadGroupFromFile(path string) ([]rln.IDCommitment, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil, fmt.Errorf("group file %s is empty", path)
+	}
+
+	var encodedCommitments []string
+	if content[0] == '[' {
+		err = json.Unmarshal(content, &encodedCommitments)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse group file %s: %w", path, err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			encodedCommitments = append(encodedCommitments, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("could not read group file %s: %w", path, err)
+		}
+	}
+
+	group := make([]rln.IDCommitment, 0, len(encodedCommitments))
+	for i, encoded := range encodedCommitments {
+		commitment, err := parseIDCommitment(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commitment at position %d: %w", i, err)
+		}
+		group = append(group, commitment)
+	}
+
+	return group, nil
+}
+
+func parseIDCommitment(encoded string) (rln.IDCommitment, error) {
+	b, err := utils.DecodeHexString(strings.TrimSpace(encoded))
+	if err != nil {
+		return rln.IDCommitment{}, err
+	}
+
+	if len(b) != len(rln.IDCommitment{}) {
+		return rln.IDCommitment{}, fmt.Errorf("expected %d bytes, got %d", len(rln.IDCommitment{}), len(b))
+	}
+
+	return rln.IDCommitment(rln.Bytes32(b)), nil
+}
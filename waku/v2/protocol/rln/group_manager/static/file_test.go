@@ -0,0 +1,71 @@
+package static
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-zerokit-rln/rln"
+)
+
+func writeGroupFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "group.txt")
+	err := os.WriteFile(path, []byte(content), 0600)
+	require.NoError(t, err)
+	return path
+}
+
+func TestLoadGroupFromFile(t *testing.T) {
+	groupKeys, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	var expected []rln.IDCommitment
+	var encoded []string
+	for _, k := range groupKeys {
+		expected = append(expected, k.IDCommitment)
+		encoded = append(encoded, hex.EncodeToString(k.IDCommitment[:]))
+	}
+
+	// Line delimited, with comments, empty lines and 0x prefixes
+	lines := "# static group\n\n0x" + strings.Join(encoded, "\n0x") + "\n"
+	group, err := LoadGroupFromFile(writeGroupFile(t, lines))
+	require.NoError(t, err)
+	require.Equal(t, expected, group)
+
+	// JSON
+	jsonContent, err := json.Marshal(encoded)
+	require.NoError(t, err)
+	group, err = LoadGroupFromFile(writeGroupFile(t, string(jsonContent)))
+	require.NoError(t, err)
+	require.Equal(t, expected, group)
+}
+
+func TestLoadGroupFromFileMalformed(t *testing.T) {
+	// Missing file
+	_, err := LoadGroupFromFile(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+
+	// Empty file
+	_, err = LoadGroupFromFile(writeGroupFile(t, "  \n"))
+	require.Error(t, err)
+
+	// Invalid hex
+	_, err = LoadGroupFromFile(writeGroupFile(t, "zz"+strings.Repeat("00", 31)))
+	require.Error(t, err)
+
+	// Commitment with the wrong length
+	_, err = LoadGroupFromFile(writeGroupFile(t, strings.Repeat("00", 31)))
+	require.Error(t, err)
+
+	// Invalid JSON
+	_, err = LoadGroupFromFile(writeGroupFile(t, `["`+strings.Repeat("00", 32)+`"`))
+	require.Error(t, err)
+
+	// JSON with a commitment with the wrong length
+	_, err = LoadGroupFromFile(writeGroupFile(t, `["`+strings.Repeat("00", 33)+`"]`))
+	require.Error(t, err)
+}
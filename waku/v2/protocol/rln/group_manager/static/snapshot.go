@@ -0,0 +1,196 @@
+package static
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"github.com/waku-org/go-waku/waku/v2/hash"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
+	"github.com/waku-org/go-zerokit-rln/rln"
+	"go.uber.org/zap"
+)
+
+// ErrIncompatibleSnapshot is returned when a snapshot cannot be used with the current tree or group
+var ErrIncompatibleSnapshot = errors.New("incompatible rln tree snapshot")
+
+const snapshotVersion = 2
+
+// snapshot describes the state of the Merkle tree after the first nextIndex members were inserted
+type snapshot struct {
+	nextIndex          uint64
+	root               rln.MerkleNode
+	membersHash        [32]byte // hash of the IDCommitments of the first nextIndex members
+	validRootsPerBlock []group_manager.RootsPerBlock
+}
+
+const snapshotHeaderLen = 1 + 8 + 32 + 32 + 8 // version + nextIndex + root + membersHash + len(validRootsPerBlock)
+
+// hashMembers returns the hash of a list of IDCommitments, used to check that a snapshot
+// was taken with the same members as the group being restored
+func hashMembers(idCommitments []rln.IDCommitment) [32]byte {
+	data := make([][]byte, len(idCommitments))
+	for i := range idCommitments {
+		data[i] = idCommitments[i][:]
+	}
+
+	var result [32]byte
+	copy(result[:], hash.SHA256(data...))
+	return result
+}
+
+func (s snapshot) serialize() []byte {
+	result := []byte{snapshotVersion}
+	result = binary.LittleEndian.AppendUint64(result, s.nextIndex)
+	result = append(result, s.root[:]...)
+	result = append(result, s.membersHash[:]...)
+	result = binary.LittleEndian.AppendUint64(result, uint64(len(s.validRootsPerBlock)))
+	for _, v := range s.validRootsPerBlock {
+		result = append(result, v.Root[:]...)
+		result = binary.LittleEndian.AppendUint64(result, v.BlockNumber)
+	}
+	return result
+}
+
+func deserializeSnapshot(b []byte) (snapshot, error) {
+	if len(b) < snapshotHeaderLen || b[0] != snapshotVersion {
+		return snapshot{}, ErrIncompatibleSnapshot
+	}
+
+	var result snapshot
+	result.nextIndex = binary.LittleEndian.Uint64(b[1:9])
+	copy(result.root[:], b[9:41])
+	copy(result.membersHash[:], b[41:73])
+
+	validRootsLen := binary.LittleEndian.Uint64(b[73:81])
+	if uint64(len(b)) != snapshotHeaderLen+validRootsLen*(32+8) {
+		return snapshot{}, ErrIncompatibleSnapshot
+	}
+
+	for i := 0; i < int(validRootsLen); i++ {
+		offset := snapshotHeaderLen + i*(32+8)
+		var root rln.MerkleNode
+		copy(root[:], b[offset:offset+32])
+		result.validRootsPerBlock = append(result.validRootsPerBlock, group_manager.RootsPerBlock{
+			Root:        root,
+			BlockNumber: binary.LittleEndian.Uint64(b[offset+32 : offset+40]),
+		})
+	}
+
+	return result, nil
+}
+
+// Persist writes a snapshot of the Merkle tree state in path. The tree itself is flushed to the
+// RLN database, so the snapshot only contains what is needed to verify it and resume from it
+func (gm *StaticGroupManager) Persist(path string) error {
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return ErrGroupManagerStopped
+	}
+
+	root, err := gm.flush()
+	if err != nil {
+		return err
+	}
+
+	// The group is released once the tree is built, so the members are read from the tree
+	members, err := gm.leaves()
+	if err != nil {
+		return err
+	}
+
+	s := snapshot{
+		nextIndex:          gm.nextIndex,
+		root:               root,
+		membersHash:        hashMembers(members),
+		validRootsPerBlock: gm.rootTracker.ValidRootsPerBlock(),
+	}
+
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, s.serialize(), 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// flush flushes the Merkle tree to the RLN database, and returns its root
+func (gm *StaticGroupManager) flush() (rln.MerkleNode, error) {
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	defer rlnLock.Unlock()
+
+	err := gm.rln.Flush()
+	if err != nil {
+		return rln.MerkleNode{}, err
+	}
+
+	return gm.rln.GetMerkleRoot()
+}
+
+// Restore loads a snapshot of the Merkle tree state from path. If the snapshot matches the
+// tree stored in the RLN database and was taken with the same first members as the group,
+// Start only inserts the members added after the snapshot. Otherwise an error is returned,
+// and Start rebuilds the whole tree
+func (gm *StaticGroupManager) Restore(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	s, err := deserializeSnapshot(b)
+	if err != nil {
+		return err
+	}
+
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return ErrGroupManagerStopped
+	}
+
+	if s.nextIndex > uint64(len(gm.group)) {
+		return ErrIncompatibleSnapshot
+	}
+
+	// The snapshot must have been taken with the same members as the group
+	if hashMembers(gm.group[:s.nextIndex]) != s.membersHash {
+		return ErrIncompatibleSnapshot
+	}
+
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	root, err := gm.rln.GetMerkleRoot()
+	rlnLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if root != s.root {
+		return ErrIncompatibleSnapshot
+	}
+
+	// The snapshot must belong to the same group
+	if uint64(gm.membershipIndex) < s.nextIndex {
+		leaf, err := gm.getLeaf(gm.membershipIndex)
+		if err != nil {
+			return err
+		}
+
+		if leaf != gm.identityCredential.IDCommitment {
+			return ErrIncompatibleSnapshot
+		}
+	}
+
+	gm.nextIndex = s.nextIndex
+	gm.restored = true
+	gm.rootTracker.SetValidRootsPerBlock(s.validRootsPerBlock)
+
+	gm.log.Info("restored rln tree snapshot", zap.Uint64("members", s.nextIndex))
+
+	return nil
+}
@@ -3,24 +3,92 @@ package static
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
 	"github.com/waku-org/go-zerokit-rln/rln"
 	"go.uber.org/zap"
 )
 
+// ErrMemberAlreadyInserted is returned when attempting to insert a member at an index that is already occupied
+var ErrMemberAlreadyInserted = errors.New("a member has already been inserted at this index")
+
+// ErrMemberNotFound is returned when attempting to remove a member from an index that is empty
+var ErrMemberNotFound = errors.New("there is no member at this index")
+
+// ErrRemoveOwnMembership is returned when attempting to remove the member of the node itself
+var ErrRemoveOwnMembership = errors.New("cannot remove the node's own membership")
+
+// ErrDuplicateIDCommitment is returned when attempting to insert an IDCommitment that is already in the group
+var ErrDuplicateIDCommitment = errors.New("IDCommitment has already been inserted")
+
+// ErrMemberIndexOutOfRange is returned when attempting to insert a member at an index beyond the capacity of the Merkle tree
+var ErrMemberIndexOutOfRange = errors.New("member index is beyond the capacity of the merkle tree")
+
+// maxMembers is the number of leaves of the Merkle tree
+const maxMembers = uint64(1) << rln.DefaultTreeDepth
+
+// ErrMemberIndexGap is returned when a member that must be appended to the tree does not have the next index
+var ErrMemberIndexGap = errors.New("member index is not the next index of the tree")
+
+// ErrMembershipNotInTree is returned when the IDCommitment of the node is not at the index of its membership
+// once the Merkle tree is built
+var ErrMembershipNotInTree = errors.New("the IDCommitment of the node is not in the merkle tree at its membership index")
+
+// ErrGroupManagerStopped is returned when using a group manager that was stopped
+var ErrGroupManagerStopped = errors.New("static group manager is stopped")
+
+// ErrRLNInstanceReleased is returned when starting a group manager whose RLN instance was released when it was stopped
+var ErrRLNInstanceReleased = errors.New("the rln instance owned by the static group manager was released")
+
+// ErrContentTopicMembershipExists is returned when adding a membership for a content topic that already has one
+var ErrContentTopicMembershipExists = errors.New("a membership has already been added for this content topic")
+
+type membership struct {
+	identityCredential rln.IdentityCredential
+	index              rln.MembershipIndex
+}
+
 type StaticGroupManager struct {
+	sync.Mutex
+	group_manager.MemberInsertedCallbacks
+
 	rln *rln.RLN
 	log *zap.Logger
 
 	identityCredential *rln.IdentityCredential
 	membershipIndex    rln.MembershipIndex
 
+	// additional memberships of the node, per content topic
+	memberships map[string]membership
+
 	group       []rln.IDCommitment
+	members     map[rln.IDCommitment]struct{} // IDCommitments in the tree, to detect duplicates
 	rootTracker *group_manager.MerkleRootTracker
 	nextIndex   uint64
+	restored    bool
+	started     bool
+	stopped     bool
+
+	// whether the RLN instance is released when the group manager is stopped
+	ownsRLN bool
 }
 
+// Option is used to configure a StaticGroupManager
+type Option func(*StaticGroupManager)
+
+// WithOwnedRLN indicates that the RLN instance is only used by the group manager, so it is released once the
+// group manager is stopped instead of being kept to restart it. The RLN instance and the root tracker must not
+// be shared with the relay or other group managers
+func WithOwnedRLN() Option {
+	return func(gm *StaticGroupManager) {
+		gm.ownsRLN = true
+	}
+}
+
+// NewStaticGroupManager creates a group manager for a static group. By default the RLN instance is owned by
+// the caller, which usually shares it with the relay to verify proofs, so the group manager never releases it
 func NewStaticGroupManager(
 	group []rln.IDCommitment,
 	identityCredential rln.IdentityCredential,
@@ -28,53 +96,583 @@ func NewStaticGroupManager(
 	rlnInstance *rln.RLN,
 	rootTracker *group_manager.MerkleRootTracker,
 	log *zap.Logger,
+	opts ...Option,
 ) (*StaticGroupManager, error) {
+	if len(group) == 0 {
+		return nil, errors.New("the static group is empty")
+	}
+
+	if int(index) >= len(group) {
+		return nil, fmt.Errorf("membership index %d is out of range, the static group has %d members", index, len(group))
+	}
+
 	// check the peer's index and the inclusion of user's identity commitment in the group
 	if identityCredential.IDCommitment != group[int(index)] {
 		return nil, errors.New("peer's IDCommitment does not match commitment in group")
 	}
 
-	return &StaticGroupManager{
+	gm := &StaticGroupManager{
 		log:                log.Named("rln-static"),
 		group:              group,
 		identityCredential: &identityCredential,
 		membershipIndex:    index,
 		rln:                rlnInstance,
 		rootTracker:        rootTracker,
-	}, nil
+		memberships:        make(map[string]membership),
+		members:            make(map[rln.IDCommitment]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(gm)
+	}
+
+	return gm, nil
+}
+
+// AddMembership adds another membership of the node in the group, whose credential is used to generate
+// the proofs of the messages published in contentTopics. The proofs of messages published in other content
+// topics keep being generated with the credential the group manager was created with
+func (gm *StaticGroupManager) AddMembership(identityCredential rln.IdentityCredential, index rln.MembershipIndex, contentTopics ...string) error {
+	if len(contentTopics) == 0 {
+		return errors.New("at least one content topic is required")
+	}
+
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return ErrGroupManagerStopped
+	}
+
+	// check the inclusion of the identity commitment in the group. Once started,
+	// the group is only available in the Merkle tree
+	var idCommitment rln.IDCommitment
+	if gm.group != nil {
+		if int(index) >= len(gm.group) {
+			return errors.New("wrong membership index")
+		}
+		idCommitment = gm.group[int(index)]
+	} else {
+		leaf, err := gm.getLeaf(index)
+		if err != nil {
+			return err
+		}
+		idCommitment = leaf
+	}
+
+	if identityCredential.IDCommitment != idCommitment {
+		return errors.New("peer's IDCommitment does not match commitment in group")
+	}
+
+	for _, contentTopic := range contentTopics {
+		if _, ok := gm.memberships[contentTopic]; ok {
+			return ErrContentTopicMembershipExists
+		}
+	}
+
+	for _, contentTopic := range contentTopics {
+		gm.memberships[contentTopic] = membership{
+			identityCredential: identityCredential,
+			index:              index,
+		}
+	}
+
+	return nil
+}
+
+// CredentialFor returns the identity credential and membership index used to generate the proofs
+// of the messages published in a content topic
+func (gm *StaticGroupManager) CredentialFor(contentTopic string) (rln.IdentityCredential, rln.MembershipIndex, error) {
+	gm.Lock()
+	m, ok := gm.memberships[contentTopic]
+	gm.Unlock()
+
+	if ok {
+		return m.identityCredential, m.index, nil
+	}
+
+	identityCredential, err := gm.IdentityCredentials()
+	if err != nil {
+		return rln.IdentityCredential{}, 0, err
+	}
+
+	return identityCredential, gm.membershipIndex, nil
+}
+
+func (gm *StaticGroupManager) isMember(idCommitment rln.IDCommitment) bool {
+	_, ok := gm.members[idCommitment]
+	return ok
+}
+
+// verifyOwnMemberships checks that the IDCommitments of the node are in the Merkle tree at the index
+// of their membership. Otherwise all the proofs generated by the node would be rejected by its peers
+func (gm *StaticGroupManager) verifyOwnMemberships() error {
+	gm.Lock()
+	defer gm.Unlock()
+
+	ownMemberships := []membership{{identityCredential: *gm.identityCredential, index: gm.membershipIndex}}
+	for _, m := range gm.memberships {
+		ownMemberships = append(ownMemberships, m)
+	}
+
+	for _, m := range ownMemberships {
+		leaf, err := gm.getLeaf(m.index)
+		if err != nil {
+			return err
+		}
+
+		if leaf != m.identityCredential.IDCommitment {
+			gm.log.Error("membership of the node not found in the merkle tree", zap.Uint("index", uint(m.index)))
+			return fmt.Errorf("%w: index %d", ErrMembershipNotInTree, m.index)
+		}
+	}
+
+	return nil
+}
+
+func (gm *StaticGroupManager) isOwnMembership(index rln.MembershipIndex) bool {
+	if index == gm.membershipIndex {
+		return true
+	}
+
+	for _, m := range gm.memberships {
+		if m.index == index {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (gm *StaticGroupManager) Start(ctx context.Context) error {
 	gm.log.Info("mounting rln-relay in off-chain/static mode")
 
-	// add members to the Merkle tree
+	gm.Lock()
+	stopped := gm.stopped
+	released := gm.rln == nil
+	gm.Unlock()
+	if released {
+		return ErrRLNInstanceReleased
+	}
+	if stopped {
+		return gm.restart()
+	}
+
+	// add members to the Merkle tree. When a snapshot was restored, the members
+	// it contains are already in the tree
+	members := gm.group
+	if gm.restored {
+		members = gm.group[gm.nextIndex:]
+
+		gm.Lock()
+		for _, idCommitment := range gm.group[:gm.nextIndex] {
+			gm.members[idCommitment] = struct{}{}
+		}
+		gm.Unlock()
+	}
+
+	err := gm.InsertMembers(members)
+	if err != nil {
+		return err
+	}
 
-	err := gm.insertMembers(gm.group)
+	err = gm.verifyOwnMemberships()
 	if err != nil {
 		return err
 	}
 
+	gm.Lock()
 	gm.group = nil // Deleting group to release memory
+	gm.started = true
+	gm.Unlock()
+
+	return nil
+}
+
+// InsertMembers appends a batch of IDCommitments to the Merkle tree. The merkle root is
+// only updated once all the members have been inserted. If any of the IDCommitments is
+// already in the group, no member is inserted and ErrDuplicateIDCommitment is returned. If
+// the batch does not fit in the tree, ErrMemberIndexOutOfRange is returned instead
+func (gm *StaticGroupManager) InsertMembers(idCommitments []rln.IDCommitment) error {
+	if len(idCommitments) == 0 {
+		return nil
+	}
+
+	startIndex, err := gm.insertMembers(idCommitments)
+	if err != nil {
+		return err
+	}
+
+	members := make([]group_manager.Member, len(idCommitments))
+	for i, idCommitment := range idCommitments {
+		members[i] = group_manager.Member{IDCommitment: idCommitment, Index: startIndex + rln.MembershipIndex(i)}
+	}
+	gm.NotifyMemberInserted(gm.log, members)
 
 	return nil
 }
 
-func (gm *StaticGroupManager) insertMembers(idCommitments []rln.IDCommitment) error {
-	err := gm.rln.InsertMembers(rln.MembershipIndex(gm.nextIndex), idCommitments)
+// InsertMember appends an IDCommitment to the Merkle tree, and returns the index of the leaf it was
+// inserted at. ErrDuplicateIDCommitment is returned if the IDCommitment is already in the group,
+// and ErrMemberIndexOutOfRange if the tree is full
+func (gm *StaticGroupManager) InsertMember(idCommitment rln.IDCommitment) (rln.MembershipIndex, error) {
+	index, err := gm.insertMembers([]rln.IDCommitment{idCommitment})
+	if err != nil {
+		return 0, err
+	}
+
+	gm.NotifyMemberInserted(gm.log, []group_manager.Member{{IDCommitment: idCommitment, Index: index}})
+
+	return index, nil
+}
+
+// insertMembers appends a batch of IDCommitments to the Merkle tree, and returns the index of the first one
+func (gm *StaticGroupManager) insertMembers(idCommitments []rln.IDCommitment) (rln.MembershipIndex, error) {
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return 0, ErrGroupManagerStopped
+	}
+
+	batch := make(map[rln.IDCommitment]struct{}, len(idCommitments))
+	for i, idCommitment := range idCommitments {
+		if _, ok := batch[idCommitment]; ok || gm.isMember(idCommitment) {
+			gm.log.Warn("duplicate IDCommitment", zap.Uint64("index", gm.nextIndex+uint64(i)))
+			return 0, fmt.Errorf("%w: member %d of the batch", ErrDuplicateIDCommitment, i)
+		}
+		batch[idCommitment] = struct{}{}
+	}
+
+	if gm.nextIndex+uint64(len(idCommitments)) > maxMembers {
+		return 0, fmt.Errorf("%w: %d members from index %d, capacity %d", ErrMemberIndexOutOfRange, len(idCommitments), gm.nextIndex, maxMembers)
+	}
+
+	startIndex := rln.MembershipIndex(gm.nextIndex)
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	err := gm.rln.InsertMembers(startIndex, idCommitments)
+	rlnLock.Unlock()
 	if err != nil {
 		gm.log.Error("inserting members into merkletree", zap.Error(err))
+		return 0, err
+	}
+
+	gm.nextIndex += uint64(len(idCommitments))
+	for idCommitment := range batch {
+		gm.members[idCommitment] = struct{}{}
+	}
+
+	gm.rootTracker.UpdateLatestRoot(gm.nextIndex - 1)
+
+	return startIndex, nil
+}
+
+// InsertMemberAt appends an IDCommitment to the Merkle tree, checking that index is the next
+// index of the tree. A member arriving out of order is rejected with ErrMemberIndexGap, so a
+// missed event is detected before the IDCommitments and their indices get misaligned. Inserting
+// it beyond the capacity of the tree returns ErrMemberIndexOutOfRange, and inserting an
+// IDCommitment that is already in the group returns ErrDuplicateIDCommitment
+func (gm *StaticGroupManager) InsertMemberAt(index rln.MembershipIndex, idCommitment rln.IDCommitment) error {
+	err := gm.insertMemberAt(index, idCommitment, true)
+	if err != nil {
+		return err
+	}
+
+	gm.NotifyMemberInserted(gm.log, []group_manager.Member{{IDCommitment: idCommitment, Index: index}})
+
+	return nil
+}
+
+// InsertMemberOutOfOrder inserts an IDCommitment at a specific index of the Merkle tree. Unlike
+// InsertMemberAt, members can be inserted in any order: leaves between the indices of the members
+// inserted so far remain empty until their member arrives. Inserting a member in an index that is
+// already occupied returns ErrMemberAlreadyInserted, inserting it beyond the capacity of the tree
+// returns ErrMemberIndexOutOfRange, and inserting an IDCommitment that is already in the group
+// returns ErrDuplicateIDCommitment
+func (gm *StaticGroupManager) InsertMemberOutOfOrder(index rln.MembershipIndex, idCommitment rln.IDCommitment) error {
+	err := gm.insertMemberAt(index, idCommitment, false)
+	if err != nil {
+		return err
+	}
+
+	gm.NotifyMemberInserted(gm.log, []group_manager.Member{{IDCommitment: idCommitment, Index: index}})
+
+	return nil
+}
+
+func (gm *StaticGroupManager) insertMemberAt(index rln.MembershipIndex, idCommitment rln.IDCommitment, inOrder bool) error {
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return ErrGroupManagerStopped
+	}
+
+	if uint64(index) >= maxMembers {
+		return fmt.Errorf("%w: index %d, capacity %d", ErrMemberIndexOutOfRange, index, maxMembers)
+	}
+
+	if inOrder && uint64(index) != gm.nextIndex {
+		gm.log.Warn("member out of order", zap.Uint("index", uint(index)), zap.Uint64("nextIndex", gm.nextIndex))
+		return fmt.Errorf("%w: expected %d, got %d", ErrMemberIndexGap, gm.nextIndex, index)
+	}
+
+	if gm.isMember(idCommitment) {
+		gm.log.Warn("duplicate IDCommitment", zap.Uint("index", uint(index)))
+		return ErrDuplicateIDCommitment
+	}
+
+	if uint64(index) < gm.nextIndex {
+		leaf, err := gm.getLeaf(index)
+		if err != nil {
+			return err
+		}
+
+		if leaf != (rln.IDCommitment{}) {
+			return ErrMemberAlreadyInserted
+		}
+	}
+
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	err := gm.rln.InsertMemberAt(index, idCommitment)
+	rlnLock.Unlock()
+	if err != nil {
+		gm.log.Error("inserting member into merkletree", zap.Uint("index", uint(index)), zap.Error(err))
 		return err
 	}
 
-	latestIndex := gm.nextIndex + uint64(len(idCommitments))
+	if uint64(index) >= gm.nextIndex {
+		gm.nextIndex = uint64(index) + 1
+	}
+	gm.members[idCommitment] = struct{}{}
+
+	gm.rootTracker.UpdateLatestRoot(uint64(index))
+
+	return nil
+}
+
+// RemoveMember deletes the IDCommitment at a specific index of the Merkle tree, i.e. when a
+// membership is revoked. The memberships of the node itself cannot be removed
+func (gm *StaticGroupManager) RemoveMember(index rln.MembershipIndex) error {
+	gm.Lock()
+	defer gm.Unlock()
 
-	gm.rootTracker.UpdateLatestRoot(latestIndex)
+	if gm.stopped {
+		return ErrGroupManagerStopped
+	}
 
-	gm.nextIndex = latestIndex + 1
+	if gm.isOwnMembership(index) {
+		return ErrRemoveOwnMembership
+	}
+
+	if uint64(index) >= gm.nextIndex {
+		return ErrMemberNotFound
+	}
+
+	leaf, err := gm.getLeaf(index)
+	if err != nil {
+		return err
+	}
+
+	if leaf == (rln.IDCommitment{}) {
+		return ErrMemberNotFound
+	}
+
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	err = gm.rln.DeleteMember(index)
+	rlnLock.Unlock()
+	if err != nil {
+		gm.log.Error("deleting member from merkletree", zap.Uint("index", uint(index)), zap.Error(err))
+		return err
+	}
+	delete(gm.members, leaf)
+
+	gm.rootTracker.UpdateLatestRoot(uint64(index))
 
 	return nil
 }
 
+// ReloadGroup replaces the members of the group by newGroup while the node is running. Only the leaves
+// of the Merkle tree that differ are updated, and the merkle root is updated once all the changes were
+// applied. The memberships of the node must still be in the new group at the same index. The new group
+// is validated before the tree is modified, and the tree is left untouched if the reload fails
+func (gm *StaticGroupManager) ReloadGroup(newGroup []rln.IDCommitment) error {
+	inserted, err := gm.reloadGroup(newGroup)
+	if err != nil {
+		return err
+	}
+
+	gm.NotifyMemberInserted(gm.log, inserted)
+
+	return nil
+}
+
+func (gm *StaticGroupManager) reloadGroup(newGroup []rln.IDCommitment) ([]group_manager.Member, error) {
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return nil, ErrGroupManagerStopped
+	}
+
+	if gm.group != nil {
+		return nil, errors.New("the group can only be reloaded once the group manager is started")
+	}
+
+	ownMemberships := []membership{{identityCredential: *gm.identityCredential, index: gm.membershipIndex}}
+	for _, m := range gm.memberships {
+		ownMemberships = append(ownMemberships, m)
+	}
+
+	for _, m := range ownMemberships {
+		if int(m.index) >= len(newGroup) || newGroup[m.index] != m.identityCredential.IDCommitment {
+			return nil, errors.New("the memberships of the node are not in the new group")
+		}
+	}
+
+	if uint64(len(newGroup)) > maxMembers {
+		return nil, fmt.Errorf("%w: %d members, capacity %d", ErrMemberIndexOutOfRange, len(newGroup), maxMembers)
+	}
+
+	// empty commitments leave their leaf empty
+	members := make(map[rln.IDCommitment]struct{}, len(newGroup))
+	for i, idCommitment := range newGroup {
+		if idCommitment == (rln.IDCommitment{}) {
+			continue
+		}
+		if _, ok := members[idCommitment]; ok {
+			return nil, fmt.Errorf("%w: member %d of the new group", ErrDuplicateIDCommitment, i)
+		}
+		members[idCommitment] = struct{}{}
+	}
+
+	size := max(gm.nextIndex, uint64(len(newGroup)))
+
+	// the lock is held while the tree is updated, so proofs are never generated with a partially reloaded group
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	changes, err := gm.leafChanges(newGroup, size)
+	if err == nil {
+		err = gm.applyLeafChanges(changes)
+	}
+	rlnLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var inserted []group_manager.Member
+	var removed int
+	for _, c := range changes {
+		if c.current != (rln.IDCommitment{}) {
+			removed++
+		}
+		if c.desired != (rln.IDCommitment{}) {
+			inserted = append(inserted, group_manager.Member{IDCommitment: c.desired, Index: c.index})
+		}
+	}
+
+	gm.members = members
+	gm.nextIndex = uint64(len(newGroup))
+
+	if len(changes) != 0 {
+		gm.rootTracker.UpdateLatestRoot(size - 1)
+	}
+
+	gm.log.Info("reloaded rln group", zap.Int("members", len(newGroup)), zap.Int("inserted", len(inserted)), zap.Int("removed", removed))
+
+	return inserted, nil
+}
+
+// leafChange is a leaf of the Merkle tree that must be updated to reload the group
+type leafChange struct {
+	index   rln.MembershipIndex
+	current rln.IDCommitment
+	desired rln.IDCommitment
+}
+
+// leafChanges returns the changes needed for the first size leaves of the Merkle tree to match
+// newGroup. The RLN lock must be held
+func (gm *StaticGroupManager) leafChanges(newGroup []rln.IDCommitment, size uint64) ([]leafChange, error) {
+	var changes []leafChange
+	for i := uint64(0); i < size; i++ {
+		index := rln.MembershipIndex(i)
+
+		var current, desired rln.IDCommitment
+		if i < gm.nextIndex {
+			leaf, err := gm.rln.GetLeaf(index)
+			if err != nil {
+				return nil, err
+			}
+			current = leaf
+		}
+
+		if i < uint64(len(newGroup)) {
+			desired = newGroup[i]
+		}
+
+		if current != desired {
+			changes = append(changes, leafChange{index: index, current: current, desired: desired})
+		}
+	}
+	return changes, nil
+}
+
+// applyLeafChanges updates the leaves of the Merkle tree. If a change fails, the changes applied
+// so far are rolled back, so the tree is left as it was. The RLN lock must be held
+func (gm *StaticGroupManager) applyLeafChanges(changes []leafChange) error {
+	for i, c := range changes {
+		err := gm.setLeaf(c.index, c.current, c.desired)
+		if err == nil {
+			continue
+		}
+
+		gm.log.Error("updating leaf of merkletree", zap.Uint("index", uint(c.index)), zap.Error(err))
+
+		// the failed change may have been partially applied
+		for j := i; j >= 0; j-- {
+			if rollbackErr := gm.restoreLeaf(changes[j].index, changes[j].current); rollbackErr != nil {
+				gm.log.Error("rolling back leaf of merkletree", zap.Uint("index", uint(changes[j].index)), zap.Error(rollbackErr))
+			}
+		}
+
+		return err
+	}
+	return nil
+}
+
+// setLeaf replaces the current member of the leaf at index by the desired one. Empty
+// commitments leave the leaf empty. The RLN lock must be held
+func (gm *StaticGroupManager) setLeaf(index rln.MembershipIndex, current rln.IDCommitment, desired rln.IDCommitment) error {
+	if current != (rln.IDCommitment{}) {
+		if err := gm.rln.DeleteMember(index); err != nil {
+			return err
+		}
+	}
+
+	if desired != (rln.IDCommitment{}) {
+		return gm.rln.InsertMemberAt(index, desired)
+	}
+
+	return nil
+}
+
+// restoreLeaf sets the leaf at index back to previous. The RLN lock must be held
+func (gm *StaticGroupManager) restoreLeaf(index rln.MembershipIndex, previous rln.IDCommitment) error {
+	leaf, err := gm.rln.GetLeaf(index)
+	if err != nil {
+		return err
+	}
+
+	if leaf == previous {
+		return nil
+	}
+
+	return gm.setLeaf(index, leaf, previous)
+}
+
 func (gm *StaticGroupManager) IdentityCredentials() (rln.IdentityCredential, error) {
 	if gm.identityCredential == nil {
 		return rln.IdentityCredential{}, errors.New("identity credential has not been setup")
@@ -87,10 +685,107 @@ func (gm *StaticGroupManager) MembershipIndex() rln.MembershipIndex {
 	return gm.membershipIndex
 }
 
-// Stop is a function created just to comply with the GroupManager interface (it does nothing)
+// CurrentRootHex returns the current merkle root of the tree as a hex string
+func (gm *StaticGroupManager) CurrentRootHex() string {
+	return gm.rootTracker.CurrentRootHex()
+}
+
+// CurrentRoot returns the current merkle root of the tree
+func (gm *StaticGroupManager) CurrentRoot() rln.MerkleNode {
+	return gm.rootTracker.CurrentRoot()
+}
+
+// AcceptedRoots returns a copy of the merkle roots accepted when validating the proofs of incoming messages
+func (gm *StaticGroupManager) AcceptedRoots() []rln.MerkleNode {
+	return gm.rootTracker.AcceptedRoots()
+}
+
+// restart resumes a stopped group manager. The Merkle tree built before it was stopped is kept
+// by the RLN instance, so only the members kept in memory are loaded again from the tree
+func (gm *StaticGroupManager) restart() error {
+	gm.Lock()
+
+	leaves, err := gm.leaves()
+	if err != nil {
+		gm.Unlock()
+		return err
+	}
+
+	gm.members = make(map[rln.IDCommitment]struct{}, len(leaves))
+	for _, leaf := range leaves {
+		if leaf != (rln.IDCommitment{}) {
+			gm.members[leaf] = struct{}{}
+		}
+	}
+	gm.stopped = false
+	gm.Unlock()
+
+	gm.log.Info("restarted rln group manager", zap.Uint64("members", gm.nextIndex))
+
+	return gm.verifyOwnMemberships()
+}
+
+// getLeaf returns the leaf of the Merkle tree at index
+func (gm *StaticGroupManager) getLeaf(index rln.MembershipIndex) (rln.IDCommitment, error) {
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	defer rlnLock.Unlock()
+
+	return gm.rln.GetLeaf(index)
+}
+
+// leaves returns the first nextIndex leaves of the Merkle tree
+func (gm *StaticGroupManager) leaves() ([]rln.IDCommitment, error) {
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	defer rlnLock.Unlock()
+
+	result := make([]rln.IDCommitment, gm.nextIndex)
+	for i := range result {
+		leaf, err := gm.rln.GetLeaf(rln.MembershipIndex(i))
+		if err != nil {
+			return nil, err
+		}
+		result[i] = leaf
+	}
+	return result, nil
+}
+
+// Stop flushes the Merkle tree to the RLN database and releases the members kept in memory. While
+// stopped, the operations modifying the tree return ErrGroupManagerStopped. Start resumes the group
+// manager with the tree kept by the RLN instance, unless the group manager was created WithOwnedRLN:
+// its RLN instance is then released, and Start returns ErrRLNInstanceReleased. Stopping a group manager
+// that was never started does nothing unless it owns its RLN instance
 func (gm *StaticGroupManager) Stop() error {
-	// Do nothing
-	return nil
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.stopped {
+		return nil
+	}
+
+	if !gm.started && !gm.ownsRLN {
+		// the tree has not been built yet, so the group is kept for Start
+		return nil
+	}
+
+	gm.stopped = true
+
+	gm.group = nil
+	gm.members = nil
+
+	rlnLock := gm.rootTracker.RLNLock()
+	rlnLock.Lock()
+	err := gm.rln.Flush()
+	rlnLock.Unlock()
+
+	if gm.ownsRLN {
+		// go-zerokit-rln does not expose a way to free an instance, so the group manager drops its
+		// reference to it, and never uses it again
+		gm.rln = nil
+	}
+
+	return err
 }
 
 func (gm *StaticGroupManager) IsReady(ctx context.Context) (bool, error) {
@@ -0,0 +1,666 @@
+package static
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+	"github.com/waku-org/go-zerokit-rln/rln"
+)
+
+func newTestGroupManager(t *testing.T, group []rln.IdentityCredential) *StaticGroupManager {
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+	gm, err := NewStaticGroupManager(commitments, group[0], 0, rlnInstance, rootTracker, utils.Logger())
+	require.NoError(t, err)
+
+	return gm
+}
+
+func TestInsertMemberOutOfOrder(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	// Members inserted in order
+	inOrder := newTestGroupManager(t, group)
+	err = inOrder.Start(context.Background())
+	require.NoError(t, err)
+	expectedRoot, err := inOrder.rln.GetMerkleRoot()
+	require.NoError(t, err)
+
+	// Members inserted out of order
+	outOfOrder := newTestGroupManager(t, group)
+	for _, i := range []int{3, 0, 4, 2, 1} {
+		err = outOfOrder.InsertMemberOutOfOrder(rln.MembershipIndex(i), group[i].IDCommitment)
+		require.NoError(t, err)
+	}
+
+	root, err := outOfOrder.rln.GetMerkleRoot()
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.True(t, outOfOrder.rootTracker.ContainsRoot(root))
+
+	for i := range group {
+		leaf, err := outOfOrder.rln.GetLeaf(rln.MembershipIndex(i))
+		require.NoError(t, err)
+		require.Equal(t, group[i].IDCommitment, leaf)
+	}
+}
+
+func TestInsertMemberOutOfOrderDuplicate(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group)
+
+	err = gm.InsertMemberOutOfOrder(2, group[2].IDCommitment)
+	require.NoError(t, err)
+
+	// Index is already occupied
+	err = gm.InsertMemberOutOfOrder(2, group[1].IDCommitment)
+	require.ErrorIs(t, err, ErrMemberAlreadyInserted)
+
+	// Gap left before index 2 can still be filled
+	err = gm.InsertMemberOutOfOrder(0, group[0].IDCommitment)
+	require.NoError(t, err)
+}
+
+func TestInsertMemberAtOutOfRange(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group)
+
+	err = gm.InsertMemberOutOfOrder(rln.MembershipIndex(maxMembers), group[1].IDCommitment)
+	require.ErrorIs(t, err, ErrMemberIndexOutOfRange)
+
+	err = gm.InsertMemberAt(rln.MembershipIndex(maxMembers), group[1].IDCommitment)
+	require.ErrorIs(t, err, ErrMemberIndexOutOfRange)
+
+	// The last leaf of the tree can be used
+	err = gm.InsertMemberOutOfOrder(rln.MembershipIndex(maxMembers-1), group[1].IDCommitment)
+	require.NoError(t, err)
+
+	leaf, err := gm.rln.GetLeaf(rln.MembershipIndex(maxMembers - 1))
+	require.NoError(t, err)
+	require.Equal(t, group[1].IDCommitment, leaf)
+
+	// Members cannot be appended after the last leaf
+	_, err = gm.InsertMember(group[2].IDCommitment)
+	require.ErrorIs(t, err, ErrMemberIndexOutOfRange)
+
+	err = gm.InsertMembers([]rln.IDCommitment{group[0].IDCommitment, group[2].IDCommitment})
+	require.ErrorIs(t, err, ErrMemberIndexOutOfRange)
+}
+
+func TestCurrentRootHex(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	// Compute the expected root inserting the same members in a separate tree
+	expectedRLN, err := rln.NewRLN()
+	require.NoError(t, err)
+	for i, c := range group {
+		err = expectedRLN.InsertMemberAt(rln.MembershipIndex(i), c.IDCommitment)
+		require.NoError(t, err)
+	}
+	expectedRoot, err := expectedRLN.GetMerkleRoot()
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group)
+	rootChanges, unsubscribe := gm.rootTracker.Subscribe()
+	defer unsubscribe()
+
+	for i, c := range group {
+		err = gm.InsertMemberAt(rln.MembershipIndex(i), c.IDCommitment)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, hex.EncodeToString(expectedRoot[:]), gm.CurrentRootHex())
+	require.Equal(t, expectedRoot, gm.CurrentRoot())
+
+	// A root is accepted for each insertion in addition to the root of the empty tree,
+	// and the returned roots are a copy
+	acceptedRoots := gm.AcceptedRoots()
+	require.Len(t, acceptedRoots, len(group)+1)
+	require.Equal(t, expectedRoot, acceptedRoots[len(acceptedRoots)-1])
+	acceptedRoots[len(acceptedRoots)-1] = rln.MerkleNode{}
+	require.True(t, gm.rootTracker.ContainsRoot(expectedRoot))
+	require.Equal(t, expectedRoot, gm.AcceptedRoots()[len(group)])
+
+	// A root change event is emitted for each insertion
+	var lastRoot rln.MerkleNode
+	for range group {
+		lastRoot = <-rootChanges
+	}
+	require.Equal(t, expectedRoot, lastRoot)
+}
+
+func TestRemoveMember(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group)
+	for i, c := range group {
+		err = gm.InsertMemberAt(rln.MembershipIndex(i), c.IDCommitment)
+		require.NoError(t, err)
+	}
+	previousRoot := gm.rootTracker.CurrentRoot()
+
+	err = gm.RemoveMember(2)
+	require.NoError(t, err)
+
+	leaf, err := gm.rln.GetLeaf(2)
+	require.NoError(t, err)
+	require.Equal(t, rln.IDCommitment{}, leaf)
+
+	root, err := gm.rln.GetMerkleRoot()
+	require.NoError(t, err)
+	require.NotEqual(t, previousRoot, root)
+	require.Equal(t, root, gm.rootTracker.CurrentRoot())
+
+	// The leaf is already empty
+	err = gm.RemoveMember(2)
+	require.ErrorIs(t, err, ErrMemberNotFound)
+
+	// Index 0 belongs to the node
+	err = gm.RemoveMember(0)
+	require.ErrorIs(t, err, ErrRemoveOwnMembership)
+}
+
+func TestInsertMembers(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(6)
+	require.NoError(t, err)
+
+	// Members inserted one at a time
+	oneByOne := newTestGroupManager(t, group)
+	for i, c := range group {
+		err = oneByOne.InsertMemberAt(rln.MembershipIndex(i), c.IDCommitment)
+		require.NoError(t, err)
+	}
+	expectedRoot, err := oneByOne.rln.GetMerkleRoot()
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	// Members inserted in batches
+	batched := newTestGroupManager(t, group)
+	rootChanges, unsubscribe := batched.rootTracker.Subscribe()
+	defer unsubscribe()
+
+	require.NoError(t, batched.InsertMembers(commitments[:4]))
+	require.NoError(t, batched.InsertMembers(commitments[4:]))
+	require.NoError(t, batched.InsertMembers(nil))
+
+	root, err := batched.rln.GetMerkleRoot()
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.Equal(t, expectedRoot, batched.rootTracker.CurrentRoot())
+
+	// The root is updated once per batch
+	<-rootChanges
+	require.Equal(t, expectedRoot, <-rootChanges)
+	require.Len(t, rootChanges, 0)
+}
+
+func TestPersistRestore(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	expected := newTestGroupManager(t, group)
+	require.NoError(t, expected.Start(context.Background()))
+	expectedRoot := expected.rootTracker.CurrentRoot()
+
+	path := filepath.Join(t.TempDir(), "snapshot")
+
+	// Snapshot taken when the group had 3 members
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+	gm, err := NewStaticGroupManager(commitments[:3], group[0], 0, rlnInstance, group_manager.NewMerkleRootTracker(5, rlnInstance), utils.Logger())
+	require.NoError(t, err)
+	require.NoError(t, gm.Start(context.Background()))
+	require.NoError(t, gm.Persist(path))
+
+	// Restoring the snapshot only inserts the 2 new members
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+	gm, err = NewStaticGroupManager(commitments, group[0], 0, rlnInstance, rootTracker, utils.Logger())
+	require.NoError(t, err)
+	require.NoError(t, gm.Restore(path))
+	require.Equal(t, uint64(3), gm.nextIndex)
+	require.NoError(t, gm.Start(context.Background()))
+	require.Equal(t, expectedRoot, rootTracker.CurrentRoot())
+
+	// The snapshot does not match a different tree, so the tree is rebuilt
+	fresh := newTestGroupManager(t, group)
+	require.ErrorIs(t, fresh.Restore(path), ErrIncompatibleSnapshot)
+	require.NoError(t, fresh.Start(context.Background()))
+	require.Equal(t, expectedRoot, fresh.rootTracker.CurrentRoot())
+
+	// Missing snapshot
+	require.Error(t, fresh.Restore(filepath.Join(t.TempDir(), "missing")))
+}
+
+func TestRestoreSnapshotOfOtherMembers(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(4)
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group[:3] {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot")
+
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+	gm, err := NewStaticGroupManager(commitments, group[2], 2, rlnInstance, group_manager.NewMerkleRootTracker(5, rlnInstance), utils.Logger())
+	require.NoError(t, err)
+	require.NoError(t, gm.Start(context.Background()))
+	require.NoError(t, gm.Persist(path))
+
+	// A member before the index of the node is replaced, so the tree is rebuilt
+	changedGroup := []rln.IdentityCredential{group[0], group[3], group[2]}
+	changedCommitments := []rln.IDCommitment{group[0].IDCommitment, group[3].IDCommitment, group[2].IDCommitment}
+
+	expected := newTestGroupManager(t, changedGroup)
+	require.NoError(t, expected.Start(context.Background()))
+
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+	gm, err = NewStaticGroupManager(changedCommitments, group[2], 2, rlnInstance, rootTracker, utils.Logger())
+	require.NoError(t, err)
+	require.ErrorIs(t, gm.Restore(path), ErrIncompatibleSnapshot)
+	require.NoError(t, gm.Start(context.Background()))
+	require.Equal(t, expected.rootTracker.CurrentRoot(), rootTracker.CurrentRoot())
+
+	leaf, err := rlnInstance.GetLeaf(1)
+	require.NoError(t, err)
+	require.Equal(t, group[3].IDCommitment, leaf)
+}
+
+func TestMultipleMemberships(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group)
+
+	err = gm.AddMembership(group[2], 2, "/app/1/chat/proto", "/app/1/bridge/proto")
+	require.NoError(t, err)
+
+	// The credential must match the member of the group
+	err = gm.AddMembership(group[2], 3, "/app/1/other/proto")
+	require.Error(t, err)
+
+	err = gm.AddMembership(group[3], 3, "/app/1/chat/proto")
+	require.ErrorIs(t, err, ErrContentTopicMembershipExists)
+
+	err = gm.Start(context.Background())
+	require.NoError(t, err)
+
+	// Memberships can also be added once the group is in the tree
+	err = gm.AddMembership(group[4], 4, "/app/1/status/proto")
+	require.NoError(t, err)
+
+	credential, index, err := gm.CredentialFor("/app/1/bridge/proto")
+	require.NoError(t, err)
+	require.Equal(t, group[2], credential)
+	require.Equal(t, rln.MembershipIndex(2), index)
+
+	credential, index, err = gm.CredentialFor("/app/1/status/proto")
+	require.NoError(t, err)
+	require.Equal(t, group[4], credential)
+	require.Equal(t, rln.MembershipIndex(4), index)
+
+	// Other content topics use the default membership
+	credential, index, err = gm.CredentialFor("/app/1/unknown/proto")
+	require.NoError(t, err)
+	require.Equal(t, group[0], credential)
+	require.Equal(t, rln.MembershipIndex(0), index)
+
+	require.ErrorIs(t, gm.RemoveMember(2), ErrRemoveOwnMembership)
+	require.NoError(t, gm.RemoveMember(1))
+}
+
+func TestDuplicateIDCommitment(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(4)
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	gm := newTestGroupManager(t, group)
+	require.NoError(t, gm.InsertMembers(commitments[:2]))
+
+	// Already in the tree
+	err = gm.InsertMembers([]rln.IDCommitment{commitments[2], commitments[1]})
+	require.ErrorIs(t, err, ErrDuplicateIDCommitment)
+	require.Equal(t, uint64(2), gm.nextIndex)
+
+	// Repeated within the batch
+	err = gm.InsertMembers([]rln.IDCommitment{commitments[2], commitments[2]})
+	require.ErrorIs(t, err, ErrDuplicateIDCommitment)
+	require.Equal(t, uint64(2), gm.nextIndex)
+
+	err = gm.InsertMemberOutOfOrder(3, commitments[0])
+	require.ErrorIs(t, err, ErrDuplicateIDCommitment)
+
+	// A removed member can be inserted again
+	require.NoError(t, gm.RemoveMember(1))
+	require.NoError(t, gm.InsertMemberOutOfOrder(3, commitments[1]))
+
+	// A static group with a repeated member cannot be started
+	duplicated := newTestGroupManager(t, []rln.IdentityCredential{group[0], group[1], group[0]})
+	require.ErrorIs(t, duplicated.Start(context.Background()), ErrDuplicateIDCommitment)
+}
+
+func TestMemberInsertedCallback(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(4)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group[:3])
+
+	var inserted []group_manager.Member
+	gm.OnMemberInserted(func(idCommitment rln.IDCommitment, index rln.MembershipIndex) error {
+		// The callback is executed once the lock is released
+		gm.Lock()
+		defer gm.Unlock()
+
+		inserted = append(inserted, group_manager.Member{IDCommitment: idCommitment, Index: index})
+		return nil
+	})
+
+	// An error returned by a callback does not fail the insertion
+	gm.OnMemberInserted(func(idCommitment rln.IDCommitment, index rln.MembershipIndex) error {
+		return errors.New("callback error")
+	})
+
+	require.NoError(t, gm.Start(context.Background()))
+	require.NoError(t, gm.InsertMemberOutOfOrder(5, group[3].IDCommitment))
+
+	require.Equal(t, []group_manager.Member{
+		{IDCommitment: group[0].IDCommitment, Index: 0},
+		{IDCommitment: group[1].IDCommitment, Index: 1},
+		{IDCommitment: group[2].IDCommitment, Index: 2},
+		{IDCommitment: group[3].IDCommitment, Index: 5},
+	}, inserted)
+
+	// Failed insertions are not notified
+	require.ErrorIs(t, gm.InsertMemberOutOfOrder(6, group[3].IDCommitment), ErrDuplicateIDCommitment)
+	require.Len(t, inserted, 4)
+}
+
+func TestReloadGroup(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(6)
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	gm := newTestGroupManager(t, group[:4])
+
+	// The group manager must be started
+	require.Error(t, gm.ReloadGroup(commitments))
+
+	require.NoError(t, gm.Start(context.Background()))
+
+	var inserted []group_manager.Member
+	gm.OnMemberInserted(func(idCommitment rln.IDCommitment, index rln.MembershipIndex) error {
+		inserted = append(inserted, group_manager.Member{IDCommitment: idCommitment, Index: index})
+		return nil
+	})
+
+	// Member 2 is removed, member 3 is replaced and members 4 and 5 are added
+	newGroup := []rln.IDCommitment{commitments[0], commitments[1], {}, commitments[5], commitments[4]}
+	require.NoError(t, gm.ReloadGroup(newGroup))
+
+	expectedRLN, err := rln.NewRLN()
+	require.NoError(t, err)
+	for i, c := range newGroup {
+		if c != (rln.IDCommitment{}) {
+			require.NoError(t, expectedRLN.InsertMemberAt(rln.MembershipIndex(i), c))
+		}
+	}
+	expectedRoot, err := expectedRLN.GetMerkleRoot()
+	require.NoError(t, err)
+
+	root, err := gm.rln.GetMerkleRoot()
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+	require.Equal(t, expectedRoot, gm.CurrentRoot())
+
+	require.Equal(t, []group_manager.Member{
+		{IDCommitment: commitments[5], Index: 3},
+		{IDCommitment: commitments[4], Index: 4},
+	}, inserted)
+
+	// Removed members can be inserted again
+	require.NoError(t, gm.InsertMembers([]rln.IDCommitment{commitments[2]}))
+	require.ErrorIs(t, gm.InsertMembers([]rln.IDCommitment{commitments[4]}), ErrDuplicateIDCommitment)
+
+	// The membership of the node must remain in the group
+	require.Error(t, gm.ReloadGroup([]rln.IDCommitment{commitments[1], commitments[0]}))
+
+	require.ErrorIs(t, gm.ReloadGroup([]rln.IDCommitment{commitments[0], commitments[1], commitments[1]}), ErrDuplicateIDCommitment)
+
+	// A group that does not fit in the tree is rejected before any leaf is modified
+	previousRoot, err := gm.rln.GetMerkleRoot()
+	require.NoError(t, err)
+	oversized := make([]rln.IDCommitment, maxMembers+1)
+	copy(oversized, []rln.IDCommitment{commitments[0], commitments[1], commitments[3]})
+	require.ErrorIs(t, gm.ReloadGroup(oversized), ErrMemberIndexOutOfRange)
+
+	root, err = gm.rln.GetMerkleRoot()
+	require.NoError(t, err)
+	require.Equal(t, previousRoot, root)
+	require.Equal(t, previousRoot, gm.CurrentRoot())
+}
+
+func TestNewStaticGroupManagerIndexBounds(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+	rootTracker := group_manager.NewMerkleRootTracker(5, rlnInstance)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	_, err = NewStaticGroupManager(nil, group[0], 0, rlnInstance, rootTracker, utils.Logger())
+	require.Error(t, err)
+
+	_, err = NewStaticGroupManager(commitments, group[0], 3, rlnInstance, rootTracker, utils.Logger())
+	require.ErrorContains(t, err, "out of range")
+
+	_, err = NewStaticGroupManager(commitments, group[0], 1, rlnInstance, rootTracker, utils.Logger())
+	require.ErrorContains(t, err, "does not match")
+
+	_, err = NewStaticGroupManager(commitments, group[2], 2, rlnInstance, rootTracker, utils.Logger())
+	require.NoError(t, err)
+}
+
+func TestInsertMemberAt(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group[:1])
+	err = gm.Start(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, gm.InsertMemberAt(1, group[1].IDCommitment))
+
+	// A gap in the indices is detected and nothing is inserted
+	err = gm.InsertMemberAt(3, group[3].IDCommitment)
+	require.ErrorIs(t, err, ErrMemberIndexGap)
+	leaf, err := gm.rln.GetLeaf(3)
+	require.NoError(t, err)
+	require.Equal(t, rln.IDCommitment{}, leaf)
+
+	// So is an index that was already used
+	require.ErrorIs(t, gm.InsertMemberAt(1, group[2].IDCommitment), ErrMemberIndexGap)
+
+	require.NoError(t, gm.InsertMemberAt(2, group[2].IDCommitment))
+	require.NoError(t, gm.InsertMemberAt(3, group[3].IDCommitment))
+
+	for i := 0; i < 4; i++ {
+		leaf, err := gm.rln.GetLeaf(rln.MembershipIndex(i))
+		require.NoError(t, err)
+		require.Equal(t, group[i].IDCommitment, leaf)
+	}
+}
+
+func TestStop(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group[:3])
+	err = gm.Start(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, gm.Stop())
+	// Stopping twice is a no-op
+	require.NoError(t, gm.Stop())
+
+	require.ErrorIs(t, gm.InsertMembers([]rln.IDCommitment{group[3].IDCommitment}), ErrGroupManagerStopped)
+	require.ErrorIs(t, gm.InsertMemberOutOfOrder(3, group[3].IDCommitment), ErrGroupManagerStopped)
+	require.ErrorIs(t, gm.InsertMemberAt(3, group[3].IDCommitment), ErrGroupManagerStopped)
+	require.ErrorIs(t, gm.RemoveMember(1), ErrGroupManagerStopped)
+	require.ErrorIs(t, gm.ReloadGroup([]rln.IDCommitment{group[0].IDCommitment}), ErrGroupManagerStopped)
+	require.ErrorIs(t, gm.AddMembership(group[1], 1, "topic"), ErrGroupManagerStopped)
+	require.ErrorIs(t, gm.Persist(filepath.Join(t.TempDir(), "snapshot")), ErrGroupManagerStopped)
+
+	// The tree is left untouched
+	leaf, err := gm.rln.GetLeaf(3)
+	require.NoError(t, err)
+	require.Equal(t, rln.IDCommitment{}, leaf)
+}
+
+func TestRestart(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group[:3])
+	require.NoError(t, gm.AddMembership(group[2], 2, "/app/1/chat/proto"))
+	require.NoError(t, gm.Start(context.Background()))
+	root := gm.CurrentRoot()
+
+	require.NoError(t, gm.Stop())
+	require.NoError(t, gm.Start(context.Background()))
+
+	// The tree built before stopping is kept
+	require.Equal(t, root, gm.CurrentRoot())
+	require.Equal(t, uint64(3), gm.nextIndex)
+
+	// The members and memberships are still known
+	require.ErrorIs(t, gm.InsertMembers([]rln.IDCommitment{group[1].IDCommitment}), ErrDuplicateIDCommitment)
+	_, index, err := gm.CredentialFor("/app/1/chat/proto")
+	require.NoError(t, err)
+	require.Equal(t, rln.MembershipIndex(2), index)
+
+	require.NoError(t, gm.InsertMembers([]rln.IDCommitment{group[3].IDCommitment}))
+	leaf, err := gm.rln.GetLeaf(3)
+	require.NoError(t, err)
+	require.Equal(t, group[3].IDCommitment, leaf)
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group)
+	require.NoError(t, gm.Stop())
+
+	// The group manager was never started, so it starts as usual
+	require.NoError(t, gm.Start(context.Background()))
+	for i := range group {
+		leaf, err := gm.rln.GetLeaf(rln.MembershipIndex(i))
+		require.NoError(t, err)
+		require.Equal(t, group[i].IDCommitment, leaf)
+	}
+}
+
+func TestStopReleasesOwnedRLN(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(3)
+	require.NoError(t, err)
+
+	rlnInstance, err := rln.NewRLN()
+	require.NoError(t, err)
+
+	var commitments []rln.IDCommitment
+	for _, c := range group {
+		commitments = append(commitments, c.IDCommitment)
+	}
+
+	gm, err := NewStaticGroupManager(commitments, group[0], 0, rlnInstance, group_manager.NewMerkleRootTracker(5, rlnInstance), utils.Logger(), WithOwnedRLN())
+	require.NoError(t, err)
+
+	require.NoError(t, gm.Start(context.Background()))
+	require.NoError(t, gm.Stop())
+	require.Nil(t, gm.rln)
+
+	// The released instance is not used to restart the group manager
+	require.ErrorIs(t, gm.Start(context.Background()), ErrRLNInstanceReleased)
+	require.ErrorIs(t, gm.InsertMembers([]rln.IDCommitment{group[1].IDCommitment}), ErrGroupManagerStopped)
+	require.Nil(t, gm.rln)
+}
+
+func TestInsertMember(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(5)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group[:2])
+	err = gm.Start(context.Background())
+	require.NoError(t, err)
+
+	for i := 2; i < 5; i++ {
+		index, err := gm.InsertMember(group[i].IDCommitment)
+		require.NoError(t, err)
+		require.Equal(t, rln.MembershipIndex(i), index)
+
+		leaf, err := gm.rln.GetLeaf(index)
+		require.NoError(t, err)
+		require.Equal(t, group[i].IDCommitment, leaf)
+	}
+
+	_, err = gm.InsertMember(group[0].IDCommitment)
+	require.ErrorIs(t, err, ErrDuplicateIDCommitment)
+}
+
+func TestStartVerifiesOwnMembership(t *testing.T) {
+	group, _, err := rln.CreateMembershipList(4)
+	require.NoError(t, err)
+
+	gm := newTestGroupManager(t, group[:3])
+
+	// The group slice is modified after the group manager was created, so the
+	// IDCommitment of the node is no longer at its index
+	gm.group[0] = group[3].IDCommitment
+
+	err = gm.Start(context.Background())
+	require.ErrorIs(t, err, ErrMembershipNotInTree)
+}
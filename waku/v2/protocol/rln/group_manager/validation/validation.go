@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"context"
+	"errors"
+
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
+	"github.com/waku-org/go-zerokit-rln/rln"
+	"go.uber.org/zap"
+)
+
+// ErrNoMembership is returned when requesting the credential of the node, since a validation only
+// group manager has no membership in the group
+var ErrNoMembership = errors.New("validation only rln relay has no membership, so it cannot generate proofs")
+
+// ErrNoMerkleTree is returned when inserting members, since a validation only group manager does not
+// hold the Merkle tree of the group
+var ErrNoMerkleTree = errors.New("validation only rln relay does not hold the merkle tree of the group")
+
+// ValidationGroupManager is used by nodes that verify the proofs of the messages they relay without
+// holding the Merkle tree of the group. Instead of inserting the members in the tree, it only tracks
+// the merkle roots that are accepted, which must be provided with AddRoot, i.e. by a node following
+// the membership contract. Such a node has no membership, so it cannot generate proofs
+type ValidationGroupManager struct {
+	group_manager.MemberInsertedCallbacks
+
+	log         *zap.Logger
+	rootTracker *group_manager.MerkleRootTracker
+}
+
+// NewValidationGroupManager creates a validation only group manager. The merkle roots are tracked by
+// rootTracker, which must be the one used by the relay to validate the messages. Since the node does not
+// hold the Merkle tree, it is usually created with group_manager.NewRootOnlyMerkleRootTracker
+func NewValidationGroupManager(rootTracker *group_manager.MerkleRootTracker, log *zap.Logger) *ValidationGroupManager {
+	// A tracker bound to an RLN instance starts with the root of the empty tree, which is not a root of the group
+	rootTracker.SetValidRootsPerBlock(nil)
+
+	return &ValidationGroupManager{
+		log:         log.Named("rln-validation"),
+		rootTracker: rootTracker,
+	}
+}
+
+// AddRoot adds a merkle root of the group to the window of accepted roots. Once the window is full,
+// the oldest root is no longer accepted
+func (gm *ValidationGroupManager) AddRoot(blockNumber uint64, root rln.MerkleNode) {
+	gm.rootTracker.PushRoot(blockNumber, root)
+}
+
+func (gm *ValidationGroupManager) Start(ctx context.Context) error {
+	gm.log.Info("mounting rln-relay in validation only mode")
+	return nil
+}
+
+// IdentityCredentials always returns ErrNoMembership
+func (gm *ValidationGroupManager) IdentityCredentials() (rln.IdentityCredential, error) {
+	return rln.IdentityCredential{}, ErrNoMembership
+}
+
+// MembershipIndex is a function created just to comply with the GroupManager interface (it always returns 0)
+func (gm *ValidationGroupManager) MembershipIndex() rln.MembershipIndex {
+	return 0
+}
+
+func (gm *ValidationGroupManager) CurrentRootHex() string {
+	return gm.rootTracker.CurrentRootHex()
+}
+
+func (gm *ValidationGroupManager) CurrentRoot() rln.MerkleNode {
+	return gm.rootTracker.CurrentRoot()
+}
+
+func (gm *ValidationGroupManager) AcceptedRoots() []rln.MerkleNode {
+	return gm.rootTracker.AcceptedRoots()
+}
+
+// Stop is a function created just to comply with the GroupManager interface (it does nothing)
+func (gm *ValidationGroupManager) Stop() error {
+	return nil
+}
+
+// IsReady returns true once a merkle root has been added, so the messages can be validated
+func (gm *ValidationGroupManager) IsReady(ctx context.Context) (bool, error) {
+	return len(gm.rootTracker.AcceptedRoots()) != 0, nil
+}
+
+// Persist is a function created just to comply with the GroupManager interface (it does nothing).
+// There is no Merkle tree to persist
+func (gm *ValidationGroupManager) Persist(path string) error {
+	return nil
+}
+
+// InsertMembers always returns ErrNoMerkleTree, since a validation only group manager does not hold
+// the Merkle tree of the group
+func (gm *ValidationGroupManager) InsertMembers(idCommitments []rln.IDCommitment) error {
+	return ErrNoMerkleTree
+}
+
+// Restore is a function created just to comply with the GroupManager interface (it does nothing).
+// There is no Merkle tree to restore
+func (gm *ValidationGroupManager) Restore(path string) error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package rln
 
 import (
+	"errors"
 	"time"
 
 	"github.com/libp2p/go-libp2p/p2p/metricshelper"
@@ -27,10 +28,20 @@ var invalidMessagesTotal = prometheus.NewCounterVec(
 	},
 	[]string{"type"})
 
+var validationResultsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "waku_rln_validation_results_total",
+		Help: "number of messages validated per result and reason",
+	},
+	[]string{"result", "reason"})
+
 func generateBucketsForHistogram(length int) []float64 {
 	// Generate a custom set of 5 buckets for a given length
 	numberOfBuckets := 5
 	stepSize := length / numberOfBuckets
+	if stepSize == 0 {
+		stepSize = 1
+	}
 	var buckets []float64
 	for i := 1; i <= 5; i++ {
 		buckets = append(buckets, float64(stepSize*i))
@@ -38,11 +49,35 @@ func generateBucketsForHistogram(length int) []float64 {
 	return buckets
 }
 
-// This metric will be useful in detecting the index of the root in the acceptable window of roots
-var validMessagesTotal = prometheus.NewHistogram(prometheus.HistogramOpts{
-	Name:    "waku_rln_valid_messages_total",
-	Help:    "number of valid messages with their roots tracked",
-	Buckets: generateBucketsForHistogram(acceptableRootWindowSize),
+// newValidMessagesHistogram creates the metric used to detect the index of the root in the acceptable
+// window of roots, with buckets spread over the window. If the metric was already registered, i.e. by
+// another relay, the registered one is used
+func newValidMessagesHistogram(reg prometheus.Registerer, acceptableRootWindowSize int) prometheus.Histogram {
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "waku_rln_valid_messages_total",
+		Help:    "number of valid messages with their roots tracked",
+		Buckets: generateBucketsForHistogram(acceptableRootWindowSize),
+	})
+
+	err := reg.Register(histogram)
+	if err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &alreadyRegistered) {
+			panic(err)
+		}
+		if existing, ok := alreadyRegistered.ExistingCollector.(prometheus.Histogram); ok {
+			return existing
+		}
+	}
+
+	return histogram
+}
+
+// This metric can be used to detect clock skew between the node and the publishers of messages
+var epochGap = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "waku_rln_epoch_gap",
+	Help:    "difference between the current epoch and the epoch of the messages received",
+	Buckets: []float64{-8, -4, -2, -1, 0, 1, 2, 4, 8},
 })
 
 var errorsTotal = prometheus.NewCounterVec(
@@ -81,8 +116,9 @@ var collectors = []prometheus.Collector{
 	messagesTotal,
 	spamMessagesTotal,
 	invalidMessagesTotal,
+	validationResultsTotal,
 	errorsTotal,
-	validMessagesTotal,
+	epochGap,
 	proofVerificationTotal,
 	proofVerificationDurationSeconds,
 	proofGenerationDurationSeconds,
@@ -113,21 +149,25 @@ type Metrics interface {
 	RecordMessage()
 	RecordSpam(contentTopic string)
 	RecordInvalidMessage(cause invalidCategory)
+	RecordValidationResult(result messageValidationResult, reason string)
 	RecordError(err errCategory)
 	RecordProofVerification(duration time.Duration)
 	RecordProofGeneration(duration time.Duration)
 	RecordValidMessages(rootIndex int)
+	RecordEpochGap(gap int64)
 	RecordInstanceCreation(duration time.Duration)
 }
 
 type metricsImpl struct {
-	reg prometheus.Registerer
+	reg           prometheus.Registerer
+	validMessages prometheus.Histogram
 }
 
-func newMetrics(reg prometheus.Registerer) Metrics {
+func newMetrics(reg prometheus.Registerer, acceptableRootWindowSize int) Metrics {
 	metricshelper.RegisterCollectors(reg, collectors...)
 	return &metricsImpl{
-		reg: reg,
+		reg:           reg,
+		validMessages: newValidMessagesHistogram(reg, acceptableRootWindowSize),
 	}
 }
 
@@ -167,7 +207,18 @@ func (m *metricsImpl) RecordInvalidMessage(cause invalidCategory) {
 	invalidMessagesTotal.WithLabelValues(string(cause)).Inc()
 }
 
+// RecordValidationResult increases the counter for the outcome of a message validation. The reason
+// is empty for valid messages
+func (m *metricsImpl) RecordValidationResult(result messageValidationResult, reason string) {
+	validationResultsTotal.WithLabelValues(result.String(), reason).Inc()
+}
+
 // RecordValidMessages records the root index used for valid messages
 func (m *metricsImpl) RecordValidMessages(rootIndex int) {
-	validMessagesTotal.Observe(float64(rootIndex))
+	m.validMessages.Observe(float64(rootIndex))
+}
+
+// RecordEpochGap records the difference between the current epoch and the epoch of a message
+func (m *metricsImpl) RecordEpochGap(gap int64) {
+	epochGap.Observe(float64(gap))
 }
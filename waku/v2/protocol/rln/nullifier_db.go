@@ -0,0 +1,110 @@
+package rln
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/waku-org/go-zerokit-rln/rln"
+)
+
+// key: epoch (8 bytes) + external nullifier + nullifier + share x + share y
+const nullifierKeyLen = 8 + 32 + 32 + 32 + 32
+
+// NullifierDB persists the proofs of the nullifier log in a LevelDB database keyed by epoch, so that
+// messages exceeding the rate limit can still be detected after a restart
+type NullifierDB struct {
+	db *leveldb.DB
+}
+
+// NewNullifierDB opens (or creates) the nullifier log database stored in path
+func NewNullifierDB(path string) (*NullifierDB, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &NullifierDB{db: db}, nil
+}
+
+func epochKey(epoch uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, epoch)
+}
+
+func nullifierKey(epoch uint64, proofMD rln.ProofMetadata) []byte {
+	key := epochKey(epoch)
+	key = append(key, proofMD.ExternalNullifier[:]...)
+	key = append(key, proofMD.Nullifier[:]...)
+	key = append(key, proofMD.ShareX[:]...)
+	key = append(key, proofMD.ShareY[:]...)
+	return key
+}
+
+// Put stores the metadata of a proof for an epoch
+func (d *NullifierDB) Put(epoch uint64, proofMD rln.ProofMetadata) error {
+	return d.db.Put(nullifierKey(epoch, proofMD), nil, nil)
+}
+
+// nullifierEntry is the metadata of a proof along with the epoch of its message
+type nullifierEntry struct {
+	epoch   uint64
+	proofMD rln.ProofMetadata
+}
+
+// putBatch stores the metadata of multiple proofs in a single write
+func (d *NullifierDB) putBatch(entries []nullifierEntry) error {
+	batch := new(leveldb.Batch)
+	for _, e := range entries {
+		batch.Put(nullifierKey(e.epoch, e.proofMD), nil)
+	}
+	return d.db.Write(batch, nil)
+}
+
+// Load returns all the proofs stored in the database, grouped by epoch
+func (d *NullifierDB) Load() (map[uint64][]rln.ProofMetadata, error) {
+	result := make(map[uint64][]rln.ProofMetadata)
+
+	iter := d.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != nullifierKeyLen {
+			return nil, errors.New("invalid nullifier log entry")
+		}
+
+		epoch := binary.BigEndian.Uint64(key[0:8])
+
+		var proofMD rln.ProofMetadata
+		copy(proofMD.ExternalNullifier[:], key[8:40])
+		copy(proofMD.Nullifier[:], key[40:72])
+		copy(proofMD.ShareX[:], key[72:104])
+		copy(proofMD.ShareY[:], key[104:136])
+
+		result[epoch] = append(result[epoch], proofMD)
+	}
+
+	return result, iter.Error()
+}
+
+// Prune deletes the proofs of all the epochs older than beforeEpoch
+func (d *NullifierDB) Prune(beforeEpoch uint64) error {
+	iter := d.db.NewIterator(&util.Range{Start: epochKey(0), Limit: epochKey(beforeEpoch)}, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(iter.Key())
+	}
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return d.db.Write(batch, nil)
+}
+
+// Close closes the database
+func (d *NullifierDB) Close() error {
+	return d.db.Close()
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"github.com/waku-org/go-zerokit-rln/rln"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 // NullifierLog is the log of nullifiers and Shamir shares of the past messages grouped per epoch
@@ -19,20 +21,100 @@ type NullifierLog struct {
 	log            *zap.Logger
 	nullifierLog   map[rln.Nullifier][]rln.ProofMetadata // Might make sense to replace this map by a shrinkable map due to https://github.com/golang/go/issues/20135.
 	nullifierQueue []rln.Nullifier
+
+	db          *NullifierDB
+	pending     []nullifierEntry // proofs not written in the database yet
+	latestEpoch uint64
+	maxEpochGap int64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
+// nullifierFlushInterval is how often the proofs inserted in a persistent log are written in the database
+const nullifierFlushInterval = 1 * time.Second
+
 // NewNullifierLog creates an instance of NullifierLog
 func NewNullifierLog(ctx context.Context, log *zap.Logger) *NullifierLog {
+	return newNullifierLog(ctx, defaultMaxEpochGap, log)
+}
+
+func newNullifierLog(ctx context.Context, maxEpochGap int64, log *zap.Logger) *NullifierLog {
 	result := &NullifierLog{
 		nullifierLog: make(map[rln.Nullifier][]rln.ProofMetadata),
 		log:          log,
+		maxEpochGap:  maxEpochGap,
 	}
 
-	go result.cleanup(ctx)
+	result.start(ctx)
 
 	return result
 }
 
+// NewPersistentNullifierLog creates an instance of NullifierLog whose proofs are also stored in a
+// database. The proofs of the recent epochs stored in the database are loaded, so messages exceeding
+// the rate limit are still detected after a restart
+func NewPersistentNullifierLog(ctx context.Context, db *NullifierDB, log *zap.Logger) (*NullifierLog, error) {
+	return newPersistentNullifierLog(ctx, db, defaultMaxEpochGap, log)
+}
+
+func newPersistentNullifierLog(ctx context.Context, db *NullifierDB, maxEpochGap int64, log *zap.Logger) (*NullifierLog, error) {
+	stored, err := db.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NullifierLog{
+		nullifierLog: make(map[rln.Nullifier][]rln.ProofMetadata),
+		log:          log,
+		db:           db,
+		maxEpochGap:  maxEpochGap,
+	}
+
+	for epoch := range stored {
+		if epoch > result.latestEpoch {
+			result.latestEpoch = epoch
+		}
+	}
+
+	oldestEpoch := result.oldestEpoch()
+	err = db.Prune(oldestEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	epochs := maps.Keys(stored)
+	slices.Sort(epochs)
+	for _, epoch := range epochs {
+		if epoch < oldestEpoch {
+			continue
+		}
+		for _, proofMD := range stored[epoch] {
+			_ = result.insert(proofMD)
+		}
+	}
+
+	log.Debug("loaded nullifier log", zap.Int("epochs", len(epochs)), zap.Uint64("latestEpoch", result.latestEpoch))
+
+	result.start(ctx)
+
+	return result, nil
+}
+
+func (n *NullifierLog) start(ctx context.Context) {
+	ctx, n.cancel = context.WithCancel(ctx)
+	n.wg.Add(1)
+	go n.cleanup(ctx)
+}
+
+// Stop stops the cleanup of the log and writes the pending proofs in the database. The database
+// must not be closed before Stop returns
+func (n *NullifierLog) Stop() {
+	n.cancel()
+	n.wg.Wait()
+	n.flushDB()
+}
+
 var errAlreadyExists = errors.New("proof already exists")
 
 // Insert stores a proof in the nullifier log only if it doesnt exist already
@@ -40,6 +122,42 @@ func (n *NullifierLog) Insert(proofMD rln.ProofMetadata) error {
 	n.Lock()
 	defer n.Unlock()
 
+	return n.insert(proofMD)
+}
+
+// InsertForEpoch stores a proof of a message from an epoch in the nullifier log only if it doesnt
+// exist already. If the log is persistent, the proof is also queued to be written in the database
+func (n *NullifierLog) InsertForEpoch(epoch uint64, proofMD rln.ProofMetadata) error {
+	n.Lock()
+	defer n.Unlock()
+
+	err := n.insert(proofMD)
+	if err != nil {
+		return err
+	}
+
+	if n.db == nil {
+		return nil
+	}
+
+	if epoch > n.latestEpoch {
+		n.latestEpoch = epoch
+	}
+
+	n.pending = append(n.pending, nullifierEntry{epoch: epoch, proofMD: proofMD})
+
+	return nil
+}
+
+// oldestEpoch returns the oldest epoch whose proofs are kept in the database
+func (n *NullifierLog) oldestEpoch() uint64 {
+	if n.latestEpoch < uint64(n.maxEpochGap) {
+		return 0
+	}
+	return n.latestEpoch - uint64(n.maxEpochGap)
+}
+
+func (n *NullifierLog) insert(proofMD rln.ProofMetadata) error {
 	proofs, ok := n.nullifierLog[proofMD.ExternalNullifier]
 	if ok {
 		// check if an identical record exists
@@ -60,63 +178,128 @@ func (n *NullifierLog) Insert(proofMD rln.ProofMetadata) error {
 // epoch and nullifier as `msg`'s epoch and nullifier but different Shamir secret shares
 // otherwise, returns false
 func (n *NullifierLog) HasDuplicate(proofMD rln.ProofMetadata) (bool, error) {
+	_, found, err := n.FindDuplicate(proofMD)
+	return found, err
+}
+
+// Contains returns true if the exact same proof is already in the log, i.e. the message was re-delivered
+func (n *NullifierLog) Contains(proofMD rln.ProofMetadata) bool {
+	n.RLock()
+	defer n.RUnlock()
+
+	for _, p := range n.nullifierLog[proofMD.ExternalNullifier] {
+		if p.Equals(proofMD) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindDuplicate works like HasDuplicate, and additionally returns the proof of the message that was
+// already in the log, so the shares of both messages can be used to recover the secret of the sender.
+// An identical proof is not a duplicate, as it belongs to the same message
+func (n *NullifierLog) FindDuplicate(proofMD rln.ProofMetadata) (rln.ProofMetadata, bool, error) {
 	n.RLock()
 	defer n.RUnlock()
 
 	proofs, ok := n.nullifierLog[proofMD.ExternalNullifier]
 	if !ok {
 		// epoch does not exist
-		return false, nil
+		return rln.ProofMetadata{}, false, nil
 	}
 
 	for _, p := range proofs {
 		if p.Equals(proofMD) {
-			// there is an identical record, ignore the msg
-			return true, nil
+			// there is an identical record, the msg was re-delivered
+			return rln.ProofMetadata{}, false, nil
 		}
 	}
 
 	// check for a message with the same nullifier but different secret shares
-	matched := false
 	for _, it := range proofs {
 		if bytes.Equal(it.Nullifier[:], proofMD.Nullifier[:]) && (!bytes.Equal(it.ShareX[:], proofMD.ShareX[:]) || !bytes.Equal(it.ShareY[:], proofMD.ShareY[:])) {
-			matched = true
-			break
+			return it, true, nil
 		}
 	}
 
-	return matched, nil
+	return rln.ProofMetadata{}, false, nil
 }
 
-// cleanup cleans up the log every time there are more than MaxEpochGap epochs stored in it
+// cleanup cleans up the log every time there are more than maxEpochGap epochs stored in it
+// and writes the pending proofs in the database every nullifierFlushInterval
 func (n *NullifierLog) cleanup(ctx context.Context) {
 	defer utils.LogOnPanic()
+	defer n.wg.Done()
 	t := time.NewTicker(1 * time.Minute) // TODO: tune this
 	defer t.Stop()
+	flushTicker := time.NewTicker(nullifierFlushInterval)
+	defer flushTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
+		case <-flushTicker.C:
+			n.flushDB()
+
 		case <-t.C:
 			func() {
 				n.Lock()
 				defer n.Unlock()
 
-				if int64(len(n.nullifierQueue)) < maxEpochGap {
+				// at least one epoch is cleared, as a gap of 0 only accepts messages from the current epoch
+				count := max(n.maxEpochGap, 1)
+				if int64(len(n.nullifierQueue)) < count {
 					return
 				}
 
-				n.log.Debug("clearing epochs from the nullifier log", zap.Int64("count", maxEpochGap))
+				n.log.Debug("clearing epochs from the nullifier log", zap.Int64("count", count))
 
-				toDelete := n.nullifierQueue[0:maxEpochGap]
+				toDelete := n.nullifierQueue[0:count]
 				for _, l := range toDelete {
 					delete(n.nullifierLog, l)
 				}
-				n.nullifierQueue = n.nullifierQueue[maxEpochGap:]
+				n.nullifierQueue = n.nullifierQueue[count:]
 			}()
+
+			n.flushDB()
+			n.pruneDB()
 		}
 	}
 
 }
+
+// flushDB writes in the database the proofs inserted since the last flush
+func (n *NullifierLog) flushDB() {
+	n.Lock()
+	pending := n.pending
+	n.pending = nil
+	n.Unlock()
+
+	if n.db == nil || len(pending) == 0 {
+		return
+	}
+
+	err := n.db.putBatch(pending)
+	if err != nil {
+		n.log.Error("writing nullifier log database", zap.Error(err))
+	}
+}
+
+// pruneDB deletes from the database the proofs of the epochs older than maxEpochGap
+func (n *NullifierLog) pruneDB() {
+	if n.db == nil {
+		return
+	}
+
+	n.RLock()
+	oldestEpoch := n.oldestEpoch()
+	n.RUnlock()
+
+	err := n.db.Prune(oldestEpoch)
+	if err != nil {
+		n.log.Error("pruning nullifier log database", zap.Error(err))
+	}
+}
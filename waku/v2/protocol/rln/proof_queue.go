@@ -0,0 +1,123 @@
+package rln
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+)
+
+// DefaultProofQueueSize is the default number of messages that can wait for a proof to be generated
+const DefaultProofQueueSize = 100
+
+// ErrProofQueueFull is returned when a message cannot be queued for proof generation because
+// the queue is full
+var ErrProofQueueFull = errors.New("rln proof generation queue is full")
+
+// ErrProofQueueStopped is returned when a message is queued for proof generation while the
+// queue is not running
+var ErrProofQueueStopped = errors.New("rln proof generation queue is not running")
+
+type proofRequest struct {
+	msg             *pb.WakuMessage
+	senderEpochTime time.Time
+	resultCh        chan error
+}
+
+// proofQueue generates the proofs of the queued messages in the background, so publishers do not
+// block while a proof is generated. zerokit generates proofs with the Merkle tree of the RLN instance,
+// which must not be used concurrently, so the proofs are generated one at a time in the order they
+// were queued. Once queueSize messages are waiting, new messages are rejected instead of piling up
+type proofQueue struct {
+	sync.RWMutex
+
+	queueSize int
+
+	queue  chan proofRequest
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newProofQueue(queueSize int) *proofQueue {
+	if queueSize <= 0 {
+		queueSize = DefaultProofQueueSize
+	}
+
+	return &proofQueue{
+		queueSize: queueSize,
+	}
+}
+
+func (p *proofQueue) start(ctx context.Context, generate func(msg *pb.WakuMessage, senderEpochTime time.Time) error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.queue != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.queue = make(chan proofRequest, p.queueSize)
+
+	p.wg.Add(1)
+	go p.work(ctx, p.queue, generate)
+}
+
+func (p *proofQueue) work(ctx context.Context, queue <-chan proofRequest, generate func(msg *pb.WakuMessage, senderEpochTime time.Time) error) {
+	defer utils.LogOnPanic()
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-queue:
+			req.resultCh <- generate(req.msg, req.senderEpochTime)
+		}
+	}
+}
+
+func (p *proofQueue) stop() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.queue == nil {
+		return
+	}
+
+	p.cancel()
+	p.wg.Wait()
+
+	// Requests that were not processed are failed so nobody waits on them forever
+	close(p.queue)
+	for req := range p.queue {
+		req.resultCh <- ErrProofQueueStopped
+	}
+	p.queue = nil
+}
+
+func (p *proofQueue) enqueue(msg *pb.WakuMessage, senderEpochTime time.Time) (<-chan error, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	if p.queue == nil {
+		return nil, ErrProofQueueStopped
+	}
+
+	req := proofRequest{
+		msg:             msg,
+		senderEpochTime: senderEpochTime,
+		resultCh:        make(chan error, 1),
+	}
+
+	select {
+	case p.queue <- req:
+		return req.resultCh, nil
+	default:
+		return nil, ErrProofQueueFull
+	}
+}
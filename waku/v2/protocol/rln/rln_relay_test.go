@@ -3,6 +3,9 @@ package rln
 import (
 	"context"
 	"crypto/rand"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/waku-org/go-waku/waku/v2/protocol/relay"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager"
 	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager/static"
+	"github.com/waku-org/go-waku/waku/v2/protocol/rln/group_manager/validation"
 	rlnpb "github.com/waku-org/go-waku/waku/v2/protocol/rln/pb"
 	"github.com/waku-org/go-waku/waku/v2/timesource"
 	"github.com/waku-org/go-waku/waku/v2/utils"
@@ -51,7 +55,7 @@ func (s *WakuRLNRelaySuite) TestOffchainMode() {
 		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
 	}
 
-	rlnInstance, rootTracker, err := GetRLNInstanceAndRootTracker("")
+	rlnInstance, rootTracker, err := GetRLNInstanceAndRootTracker(filepath.Join(s.T().TempDir(), "rln_tree.db"))
 	s.Require().NoError(err)
 
 	// index indicates the position of a membership key pair in the static list of group keys i.e., groupKeyPairs
@@ -88,7 +92,7 @@ func (s *WakuRLNRelaySuite) TestUpdateLogAndHasDuplicate() {
 	rlnInstance, err := r.NewRLN()
 	s.Require().NoError(err)
 
-	rootTracker := group_manager.NewMerkleRootTracker(acceptableRootWindowSize, rlnInstance)
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
 
 	rlnRelay := &WakuRLNRelay{
 		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
@@ -115,13 +119,18 @@ func (s *WakuRLNRelaySuite) TestUpdateLogAndHasDuplicate() {
 		shareY3[i] = shareX3[i]
 	}
 
-	rlpProof1, err := proto.Marshal(&rlnpb.RateLimitProof{Epoch: epoch[:], Nullifier: nullifier1[:], ShareX: shareX1[:], ShareY: shareY1[:]})
+	// the proof, root and identifier are not used, but must have the expected length
+	var zkProof r.ZKSNARK
+	var root r.MerkleNode
+	var rlnIdentifier r.RLNIdentifier
+
+	rlpProof1, err := proto.Marshal(&rlnpb.RateLimitProof{Epoch: epoch[:], Nullifier: nullifier1[:], ShareX: shareX1[:], ShareY: shareY1[:], Proof: zkProof[:], MerkleRoot: root[:], RlnIdentifier: rlnIdentifier[:]})
 	s.Require().NoError(err)
 
-	rlpProof2, err := proto.Marshal(&rlnpb.RateLimitProof{Epoch: epoch[:], Nullifier: nullifier2[:], ShareX: shareX2[:], ShareY: shareY2[:]})
+	rlpProof2, err := proto.Marshal(&rlnpb.RateLimitProof{Epoch: epoch[:], Nullifier: nullifier2[:], ShareX: shareX2[:], ShareY: shareY2[:], Proof: zkProof[:], MerkleRoot: root[:], RlnIdentifier: rlnIdentifier[:]})
 	s.Require().NoError(err)
 
-	rlpProof3, err := proto.Marshal(&rlnpb.RateLimitProof{Epoch: epoch[:], Nullifier: nullifier3[:], ShareX: shareX3[:], ShareY: shareY3[:]})
+	rlpProof3, err := proto.Marshal(&rlnpb.RateLimitProof{Epoch: epoch[:], Nullifier: nullifier3[:], ShareX: shareX3[:], ShareY: shareY3[:], Proof: zkProof[:], MerkleRoot: root[:], RlnIdentifier: rlnIdentifier[:]})
 	s.Require().NoError(err)
 
 	msgProof1, err := BytesToRateLimitProof(rlpProof1)
@@ -150,6 +159,12 @@ func (s *WakuRLNRelaySuite) TestUpdateLogAndHasDuplicate() {
 	err = rlnRelay.nullifierLog.Insert(md1)
 	s.Require().NoError(err)
 
+	// a re-delivery of wm1 is not a duplicate
+	result1, err = rlnRelay.nullifierLog.HasDuplicate(md1)
+	s.Require().NoError(err)
+	s.Require().False(result1)
+	s.Require().True(rlnRelay.nullifierLog.Contains(md1))
+
 	// no duplicate for wm2 should be found, its nullifier differs from wm1
 	result2, err := rlnRelay.nullifierLog.HasDuplicate(md2)
 	s.Require().NoError(err)
@@ -164,6 +179,94 @@ func (s *WakuRLNRelaySuite) TestUpdateLogAndHasDuplicate() {
 	s.Require().NoError(err)
 	s.Require().True(result3) // It's a duplicate
 
+	// The proof of wm1 is returned as evidence of the double signaling
+	previous, found, err := rlnRelay.nullifierLog.FindDuplicate(md3)
+	s.Require().NoError(err)
+	s.Require().True(found)
+	s.Require().Equal(md1, previous)
+
+	// AdaptSpamHandler keeps supporting handlers that do not use the evidence
+	called := false
+	handler := AdaptSpamHandler(func(msg *pb.WakuMessage, topic string) error {
+		called = true
+		return nil
+	})
+	s.Require().NoError(handler(&pb.WakuMessage{}, "topic", SpamEvidence{}))
+	s.Require().True(called)
+	s.Require().Nil(AdaptSpamHandler(nil))
+}
+
+func (s *WakuRLNRelaySuite) TestRedeliveredMessage() {
+	groupKeyPairs, _, err := r.CreateMembershipList(10)
+	s.Require().NoError(err)
+
+	var groupIDCommitments []r.IDCommitment
+	for _, c := range groupKeyPairs {
+		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
+	}
+
+	index := r.MembershipIndex(5)
+
+	rlnInstance, err := r.NewRLN()
+	s.Require().NoError(err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
+
+	groupManager, err := static.NewStaticGroupManager(groupIDCommitments, groupKeyPairs[index], index, rlnInstance, rootTracker, utils.Logger())
+	s.Require().NoError(err)
+
+	rlnRelay := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: groupManager,
+			RootTracker:  rootTracker,
+			RLN:          rlnInstance,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	err = groupManager.Start(context.Background())
+	s.Require().NoError(err)
+
+	var evidences []SpamEvidence
+	validator := rlnRelay.ValidatorWithEvidence(func(msg *pb.WakuMessage, topic string, evidence SpamEvidence) error {
+		evidences = append(evidences, evidence)
+		return nil
+	})
+
+	now := time.Now()
+
+	wm1 := &pb.WakuMessage{Payload: []byte("Valid message")}
+	err = rlnRelay.AppendRLNProof(wm1, now)
+	s.Require().NoError(err)
+
+	wm2 := &pb.WakuMessage{Payload: []byte("Spam")}
+	err = rlnRelay.AppendRLNProof(wm2, now)
+	s.Require().NoError(err)
+
+	s.Require().True(validator(context.Background(), wm1, "test"))
+
+	// The same message delivered again is ignored without being considered spam
+	result, err := rlnRelay.ValidateMessage(wm1, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(duplicateMessage, result)
+	s.Require().False(validator(context.Background(), wm1, "test"))
+	s.Require().Empty(evidences)
+
+	// A different message in the same epoch is spam, and the evidence has the shares of both messages
+	s.Require().False(validator(context.Background(), wm2, "test"))
+	s.Require().Len(evidences, 1)
+
+	proof1, err := BytesToRateLimitProof(wm1.RateLimitProof)
+	s.Require().NoError(err)
+	proof2, err := BytesToRateLimitProof(wm2.RateLimitProof)
+	s.Require().NoError(err)
+	s.Require().Equal([32]byte(proof1.ShareX), evidences[0].PreviousShareX)
+	s.Require().Equal([32]byte(proof1.ShareY), evidences[0].PreviousShareY)
+	s.Require().Equal([32]byte(proof2.ShareX), evidences[0].ShareX)
+	s.Require().Equal([32]byte(proof2.ShareY), evidences[0].ShareY)
 }
 
 func (s *WakuRLNRelaySuite) TestValidateMessage() {
@@ -189,7 +292,7 @@ func (s *WakuRLNRelaySuite) TestValidateMessage() {
 	rlnInstance, err := r.NewRLN()
 	s.Require().NoError(err)
 
-	rootTracker := group_manager.NewMerkleRootTracker(acceptableRootWindowSize, rlnInstance)
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
 
 	idCredential := groupKeyPairs[index]
 	groupManager, err := static.NewStaticGroupManager(groupIDCommitments, idCredential, index, rlnInstance, rootTracker, utils.Logger())
@@ -204,7 +307,7 @@ func (s *WakuRLNRelaySuite) TestValidateMessage() {
 		},
 		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
 		log:          utils.Logger(),
-		metrics:      newMetrics(prometheus.DefaultRegisterer),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
 	}
 
 	// get the current epoch time
@@ -304,7 +407,7 @@ func (s *WakuRLNRelaySuite) TestRLNRelayGetters() {
 		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
 	}
 
-	rlnInstance, rootTracker, err := GetRLNInstanceAndRootTracker("root")
+	rlnInstance, rootTracker, err := GetRLNInstanceAndRootTracker(filepath.Join(s.T().TempDir(), "rln_tree.db"))
 	s.Require().NoError(err)
 
 	// Set index
@@ -356,7 +459,7 @@ func (s *WakuRLNRelaySuite) TestEdgeCasesValidateMessage() {
 	rlnInstance, err := r.NewRLN()
 	s.Require().NoError(err)
 
-	rootTracker := group_manager.NewMerkleRootTracker(acceptableRootWindowSize, rlnInstance)
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
 
 	idCredential := groupKeyPairs[index]
 	groupManager, err := static.NewStaticGroupManager(groupIDCommitments, idCredential, index, rlnInstance, rootTracker, utils.Logger())
@@ -371,7 +474,7 @@ func (s *WakuRLNRelaySuite) TestEdgeCasesValidateMessage() {
 		},
 		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
 		log:          utils.Logger(),
-		metrics:      newMetrics(prometheus.DefaultRegisterer),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
 	}
 
 	// Get the current epoch time
@@ -409,4 +512,558 @@ func (s *WakuRLNRelaySuite) TestEdgeCasesValidateMessage() {
 	s.Require().NoError(err)
 	s.Require().Equal(invalidMessage, msgValidate2)
 
+	// Test the reported epoch gap matches the age of the message's epoch
+	result, err := rlnRelay.ValidateMessageWithReason(wm2, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(invalidMessage, result.Result)
+	s.Require().True(result.IsInvalid())
+	s.Require().False(result.IsValid())
+	s.Require().Equal(int64(100), result.EpochGap)
+	s.Require().Contains(result.Reason, "epoch gap 100")
+
+	// Test the message is accepted when tolerating a larger clock gap
+	s.Require().Error(rlnRelay.SetMaxClockGap(-time.Second))
+	s.Require().Equal(defaultMaxEpochGap, rlnRelay.MaxEpochGap())
+	s.Require().NoError(rlnRelay.SetMaxClockGap(200 * time.Second))
+	s.Require().Equal(int64(200/r.EPOCH_UNIT_SECONDS), rlnRelay.MaxEpochGap())
+	msgValidate2, err = rlnRelay.ValidateMessage(wm2, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(validMessage, msgValidate2)
+
+}
+
+func (s *WakuRLNRelaySuite) TestPersistentNullifierLog() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := s.T().TempDir()
+
+	md1 := r.ProofMetadata{
+		Nullifier:         r.Nullifier{1},
+		ShareX:            r.MerkleNode{1},
+		ShareY:            r.MerkleNode{1},
+		ExternalNullifier: r.Nullifier{1},
+	}
+	md2 := md1
+	md2.ShareX = r.MerkleNode{2}
+	md2.ShareY = r.MerkleNode{2}
+	oldMD := md1
+	oldMD.ExternalNullifier = r.Nullifier{2}
+
+	db, err := NewNullifierDB(path)
+	s.Require().NoError(err)
+	nullifierLog, err := NewPersistentNullifierLog(ctx, db, utils.Logger())
+	s.Require().NoError(err)
+	s.Require().NoError(nullifierLog.InsertForEpoch(100, oldMD))
+	s.Require().NoError(nullifierLog.InsertForEpoch(100+uint64(defaultMaxEpochGap)+1, md1))
+
+	// Proofs are written in the database when the log is stopped
+	nullifierLog.Stop()
+	s.Require().NoError(db.Close())
+
+	// Restarting the node keeps the proofs of the recent epochs
+	db, err = NewNullifierDB(path)
+	s.Require().NoError(err)
+	defer db.Close()
+	nullifierLog, err = NewPersistentNullifierLog(ctx, db, utils.Logger())
+	s.Require().NoError(err)
+	defer nullifierLog.Stop()
+
+	hasDup, err := nullifierLog.HasDuplicate(md2)
+	s.Require().NoError(err)
+	s.Require().True(hasDup)
+
+	// Epochs older than defaultMaxEpochGap are pruned
+	stored, err := db.Load()
+	s.Require().NoError(err)
+	s.Require().Len(stored, 1)
+	hasDup, err = nullifierLog.HasDuplicate(oldMD)
+	s.Require().NoError(err)
+	s.Require().False(hasDup)
+}
+
+func (s *WakuRLNRelaySuite) TestStartFailureClosesNullifierLog() {
+	groupKeyPairs, _, err := r.CreateMembershipList(2)
+	s.Require().NoError(err)
+
+	rlnInstance, rootTracker, err := GetRLNInstanceAndRootTracker(filepath.Join(s.T().TempDir(), "rln_tree.db"))
+	s.Require().NoError(err)
+
+	// A static group with a repeated member cannot be started
+	groupIDCommitments := []r.IDCommitment{groupKeyPairs[0].IDCommitment, groupKeyPairs[1].IDCommitment, groupKeyPairs[0].IDCommitment}
+	groupManager, err := static.NewStaticGroupManager(groupIDCommitments, groupKeyPairs[1], 1, rlnInstance, rootTracker, utils.Logger())
+	s.Require().NoError(err)
+
+	rlnRelay := New(group_manager.Details{
+		GroupManager: groupManager,
+		RootTracker:  rootTracker,
+		RLN:          rlnInstance,
+	}, timesource.NewDefaultClock(), prometheus.DefaultRegisterer, utils.Logger())
+
+	path := s.T().TempDir()
+	rlnRelay.SetNullifierLogPath(path)
+	s.Require().ErrorIs(rlnRelay.Start(context.Background()), static.ErrDuplicateIDCommitment)
+	s.Require().Nil(rlnRelay.nullifierDB)
+
+	// The database was closed, so it can be opened again
+	db, err := NewNullifierDB(path)
+	s.Require().NoError(err)
+	s.Require().NoError(db.Close())
+}
+
+func (s *WakuRLNRelaySuite) TestAppendRLNProofAsync() {
+	groupKeyPairs, _, err := r.CreateMembershipList(10)
+	s.Require().NoError(err)
+
+	var groupIDCommitments []r.IDCommitment
+	for _, c := range groupKeyPairs {
+		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
+	}
+
+	index := r.MembershipIndex(5)
+
+	rlnInstance, err := r.NewRLN()
+	s.Require().NoError(err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
+
+	groupManager, err := static.NewStaticGroupManager(groupIDCommitments, groupKeyPairs[index], index, rlnInstance, rootTracker, utils.Logger())
+	s.Require().NoError(err)
+
+	rlnRelay := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: groupManager,
+			RootTracker:  rootTracker,
+			RLN:          rlnInstance,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	err = groupManager.Start(context.Background())
+	s.Require().NoError(err)
+
+	now := time.Now()
+
+	// The queue is not running
+	_, err = rlnRelay.AppendRLNProofAsync(&pb.WakuMessage{Payload: []byte("Valid message")}, now)
+	s.Require().ErrorIs(err, ErrProofQueueStopped)
+
+	s.Require().Error(rlnRelay.SetProofQueueSize(-1))
+	s.Require().NoError(rlnRelay.SetProofQueueSize(10))
+	rlnRelay.startProofQueue(context.Background())
+	defer rlnRelay.proofQueue.stop()
+
+	wm1 := &pb.WakuMessage{Payload: []byte("Valid message 1")}
+	wm2 := &pb.WakuMessage{Payload: []byte("Valid message 2")}
+
+	resultCh1, err := rlnRelay.AppendRLNProofAsync(wm1, now)
+	s.Require().NoError(err)
+	resultCh2, err := rlnRelay.AppendRLNProofAsync(wm2, now.Add(time.Second*time.Duration(r.EPOCH_UNIT_SECONDS)))
+	s.Require().NoError(err)
+
+	s.Require().NoError(<-resultCh1)
+	s.Require().NoError(<-resultCh2)
+
+	msgValidate1, err := rlnRelay.ValidateMessage(wm1, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(validMessage, msgValidate1)
+
+	msgValidate2, err := rlnRelay.ValidateMessage(wm2, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(validMessage, msgValidate2)
+}
+
+// Run with -race: the proofs are generated while the tree is modified
+func (s *WakuRLNRelaySuite) TestAppendRLNProofAsyncWhileInsertingMembers() {
+	groupKeyPairs, _, err := r.CreateMembershipList(20)
+	s.Require().NoError(err)
+
+	var groupIDCommitments []r.IDCommitment
+	for _, c := range groupKeyPairs {
+		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
+	}
+
+	index := r.MembershipIndex(5)
+
+	rlnInstance, err := r.NewRLN()
+	s.Require().NoError(err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
+
+	groupManager, err := static.NewStaticGroupManager(groupIDCommitments[:10], groupKeyPairs[index], index, rlnInstance, rootTracker, utils.Logger())
+	s.Require().NoError(err)
+
+	rlnRelay := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: groupManager,
+			RootTracker:  rootTracker,
+			RLN:          rlnInstance,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	err = groupManager.Start(context.Background())
+	s.Require().NoError(err)
+
+	s.Require().NoError(rlnRelay.SetProofQueueSize(10))
+	rlnRelay.startProofQueue(context.Background())
+	defer rlnRelay.proofQueue.stop()
+
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var insertErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, idCommitment := range groupIDCommitments[10:] {
+			_, insertErr = groupManager.InsertMember(idCommitment)
+			if insertErr != nil {
+				return
+			}
+		}
+	}()
+
+	var resultChs []<-chan error
+	for i := 0; i < 10; i++ {
+		msg := &pb.WakuMessage{Payload: []byte(fmt.Sprintf("message %d", i))}
+		resultCh, err := rlnRelay.AppendRLNProofAsync(msg, now.Add(time.Duration(i)*time.Second*time.Duration(r.EPOCH_UNIT_SECONDS)))
+		s.Require().NoError(err)
+		resultChs = append(resultChs, resultCh)
+	}
+
+	for _, resultCh := range resultChs {
+		s.Require().NoError(<-resultCh)
+	}
+
+	wg.Wait()
+	s.Require().NoError(insertErr)
+	s.Require().Equal(uint(20), rlnInstance.LeavesSet())
+}
+
+func (s *WakuRLNRelaySuite) TestProofQueueBackPressure() {
+	queue := newProofQueue(1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	queue.start(context.Background(), func(msg *pb.WakuMessage, senderEpochTime time.Time) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	now := time.Now()
+
+	// Keep the proof generation busy
+	resultCh1, err := queue.enqueue(&pb.WakuMessage{}, now)
+	s.Require().NoError(err)
+	<-started
+
+	// Fill the queue
+	resultCh2, err := queue.enqueue(&pb.WakuMessage{}, now)
+	s.Require().NoError(err)
+
+	_, err = queue.enqueue(&pb.WakuMessage{}, now)
+	s.Require().ErrorIs(err, ErrProofQueueFull)
+
+	close(release)
+	s.Require().NoError(<-resultCh1)
+	<-started
+	s.Require().NoError(<-resultCh2)
+
+	queue.stop()
+
+	_, err = queue.enqueue(&pb.WakuMessage{}, now)
+	s.Require().ErrorIs(err, ErrProofQueueStopped)
+}
+
+func (s *WakuRLNRelaySuite) TestMalformedRateLimitProof() {
+	newProof := func() *rlnpb.RateLimitProof {
+		return &rlnpb.RateLimitProof{
+			Proof:         make([]byte, 128),
+			MerkleRoot:    make([]byte, 32),
+			Epoch:         make([]byte, 32),
+			ShareX:        make([]byte, 32),
+			ShareY:        make([]byte, 32),
+			Nullifier:     make([]byte, 32),
+			RlnIdentifier: make([]byte, 32),
+		}
+	}
+
+	b, err := proto.Marshal(newProof())
+	s.Require().NoError(err)
+	_, err = BytesToRateLimitProof(b)
+	s.Require().NoError(err)
+
+	rlnRelay := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		log:        utils.Logger(),
+		metrics:    newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	malformations := map[string]func(p *rlnpb.RateLimitProof){
+		"short proof":          func(p *rlnpb.RateLimitProof) { p.Proof = p.Proof[:127] },
+		"long proof":           func(p *rlnpb.RateLimitProof) { p.Proof = make([]byte, 129) },
+		"short merkle root":    func(p *rlnpb.RateLimitProof) { p.MerkleRoot = p.MerkleRoot[:31] },
+		"long merkle root":     func(p *rlnpb.RateLimitProof) { p.MerkleRoot = make([]byte, 33) },
+		"missing epoch":        func(p *rlnpb.RateLimitProof) { p.Epoch = nil },
+		"long epoch":           func(p *rlnpb.RateLimitProof) { p.Epoch = make([]byte, 64) },
+		"short share x":        func(p *rlnpb.RateLimitProof) { p.ShareX = p.ShareX[:1] },
+		"long share y":         func(p *rlnpb.RateLimitProof) { p.ShareY = make([]byte, 33) },
+		"long nullifier":       func(p *rlnpb.RateLimitProof) { p.Nullifier = make([]byte, 33) },
+		"short rln identifier": func(p *rlnpb.RateLimitProof) { p.RlnIdentifier = p.RlnIdentifier[:16] },
+	}
+
+	for name, malform := range malformations {
+		proof := newProof()
+		malform(proof)
+
+		b, err := proto.Marshal(proof)
+		s.Require().NoError(err)
+
+		_, err = BytesToRateLimitProof(b)
+		s.Require().ErrorIs(err, ErrMalformedRateLimitProof, name)
+
+		result, err := rlnRelay.ValidateMessage(&pb.WakuMessage{Payload: []byte("message"), RateLimitProof: b}, nil)
+		s.Require().Error(err, name)
+		s.Require().Equal(validationError, result, name)
+	}
+}
+
+func (s *WakuRLNRelaySuite) TestSignalVersion() {
+	msg1 := &pb.WakuMessage{Payload: []byte("payload"), ContentTopic: "/app/1/chat/proto", Timestamp: proto.Int64(1)}
+	msg2 := &pb.WakuMessage{Payload: []byte("payload"), ContentTopic: "/app/1/chat/proto", Timestamp: proto.Int64(2)}
+
+	// The legacy signal does not distinguish messages by timestamp
+	s.Require().Equal(toRLNSignal(msg1, SignalV1), toRLNSignal(msg2, SignalV1))
+	s.Require().Equal(append([]byte("payload"), "/app/1/chat/proto"...), toRLNSignal(msg1, SignalV1))
+	s.Require().NotEqual(toRLNSignal(msg1, SignalV2), toRLNSignal(msg2, SignalV2))
+
+	msg3 := &pb.WakuMessage{Payload: []byte("payload"), ContentTopic: "/app/1/chat/proto", Timestamp: proto.Int64(1), Meta: []byte("meta")}
+	s.Require().NotEqual(toRLNSignal(msg1, SignalV2), toRLNSignal(msg3, SignalV2))
+
+	groupKeyPairs, _, err := r.CreateMembershipList(10)
+	s.Require().NoError(err)
+
+	var groupIDCommitments []r.IDCommitment
+	for _, c := range groupKeyPairs {
+		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
+	}
+
+	index := r.MembershipIndex(5)
+
+	rlnInstance, err := r.NewRLN()
+	s.Require().NoError(err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, rlnInstance)
+
+	groupManager, err := static.NewStaticGroupManager(groupIDCommitments, groupKeyPairs[index], index, rlnInstance, rootTracker, utils.Logger())
+	s.Require().NoError(err)
+
+	rlnRelay := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: groupManager,
+			RootTracker:  rootTracker,
+			RLN:          rlnInstance,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	err = groupManager.Start(context.Background())
+	s.Require().NoError(err)
+
+	s.Require().Equal(DefaultSignalVersion, rlnRelay.SignalVersion())
+	s.Require().Error(rlnRelay.SetSignalVersion(SignalVersion(0)))
+	s.Require().NoError(rlnRelay.SetSignalVersion(SignalV2))
+
+	now := time.Now()
+	wm := &pb.WakuMessage{Payload: []byte("Valid message"), ContentTopic: "/app/1/chat/proto"}
+	err = rlnRelay.AppendRLNProof(wm, now)
+	s.Require().NoError(err)
+	s.Require().NotNil(wm.Timestamp)
+
+	// A validator using the legacy signal cannot verify the proof
+	s.Require().NoError(rlnRelay.SetSignalVersion(SignalV1))
+	msgValidate, err := rlnRelay.ValidateMessage(wm, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(invalidMessage, msgValidate)
+
+	s.Require().NoError(rlnRelay.SetSignalVersion(SignalV2))
+	msgValidate, err = rlnRelay.ValidateMessage(wm, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(validMessage, msgValidate)
+}
+
+func (s *WakuRLNRelaySuite) TestValidationOnly() {
+	groupKeyPairs, _, err := r.CreateMembershipList(10)
+	s.Require().NoError(err)
+
+	var groupIDCommitments []r.IDCommitment
+	for _, c := range groupKeyPairs {
+		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
+	}
+
+	index := r.MembershipIndex(5)
+
+	// Node holding the Merkle tree, which publishes the messages
+	proverRLN, err := r.NewRLN()
+	s.Require().NoError(err)
+	proverRootTracker := group_manager.NewMerkleRootTracker(DefaultAcceptableRootWindowSize, proverRLN)
+	proverGroupManager, err := static.NewStaticGroupManager(groupIDCommitments, groupKeyPairs[index], index, proverRLN, proverRootTracker, utils.Logger())
+	s.Require().NoError(err)
+	s.Require().NoError(proverGroupManager.Start(context.Background()))
+
+	prover := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: proverGroupManager,
+			RootTracker:  proverRootTracker,
+			RLN:          proverRLN,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	// Node that only validates the messages
+	validatorRLN, validatorRootTracker, err := GetValidationRLNInstanceAndRootTracker(DefaultAcceptableRootWindowSize)
+	s.Require().NoError(err)
+	validatorGroupManager := validation.NewValidationGroupManager(validatorRootTracker, utils.Logger())
+	s.Require().NoError(validatorGroupManager.Start(context.Background()))
+
+	validator := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: validatorGroupManager,
+			RootTracker:  validatorRootTracker,
+			RLN:          validatorRLN,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	ready, err := validator.IsReady(context.Background())
+	s.Require().NoError(err)
+	s.Require().False(ready)
+	s.Require().Empty(validatorGroupManager.AcceptedRoots())
+	s.Require().ErrorIs(validatorRootTracker.Sync(), group_manager.ErrRootOnlyTracker)
+
+	now := time.Now()
+	wm := &pb.WakuMessage{Payload: []byte("Valid message"), ContentTopic: "/app/1/chat/proto"}
+	err = prover.AppendRLNProof(wm, now)
+	s.Require().NoError(err)
+
+	// The root of the group is not known yet
+	msgValidate, err := validator.ValidateMessage(wm, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(invalidMessage, msgValidate)
+
+	validatorGroupManager.AddRoot(1, proverGroupManager.CurrentRoot())
+
+	ready, err = validator.IsReady(context.Background())
+	s.Require().NoError(err)
+	s.Require().True(ready)
+	s.Require().Equal(proverGroupManager.CurrentRoot(), validatorGroupManager.CurrentRoot())
+
+	msgValidate, err = validator.ValidateMessage(wm, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(validMessage, msgValidate)
+
+	// A proof that does not match the message is rejected
+	wm.Payload = []byte("Tampered message")
+	msgValidate, err = validator.ValidateMessage(wm, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(invalidMessage, msgValidate)
+
+	// The node has no membership to generate proofs
+	wm2 := &pb.WakuMessage{Payload: []byte("Other message"), ContentTopic: "/app/1/chat/proto"}
+	err = validator.AppendRLNProof(wm2, now)
+	s.Require().ErrorIs(err, validation.ErrNoMembership)
+}
+
+func (s *WakuRLNRelaySuite) TestRootWindowBoundary() {
+	groupKeyPairs, _, err := r.CreateMembershipList(10)
+	s.Require().NoError(err)
+
+	var groupIDCommitments []r.IDCommitment
+	for _, c := range groupKeyPairs {
+		groupIDCommitments = append(groupIDCommitments, c.IDCommitment)
+	}
+
+	rlnInstance, err := r.NewRLN()
+	s.Require().NoError(err)
+
+	rootTracker := group_manager.NewMerkleRootTracker(3, rlnInstance)
+	s.Require().Equal(3, rootTracker.WindowSize())
+
+	groupManager, err := static.NewStaticGroupManager(groupIDCommitments[:5], groupKeyPairs[0], 0, rlnInstance, rootTracker, utils.Logger())
+	s.Require().NoError(err)
+	s.Require().NoError(groupManager.Start(context.Background()))
+
+	rlnRelay := &WakuRLNRelay{
+		timesource: timesource.NewDefaultClock(),
+		Details: group_manager.Details{
+			GroupManager: groupManager,
+			RootTracker:  rootTracker,
+			RLN:          rlnInstance,
+		},
+		nullifierLog: NewNullifierLog(context.TODO(), utils.Logger()),
+		log:          utils.Logger(),
+		metrics:      newMetrics(prometheus.DefaultRegisterer, DefaultAcceptableRootWindowSize),
+	}
+
+	now := time.Now()
+
+	// Message built against the root of the first five members
+	oldMsg := &pb.WakuMessage{Payload: []byte("old root"), ContentTopic: "/app/1/chat/proto"}
+	s.Require().NoError(rlnRelay.AppendRLNProof(oldMsg, now))
+
+	for i := 5; i < 7; i++ {
+		_, err = groupManager.InsertMember(groupIDCommitments[i])
+		s.Require().NoError(err)
+	}
+	s.Require().Len(rootTracker.Roots(), 3)
+	s.Require().Equal(1, rootTracker.BufferLen())
+
+	// Message built against the latest root, in a different epoch so it is not a duplicate
+	newMsg := &pb.WakuMessage{Payload: []byte("new root"), ContentTopic: "/app/1/chat/proto"}
+	s.Require().NoError(rlnRelay.AppendRLNProof(newMsg, now.Add(time.Second)))
+
+	msgValidate, err := rlnRelay.ValidateMessage(newMsg, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(validMessage, msgValidate)
+
+	// The root the old message was built against leaves the window
+	_, err = groupManager.InsertMember(groupIDCommitments[7])
+	s.Require().NoError(err)
+	s.Require().Len(rootTracker.Roots(), 3)
+	s.Require().Equal(2, rootTracker.BufferLen())
+
+	msgValidate, err = rlnRelay.ValidateMessage(oldMsg, &now)
+	s.Require().NoError(err)
+	s.Require().Equal(invalidMessage, msgValidate)
+
+	// Members inserted in the RLN instance directly are only tracked once synced
+	s.Require().NoError(rlnInstance.InsertMemberAt(8, groupIDCommitments[8]))
+	root, err := rlnInstance.GetMerkleRoot()
+	s.Require().NoError(err)
+	s.Require().False(rootTracker.ContainsRoot(root))
+
+	s.Require().NoError(rootTracker.Sync())
+	s.Require().Equal(root, rootTracker.CurrentRoot())
+	s.Require().Equal(3, rootTracker.BufferLen())
+
+	// Syncing again does nothing if the tree did not change
+	s.Require().NoError(rootTracker.Sync())
+	s.Require().Equal(3, rootTracker.BufferLen())
 }
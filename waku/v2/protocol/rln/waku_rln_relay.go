@@ -3,6 +3,7 @@ package rln
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
@@ -24,18 +25,42 @@ type WakuRLNRelay struct {
 
 	group_manager.Details
 
-	nullifierLog *NullifierLog
+	nullifierLog     *NullifierLog
+	nullifierLogPath string
+	nullifierDB      *NullifierDB
+
+	// nil uses the gap derived from DefaultMaxClockGap
+	maxEpochGap *int64
+
+	proofQueue *proofQueue
+
+	signalVersion SignalVersion
 
 	log *zap.Logger
 }
 
 const rlnDefaultTreePath = "./rln_tree.db"
 
+// ErrRLNUnavailable is returned when the RLN native library fails to create an RLN instance
+var ErrRLNUnavailable = errors.New("rln native library is not available")
+
+// GetRLNInstanceAndRootTracker creates an RLN instance whose merkle tree is stored in treePath, and a root
+// tracker that accepts the latest DefaultAcceptableRootWindowSize merkle roots
 func GetRLNInstanceAndRootTracker(treePath string) (*rln.RLN, *group_manager.MerkleRootTracker, error) {
+	return GetRLNInstanceAndRootTrackerWithWindowSize(treePath, DefaultAcceptableRootWindowSize)
+}
+
+// GetRLNInstanceAndRootTrackerWithWindowSize creates an RLN instance whose merkle tree is stored in treePath,
+// and a root tracker that accepts the latest acceptableRootWindowSize merkle roots
+func GetRLNInstanceAndRootTrackerWithWindowSize(treePath string, acceptableRootWindowSize int) (*rln.RLN, *group_manager.MerkleRootTracker, error) {
 	if treePath == "" {
 		treePath = rlnDefaultTreePath
 	}
 
+	if acceptableRootWindowSize <= 0 {
+		return nil, nil, errors.New("acceptable root window size must be positive")
+	}
+
 	rlnInstance, err := rln.NewWithConfig(rln.DefaultTreeDepth, &rln.TreeConfig{
 		CacheCapacity: 15000,
 		Mode:          rln.HighThroughput,
@@ -44,23 +69,44 @@ func GetRLNInstanceAndRootTracker(treePath string) (*rln.RLN, *group_manager.Mer
 		Path:          treePath,
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("%w: %v", ErrRLNUnavailable, err)
 	}
 
 	rootTracker := group_manager.NewMerkleRootTracker(acceptableRootWindowSize, rlnInstance)
 
 	return rlnInstance, rootTracker, nil
 }
+
+// GetValidationRLNInstanceAndRootTracker creates the RLN instance and root tracker used in validation only mode.
+// The instance is only used to verify the proofs, so it gets the temporary tree zerokit creates when no tree
+// config is given instead of a tree stored on disk, and the root tracker only tracks the roots pushed to it
+func GetValidationRLNInstanceAndRootTracker(acceptableRootWindowSize int) (*rln.RLN, *group_manager.MerkleRootTracker, error) {
+	if acceptableRootWindowSize <= 0 {
+		return nil, nil, errors.New("acceptable root window size must be positive")
+	}
+
+	rlnInstance, err := rln.NewRLN()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrRLNUnavailable, err)
+	}
+
+	return rlnInstance, group_manager.NewRootOnlyMerkleRootTracker(acceptableRootWindowSize), nil
+}
 func New(
 	Details group_manager.Details,
 	timesource timesource.Timesource,
 	reg prometheus.Registerer,
 	log *zap.Logger) *WakuRLNRelay {
 
+	acceptableRootWindowSize := DefaultAcceptableRootWindowSize
+	if Details.RootTracker != nil {
+		acceptableRootWindowSize = Details.RootTracker.WindowSize()
+	}
+
 	// create the WakuRLNRelay
 	rlnPeer := &WakuRLNRelay{
 		Details:    Details,
-		metrics:    newMetrics(reg),
+		metrics:    newMetrics(reg, acceptableRootWindowSize),
 		log:        log,
 		timesource: timesource,
 	}
@@ -68,14 +114,97 @@ func New(
 	return rlnPeer
 }
 
+// SetNullifierLogPath sets the path of the database where the nullifier log is persisted. It must be
+// called before Start. By default the nullifier log is only kept in memory
+func (rlnRelay *WakuRLNRelay) SetNullifierLogPath(path string) {
+	rlnRelay.nullifierLogPath = path
+}
+
+// SetMaxClockGap sets the maximum clock difference between peers tolerated when validating the epoch
+// of the messages' proofs. It must be called before Start. By default DefaultMaxClockGap is used
+func (rlnRelay *WakuRLNRelay) SetMaxClockGap(maxClockGap time.Duration) error {
+	if maxClockGap < 0 {
+		return errors.New("max clock gap cannot be negative")
+	}
+
+	maxEpochGap := epochGapForClockGap(maxClockGap)
+	rlnRelay.maxEpochGap = &maxEpochGap
+	return nil
+}
+
+// MaxEpochGap returns the maximum allowed gap between the current epoch and the epoch of a message's proof
+func (rlnRelay *WakuRLNRelay) MaxEpochGap() int64 {
+	if rlnRelay.maxEpochGap == nil {
+		return defaultMaxEpochGap
+	}
+	return *rlnRelay.maxEpochGap
+}
+
+// SetSignalVersion sets which fields of the messages are included in the signal of their proofs, both
+// when generating and verifying them. All the nodes of a network must use the same version. By default
+// DefaultSignalVersion is used
+func (rlnRelay *WakuRLNRelay) SetSignalVersion(version SignalVersion) error {
+	if !version.valid() {
+		return fmt.Errorf("unsupported rln signal version %d", version)
+	}
+
+	rlnRelay.signalVersion = version
+	return nil
+}
+
+// SignalVersion returns the version of the signal of the messages' proofs
+func (rlnRelay *WakuRLNRelay) SignalVersion() SignalVersion {
+	if rlnRelay.signalVersion == 0 {
+		return DefaultSignalVersion
+	}
+	return rlnRelay.signalVersion
+}
+
+// SetProofQueueSize sets how many of the messages passed to AppendRLNProofAsync can wait for their proof
+// to be generated. It must be called before Start. By default up to DefaultProofQueueSize messages can wait
+func (rlnRelay *WakuRLNRelay) SetProofQueueSize(queueSize int) error {
+	if queueSize < 0 {
+		return errors.New("proof queue size cannot be negative")
+	}
+
+	rlnRelay.proofQueue = newProofQueue(queueSize)
+	return nil
+}
+
+func (rlnRelay *WakuRLNRelay) startProofQueue(ctx context.Context) {
+	if rlnRelay.proofQueue == nil {
+		rlnRelay.proofQueue = newProofQueue(0)
+	}
+	rlnRelay.proofQueue.start(ctx, rlnRelay.AppendRLNProof)
+}
+
 func (rlnRelay *WakuRLNRelay) Start(ctx context.Context) error {
-	rlnRelay.nullifierLog = NewNullifierLog(ctx, rlnRelay.log)
+	if rlnRelay.nullifierLogPath != "" {
+		db, err := NewNullifierDB(rlnRelay.nullifierLogPath)
+		if err != nil {
+			return err
+		}
+
+		rlnRelay.nullifierLog, err = newPersistentNullifierLog(ctx, db, rlnRelay.MaxEpochGap(), rlnRelay.log)
+		if err != nil {
+			db.Close()
+			return err
+		}
+		rlnRelay.nullifierDB = db
+	} else {
+		rlnRelay.nullifierLog = newNullifierLog(ctx, rlnRelay.MaxEpochGap(), rlnRelay.log)
+	}
 
 	err := rlnRelay.GroupManager.Start(ctx)
 	if err != nil {
+		if closeErr := rlnRelay.stopNullifierLog(); closeErr != nil {
+			rlnRelay.log.Error("closing nullifier log", zap.Error(closeErr))
+		}
 		return err
 	}
 
+	rlnRelay.startProofQueue(ctx)
+
 	log.Info("rln relay topic validator mounted")
 
 	return nil
@@ -83,17 +212,58 @@ func (rlnRelay *WakuRLNRelay) Start(ctx context.Context) error {
 
 // Stop will stop any operation or goroutine started while using WakuRLNRelay
 func (rlnRelay *WakuRLNRelay) Stop() error {
-	return rlnRelay.GroupManager.Stop()
+	if rlnRelay.proofQueue != nil {
+		rlnRelay.proofQueue.stop()
+	}
+
+	err := rlnRelay.GroupManager.Stop()
+
+	if dbErr := rlnRelay.stopNullifierLog(); dbErr != nil && err == nil {
+		err = dbErr
+	}
+
+	return err
+}
+
+// stopNullifierLog stops the goroutine of the nullifier log and closes its database, if any
+func (rlnRelay *WakuRLNRelay) stopNullifierLog() error {
+	// the cleanup of the nullifier log must be done before its database is closed
+	if rlnRelay.nullifierLog != nil {
+		rlnRelay.nullifierLog.Stop()
+	}
+
+	var err error
+	if rlnRelay.nullifierDB != nil {
+		err = rlnRelay.nullifierDB.Close()
+		rlnRelay.nullifierDB = nil
+	}
+
+	return err
 }
 
 // ValidateMessage validates the supplied message based on the waku-rln-relay routing protocol i.e.,
-// the message's epoch is within `maxEpochGap` of the current epoch
+// the message's epoch is within `MaxEpochGap()` of the current epoch
 // the message's has valid rate limit proof
 // the message's does not violate the rate limit
 // if `optionalTime` is supplied, then the current epoch is calculated based on that, otherwise the current time will be used
 func (rlnRelay *WakuRLNRelay) ValidateMessage(msg *pb.WakuMessage, optionalTime *time.Time) (messageValidationResult, error) {
+	result, err := rlnRelay.ValidateMessageWithReason(msg, optionalTime)
+	return result.Result, err
+}
+
+// ValidateMessageWithReason validates a message like ValidateMessage, and additionally returns the reason
+// why a message was considered invalid, as well as the epoch gap observed for the message
+func (rlnRelay *WakuRLNRelay) ValidateMessageWithReason(msg *pb.WakuMessage, optionalTime *time.Time) (ValidationResult, error) {
+	result, reason, err := rlnRelay.validateMessage(msg, optionalTime)
+	rlnRelay.metrics.RecordValidationResult(result.Result, reason)
+	return result, err
+}
+
+// validateMessage validates a message, and returns the category of the reason of the
+// validation result to be used as a metric label
+func (rlnRelay *WakuRLNRelay) validateMessage(msg *pb.WakuMessage, optionalTime *time.Time) (ValidationResult, string, error) {
 	if msg == nil {
-		return validationError, errors.New("nil message")
+		return ValidationResult{Result: validationError}, "nil_message", errors.New("nil message")
 	}
 
 	//  checks if the `msg`'s epoch is far from the current epoch
@@ -110,38 +280,47 @@ func (rlnRelay *WakuRLNRelay) ValidateMessage(msg *pb.WakuMessage, optionalTime
 	if err != nil {
 		rlnRelay.log.Debug("invalid message: could not extract proof")
 		rlnRelay.metrics.RecordInvalidMessage(proofExtractionErr)
-		return validationError, err
+		return ValidationResult{Result: validationError, Reason: "could not extract proof"}, string(proofExtractionErr), err
 	}
 
 	if msgProof == nil {
 		// message does not contain a proof
 		rlnRelay.log.Debug("invalid message: message does not contain a proof")
 		rlnRelay.metrics.RecordInvalidMessage(invalidNoProof)
-		return invalidMessage, nil
+		return ValidationResult{Result: invalidMessage, Reason: "message does not contain a proof"}, string(invalidNoProof), nil
 	}
 
+	rlnLock := rlnRelay.RootTracker.RLNLock()
+	rlnLock.Lock()
 	proofMD, err := rlnRelay.RLN.ExtractMetadata(*msgProof)
+	rlnLock.Unlock()
 	if err != nil {
 		rlnRelay.log.Debug("could not extract metadata", zap.Error(err))
 		rlnRelay.metrics.RecordError(proofMetadataExtractionErr)
-		return invalidMessage, nil
+		return ValidationResult{Result: invalidMessage, Reason: "could not extract proof metadata"}, string(proofMetadataExtractionErr), nil
 	}
 
 	// calculate the gaps and validate the epoch
 	gap := rln.Diff(epoch, msgProof.Epoch)
+	rlnRelay.metrics.RecordEpochGap(gap)
+	maxEpochGap := rlnRelay.MaxEpochGap()
 	if int64(math.Abs(float64(gap))) > maxEpochGap {
 		// message's epoch is too old or too ahead
 		// accept messages whose epoch is within +-MAX_EPOCH_GAP from the current epoch
 		rlnRelay.log.Debug("invalid message: epoch gap exceeds a threshold", zap.Int64("gap", gap))
 		rlnRelay.metrics.RecordInvalidMessage(invalidEpoch)
 
-		return invalidMessage, nil
+		return ValidationResult{
+			Result:   invalidMessage,
+			Reason:   fmt.Sprintf("epoch gap %d exceeds the maximum allowed gap of %d", gap, maxEpochGap),
+			EpochGap: gap,
+		}, string(invalidEpoch), nil
 	}
 
 	if !(rlnRelay.RootTracker.ContainsRoot(msgProof.MerkleRoot)) {
 		rlnRelay.log.Debug("invalid message: unexpected root", logging.HexBytes("msgRoot", msgProof.MerkleRoot[:]))
 		rlnRelay.metrics.RecordInvalidMessage(invalidRoot)
-		return invalidMessage, nil
+		return ValidationResult{Result: invalidMessage, Reason: "unexpected merkle root", EpochGap: gap}, string(invalidRoot), nil
 	}
 
 	start := time.Now()
@@ -149,7 +328,7 @@ func (rlnRelay *WakuRLNRelay) ValidateMessage(msg *pb.WakuMessage, optionalTime
 	if err != nil {
 		rlnRelay.log.Debug("could not verify proof")
 		rlnRelay.metrics.RecordError(proofVerificationErr)
-		return validationError, err
+		return ValidationResult{Result: validationError, Reason: "could not verify proof", EpochGap: gap}, string(proofVerificationErr), err
 	}
 	rlnRelay.metrics.RecordProofVerification(time.Since(start))
 
@@ -157,27 +336,50 @@ func (rlnRelay *WakuRLNRelay) ValidateMessage(msg *pb.WakuMessage, optionalTime
 		// invalid proof
 		rlnRelay.log.Debug("Invalid proof")
 		rlnRelay.metrics.RecordInvalidMessage(invalidProof)
-		return invalidMessage, nil
+		return ValidationResult{Result: invalidMessage, Reason: "invalid proof", EpochGap: gap}, string(invalidProof), nil
+	}
+
+	// a message that was already validated is ignored, as it does not violate the rate limit
+	if rlnRelay.nullifierLog.Contains(proofMD) {
+		rlnRelay.log.Debug("duplicate message received")
+		return ValidationResult{Result: duplicateMessage, Reason: "message already received", EpochGap: gap}, "duplicate", nil
 	}
 
 	// check if double messaging has happened
-	hasDup, err := rlnRelay.nullifierLog.HasDuplicate(proofMD)
+	previousProofMD, hasDup, err := rlnRelay.nullifierLog.FindDuplicate(proofMD)
 	if err != nil {
 		rlnRelay.log.Debug("validation error", zap.Error(err))
 		rlnRelay.metrics.RecordError(duplicateCheckErr)
-		return validationError, err
+		return ValidationResult{Result: validationError, Reason: "could not check for duplicates", EpochGap: gap}, string(duplicateCheckErr), err
 	}
 
 	if hasDup {
 		rlnRelay.log.Debug("spam received")
-		return spamMessage, nil
+		return ValidationResult{
+			Result:   spamMessage,
+			Reason:   "message exceeds the rate limit",
+			EpochGap: gap,
+			SpamEvidence: &SpamEvidence{
+				Nullifier:         proofMD.Nullifier,
+				ExternalNullifier: proofMD.ExternalNullifier,
+				ShareX:            proofMD.ShareX,
+				ShareY:            proofMD.ShareY,
+				PreviousShareX:    previousProofMD.ShareX,
+				PreviousShareY:    previousProofMD.ShareY,
+			},
+		}, "rate_limit_exceeded", nil
 	}
 
-	err = rlnRelay.nullifierLog.Insert(proofMD)
+	err = rlnRelay.nullifierLog.InsertForEpoch(msgProof.Epoch.Uint64(), proofMD)
+	if errors.Is(err, errAlreadyExists) {
+		// the same message was validated concurrently
+		rlnRelay.log.Debug("duplicate message received")
+		return ValidationResult{Result: duplicateMessage, Reason: "message already received", EpochGap: gap}, "duplicate", nil
+	}
 	if err != nil {
 		rlnRelay.log.Debug("could not insert proof into log")
 		rlnRelay.metrics.RecordError(logInsertionErr)
-		return validationError, err
+		return ValidationResult{Result: validationError, Reason: "could not insert proof into log", EpochGap: gap}, string(logInsertionErr), err
 	}
 
 	rlnRelay.log.Debug("message is valid")
@@ -185,13 +387,21 @@ func (rlnRelay *WakuRLNRelay) ValidateMessage(msg *pb.WakuMessage, optionalTime
 	rootIndex := rlnRelay.RootTracker.IndexOf(msgProof.MerkleRoot)
 	rlnRelay.metrics.RecordValidMessages(rootIndex)
 
-	return validMessage, nil
+	return ValidationResult{Result: validMessage, EpochGap: gap}, "", nil
 }
 
 func (rlnRelay *WakuRLNRelay) verifyProof(msg *pb.WakuMessage, proof *rln.RateLimitProof) (bool, error) {
-	contentTopicBytes := []byte(msg.ContentTopic)
-	input := append(msg.Payload, contentTopicBytes...)
-	return rlnRelay.RLN.Verify(input, *proof, rlnRelay.RootTracker.Roots()...)
+	input := toRLNSignal(msg, rlnRelay.SignalVersion())
+	roots := rlnRelay.RootTracker.Roots()
+
+	// zerokit takes the RLN instance mutably to verify a proof as well, so verifying concurrently with the
+	// proof queue or with the group manager is a data race. Proofs are generated one at a time by the queue,
+	// so a verification waits for at most one proof generation
+	rlnLock := rlnRelay.RootTracker.RLNLock()
+	rlnLock.Lock()
+	defer rlnLock.Unlock()
+
+	return rlnRelay.RLN.Verify(input, *proof, roots...)
 }
 
 func (rlnRelay *WakuRLNRelay) AppendRLNProof(msg *pb.WakuMessage, senderEpochTime time.Time) error {
@@ -203,10 +413,16 @@ func (rlnRelay *WakuRLNRelay) AppendRLNProof(msg *pb.WakuMessage, senderEpochTim
 		return errors.New("nil message")
 	}
 
-	input := toRLNSignal(msg)
+	//If msgTimeStamp is not set, then set it to timestamp of proof. It is set before
+	// generating the proof, as it may be part of the signal
+	if msg.Timestamp == nil {
+		msg.Timestamp = proto.Int64(senderEpochTime.Unix())
+	}
+
+	input := toRLNSignal(msg, rlnRelay.SignalVersion())
 
 	start := time.Now()
-	proof, err := rlnRelay.generateProof(input, rln.CalcEpoch(senderEpochTime))
+	proof, err := rlnRelay.generateProof(input, msg.ContentTopic, rln.CalcEpoch(senderEpochTime))
 	if err != nil {
 		return err
 	}
@@ -218,18 +434,37 @@ func (rlnRelay *WakuRLNRelay) AppendRLNProof(msg *pb.WakuMessage, senderEpochTim
 	}
 
 	msg.RateLimitProof = b
-	//If msgTimeStamp is not set, then set it to timestamp of proof
-	if msg.Timestamp == nil {
-		msg.Timestamp = proto.Int64(senderEpochTime.Unix())
-	}
 
 	return nil
 }
 
+// AppendRLNProofAsync queues a message to have a `RateLimitProof` generated and appended to it in the
+// background. Proofs are generated one at a time, in the order the messages were queued. The returned
+// channel receives the result once the proof was appended, and the message must not be used until then.
+// If the queue is full, ErrProofQueueFull is returned instead of waiting
+func (rlnRelay *WakuRLNRelay) AppendRLNProofAsync(msg *pb.WakuMessage, senderEpochTime time.Time) (<-chan error, error) {
+	if msg == nil {
+		return nil, errors.New("nil message")
+	}
+
+	if rlnRelay.proofQueue == nil {
+		return nil, ErrProofQueueStopped
+	}
+
+	return rlnRelay.proofQueue.enqueue(msg, senderEpochTime)
+}
+
 // Validator returns a validator for the waku messages.
 // The message validation logic is according to https://rfc.vac.dev/spec/17/
 func (rlnRelay *WakuRLNRelay) Validator(
 	spamHandler SpamHandler) func(ctx context.Context, msg *pb.WakuMessage, topic string) bool {
+	return rlnRelay.ValidatorWithEvidence(AdaptSpamHandler(spamHandler))
+}
+
+// ValidatorWithEvidence returns a validator for the waku messages like Validator, whose spam handler
+// also receives the proofs of the conflicting messages, i.e. to slash the membership of the spammer
+func (rlnRelay *WakuRLNRelay) ValidatorWithEvidence(
+	spamHandler SlashingSpamHandler) func(ctx context.Context, msg *pb.WakuMessage, topic string) bool {
 	return func(ctx context.Context, msg *pb.WakuMessage, topic string) bool {
 
 		hash := msg.Hash(topic)
@@ -245,18 +480,18 @@ func (rlnRelay *WakuRLNRelay) Validator(
 		rlnRelay.metrics.RecordMessage()
 
 		// validate the message
-		validationRes, err := rlnRelay.ValidateMessage(msg, nil)
+		validationRes, err := rlnRelay.ValidateMessageWithReason(msg, nil)
 		if err != nil {
 			log.Debug("validating message", zap.Error(err))
 			return false
 		}
 
-		switch validationRes {
+		switch validationRes.Result {
 		case validMessage:
 			log.Debug("message verified")
 			return true
 		case invalidMessage:
-			log.Debug("message could not be verified")
+			log.Debug("message could not be verified", zap.String("reason", validationRes.Reason), zap.Int64("epochGap", validationRes.EpochGap))
 			return false
 		case spamMessage:
 			log.Debug("spam message found")
@@ -264,28 +499,33 @@ func (rlnRelay *WakuRLNRelay) Validator(
 			rlnRelay.metrics.RecordSpam(msg.ContentTopic)
 
 			if spamHandler != nil {
-				if err := spamHandler(msg, topic); err != nil {
+				if err := spamHandler(msg, topic, *validationRes.SpamEvidence); err != nil {
 					log.Error("executing spam handler", zap.Error(err))
 				}
 			}
 
+			return false
+		case duplicateMessage:
+			log.Debug("duplicate message ignored")
 			return false
 		default:
-			log.Error("unhandled validation result", zap.Int("validationResult", int(validationRes)))
+			log.Error("unhandled validation result", zap.Int("validationResult", int(validationRes.Result)))
 			return false
 		}
 	}
 }
 
-func (rlnRelay *WakuRLNRelay) generateProof(input []byte, epoch rln.Epoch) (*rlnpb.RateLimitProof, error) {
-	identityCredentials, err := rlnRelay.GroupManager.IdentityCredentials()
+func (rlnRelay *WakuRLNRelay) generateProof(input []byte, contentTopic string, epoch rln.Epoch) (*rlnpb.RateLimitProof, error) {
+	identityCredentials, membershipIndex, err := rlnRelay.credentialFor(contentTopic)
 	if err != nil {
 		return nil, err
 	}
 
-	membershipIndex := rlnRelay.GroupManager.MembershipIndex()
-
+	// the tree must not be modified while the proof is generated
+	rlnLock := rlnRelay.RootTracker.RLNLock()
+	rlnLock.Lock()
 	proof, err := rlnRelay.RLN.GenerateProof(input, identityCredentials, membershipIndex, epoch)
+	rlnLock.Unlock()
 	if err != nil {
 		return nil, err
 	}
@@ -301,6 +541,20 @@ func (rlnRelay *WakuRLNRelay) generateProof(input []byte, epoch rln.Epoch) (*rln
 	}, nil
 }
 
+// credentialFor returns the membership used to generate the proofs of the messages published in a content topic
+func (rlnRelay *WakuRLNRelay) credentialFor(contentTopic string) (rln.IdentityCredential, rln.MembershipIndex, error) {
+	if selector, ok := rlnRelay.GroupManager.(group_manager.CredentialSelector); ok {
+		return selector.CredentialFor(contentTopic)
+	}
+
+	identityCredentials, err := rlnRelay.GroupManager.IdentityCredentials()
+	if err != nil {
+		return rln.IdentityCredential{}, 0, err
+	}
+
+	return identityCredentials, rlnRelay.GroupManager.MembershipIndex(), nil
+}
+
 func (rlnRelay *WakuRLNRelay) IdentityCredential() (rln.IdentityCredential, error) {
 	return rlnRelay.GroupManager.IdentityCredentials()
 }
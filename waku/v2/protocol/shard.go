@@ -4,7 +4,9 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"sync"
 
 	"github.com/waku-org/go-waku/waku/v2/hash"
 )
@@ -135,7 +137,26 @@ func (rs RelayShards) ContainsTopic(topic string) bool {
 	return rs.ContainsShardPubsubTopic(wTopic)
 }
 
+// Validate checks that the shard indices are within the range supported by the ENR encodings
+func (rs RelayShards) Validate() error {
+	for _, index := range rs.ShardIDs {
+		if index > MaxShardIndex {
+			return ErrInvalidShard
+		}
+	}
+	return nil
+}
+
 func (rs RelayShards) ShardList() ([]byte, error) {
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The number of shards is encoded in a single byte
+	if len(rs.ShardIDs) > math.MaxUint8 {
+		return nil, ErrInvalidShardCount
+	}
+
 	var result []byte
 
 	result = binary.BigEndian.AppendUint16(result, rs.ClusterID)
@@ -233,13 +254,55 @@ func GetShardFromContentTopic(topic ContentTopic, shardCount int) StaticSharding
 }
 
 func GetPubSubTopicFromContentTopic(cTopicString string) (string, error) {
-	cTopic, err := StringToContentTopic(cTopicString)
+	return ShardForContentTopic(cTopicString)
+}
+
+// maxShardCacheSize is the maximum number of content topics whose shard is cached
+const maxShardCacheSize = 1000
+
+// shardCache stores the pubsub topic computed for a content topic using autosharding. The
+// cluster and the number of shards used by autosharding are constants, so the cached entries
+// never become stale. The cache is cleared once it is full
+type shardCache struct {
+	sync.RWMutex
+	topics map[string]string
+}
+
+var contentTopicShards = &shardCache{topics: make(map[string]string)}
+
+func (c *shardCache) get(contentTopic string) (string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	pubsubTopic, ok := c.topics[contentTopic]
+	return pubsubTopic, ok
+}
+
+func (c *shardCache) set(contentTopic string, pubsubTopic string) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.topics) >= maxShardCacheSize {
+		c.topics = make(map[string]string)
+	}
+	c.topics[contentTopic] = pubsubTopic
+}
+
+// ShardForContentTopic returns the pubsub topic a content topic is mapped to
+// using autosharding. Results are cached so repeated lookups for the same
+// content topic do not recompute the hash
+func ShardForContentTopic(contentTopic string) (string, error) {
+	if pubsubTopic, ok := contentTopicShards.get(contentTopic); ok {
+		return pubsubTopic, nil
+	}
+
+	cTopic, err := StringToContentTopic(contentTopic)
 	if err != nil {
-		return "", fmt.Errorf("%s : %s", err.Error(), cTopicString)
+		return "", fmt.Errorf("%s : %s", err.Error(), contentTopic)
 	}
-	pTopic := GetShardFromContentTopic(cTopic, GenerationZeroShardsCount)
+	pubsubTopic := GetShardFromContentTopic(cTopic, GenerationZeroShardsCount).String()
+
+	contentTopicShards.set(contentTopic, pubsubTopic)
 
-	return pTopic.String(), nil
+	return pubsubTopic, nil
 }
 
 func GeneratePubsubToContentTopicMap(pubsubTopic string, contentTopics []string) (map[string][]string, error) {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sync"
 
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -73,6 +74,9 @@ type WakuStore struct {
 
 	defaultRatelimit rate.Limit
 	rateLimiters     map[peer.ID]*rate.Limiter
+
+	pingResultsLock sync.RWMutex
+	pingResults     map[peer.ID]pingResult
 }
 
 // NewWakuStore is used to instantiate a StoreV3 client
@@ -83,6 +87,7 @@ func NewWakuStore(pm *peermanager.PeerManager, timesource timesource.Timesource,
 	s.pm = pm
 	s.defaultRatelimit = defaultRatelimit
 	s.rateLimiters = make(map[peer.ID]*rate.Limiter)
+	s.pingResults = make(map[peer.ID]pingResult)
 
 	if pm != nil {
 		pm.RegisterWakuProtocol(StoreQueryID_v300, StoreENRField)
@@ -130,15 +135,18 @@ func (s *WakuStore) Request(ctx context.Context, criteria Criteria, opts ...Requ
 
 	if s.pm != nil && params.selectedPeer == "" {
 		if isFilterCriteria {
-			selectedPeers, err := s.pm.SelectPeers(
-				peermanager.PeerSelectionCriteria{
-					SelectionType: params.peerSelectionType,
-					Proto:         StoreQueryID_v300,
-					PubsubTopics:  []string{filterCriteria.PubsubTopic},
-					SpecificPeers: params.preferredPeers,
-					Ctx:           ctx,
-				},
-			)
+			selectionCriteria := peermanager.PeerSelectionCriteria{
+				SelectionType: params.peerSelectionType,
+				Proto:         StoreQueryID_v300,
+				PubsubTopics:  []string{filterCriteria.PubsubTopic},
+				SpecificPeers: params.preferredPeers,
+				Ctx:           ctx,
+				CheckLiveness: params.checkLiveness,
+			}
+			if params.lowestLatency {
+				selectionCriteria.Strategy = s.lowestLatencyStrategy
+			}
+			selectedPeers, err := s.pm.SelectPeers(selectionCriteria)
 			if err != nil {
 				return nil, err
 			}
@@ -316,6 +324,11 @@ func (s *WakuStore) queryFrom(ctx context.Context, storeRequest *pb.StoreQueryRe
 		return nil, err
 	}
 
+	if deadline, ok := ctx.Deadline(); ok {
+		// Avoid blocking indefinitely on unresponsive storenodes
+		_ = stream.SetDeadline(deadline)
+	}
+
 	writer := pbio.NewDelimitedWriter(stream)
 	reader := pbio.NewDelimitedReader(stream, math.MaxInt32)
 
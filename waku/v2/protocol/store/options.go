@@ -14,6 +14,8 @@ type Parameters struct {
 	peerAddr          multiaddr.Multiaddr
 	peerSelectionType peermanager.PeerSelection
 	preferredPeers    peer.IDSlice
+	checkLiveness     bool
+	lowestLatency     bool
 	requestID         []byte
 	cursor            []byte
 	pageLimit         uint64
@@ -78,6 +80,32 @@ func WithFastestPeerSelection(fromThesePeers ...peer.ID) RequestOption {
 	}
 }
 
+// WithLowestLatencyPeerSelection is an option used to select the storenode that replied the fastest
+// to PingStore. Storenodes that were never pinged are selected after those that replied, and storenodes
+// that failed to reply are selected last. If a list of specific peers is passed, the peer will be chosen
+// from that list assuming it supports the chosen protocol, otherwise it will chose a peer from the node
+// peerstore
+// Note: This option is avaiable only with peerManager
+func WithLowestLatencyPeerSelection(fromThesePeers ...peer.ID) RequestOption {
+	return func(params *Parameters) error {
+		params.peerSelectionType = peermanager.Automatic
+		params.preferredPeers = fromThesePeers
+		params.lowestLatency = true
+		return nil
+	}
+}
+
+// WithLivenessCheck is an option used to probe the peer selected from the peer store
+// by negotiating the store protocol, skipping it if it does not respond. It adds a round trip
+// to the request
+// Note: This option is avaiable only with peerManager
+func WithLivenessCheck() RequestOption {
+	return func(params *Parameters) error {
+		params.checkLiveness = true
+		return nil
+	}
+}
+
 // WithRequestID is an option to set a specific request ID to be used when
 // creating a store request
 func WithRequestID(requestID []byte) RequestOption {
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/store/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// pingResult is the outcome of the last PingStore to a storenode
+type pingResult struct {
+	latency time.Duration
+	failed  bool
+}
+
+// PingStore sends a minimal store query to a peer and measures the round trip time. The query
+// looks up a single message hash without requesting its data, so the cost for the storenode
+// is negligible. The outcome is remembered and used by WithLowestLatencyPeerSelection, and the
+// measured latency is also recorded in the peerstore
+func (s *WakuStore) PingStore(ctx context.Context, peerID peer.ID) (time.Duration, error) {
+	if peerID == "" {
+		return 0, ErrMustSelectPeer
+	}
+
+	storeRequest := &pb.StoreQueryRequest{
+		RequestId:       hex.EncodeToString(protocol.GenerateRequestID()),
+		IncludeData:     false,
+		MessageHashes:   [][]byte{make([]byte, 32)},
+		PaginationLimit: proto.Uint64(1),
+	}
+
+	params := &Parameters{
+		selectedPeer:  peerID,
+		skipRatelimit: true,
+	}
+
+	start := time.Now()
+	_, err := s.queryFrom(ctx, storeRequest, params)
+	if err != nil {
+		s.setPingResult(peerID, pingResult{failed: true})
+		return 0, err
+	}
+	latency := time.Since(start)
+
+	s.setPingResult(peerID, pingResult{latency: latency})
+	s.h.Peerstore().RecordLatency(peerID, latency)
+
+	return latency, nil
+}
+
+func (s *WakuStore) setPingResult(peerID peer.ID, result pingResult) {
+	s.pingResultsLock.Lock()
+	defer s.pingResultsLock.Unlock()
+	s.pingResults[peerID] = result
+}
+
+// lowestLatencyStrategy is a peermanager.PeerSelectionStrategy that sorts the candidates by the
+// outcome of their last PingStore: the storenodes that replied go first by latency, followed by
+// the storenodes that were never pinged, and the storenodes that failed to reply go last
+func (s *WakuStore) lowestLatencyStrategy(ctx context.Context, candidates peer.IDSlice, maxPeers int) (peer.IDSlice, error) {
+	s.pingResultsLock.RLock()
+	defer s.pingResultsLock.RUnlock()
+
+	rank := func(p peer.ID) (int, time.Duration) {
+		result, ok := s.pingResults[p]
+		switch {
+		case !ok:
+			return 1, 0
+		case result.failed:
+			return 2, 0
+		default:
+			return 0, result.latency
+		}
+	}
+
+	peers := make(peer.IDSlice, len(candidates))
+	copy(peers, candidates)
+	sort.SliceStable(peers, func(i, j int) bool {
+		rankI, latencyI := rank(peers[i])
+		rankJ, latencyJ := rank(peers[j])
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return latencyI < latencyJ
+	})
+
+	if len(peers) > maxPeers {
+		peers = peers[:maxPeers]
+	}
+
+	return peers, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-msgio/pbio"
+	"github.com/stretchr/testify/require"
+	"github.com/waku-org/go-waku/tests"
+	"github.com/waku-org/go-waku/waku/v2/protocol/store/pb"
+	"github.com/waku-org/go-waku/waku/v2/timesource"
+	"github.com/waku-org/go-waku/waku/v2/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+func makeTestHost(t *testing.T) host.Host {
+	port, err := tests.FindFreePort(t, "", 5)
+	require.NoError(t, err)
+
+	h, err := tests.MakeHost(context.Background(), port, rand.Reader)
+	require.NoError(t, err)
+
+	return h
+}
+
+func TestPingStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Fake storenode that replies to every request with an empty response
+	storeHost := makeTestHost(t)
+	defer storeHost.Close()
+	storeHost.SetStreamHandler(StoreQueryID_v300, func(stream network.Stream) {
+		defer stream.Close()
+
+		request := &pb.StoreQueryRequest{}
+		err := pbio.NewDelimitedReader(stream, math.MaxInt32).ReadMsg(request)
+		if err != nil {
+			return
+		}
+
+		_ = pbio.NewDelimitedWriter(stream).WriteMsg(&pb.StoreQueryResponse{
+			RequestId:  request.RequestId,
+			StatusCode: proto.Uint32(ok),
+		})
+	})
+
+	// Fake storenode that never replies
+	unresponsiveHost := makeTestHost(t)
+	defer unresponsiveHost.Close()
+	unresponsiveHost.SetStreamHandler(StoreQueryID_v300, func(stream network.Stream) {
+		<-ctx.Done()
+		_ = stream.Reset()
+	})
+
+	clientHost := makeTestHost(t)
+	defer clientHost.Close()
+	clientHost.Peerstore().AddAddrs(storeHost.ID(), storeHost.Addrs(), peerstore.PermanentAddrTTL)
+	clientHost.Peerstore().AddAddrs(unresponsiveHost.ID(), unresponsiveHost.Addrs(), peerstore.PermanentAddrTTL)
+
+	wakuStore := NewWakuStore(nil, timesource.NewDefaultClock(), utils.Logger(), 8)
+	wakuStore.SetHost(clientHost)
+
+	latency, err := wakuStore.PingStore(ctx, storeHost.ID())
+	require.NoError(t, err)
+	require.Greater(t, latency, time.Duration(0))
+	require.NotZero(t, clientHost.Peerstore().LatencyEWMA(storeHost.ID()))
+
+	pingCtx, pingCancel := context.WithTimeout(ctx, time.Second)
+	defer pingCancel()
+	_, err = wakuStore.PingStore(pingCtx, unresponsiveHost.ID())
+	require.Error(t, err)
+
+	_, err = wakuStore.PingStore(ctx, "")
+	require.ErrorIs(t, err, ErrMustSelectPeer)
+
+	// The storenode that replied is selected first, and the one that failed to reply last
+	neverPinged := peer.ID("never-pinged")
+	candidates := peer.IDSlice{unresponsiveHost.ID(), neverPinged, storeHost.ID()}
+	selected, err := wakuStore.lowestLatencyStrategy(ctx, candidates, len(candidates))
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{storeHost.ID(), neverPinged, unresponsiveHost.ID()}, selected)
+
+	selected, err = wakuStore.lowestLatencyStrategy(ctx, candidates, 1)
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{storeHost.ID()}, selected)
+}
+
+func TestLowestLatencyStrategy(t *testing.T) {
+	wakuStore := NewWakuStore(nil, timesource.NewDefaultClock(), utils.Logger(), 8)
+	wakuStore.setPingResult("slow", pingResult{latency: 200 * time.Millisecond})
+	wakuStore.setPingResult("fast", pingResult{latency: 10 * time.Millisecond})
+	wakuStore.setPingResult("failed", pingResult{failed: true})
+
+	selected, err := wakuStore.lowestLatencyStrategy(context.Background(), peer.IDSlice{"failed", "slow", "unknown", "fast"}, 4)
+	require.NoError(t, err)
+	require.Equal(t, peer.IDSlice{"fast", "slow", "unknown", "failed"}, selected)
+}
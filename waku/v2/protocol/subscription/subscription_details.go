@@ -3,9 +3,13 @@ package subscription
 import (
 	"encoding/json"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
+	"go.uber.org/zap"
 )
 
 // Map of SubscriptionDetails.ID to subscriptions
@@ -22,6 +26,26 @@ type PeerContentFilter struct {
 	ContentTopics []string `json:"contentTopics"`
 }
 
+// MessageMetadata contains information about the delivery of a message to a subscription
+type MessageMetadata struct {
+	PeerID      peer.ID
+	PubsubTopic string
+	ReceivedAt  time.Time
+}
+
+// SubscriptionInfo is a snapshot of the state of a subscription
+type SubscriptionInfo struct {
+	ID            string    `json:"subscriptionID"`
+	PeerID        peer.ID   `json:"peerID"`
+	PubsubTopic   string    `json:"pubsubTopic"`
+	ContentTopics []string  `json:"contentTopics"`
+	Closed        bool      `json:"closed"`
+	LastMessageAt time.Time `json:"lastMessageAt"` // zero if no message was received yet
+}
+
+// MessageHandler is a callback invoked each time a message is delivered to a subscription
+type MessageHandler func(subscriptionID string, msg *pb.WakuMessage, meta MessageMetadata)
+
 type SubscriptionDetails struct {
 	sync.RWMutex
 
@@ -34,6 +58,48 @@ type SubscriptionDetails struct {
 	PeerID        peer.ID                 `json:"peerID"`
 	ContentFilter protocol.ContentFilter  `json:"contentFilters"`
 	C             chan *protocol.Envelope `json:"-"`
+
+	onMessage     MessageHandler
+	lastMessageAt atomic.Int64 // unix nanoseconds
+}
+
+// invokeMessageHandler calls the subscription message handler, if any. A panic
+// in the handler is recovered so it does not affect the delivery of messages
+func (s *SubscriptionDetails) invokeMessageHandler(logger *zap.Logger, envelope *protocol.Envelope) {
+	if s.onMessage == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("recovered from panic in message handler", zap.String("subscriptionID", s.ID), zap.Any("panic", r))
+		}
+	}()
+
+	s.onMessage(s.ID, envelope.Message(), MessageMetadata{
+		PeerID:      s.PeerID,
+		PubsubTopic: envelope.PubsubTopic(),
+		ReceivedAt:  time.Unix(0, envelope.Index().ReceiverTime),
+	})
+}
+
+// Info returns a snapshot of the subscription, which is safe to use after the subscription changes
+func (s *SubscriptionDetails) Info() SubscriptionInfo {
+	s.RLock()
+	defer s.RUnlock()
+
+	info := SubscriptionInfo{
+		ID:            s.ID,
+		PeerID:        s.PeerID,
+		PubsubTopic:   s.ContentFilter.PubsubTopic,
+		ContentTopics: s.ContentFilter.ContentTopics.ToList(),
+		Closed:        s.Closed,
+	}
+	if lastMessageAt := s.lastMessageAt.Load(); lastMessageAt != 0 {
+		info.LastMessageAt = time.Unix(0, lastMessageAt)
+	}
+
+	return info
 }
 
 func (s *SubscriptionDetails) Add(contentTopics ...string) {
@@ -17,6 +17,9 @@ type SubscriptionsMap struct {
 	logger   *zap.Logger
 	items    map[peer.ID]*PeerSubscription
 	noOfSubs map[string]map[string]int
+
+	onPeerAdded   func(peer.ID)
+	onPeerRemoved func(peer.ID)
 }
 
 var ErrNotFound = errors.New("not found")
@@ -29,12 +32,34 @@ func NewSubscriptionMap(logger *zap.Logger) *SubscriptionsMap {
 	}
 }
 
+// SetPeerCallbacks sets the functions invoked when the map starts having subscriptions
+// for a peer, and when the last subscription for a peer is removed
+func (m *SubscriptionsMap) SetPeerCallbacks(onPeerAdded func(peer.ID), onPeerRemoved func(peer.ID)) {
+	m.Lock()
+	defer m.Unlock()
+	m.onPeerAdded = onPeerAdded
+	m.onPeerRemoved = onPeerRemoved
+}
+
 func (m *SubscriptionsMap) Count() int {
 	m.RLock()
 	defer m.RUnlock()
 	return len(m.items)
 }
 
+// SubscriptionCount returns the number of active subscriptions across all peers
+func (m *SubscriptionsMap) SubscriptionCount() int {
+	m.RLock()
+	defer m.RUnlock()
+	count := 0
+	for _, peerSubscription := range m.items {
+		for _, subscriptions := range peerSubscription.SubsPerPubsubTopic {
+			count += len(subscriptions)
+		}
+	}
+	return count
+}
+
 func (m *SubscriptionsMap) IsListening(pubsubTopic, contentTopic string) bool {
 	m.RLock()
 	defer m.RUnlock()
@@ -53,6 +78,12 @@ func (m *SubscriptionsMap) decreaseSubFor(pubsubTopic, contentTopic string) {
 }
 
 func (sub *SubscriptionsMap) NewSubscription(peerID peer.ID, cf protocol.ContentFilter) *SubscriptionDetails {
+	return sub.NewSubscriptionWithHandler(peerID, cf, nil)
+}
+
+// NewSubscriptionWithHandler creates a subscription that, in addition to delivering the messages
+// in its channel, invokes the handler synchronously for each message that matches the subscription
+func (sub *SubscriptionsMap) NewSubscriptionWithHandler(peerID peer.ID, cf protocol.ContentFilter, handler MessageHandler) *SubscriptionDetails {
 	sub.Lock()
 	defer sub.Unlock()
 
@@ -63,6 +94,9 @@ func (sub *SubscriptionsMap) NewSubscription(peerID peer.ID, cf protocol.Content
 			SubsPerPubsubTopic: make(map[string]SubscriptionSet),
 		}
 		sub.items[peerID] = peerSubscription
+		if sub.onPeerAdded != nil {
+			sub.onPeerAdded(peerID)
+		}
 	}
 
 	_, ok = peerSubscription.SubsPerPubsubTopic[cf.PubsubTopic]
@@ -77,6 +111,7 @@ func (sub *SubscriptionsMap) NewSubscription(peerID peer.ID, cf protocol.Content
 		C:             make(chan *protocol.Envelope, 1024),
 		ContentFilter: protocol.ContentFilter{PubsubTopic: cf.PubsubTopic, ContentTopics: maps.Clone(cf.ContentTopics)},
 		Closing:       make(chan bool),
+		onMessage:     handler,
 	}
 
 	// Increase the number of subscriptions for this (pubsubTopic, contentTopic) pair
@@ -114,12 +149,23 @@ func (sub *SubscriptionsMap) Has(peerID peer.ID, cf protocol.ContentFilter) bool
 		return false
 	}
 
-	// Check if the content topic exists within the list of subscriptions for this peer
+	// Subscriptions to all the messages of a pubsub topic have no content topics
+	if len(cf.ContentTopics) == 0 {
+		for _, subscription := range subscriptions {
+			if len(subscription.ContentFilter.ContentTopics) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Check if the content topic exists within the list of subscriptions for this peer, or if
+	// the peer is subscribed to all the messages of the pubsub topic
 	for _, ct := range cf.ContentTopicsList() {
 		found := false
 		for _, subscription := range subscriptions {
 			_, exists := subscription.ContentFilter.ContentTopics[ct]
-			if exists {
+			if exists || len(subscription.ContentFilter.ContentTopics) == 0 {
 				found = true
 				break
 			}
@@ -156,18 +202,24 @@ func (sub *SubscriptionsMap) DeleteNoLock(subscription *SubscriptionDetails) err
 	if len(peerSubscription.SubsPerPubsubTopic) == 0 {
 		sub.logger.Debug("no more subs for peer", zap.Stringer("id", subscription.PeerID))
 		delete(sub.items, subscription.PeerID)
+		if sub.onPeerRemoved != nil {
+			sub.onPeerRemoved(subscription.PeerID)
+		}
 	}
 
 	return nil
 }
 
 func (sub *SubscriptionsMap) clear() {
-	for _, peerSubscription := range sub.items {
+	for peerID, peerSubscription := range sub.items {
 		for _, subscriptionSet := range peerSubscription.SubsPerPubsubTopic {
 			for _, subscription := range subscriptionSet {
 				subscription.CloseC()
 			}
 		}
+		if sub.onPeerRemoved != nil {
+			sub.onPeerRemoved(peerID)
+		}
 	}
 
 	sub.items = make(map[peer.ID]*PeerSubscription)
@@ -195,12 +247,17 @@ func iterateSubscriptionSet(ctx context.Context, logger *zap.Logger, subscriptio
 			subscription.RLock()
 			defer subscription.RUnlock()
 
+			// only send the msg to subscriptions that have matching contentTopic,
+			// or that match all the messages of the pubsub topic
 			_, ok := subscription.ContentFilter.ContentTopics[envelope.Message().ContentTopic]
-			if !ok { // only send the msg to subscriptions that have matching contentTopic
+			if !ok && len(subscription.ContentFilter.ContentTopics) != 0 {
 				return
 			}
 
 			if !subscription.Closed {
+				subscription.lastMessageAt.Store(envelope.Index().ReceiverTime)
+				subscription.invokeMessageHandler(logger, envelope)
+
 				select {
 				case <-ctx.Done():
 					return
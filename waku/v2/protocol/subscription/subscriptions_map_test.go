@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/waku-org/go-waku/tests"
 	"github.com/waku-org/go-waku/waku/v2/protocol"
+	"github.com/waku-org/go-waku/waku/v2/protocol/pb"
 	"github.com/waku-org/go-waku/waku/v2/utils"
 	"google.golang.org/protobuf/proto"
 )
@@ -217,3 +218,56 @@ func TestSubscriptionsNotify(t *testing.T) {
 	wg.Wait()
 	<-successChan
 }
+
+func TestSubscriptionMessageHandler(t *testing.T) {
+	fmap := NewSubscriptionMap(utils.Logger())
+	peerID := createPeerID(t)
+
+	var received []MessageMetadata
+	handler := func(subscriptionID string, msg *pb.WakuMessage, meta MessageMetadata) {
+		received = append(received, meta)
+		panic("handler failure")
+	}
+
+	sub := fmap.NewSubscriptionWithHandler(peerID, protocol.ContentFilter{PubsubTopic: PUBSUB_TOPIC, ContentTopics: protocol.NewContentTopicSet("ct1")}, handler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	env := protocol.NewEnvelope(tests.CreateWakuMessage("ct1", proto.Int64(1)), 123, PUBSUB_TOPIC)
+	fmap.Notify(ctx, peerID, env)
+	fmap.Notify(ctx, peerID, protocol.NewEnvelope(tests.CreateWakuMessage("ct2", proto.Int64(1)), 124, PUBSUB_TOPIC))
+
+	// The handler panicked, but the message is still delivered on the channel
+	require.Equal(t, env, <-sub.C)
+
+	require.Len(t, received, 1)
+	require.Equal(t, peerID, received[0].PeerID)
+	require.Equal(t, PUBSUB_TOPIC, received[0].PubsubTopic)
+	require.Equal(t, int64(123), received[0].ReceivedAt.UnixNano())
+}
+
+func TestSubscriptionInfo(t *testing.T) {
+	fmap := NewSubscriptionMap(utils.Logger())
+	peerID := createPeerID(t)
+
+	sub := fmap.NewSubscription(peerID, protocol.ContentFilter{PubsubTopic: PUBSUB_TOPIC, ContentTopics: protocol.NewContentTopicSet("ct1")})
+
+	info := sub.Info()
+	require.Equal(t, sub.ID, info.ID)
+	require.Equal(t, peerID, info.PeerID)
+	require.Equal(t, PUBSUB_TOPIC, info.PubsubTopic)
+	require.Equal(t, []string{"ct1"}, info.ContentTopics)
+	require.True(t, info.LastMessageAt.IsZero())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fmap.Notify(ctx, peerID, protocol.NewEnvelope(tests.CreateWakuMessage("ct1", proto.Int64(1)), 123, PUBSUB_TOPIC))
+	require.Equal(t, int64(123), sub.Info().LastMessageAt.UnixNano())
+
+	// The snapshot is not affected by later changes
+	sub.Add("ct2")
+	require.Equal(t, []string{"ct1"}, info.ContentTopics)
+	require.NoError(t, sub.Close())
+	require.True(t, sub.Info().Closed)
+}
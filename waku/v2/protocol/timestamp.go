@@ -0,0 +1,27 @@
+package protocol
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrFutureMessage indicates that the timestamp of a message is too far in the future
+var ErrFutureMessage = errors.New("message timestamp in the future")
+
+// ErrMessageTooOld indicates that the timestamp of a message is older than the maximum message age
+var ErrMessageTooOld = errors.New("message too old")
+
+// CheckTimestampBounds verifies that a message timestamp is not older than maxMessageAge, nor more
+// than maxFutureDrift ahead of now. A zero value disables the corresponding check
+func CheckTimestampBounds(timestamp int64, now time.Time, maxMessageAge time.Duration, maxFutureDrift time.Duration) error {
+	// Ensure that messages don't "jump" to the front of the queue with future timestamps
+	if maxFutureDrift > 0 && timestamp > now.Add(maxFutureDrift).UnixNano() {
+		return ErrFutureMessage
+	}
+
+	if maxMessageAge > 0 && timestamp < now.Add(-maxMessageAge).UnixNano() {
+		return ErrMessageTooOld
+	}
+
+	return nil
+}
@@ -164,3 +164,36 @@ func TestShardPubsubTopic(t *testing.T) {
 	}
 
 }
+
+func TestShardForContentTopic(t *testing.T) {
+	contentTopic := "/toychat/2/huilong/proto"
+
+	pubsubTopic, err := ShardForContentTopic(contentTopic)
+	require.NoError(t, err)
+	require.Equal(t, NewStaticShardingPubsubTopic(ClusterIndex, 3).String(), pubsubTopic)
+
+	// Result is cached
+	cached, ok := contentTopicShards.get(contentTopic)
+	require.True(t, ok)
+	require.Equal(t, pubsubTopic, cached)
+
+	_, err = ShardForContentTopic("toychat/2/huilong")
+	require.Error(t, err)
+}
+
+func BenchmarkShardForContentTopic(b *testing.B) {
+	contentTopic := "/toychat/2/huilong/proto"
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = ShardForContentTopic(contentTopic)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cTopic, _ := StringToContentTopic(contentTopic)
+			_ = GetShardFromContentTopic(cTopic, GenerationZeroShardsCount).String()
+		}
+	})
+}
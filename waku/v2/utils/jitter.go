@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultJitterFraction is the fraction of an interval used by default to randomize
+// periodic operations, so nodes started at the same time do not act in lockstep
+const DefaultJitterFraction = 0.1
+
+// Jitter returns a random duration within [interval*(1-fraction), interval*(1+fraction)].
+// The interval is returned as is if fraction is not positive
+func Jitter(interval time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || interval <= 0 {
+		return interval
+	}
+
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	delta := fraction * float64(interval)
+	return interval + time.Duration(delta*(2*rand.Float64()-1))
+}
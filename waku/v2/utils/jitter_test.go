@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJitter(t *testing.T) {
+	interval := 10 * time.Second
+
+	values := make(map[time.Duration]struct{})
+	for i := 0; i < 100; i++ {
+		d := Jitter(interval, 0.2)
+		require.GreaterOrEqual(t, d, 8*time.Second)
+		require.LessOrEqual(t, d, 12*time.Second)
+		values[d] = struct{}{}
+	}
+
+	// The effective interval varies between calls
+	require.Greater(t, len(values), 1)
+
+	// No jitter is applied without a fraction
+	require.Equal(t, interval, Jitter(interval, 0))
+}